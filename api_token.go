@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// APITokenPrefix prefixes the secret of an API token to tell it apart from a
+// JSON Web Token.
+const APITokenPrefix = "cce_"
+
+// APIToken is a long-lived token for automation that authenticates as a user.
+// Only the hash of the token secret is persisted, so the secret is only known
+// when the token is created. Deleting the token revokes it.
+type APIToken struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Hash is the hex-encoded SHA-256 hash of the token secret.
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewAPIToken creates an API token for a user and returns it with its secret.
+func NewAPIToken(userID, name string) (*APIToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return &APIToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Hash:      HashAPIToken(secret),
+		CreatedAt: time.Now().UTC(),
+	}, secret, nil
+}
+
+// HashAPIToken returns the hash of an API token secret.
+func HashAPIToken(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetTableName returns the name of the persistence table.
+func (*APIToken) GetTableName() string {
+	return "api_tokens"
+}
+
+// GetID gets the ID.
+func (t *APIToken) GetID() string {
+	return t.ID
+}
+
+// SetID sets the ID.
+func (t *APIToken) SetID(id string) {
+	t.ID = id
+}
+
+// Validate validates the model.
+func (t *APIToken) Validate() error {
+	if !uuid.IsValid(t.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(t.UserID) {
+		return errors.New("user_id not a valid uuid")
+	}
+	if t.Name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if t.Hash == "" {
+		return errors.New("hash cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*APIToken) FilterFields() []string {
+	return []string{
+		"user_id",
+		"hash",
+	}
+}
+
+func (t *APIToken) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+APIToken[
+    ID: %s
+    UserID: %s
+    Name: %s
+    CreatedAt: %s
+]`),
+		t.ID,
+		t.UserID,
+		t.Name,
+		t.CreatedAt)
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: APIToken", func() {
+	var (
+		token  *cce.APIToken
+		secret string
+	)
+
+	BeforeEach(func() {
+		var err error
+		token, secret, err = cce.NewAPIToken("b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54", "test-token")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "api_tokens"`, func() {
+			Expect(token.GetTableName()).To(Equal("api_tokens"))
+		})
+	})
+
+	Describe("NewAPIToken", func() {
+		It("Should only persist the hash of the secret", func() {
+			Expect(secret).To(HavePrefix(cce.APITokenPrefix))
+			Expect(token.Hash).To(Equal(cce.HashAPIToken(secret)))
+			Expect(token.String()).ToNot(ContainSubstring(secret))
+		})
+
+		It("Should generate unique secrets", func() {
+			_, other, err := cce.NewAPIToken(token.UserID, token.Name)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(other).ToNot(Equal(secret))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(token.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if UserID is not a UUID", func() {
+			token.UserID = "123"
+			Expect(token.Validate()).To(MatchError("user_id not a valid uuid"))
+		})
+
+		It("Should return an error if Name is empty", func() {
+			token.Name = ""
+			Expect(token.Validate()).To(MatchError("name cannot be empty"))
+		})
+
+		It("Should return an error if Hash is empty", func() {
+			token.Hash = ""
+			Expect(token.Validate()).To(MatchError("hash cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(token.FilterFields()).To(Equal([]string{
+				"user_id",
+				"hash",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(token.String()).To(HavePrefix(strings.TrimSpace(`
+APIToken[
+    ID: ` + token.ID + `
+    UserID: b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54
+    Name: test-token`,
+			)))
+		})
+	})
+})
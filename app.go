@@ -25,6 +25,11 @@ type App struct {
 	Ports       []PortProto  `json:"ports,omitempty"`
 	Source      string       `json:"source"`
 	EPAFeatures []EPAFeature `json:"epafeatures,omitempty"`
+	// Revision is incremented by every update that requires the nodes running
+	// the app to redeploy it.
+	Revision int `json:"revision,omitempty"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
 }
 
 // PortProto is a port and protocol combination. It is typically used to represent the ports and protocols that an
@@ -60,6 +65,16 @@ func (app *App) SetID(id string) {
 	app.ID = id
 }
 
+// GetResourceVersion gets the resource version.
+func (app *App) GetResourceVersion() uint64 {
+	return app.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (app *App) SetResourceVersion(v uint64) {
+	app.ResourceVersion = v
+}
+
 // Validate validates the model.
 func (app *App) Validate() error { // nolint: gocyclo
 	if !uuid.IsValid(app.ID) {
@@ -107,9 +122,23 @@ func (app *App) Validate() error { // nolint: gocyclo
 	return nil
 }
 
+// RedeployRequired returns whether the nodes running the app must redeploy it
+// to run the updated app.
+func (app *App) RedeployRequired(updated *App) bool {
+	return app.Source != updated.Source ||
+		app.Version != updated.Version ||
+		app.Cores != updated.Cores ||
+		app.Memory != updated.Memory
+}
+
 // FilterFields returns the filterable fields for this model.
 func (*App) FilterFields() []string {
-	return []string{}
+	return []string{
+		"type",
+		"name",
+		"version",
+		"vendor",
+	}
 }
 
 func (app *App) String() string {
@@ -125,6 +154,7 @@ App[
     Ports: %s
     Source: %s
     EPAFeatures: %s
+    Revision: %d
 ]`),
 		app.ID,
 		app.Name,
@@ -135,5 +165,6 @@ App[
 		app.Memory,
 		app.Ports,
 		app.Source,
-		app.EPAFeatures)
+		app.EPAFeatures,
+		app.Revision)
 }
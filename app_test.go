@@ -129,6 +129,40 @@ var _ = Describe("Entities: App", func() {
 		})
 	})
 
+	Describe("RedeployRequired", func() {
+		var updated *cce.App
+
+		BeforeEach(func() {
+			updated = &cce.App{}
+			*updated = *app
+		})
+
+		It("Should return false if only the description changed", func() {
+			updated.Description = "updated-description"
+			Expect(app.RedeployRequired(updated)).To(BeFalse())
+		})
+
+		It("Should return true if Source changed", func() {
+			updated.Source = "https://path/to/updated.zip"
+			Expect(app.RedeployRequired(updated)).To(BeTrue())
+		})
+
+		It("Should return true if Version changed", func() {
+			updated.Version = "2.0"
+			Expect(app.RedeployRequired(updated)).To(BeTrue())
+		})
+
+		It("Should return true if Cores changed", func() {
+			updated.Cores = 2
+			Expect(app.RedeployRequired(updated)).To(BeTrue())
+		})
+
+		It("Should return true if Memory changed", func() {
+			updated.Memory = 2048
+			Expect(app.RedeployRequired(updated)).To(BeTrue())
+		})
+	})
+
 	Describe("String", func() {
 		It("Should return the string value", func() {
 			Expect(app.String()).To(Equal(strings.TrimSpace(`
@@ -143,6 +177,7 @@ App[
     Ports: [80/tcp 443/tcp]
     Source: https://path/to/file.zip
     EPAFeatures: []
+    Revision: 0
 ]`,
 			)))
 		})
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// AuditTimestampFormat is the format of AuditEvent timestamps. It is RFC 3339
+// in UTC with a fixed number of fractional digits so that timestamps sort
+// lexically.
+const AuditTimestampFormat = "2006-01-02T15:04:05.000000000Z"
+
+// Results of an audited call.
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+)
+
+// AuditActorNodePrefix prefixes the serial of a node in the actor of an
+// audited node enrollment.
+const AuditActorNodePrefix = "node:"
+
+// AuditActorLoginPrefix prefixes the attempted username in the actor of an
+// audited failed login.
+const AuditActorLoginPrefix = "login:"
+
+// AuditActorAnonymous is the actor of an audited call without valid
+// credentials.
+const AuditActorAnonymous = "anonymous"
+
+// AuditEvent records a call that changed the state of the controller. Audit
+// events are append-only: they are never updated or deleted.
+type AuditEvent struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	// Actor is the ID of the user making the call, the serial of the node
+	// prefixed by AuditActorNodePrefix for a node enrollment, the attempted
+	// username prefixed by AuditActorLoginPrefix for a failed login or
+	// AuditActorAnonymous for a call without valid credentials.
+	Actor string `json:"actor"`
+	// Method is the HTTP method, or "gRPC" for a gRPC call.
+	Method string `json:"method"`
+	// Route is the route template of an HTTP call or the full method name of
+	// a gRPC call.
+	Route string `json:"route"`
+	// Path is the request path of an HTTP call.
+	Path     string `json:"path,omitempty"`
+	EntityID string `json:"entity_id,omitempty"`
+	// Before and After are the representations of the entity before and after
+	// the call, if it exists.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+	// Status is the HTTP status code or gRPC status code of the call.
+	Status int    `json:"status"`
+	Result string `json:"result"`
+}
+
+// NewAuditEvent creates an audit event with a new ID timestamped now.
+func NewAuditEvent(actor, method, route string) *AuditEvent {
+	return &AuditEvent{
+		ID:        uuid.New(),
+		Timestamp: time.Now().UTC().Format(AuditTimestampFormat),
+		Actor:     actor,
+		Method:    method,
+		Route:     route,
+	}
+}
+
+// GetTableName returns the name of the persistence table.
+func (*AuditEvent) GetTableName() string {
+	return "audit_events"
+}
+
+// GetID gets the ID.
+func (e *AuditEvent) GetID() string {
+	return e.ID
+}
+
+// SetID sets the ID.
+func (e *AuditEvent) SetID(id string) {
+	e.ID = id
+}
+
+// Validate validates the model.
+func (e *AuditEvent) Validate() error {
+	if !uuid.IsValid(e.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if _, err := time.Parse(AuditTimestampFormat, e.Timestamp); err != nil {
+		return fmt.Errorf("timestamp %q not in format %s", e.Timestamp, AuditTimestampFormat)
+	}
+	if e.Actor == "" {
+		return errors.New("actor cannot be empty")
+	}
+	if e.Method == "" {
+		return errors.New("method cannot be empty")
+	}
+	if e.Route == "" {
+		return errors.New("route cannot be empty")
+	}
+	if e.Result != AuditResultSuccess && e.Result != AuditResultFailure {
+		return fmt.Errorf("result must be %s or %s", AuditResultSuccess, AuditResultFailure)
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*AuditEvent) FilterFields() []string {
+	return []string{
+		"timestamp",
+		"actor",
+		"method",
+		"route",
+		"entity_id",
+		"result",
+	}
+}
+
+func (e *AuditEvent) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+AuditEvent[
+    ID: %s
+    Timestamp: %s
+    Actor: %s
+    Method: %s
+    Route: %s
+    Path: %s
+    EntityID: %s
+    Status: %d
+    Result: %s
+]`),
+		e.ID,
+		e.Timestamp,
+		e.Actor,
+		e.Method,
+		e.Route,
+		e.Path,
+		e.EntityID,
+		e.Status,
+		e.Result)
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: AuditEvent", func() {
+	var event *cce.AuditEvent
+
+	BeforeEach(func() {
+		event = &cce.AuditEvent{
+			ID:        "3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b",
+			Timestamp: "2019-12-01T10:00:00.500000000Z",
+			Actor:     "b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54",
+			Method:    "PATCH",
+			Route:     "/nodes/{node_id}",
+			Path:      "/nodes/9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d",
+			EntityID:  "9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d",
+			Status:    200,
+			Result:    cce.AuditResultSuccess,
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "audit_events"`, func() {
+			Expect(event.GetTableName()).To(Equal("audit_events"))
+		})
+	})
+
+	Describe("NewAuditEvent", func() {
+		It("Should create a valid event timestamped now", func() {
+			e := cce.NewAuditEvent("test-actor", "POST", "/nodes")
+			e.Result = cce.AuditResultSuccess
+			Expect(e.Validate()).To(Succeed())
+		})
+
+		It("Should create timestamps that sort in time order", func() {
+			first := cce.NewAuditEvent("test-actor", "POST", "/nodes")
+			second := cce.NewAuditEvent("test-actor", "POST", "/nodes")
+			Expect(len(first.Timestamp)).To(Equal(len(second.Timestamp)))
+			Expect(first.Timestamp <= second.Timestamp).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(event.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			event.ID = "123"
+			Expect(event.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if Timestamp is not in the audit format", func() {
+			event.Timestamp = "2019-12-01T10:00:00Z"
+			Expect(event.Validate()).To(MatchError(
+				`timestamp "2019-12-01T10:00:00Z" not in format ` + cce.AuditTimestampFormat))
+		})
+
+		It("Should return an error if Actor is empty", func() {
+			event.Actor = ""
+			Expect(event.Validate()).To(MatchError("actor cannot be empty"))
+		})
+
+		It("Should return an error if Method is empty", func() {
+			event.Method = ""
+			Expect(event.Validate()).To(MatchError("method cannot be empty"))
+		})
+
+		It("Should return an error if Route is empty", func() {
+			event.Route = ""
+			Expect(event.Validate()).To(MatchError("route cannot be empty"))
+		})
+
+		It("Should return an error if Result is invalid", func() {
+			event.Result = "unknown"
+			Expect(event.Validate()).To(MatchError("result must be success or failure"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(event.FilterFields()).To(Equal([]string{
+				"timestamp",
+				"actor",
+				"method",
+				"route",
+				"entity_id",
+				"result",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(event.String()).To(Equal(strings.TrimSpace(`
+AuditEvent[
+    ID: 3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b
+    Timestamp: 2019-12-01T10:00:00.500000000Z
+    Actor: b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54
+    Method: PATCH
+    Route: /nodes/{node_id}
+    Path: /nodes/9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d
+    EntityID: 9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d
+    Status: 200
+    Result: success
+]`,
+			)))
+		})
+	})
+})
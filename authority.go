@@ -3,13 +3,22 @@
 
 package cce
 
-import "crypto/x509"
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"time"
+)
 
 // AuthorityService manages digital certificates.
 type AuthorityService interface {
 	// CAChain returns the certificate authority chain, starting with the
 	// issuing CA and ending with the root CA (inclusive).
 	CAChain() ([]*x509.Certificate, error)
-	// SignCSR signs a ASN.1 DER encoded certificate signing request.
+	// SignCSR signs a ASN.1 DER encoded certificate signing request. The
+	// certificate expires at the NotAfter of the template, if set, or
+	// earlier if the issuing CA expires first.
 	SignCSR(der []byte, template *x509.Certificate) (*x509.Certificate, error)
+	// CreateCRL creates a DER-encoded certificate revocation list of the
+	// revoked certificates signed by the issuing CA, valid until nextUpdate.
+	CreateCRL(revoked []pkix.RevokedCertificate, nextUpdate time.Time) ([]byte, error)
 }
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package bolt_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBolt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bolt Persistence Suite")
+}
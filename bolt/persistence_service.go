@@ -0,0 +1,609 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package bolt
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"sort"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// entitiesBucket holds the JSON-encoded entities of a table keyed by
+	// insertion sequence, so that reads return entities in insertion order.
+	entitiesBucket = []byte("entities")
+	// idsBucket indexes the insertion sequence of an entity by its ID.
+	idsBucket = []byte("ids")
+)
+
+// PersistenceService implements cce.PersistenceService on an embedded BoltDB
+// file. Each table from the schema is stored in its own bucket and the unique
+// and foreign key constraints of the mysql schema are enforced within the
+// write transaction.
+type PersistenceService struct {
+	DB *bolt.DB
+}
+
+// Create persists a resource.
+func (s *PersistenceService) Create(
+	ctx context.Context,
+	e cce.Persistable,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return insert(tx, e)
+	})
+}
+
+// insert inserts the entity in the transaction.
+func insert(tx *bolt.Tx, e cce.Persistable) error {
+	if v, ok := e.(cce.Versioned); ok && v.GetResourceVersion() == 0 {
+		v.SetResourceVersion(1)
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling")
+	}
+
+	tbl, err := createBucket(tx, e.GetTableName())
+	if err != nil {
+		return err
+	}
+	if tbl.Bucket(idsBucket).Get([]byte(e.GetID())) != nil {
+		return errors.Errorf("error inserting record: duplicate entry %q for key 'id'", e.GetID())
+	}
+	if err = checkConstraints(tx, e.GetTableName(), e.GetID(), bytes); err != nil {
+		return errors.Wrap(err, "error inserting record")
+	}
+
+	seq, err := tbl.Bucket(entitiesBucket).NextSequence()
+	if err != nil {
+		return errors.Wrap(err, "error inserting record")
+	}
+	key := itob(seq)
+	if err = tbl.Bucket(entitiesBucket).Put(key, bytes); err != nil {
+		return errors.Wrap(err, "error inserting record")
+	}
+	return tbl.Bucket(idsBucket).Put([]byte(e.GetID()), key)
+}
+
+// Read retrieves a single resource of the given type by ID.
+func (s *PersistenceService) Read(
+	ctx context.Context,
+	id string,
+	zv cce.Persistable,
+) (e cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		bytes := get(tx, zv.GetTableName(), id)
+		if bytes == nil {
+			return nil
+		}
+		e, err = scan(bytes, zv)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Filter retrieves a collection of resources of the given type using a set of
+// filters.
+func (s *PersistenceService) Filter(
+	ctx context.Context,
+	zv cce.Filterable,
+	fs []cce.Filter,
+) (es []cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ffs := zv.FilterFields()
+	sort.Strings(ffs)
+
+	for _, f := range fs {
+		// Only whitelisted filters are allowed, to match the mysql
+		// implementation
+		i := sort.SearchStrings(ffs, f.Field)
+		if i == len(ffs) || ffs[i] != f.Field {
+			return nil, errors.Errorf("disallowed filter field %q", f.Field)
+		}
+	}
+
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		return forEach(tx, zv.GetTableName(), func(bytes []byte) error {
+			cols, err := columns(bytes)
+			if err != nil {
+				return err
+			}
+			for _, f := range fs {
+				if v, ok := cols[f.Field]; !ok || v != f.Value {
+					return nil
+				}
+			}
+
+			e, err := scan(bytes, zv)
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// FilterPage retrieves a sorted page of a collection of resources of the given
+// type using a set of filters. The returned token requests the next page and
+// is empty on the last page.
+func (s *PersistenceService) FilterPage(
+	ctx context.Context,
+	zv cce.Filterable,
+	q cce.Query,
+) (es []cce.Persistable, next string, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if err = q.Validate(zv); err != nil {
+		return nil, "", errors.Wrap(cce.ErrInvalidQuery, err.Error())
+	}
+	cursor, _ := q.Cursor()
+	field, desc := q.SortField()
+
+	// less orders entities by sort value then ID, like the mysql
+	// implementation
+	type sortable struct {
+		value, id string
+		e         cce.Persistable
+	}
+	less := func(a, b sortable) bool {
+		if desc {
+			a, b = b, a
+		}
+		return a.value < b.value || a.value == b.value && a.id < b.id
+	}
+
+	var matches []sortable
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		return forEach(tx, zv.GetTableName(), func(bytes []byte) error {
+			cols, err := columns(bytes)
+			if err != nil {
+				return err
+			}
+			for _, f := range q.Filters {
+				if v, ok := cols[f.Field]; !ok || v != f.Value {
+					return nil
+				}
+			}
+			if !q.Selector.Empty() {
+				var labeled struct {
+					Labels map[string]string `json:"labels"`
+				}
+				if err = json.Unmarshal(bytes, &labeled); err != nil {
+					return errors.Wrap(err, "error unmarshaling")
+				}
+				if !q.Selector.Matches(labeled.Labels) {
+					return nil
+				}
+			}
+
+			m := sortable{value: cols[field], id: cols["id"]}
+			if cursor != nil && !less(sortable{value: cursor.Value, id: cursor.ID}, m) {
+				return nil
+			}
+			if m.e, err = scan(bytes, zv); err != nil {
+				return err
+			}
+			matches = append(matches, m)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+	if q.Limit > 0 && len(matches) > q.Limit {
+		matches = matches[:q.Limit]
+		last := matches[len(matches)-1]
+		next = (&cce.PageCursor{Sort: q.Sort, Value: last.value, ID: last.id}).Token()
+	}
+	for _, m := range matches {
+		es = append(es, m.e)
+	}
+
+	return es, next, nil
+}
+
+// ReadAll retrieves all resources of the given type.
+func (s *PersistenceService) ReadAll(
+	ctx context.Context,
+	zv cce.Persistable,
+) (es []cce.Persistable, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		return forEach(tx, zv.GetTableName(), func(bytes []byte) error {
+			e, err := scan(bytes, zv)
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+// BulkUpdate updates multiple resources. All updates are applied in a single
+// transaction. The resource version of Versioned resources is checked and
+// incremented.
+func (s *PersistenceService) BulkUpdate(
+	ctx context.Context,
+	es []cce.Persistable,
+) error {
+	return s.BulkWrite(ctx, nil, es)
+}
+
+// BulkWrite creates and updates multiple resources in a single transaction,
+// the creates first. Resources are created and updated as by Create and
+// BulkUpdate.
+func (s *PersistenceService) BulkWrite(
+	ctx context.Context,
+	creates []cce.Persistable,
+	updates []cce.Persistable,
+) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		for _, e := range creates {
+			if err := insert(tx, e); err != nil {
+				return err
+			}
+		}
+		for _, e := range updates {
+			if err := update(tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// update updates the entity in the transaction.
+func update(tx *bolt.Tx, e cce.Persistable) error {
+	tbl, err := createBucket(tx, e.GetTableName())
+	if err != nil {
+		return err
+	}
+	key := tbl.Bucket(idsBucket).Get([]byte(e.GetID()))
+	if key == nil {
+		// nothing to update, like an UPDATE matching no rows
+		return nil
+	}
+
+	if v, ok := e.(cce.Versioned); ok {
+		current, err := resourceVersion(tbl.Bucket(entitiesBucket).Get(key))
+		if err != nil {
+			return err
+		}
+		if v.GetResourceVersion() != 0 && v.GetResourceVersion() != current {
+			return errors.Wrapf(cce.ErrVersionConflict,
+				"%s %s is at version %d, not %d",
+				e.GetTableName(), e.GetID(), current, v.GetResourceVersion())
+		}
+		v.SetResourceVersion(current + 1)
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling")
+	}
+	if err = checkConstraints(tx, e.GetTableName(), e.GetID(), bytes); err != nil {
+		return errors.Wrap(err, "error updating record")
+	}
+	if err = tbl.Bucket(entitiesBucket).Put(key, bytes); err != nil {
+		return errors.Wrap(err, "error updating record")
+	}
+	return nil
+}
+
+// Delete deletes a resource of the given type. Entities referencing the
+// resource are deleted if their foreign key cascades, otherwise the delete
+// fails. If zv is Versioned with a non-zero resource version, the resource is
+// only deleted if the version matches.
+func (s *PersistenceService) Delete(
+	ctx context.Context,
+	id string,
+	zv cce.Persistable,
+) (ok bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		if v, ok := zv.(cce.Versioned); ok && v.GetResourceVersion() != 0 {
+			bytes := get(tx, zv.GetTableName(), id)
+			if bytes == nil {
+				return nil
+			}
+			current, err := resourceVersion(bytes)
+			if err != nil {
+				return err
+			}
+			if current != v.GetResourceVersion() {
+				return errors.Wrapf(cce.ErrVersionConflict,
+					"%s %s is at version %d, not %d",
+					zv.GetTableName(), id, current, v.GetResourceVersion())
+			}
+		}
+		ok, err = deleteByID(tx, zv.GetTableName(), id)
+		return err
+	})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		return false, err
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "error deleting record")
+	}
+
+	return ok, nil
+}
+
+func deleteByID(tx *bolt.Tx, tableName, id string) (bool, error) {
+	tbl := tx.Bucket([]byte(tableName))
+	if tbl == nil {
+		return false, nil
+	}
+	key := tbl.Bucket(idsBucket).Get([]byte(id))
+	if key == nil {
+		return false, nil
+	}
+	// Copy the key since it is invalidated by the writes below
+	key = append([]byte(nil), key...)
+
+	// Resolve the entities referencing this one before deleting it
+	for childName, child := range schema {
+		for _, fk := range child.foreignKeys {
+			if fk.table != tableName {
+				continue
+			}
+			childIDs, err := referencing(tx, childName, fk.field, id)
+			if err != nil {
+				return false, err
+			}
+			if len(childIDs) > 0 && !fk.onDeleteCascade {
+				return false, errors.Errorf(
+					"cannot delete or update a parent row: a foreign key constraint fails (%s.%s references %s.id)",
+					childName, fk.field, tableName)
+			}
+			for _, childID := range childIDs {
+				if _, err = deleteByID(tx, childName, childID); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	if err := tbl.Bucket(entitiesBucket).Delete(key); err != nil {
+		return false, err
+	}
+	if err := tbl.Bucket(idsBucket).Delete([]byte(id)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// referencing returns the IDs of the entities in a table whose field equals
+// the value.
+func referencing(tx *bolt.Tx, tableName, field, value string) ([]string, error) {
+	var ids []string
+	err := forEach(tx, tableName, func(bytes []byte) error {
+		cols, err := columns(bytes)
+		if err != nil {
+			return err
+		}
+		if v, ok := cols[field]; ok && v == value {
+			ids = append(ids, cols["id"])
+		}
+		return nil
+	})
+	return ids, err
+}
+
+// checkConstraints verifies the unique and foreign keys of the table for an
+// entity about to be written.
+func checkConstraints(tx *bolt.Tx, tableName, id string, bytes []byte) error {
+	tbl, ok := schema[tableName]
+	if !ok {
+		return errors.Errorf("table %q doesn't exist", tableName)
+	}
+
+	cols, err := columns(bytes)
+	if err != nil {
+		return err
+	}
+
+	for _, fk := range tbl.foreignKeys {
+		v, ok := cols[fk.field]
+		if !ok {
+			continue
+		}
+		if get(tx, fk.table, v) == nil {
+			return errors.Errorf(
+				"cannot add or update a child row: a foreign key constraint fails (%s.%s references %s.id)",
+				tableName, fk.field, fk.table)
+		}
+	}
+
+	for _, uk := range tbl.uniqueKeys {
+		var (
+			values []string
+			isNull bool
+		)
+		for _, field := range uk {
+			v, ok := cols[field]
+			if !ok {
+				// NULL values never collide in a unique key
+				isNull = true
+				break
+			}
+			values = append(values, v)
+		}
+		if isNull {
+			continue
+		}
+
+		err = forEach(tx, tableName, func(other []byte) error {
+			otherCols, err := columns(other)
+			if err != nil {
+				return err
+			}
+			if otherCols["id"] == id {
+				return nil
+			}
+			for i, field := range uk {
+				if otherCols[field] != values[i] {
+					return nil
+				}
+			}
+			return errors.Errorf("duplicate entry %q for key %q", values, uk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createBucket returns the bucket for a table, creating it if needed.
+func createBucket(tx *bolt.Tx, tableName string) (*bolt.Bucket, error) {
+	if _, ok := schema[tableName]; !ok {
+		return nil, errors.Errorf("table %q doesn't exist", tableName)
+	}
+
+	tbl, err := tx.CreateBucketIfNotExists([]byte(tableName))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating bucket %s", tableName)
+	}
+	if _, err = tbl.CreateBucketIfNotExists(entitiesBucket); err != nil {
+		return nil, errors.Wrapf(err, "error creating bucket %s", tableName)
+	}
+	if _, err = tbl.CreateBucketIfNotExists(idsBucket); err != nil {
+		return nil, errors.Wrapf(err, "error creating bucket %s", tableName)
+	}
+
+	return tbl, nil
+}
+
+// get returns the JSON-encoded entity of a table by ID or nil if it does not
+// exist.
+func get(tx *bolt.Tx, tableName, id string) []byte {
+	tbl := tx.Bucket([]byte(tableName))
+	if tbl == nil {
+		return nil
+	}
+	key := tbl.Bucket(idsBucket).Get([]byte(id))
+	if key == nil {
+		return nil
+	}
+	return tbl.Bucket(entitiesBucket).Get(key)
+}
+
+// forEach calls fn with each JSON-encoded entity of a table in insertion
+// order.
+func forEach(tx *bolt.Tx, tableName string, fn func([]byte) error) error {
+	tbl := tx.Bucket([]byte(tableName))
+	if tbl == nil {
+		return nil
+	}
+	return tbl.Bucket(entitiesBucket).ForEach(func(_, v []byte) error {
+		return fn(v)
+	})
+}
+
+// columns extracts the top-level fields of a JSON-encoded entity the way the
+// generated columns of the mysql schema do (entity->>'$.field'). Fields that
+// are absent or null are omitted.
+func columns(bytes []byte) (map[string]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(bytes, &fields); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling")
+	}
+
+	cols := make(map[string]string, len(fields))
+	for k, raw := range fields {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling")
+		}
+		switch v := v.(type) {
+		case nil:
+		case string:
+			cols[k] = v
+		default:
+			cols[k] = string(raw)
+		}
+	}
+
+	return cols, nil
+}
+
+// resourceVersion returns the resource version of a JSON-encoded entity, or 0
+// if it has none.
+func resourceVersion(bytes []byte) (uint64, error) {
+	var e struct {
+		ResourceVersion uint64 `json:"resource_version"`
+	}
+	if err := json.Unmarshal(bytes, &e); err != nil {
+		return 0, errors.Wrap(err, "error unmarshaling")
+	}
+
+	return e.ResourceVersion, nil
+}
+
+func scan(bytes []byte, zv cce.Persistable) (cce.Persistable, error) {
+	e := reflect.New(reflect.ValueOf(zv).Elem().Type()).Interface().(cce.Persistable)
+	if err := json.Unmarshal(bytes, e); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling")
+	}
+
+	return e, nil
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
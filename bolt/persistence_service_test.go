@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package bolt_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("PersistenceService", func() {
+	var (
+		ctx    = context.Background()
+		tmpDir string
+		db     *bbolt.DB
+		ps     *bolt.PersistenceService
+		node   *cce.Node
+		app    *cce.App
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		By("Opening a db in a temp directory")
+		tmpDir, err = ioutil.TempDir("", "bolt_test")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bbolt.Open(filepath.Join(tmpDir, "controller_ce.db"), 0600, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		By("Creating a node and an app")
+		node = &cce.Node{
+			ID:       uuid.New(),
+			Name:     "test-node",
+			Location: "test-location",
+			Serial:   "test-serial",
+		}
+		Expect(ps.Create(ctx, node)).To(Succeed())
+		app = &cce.App{
+			ID:      uuid.New(),
+			Type:    "container",
+			Name:    "test-app",
+			Version: "1.0",
+			Vendor:  "test-vendor",
+			Cores:   4,
+			Memory:  1024,
+			Source:  "http://www.test.com/test.tar.gz",
+		}
+		Expect(ps.Create(ctx, app)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	Describe("Create and Read", func() {
+		It("Should read back a created entity", func() {
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e).To(Equal(node))
+		})
+
+		It("Should return nil for an unknown ID", func() {
+			e, err := ps.Read(ctx, uuid.New(), &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e).To(BeNil())
+		})
+
+		It("Should fail on a duplicate ID", func() {
+			Expect(ps.Create(ctx, node)).ToNot(Succeed())
+		})
+
+		It("Should fail on a duplicate serial", func() {
+			Expect(ps.Create(ctx, &cce.Node{ID: uuid.New(), Name: "n2", Location: "l", Serial: node.Serial})).
+				ToNot(Succeed())
+		})
+	})
+
+	Describe("ReadAll", func() {
+		It("Should return entities in insertion order", func() {
+			node2 := &cce.Node{ID: uuid.New(), Name: "n2", Location: "l2", Serial: "s2"}
+			Expect(ps.Create(ctx, node2)).To(Succeed())
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node, node2}))
+		})
+	})
+
+	Describe("Filter", func() {
+		It("Should return entities matching all filters", func() {
+			es, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "serial", Value: "test-serial"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+
+			es, err = ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "serial", Value: "other"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(BeEmpty())
+		})
+
+		It("Should reject fields that are not filterable", func() {
+			_, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "id", Value: node.ID}})
+			Expect(err).To(MatchError(`disallowed filter field "id"`))
+		})
+	})
+
+	Describe("FilterPage", func() {
+		var nodes []cce.Persistable
+
+		BeforeEach(func() {
+			nodes = []cce.Persistable{node}
+			for _, name := range []string{"c", "a", "b"} {
+				n := &cce.Node{ID: uuid.New(), Name: name, Location: "l", Serial: "s-" + name}
+				Expect(ps.Create(ctx, n)).To(Succeed())
+				nodes = append(nodes, n)
+			}
+		})
+
+		It("Should sort and page through the entities", func() {
+			q := cce.Query{
+				Filters: []cce.Filter{{Field: "location", Value: "l"}},
+				Sort:    "-name",
+				Limit:   2,
+			}
+			es, next, err := ps.FilterPage(ctx, &cce.Node{}, q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[1], nodes[3]}))
+			Expect(next).ToNot(BeEmpty())
+
+			q.PageToken = next
+			es, next, err = ps.FilterPage(ctx, &cce.Node{}, q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[2]}))
+			Expect(next).To(BeEmpty())
+		})
+
+		It("Should select the entities by their labels", func() {
+			nodes[1].(*cce.Node).Labels = map[string]string{"region": "eu", "tier": "far-edge"}
+			nodes[2].(*cce.Node).Labels = map[string]string{"region": "eu"}
+			Expect(ps.BulkUpdate(ctx, nodes[1:3])).To(Succeed())
+
+			sel, err := cce.ParseSelector("region=eu,tier!=far-edge")
+			Expect(err).ToNot(HaveOccurred())
+			es, _, err := ps.FilterPage(ctx, &cce.Node{}, cce.Query{Selector: sel})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[2]}))
+		})
+
+		It("Should reject fields that are not filterable", func() {
+			_, _, err := ps.FilterPage(ctx, &cce.Node{}, cce.Query{Sort: "resource_version"})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrInvalidQuery))
+		})
+
+		It("Should reject selectors of entities without labels", func() {
+			sel, err := cce.ParseSelector("region=eu")
+			Expect(err).ToNot(HaveOccurred())
+			_, _, err = ps.FilterPage(ctx, &cce.App{}, cce.Query{Selector: sel})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrInvalidQuery))
+		})
+	})
+
+	Describe("BulkUpdate", func() {
+		It("Should update existing entities", func() {
+			node.Name = "updated"
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).Name).To(Equal("updated"))
+		})
+
+		It("Should increment the resource version", func() {
+			Expect(node.ResourceVersion).To(Equal(uint64(1)))
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+			Expect(node.ResourceVersion).To(Equal(uint64(2)))
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).ResourceVersion).To(Equal(uint64(2)))
+		})
+
+		It("Should reject a stale resource version", func() {
+			stale := *node
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+
+			stale.Name = "stale"
+			err := ps.BulkUpdate(ctx, []cce.Persistable{&stale})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).Name).To(Equal("test-node"))
+		})
+	})
+
+	Describe("BulkWrite", func() {
+		It("Should create and update the entities", func() {
+			created := &cce.Node{ID: uuid.New(), Name: "n2", Location: "l", Serial: "s2"}
+			node.Name = "updated"
+			Expect(ps.BulkWrite(ctx, []cce.Persistable{created}, []cce.Persistable{node})).To(Succeed())
+			Expect(created.ResourceVersion).To(Equal(uint64(1)))
+			Expect(node.ResourceVersion).To(Equal(uint64(2)))
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node, created}))
+		})
+
+		It("Should write nothing if a write fails", func() {
+			created := &cce.Node{ID: uuid.New(), Name: "n2", Location: "l", Serial: "s2"}
+			duplicate := &cce.Node{ID: node.ID, Name: "n3", Location: "l", Serial: "s3"}
+			updated := *node
+			updated.Name = "updated"
+			Expect(ps.BulkWrite(
+				ctx,
+				[]cce.Persistable{created, duplicate},
+				[]cce.Persistable{&updated})).ToNot(Succeed())
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+		})
+	})
+
+	Describe("Constraints", func() {
+		It("Should enforce foreign keys on create", func() {
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     uuid.New(),
+				NodeID: uuid.New(),
+				AppID:  app.ID,
+			})).ToNot(Succeed())
+		})
+
+		It("Should enforce unique keys on create", func() {
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     uuid.New(),
+				NodeID: node.ID,
+				AppID:  app.ID,
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     uuid.New(),
+				NodeID: node.ID,
+				AppID:  app.ID,
+			})).ToNot(Succeed())
+		})
+
+		It("Should restrict deleting a referenced entity", func() {
+			Expect(ps.Create(ctx, &cce.NodeApp{
+				ID:     uuid.New(),
+				NodeID: node.ID,
+				AppID:  app.ID,
+			})).To(Succeed())
+
+			ok, err := ps.Delete(ctx, app.ID, &cce.App{})
+			Expect(err).To(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Should cascade deletes to node_grpc_targets", func() {
+			target := &cce.NodeGRPCTarget{
+				ID:         uuid.New(),
+				NodeID:     node.ID,
+				GRPCTarget: "127.0.0.1",
+			}
+			Expect(ps.Create(ctx, target)).To(Succeed())
+
+			ok, err := ps.Delete(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+
+			e, err := ps.Read(ctx, target.ID, &cce.NodeGRPCTarget{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e).To(BeNil())
+		})
+	})
+
+	Describe("Delete", func() {
+		It("Should return false for an unknown ID", func() {
+			ok, err := ps.Delete(ctx, uuid.New(), &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Should reject a stale resource version", func() {
+			_, err := ps.Delete(ctx, node.ID, &cce.Node{ResourceVersion: 2})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+			ok, err := ps.Delete(ctx, node.ID, &cce.Node{ResourceVersion: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package bolt
+
+// table describes the constraints of a persistence table. It mirrors the
+// generated columns and keys created by the mysql migrations so that both
+// implementations of cce.PersistenceService reject the same writes.
+type table struct {
+	// uniqueKeys are the sets of entity fields that must be unique across the
+	// table. The id field is always unique and is not listed here.
+	uniqueKeys [][]string
+	// foreignKeys are the entity fields that reference the id of an entity in
+	// another table.
+	foreignKeys []foreignKey
+}
+
+// foreignKey references the id of an entity in another table.
+type foreignKey struct {
+	field string
+	table string
+	// onDeleteCascade deletes the referencing entity when the referenced
+	// entity is deleted. Otherwise the delete is restricted.
+	onDeleteCascade bool
+}
+
+var schema = map[string]table{
+	// -------------
+	// Entity tables
+	// -------------
+
+	"nodes": {
+		uniqueKeys: [][]string{
+			{"serial"},
+		},
+	},
+
+	// the grpc target for a node may or may not exist yet, so we cascade
+	// deletes to handle deletion without requiring extra logic in the code
+	"node_grpc_targets": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+			{"grpc_target"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", onDeleteCascade: true},
+		},
+	},
+
+	// the health and status events of a node are deleted with it
+	"node_health": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", onDeleteCascade: true},
+		},
+	},
+
+	"node_status_events": {
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", onDeleteCascade: true},
+		},
+	},
+
+	// the drift report of a node is deleted with it
+	"node_drift": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", onDeleteCascade: true},
+		},
+	},
+
+	// the operations on a node are deleted with it
+	"operations": {
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes", onDeleteCascade: true},
+		},
+	},
+
+	"apps": {},
+
+	"rollouts": {},
+
+	"node_groups": {},
+
+	"interface_profiles": {},
+
+	"traffic_policies": {},
+
+	"dns_configs": {},
+
+	"credentials": {},
+
+	"users": {
+		uniqueKeys: [][]string{
+			{"username"},
+		},
+	},
+
+	// revoking a token deletes it and deleting a user revokes its tokens
+	"api_tokens": {
+		uniqueKeys: [][]string{
+			{"hash"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "user_id", table: "users", onDeleteCascade: true},
+		},
+	},
+
+	// revoked tokens outlive their user until they expire
+	"revoked_tokens": {},
+
+	// audit events are append-only
+	"audit_events": {},
+
+	// revoked certificates outlive their node until they expire
+	"revoked_certificates": {
+		uniqueKeys: [][]string{
+			{"serial_number"},
+		},
+	},
+
+	"pending_nodes": {
+		uniqueKeys: [][]string{
+			{"serial"},
+		},
+	},
+
+	// -------------------
+	// Primary join tables
+	// -------------------
+
+	// dns_configs x apps
+	"dns_configs_app_aliases": {
+		uniqueKeys: [][]string{
+			{"dns_config_id", "app_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "dns_config_id", table: "dns_configs"},
+			{field: "app_id", table: "apps"},
+		},
+	},
+
+	// nodes x apps
+	"nodes_apps": {
+		uniqueKeys: [][]string{
+			{"node_id", "app_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "app_id", table: "apps"},
+		},
+	},
+
+	// nodes x dns_configs
+	"nodes_dns_configs": {
+		uniqueKeys: [][]string{
+			{"node_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "dns_config_id", table: "dns_configs"},
+		},
+	},
+
+	// nodes (network_interfaces) x traffic_policies
+	"nodes_network_interfaces_traffic_policies": {
+		uniqueKeys: [][]string{
+			{"node_id", "network_interface_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "node_id", table: "nodes"},
+			{field: "traffic_policy_id", table: "traffic_policies"},
+		},
+	},
+
+	// ---------------------
+	// Secondary join tables
+	// ---------------------
+
+	// nodes_apps x traffic_policies
+	"nodes_apps_traffic_policies": {
+		uniqueKeys: [][]string{
+			{"nodes_apps_id", "traffic_policy_id"},
+		},
+		foreignKeys: []foreignKey{
+			{field: "nodes_apps_id", table: "nodes_apps"},
+			{field: "traffic_policy_id", table: "traffic_policies"},
+		},
+	},
+}
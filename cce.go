@@ -6,11 +6,15 @@ package cce
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/open-ness/common/proxy/progutil"
 	"github.com/open-ness/edgecontroller/jose"
 	"github.com/open-ness/edgecontroller/k8s"
+	"github.com/open-ness/edgecontroller/oidc"
+	"github.com/open-ness/edgecontroller/pki"
 )
 
 // PrefaceLis Our network callback helper
@@ -41,8 +45,33 @@ type Controller struct {
 	KubernetesClient   *k8s.Client
 	PersistenceService PersistenceService
 	AuthorityService   AuthorityService
-	TokenService       *jose.JWSTokenIssuer
-	AdminCreds         *AuthCreds
+	// RevokedCertificates are the node certificates rejected when a node
+	// connects.
+	RevokedCertificates *pki.RevocationList
+	TokenService        *jose.JWSTokenIssuer
+	AdminCreds          *AuthCreds
+
+	// IdentityProvider is the external identity provider users can log in
+	// with. If nil only users with a password can log in.
+	IdentityProvider *oidc.Provider
+
+	// CredentialsPolicy is the policy for issuing and renewing node
+	// certificates.
+	CredentialsPolicy CredentialsPolicy
+
+	// PendingApproval queues the enrollment requests of nodes that are not
+	// approved so that an operator can approve them.
+	PendingApproval bool
+
+	// LegacyNodeSerials accepts nodes approved by their legacy MD5-based
+	// serial during the transition to SHA-256-based serials.
+	LegacyNodeSerials bool
+
+	// EnforcePolicyAnalysis rejects attaching a traffic policy to a node
+	// interface or app when the analysis of the policy on the node reports
+	// findings. A traffic policy of a node group with findings is reported in
+	// the drift of the node instead of being attached.
+	EnforcePolicyAnalysis bool
 
 	// The edge node's port that it listens on for gRPC connections from the
 	// Controller and serves Mm5-related endpoints for application and network
@@ -61,8 +90,31 @@ type Controller struct {
 	// EdgeNodeCreds are the transport credentials for connecting to an edge
 	// node. The server name will be overridden.
 	EdgeNodeCreds *tls.Config
+
+	// NodeConnIdleTimeout is how long an unused connection to an edge node is
+	// kept for reuse. If zero a connection is established for every request.
+	NodeConnIdleTimeout time.Duration
+
+	// ReconcileInterval is the period between two reconciliations of the
+	// enrolled nodes. If zero the nodes are only reconciled once they enroll
+	// or their node groups change.
+	ReconcileInterval time.Duration
+
+	// ReconcileWorkers is the maximum number of nodes reconciled at once. If
+	// zero the default of 16 is used.
+	ReconcileWorkers int
+
+	// NodeEnrolled is called with the ID of a node once it is issued
+	// credentials, so that the configuration of its node groups is assigned
+	// to it. It is set by the HTTP API; if nil nothing is called.
+	NodeEnrolled func(nodeID string)
 }
 
+// ErrVersionConflict is returned by PersistenceService when a Versioned entity
+// is written or deleted with a resource version that no longer matches the
+// persisted one.
+var ErrVersionConflict = errors.New("resource version conflict")
+
 // PersistenceService manages entity persistence. The methods with zv parameters take a zero-value Persistable for
 // reflectively creating new instances of the concrete type. In the case of Delete it is used to get the table name.
 type PersistenceService interface {
@@ -70,7 +122,9 @@ type PersistenceService interface {
 	Read(ctx context.Context, id string, zv Persistable) (e Persistable, err error)
 	ReadAll(ctx context.Context, zv Persistable) (ps []Persistable, err error)
 	Filter(ctx context.Context, zv Filterable, fs []Filter) (ps []Persistable, err error)
+	FilterPage(ctx context.Context, zv Filterable, q Query) (ps []Persistable, next string, err error)
 	BulkUpdate(ctx context.Context, ps []Persistable) error
+	BulkWrite(ctx context.Context, creates []Persistable, updates []Persistable) error
 	Delete(ctx context.Context, id string, zv Persistable) (ok bool, err error)
 }
 
@@ -86,6 +140,17 @@ type Persistable interface {
 	SetID(id string)
 }
 
+// Versioned is a Persistable with a resource version for optimistic concurrency
+// control. PersistenceService sets the version to 1 on Create and increments it
+// on every BulkUpdate. A non-zero version passed to BulkUpdate or Delete must
+// match the persisted version or ErrVersionConflict is returned; a zero
+// version writes unconditionally.
+type Versioned interface {
+	Persistable
+	GetResourceVersion() uint64
+	SetResourceVersion(v uint64)
+}
+
 // Filterable is a Persistable that can be filtered.
 type Filterable interface {
 	Persistable
@@ -107,7 +172,9 @@ type NodeEntity interface {
 	GetNodeID() string
 }
 
-// Filter filters queries in PersistenceService.Filter.
+// Filter filters queries in PersistenceService.Filter and
+// PersistenceService.FilterPage. The value is compared to the string value of
+// the field.
 type Filter struct {
 	Field string
 	Value string
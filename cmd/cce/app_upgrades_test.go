@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App upgrades", func() {
+	var (
+		appID string
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		appID = postApps("container")
+	})
+
+	patchApp := func(version, description string) {
+		By("Sending a PATCH /apps/{app_id} request")
+		resp, err := apiCli.Patch(
+			fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`
+				{
+					"id": "%s",
+					"type": "container",
+					"name": "container app",
+					"version": "%s",
+					"vendor": "smart edge",
+					"description": "%s",
+					"cores": 4,
+					"memory": 1024,
+					"ports": [{"port": 80, "protocol": "tcp"}],
+					"source": "http://www.test.com/my_container_app.tar.gz"
+				}`, appID, version, description)))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	getAppNodes := func(id string) *swagger.AppNodeList {
+		By("Sending a GET /apps/{app_id}/nodes request")
+		resp, err := apiCli.Get(fmt.Sprintf("http://127.0.0.1:8080/apps/%s/nodes", id))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var list swagger.AppNodeList
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(body, &list)).To(Succeed())
+		return &list
+	}
+
+	Describe("PATCH /apps/{app_id}", func() {
+		It("Should upgrade the app on the nodes running it", func() {
+			nodeCfg := createAndRegisterNode()
+			postNodeApps(nodeCfg.nodeID, appID)
+
+			patchApp("2.0", "my container app")
+
+			By("Verifying the revision was incremented")
+			Expect(getApp(appID).Revision).To(Equal(1))
+
+			By("Waiting for the node to run the new revision")
+			Eventually(func() bool {
+				return getAppNodes(appID).Nodes[0].UpToDate
+			}, 30*time.Second, 100*time.Millisecond).Should(BeTrue())
+
+			nodes := getAppNodes(appID).Nodes
+			Expect(nodes).To(HaveLen(1))
+			Expect(nodes[0].NodeID).To(Equal(nodeCfg.nodeID))
+			Expect(nodes[0].Revision).To(Equal(1))
+			Expect(nodes[0].Upgrade).ToNot(BeNil())
+			Expect(nodes[0].Upgrade.Kind).To(Equal("upgrade_app"))
+			Expect(nodes[0].Upgrade.Status).To(Equal("succeeded"))
+		})
+
+		It("Should not upgrade the app if it is deployed the same way", func() {
+			nodeCfg := createAndRegisterNode()
+			postNodeApps(nodeCfg.nodeID, appID)
+
+			patchApp("latest", "updated description")
+
+			Expect(getApp(appID).Revision).To(Equal(0))
+			Expect(getAppNodes(appID).Nodes).To(Equal([]swagger.AppNode{
+				{NodeID: nodeCfg.nodeID, Revision: 0, UpToDate: true},
+			}))
+		})
+	})
+
+	Describe("GET /apps/{app_id}/nodes", func() {
+		It("Should return 404 Not Found for a nonexistent app", func() {
+			resp, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/apps/%s/nodes", uuid.New()))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
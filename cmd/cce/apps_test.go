@@ -301,6 +301,66 @@ var _ = Describe("/apps", func() {
 			},
 			Entry("GET /apps"),
 		)
+
+		It("Should page through the apps sorted by name", func() {
+			vendor := uuid.New()
+			for _, name := range []string{"b", "c", "a"} {
+				resp, err := apiCli.Post(
+					"http://127.0.0.1:8080/apps",
+					"application/json",
+					strings.NewReader(fmt.Sprintf(`
+						{
+							"type": "container",
+							"name": "%s",
+							"version": "latest",
+							"vendor": "%s",
+							"cores": 4,
+							"memory": 1024,
+							"source": "http://www.test.com/my_container_app.tar.gz"
+						}`, name, vendor)))
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			}
+
+			getPage := func(url string) *swagger.AppList {
+				resp, err := apiCli.Get("http://127.0.0.1:8080" + url)
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+				var apps swagger.AppList
+				Expect(json.NewDecoder(resp.Body).Decode(&apps)).To(Succeed())
+				return &apps
+			}
+
+			By("Getting the first page in descending order of name")
+			page := getPage(fmt.Sprintf("/apps?vendor=%s&sort=-name&limit=2", vendor))
+			Expect(page.Apps).To(HaveLen(2))
+			Expect(page.Apps[0].Name).To(Equal("c"))
+			Expect(page.Apps[1].Name).To(Equal("b"))
+			Expect(page.Next).To(ContainSubstring("page_token="))
+
+			By("Following the link to the next page")
+			page = getPage(page.Next)
+			Expect(page.Apps).To(HaveLen(1))
+			Expect(page.Apps[0].Name).To(Equal("a"))
+			Expect(page.Next).To(BeEmpty())
+		})
+
+		DescribeTable("400 Bad Request",
+			func(query string) {
+				resp, err := apiCli.Get("http://127.0.0.1:8080/apps?" + query)
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			},
+			Entry("GET /apps with an invalid limit", "limit=0"),
+			Entry("GET /apps with an unknown sort field", "sort=cores"),
+			Entry("GET /apps with a malformed page token", "page_token=malformed"),
+			Entry("GET /apps with a page token of another sort", "sort=name&page_token="+
+				(&cce.PageCursor{Sort: "-name", Value: "a", ID: uuid.New()}).Token()),
+		)
 	})
 
 	Describe("GET /apps/{app_id}", func() {
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// getAuditEvents returns the audit events of an entity ordered by time.
+func getAuditEvents(entityID string) []*cce.AuditEvent {
+	return filterAuditEvents("entity_id", entityID)
+}
+
+// getActorAuditEvents returns the audit events of an actor ordered by time.
+func getActorAuditEvents(actor string) []*cce.AuditEvent {
+	return filterAuditEvents("actor", actor)
+}
+
+// filterAuditEvents returns the audit events with the value of the field
+// ordered by time.
+func filterAuditEvents(field, value string) []*cce.AuditEvent {
+	By("Sending a GET /audit request")
+	resp, err := apiCli.Get(fmt.Sprintf(
+		"http://127.0.0.1:8080/audit?%s=%s&sort=timestamp", field, url.QueryEscape(value)))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var events swagger.AuditEventList
+	Expect(json.NewDecoder(resp.Body).Decode(&events)).To(Succeed())
+
+	return events.Events
+}
+
+var _ = Describe("Audit Log", func() {
+	const appPatch = `
+		{
+			"id": "%s",
+			"type": "container",
+			"name": "audited app",
+			"version": "latest",
+			"vendor": "smart edge",
+			"description": "%s",
+			"cores": 4,
+			"memory": 1024,
+			"source": "http://www.test.com/my_container_app.tar.gz"
+		}`
+
+	// entity unmarshals the representation of an entity in an audit event.
+	entity := func(raw json.RawMessage, v interface{}) {
+		Expect(raw).ToNot(BeEmpty())
+		Expect(json.Unmarshal(raw, v)).To(Succeed())
+	}
+
+	It("Should record the persisted app before and after the requests", func() {
+		appID := postApps("container")
+
+		By("Sending a PATCH /apps/{app_id} request")
+		resp, err := apiCli.Patch(
+			fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(appPatch, appID, "patched")))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		By("Sending a DELETE /apps/{app_id} request")
+		resp, err = apiCli.Delete(fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		events := getAuditEvents(appID)
+		Expect(events).To(HaveLen(3))
+
+		By("Verifying the POST event")
+		Expect(events[0].Route).To(Equal("/apps"))
+		Expect(events[0].Status).To(Equal(http.StatusCreated))
+		Expect(events[0].Result).To(Equal(cce.AuditResultSuccess))
+		Expect(events[0].Before).To(BeEmpty())
+		var after cce.App
+		entity(events[0].After, &after)
+		Expect(after.ID).To(Equal(appID))
+		Expect(after.Description).To(Equal("my container app"))
+
+		By("Verifying the PATCH event")
+		Expect(events[1].Route).To(Equal("/apps/{app_id}"))
+		var before cce.App
+		entity(events[1].Before, &before)
+		Expect(before.Description).To(Equal("my container app"))
+		entity(events[1].After, &after)
+		Expect(after.Description).To(Equal("patched"))
+
+		By("Verifying the DELETE event")
+		Expect(events[2].Method).To(Equal("DELETE"))
+		entity(events[2].Before, &before)
+		Expect(before.Description).To(Equal("patched"))
+		Expect(events[2].After).To(BeEmpty())
+	})
+
+	It("Should not record the state after a failed request", func() {
+		appID := postApps("container")
+
+		By("Sending an invalid PATCH /apps/{app_id} request")
+		resp, err := apiCli.Patch(
+			fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"id": "%s", "cores": -1}`, appID)))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		events := getAuditEvents(appID)
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].Status).To(Equal(http.StatusBadRequest))
+		Expect(events[1].Result).To(Equal(cce.AuditResultFailure))
+		Expect(events[1].Before).ToNot(BeEmpty())
+		Expect(events[1].After).To(BeEmpty())
+	})
+
+	It("Should not record the state of a missing entity", func() {
+		appID := uuid.New()
+
+		By("Sending a DELETE /apps/{app_id} request for a missing app")
+		resp, err := apiCli.Delete(fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+		events := getAuditEvents(appID)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Result).To(Equal(cce.AuditResultFailure))
+		Expect(events[0].Before).To(BeEmpty())
+	})
+
+	It("Should not record secrets", func() {
+		userID := postUser("audit-"+uuid.New(), uuid.New(), cce.RoleViewer)
+
+		events := getAuditEvents(userID)
+		Expect(events).To(HaveLen(1))
+		var user cce.User
+		entity(events[0].After, &user)
+		Expect(user.ID).To(Equal(userID))
+		Expect(user.PasswordHash).To(BeEmpty())
+	})
+
+	It("Should record the logins with the user or the attempted username", func() {
+		username := "audit-" + uuid.New()
+		userID := postUser(username, "password", cce.RoleViewer)
+
+		By("Sending a POST /auth request with a wrong password")
+		resp, err := http.Post(
+			"http://127.0.0.1:8080/auth",
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"username": "%s", "password": "wrong"}`, username)))
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		events := getActorAuditEvents(cce.AuditActorLoginPrefix + username)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Route).To(Equal("/auth"))
+		Expect(events[0].Status).To(Equal(http.StatusUnauthorized))
+		Expect(events[0].Result).To(Equal(cce.AuditResultFailure))
+
+		By("Logging in with the password")
+		userToken(username, "password")
+
+		events = getActorAuditEvents(userID)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Route).To(Equal("/auth"))
+		Expect(events[0].Status).To(Equal(http.StatusCreated))
+		Expect(events[0].Result).To(Equal(cce.AuditResultSuccess))
+	})
+
+	It("Should record the unauthenticated calls as anonymous", func() {
+		appID := postApps("container")
+
+		By("Sending a DELETE /apps/{app_id} request without an auth token")
+		req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://127.0.0.1:8080/apps/%s", appID), nil)
+		Expect(err).ToNot(HaveOccurred())
+		resp, err := new(http.Client).Do(req)
+		Expect(err).ToNot(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		events := getAuditEvents(appID)
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].Actor).To(Equal(cce.AuditActorAnonymous))
+		Expect(events[1].Method).To(Equal("DELETE"))
+		Expect(events[1].Status).To(Equal(http.StatusUnauthorized))
+		Expect(events[1].Result).To(Equal(cce.AuditResultFailure))
+		Expect(events[1].Before).To(BeEmpty())
+	})
+
+	It("Should not record the state held by the nodes", func() {
+		nodeCfg := createAndRegisterNode()
+		patchNodeDNS(nodeCfg.nodeID)
+
+		events := getAuditEvents(nodeCfg.nodeID)
+		Expect(events).ToNot(BeEmpty())
+		last := events[len(events)-1]
+		Expect(last.Route).To(Equal("/nodes/{node_id}/dns"))
+		Expect(last.Result).To(Equal(cce.AuditResultSuccess))
+		Expect(last.Before).To(BeEmpty())
+		Expect(last.After).To(BeEmpty())
+	})
+})
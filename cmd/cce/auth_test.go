@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var _ = Describe("Auth Tokens", func() {
+	var (
+		username string
+		password string
+		userID   string
+	)
+
+	BeforeEach(func() {
+		username = "auth-" + uuid.New()
+		password = uuid.New()
+		userID = postUser(username, password, cce.RoleViewer)
+	})
+
+	Describe("POST /auth/logout", func() {
+		It("Should reject the token after logging out", func() {
+			token := userToken(username, password)
+			Expect(statusCode(token, "GET", "/apps", "")).To(Equal(http.StatusOK))
+
+			By("Sending a POST /auth/logout request")
+			Expect(statusCode(token, "POST", "/auth/logout", "")).To(Equal(http.StatusNoContent))
+
+			Expect(statusCode(token, "GET", "/apps", "")).To(Equal(http.StatusUnauthorized))
+			Expect(statusCode(token, "POST", "/auth/logout", "")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("Should not revoke another token of the user", func() {
+			token := userToken(username, password)
+			other := userToken(username, password)
+
+			Expect(statusCode(token, "POST", "/auth/logout", "")).To(Equal(http.StatusNoContent))
+
+			Expect(statusCode(other, "GET", "/apps", "")).To(Equal(http.StatusOK))
+		})
+
+		It("Should not log out with an API token", func() {
+			secret := postToken(userID)
+
+			Expect(statusCode(secret, "POST", "/auth/logout", "")).To(Equal(http.StatusBadRequest))
+			Expect(statusCode(secret, "GET", "/apps", "")).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("POST /auth/refresh", func() {
+		It("Should rotate the token", func() {
+			token := userToken(username, password)
+
+			By("Sending a POST /auth/refresh request")
+			req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8080/auth/refresh", nil)
+			Expect(err).ToNot(HaveOccurred())
+			resp, err := apiClient{Token: token}.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			var refreshed struct {
+				Token string `json:"token"`
+			}
+			Expect(json.NewDecoder(resp.Body).Decode(&refreshed)).To(Succeed())
+			Expect(refreshed.Token).ToNot(BeEmpty())
+			Expect(refreshed.Token).ToNot(Equal(token))
+
+			By("Verifying the new token is accepted and the old one rejected")
+			Expect(statusCode(refreshed.Token, "GET", "/apps", "")).To(Equal(http.StatusOK))
+			Expect(statusCode(token, "GET", "/apps", "")).To(Equal(http.StatusUnauthorized))
+			Expect(statusCode(token, "POST", "/auth/refresh", "")).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// postUser creates a user with a role and returns its ID.
+func postUser(username, password, role string) (id string) {
+	By("Sending a POST /users request")
+	resp, err := apiCli.Post(
+		"http://127.0.0.1:8080/users",
+		"application/json",
+		strings.NewReader(fmt.Sprintf(
+			`{"username": "%s", "password": "%s", "roles": ["%s"]}`, username, password, role)))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+	var rb respBody
+	Expect(json.NewDecoder(resp.Body).Decode(&rb)).To(Succeed())
+
+	return rb.ID
+}
+
+// postToken creates an API token of a user and returns its secret.
+func postToken(userID string) string {
+	By("Sending a POST /tokens request")
+	resp, err := apiCli.Post(
+		"http://127.0.0.1:8080/tokens",
+		"application/json",
+		strings.NewReader(fmt.Sprintf(`{"user_id": "%s", "name": "ci"}`, userID)))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+	var token swagger.TokenDetail
+	Expect(json.NewDecoder(resp.Body).Decode(&token)).To(Succeed())
+	Expect(token.Token).To(HavePrefix(cce.APITokenPrefix))
+
+	return token.Token
+}
+
+// statusCode sends a request with an auth token and returns the status code of
+// the response.
+func statusCode(token, method, path, body string) int {
+	req, err := http.NewRequest(method, "http://127.0.0.1:8080"+path, strings.NewReader(body))
+	Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := apiClient{Token: token}.Do(req)
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+var _ = Describe("Authorization", func() {
+	const appReq = `
+		{
+			"type": "container",
+			"name": "authz app",
+			"version": "latest",
+			"vendor": "smart edge",
+			"cores": 4,
+			"memory": 1024,
+			"source": "http://www.test.com/my_container_app.tar.gz"
+		}`
+
+	var (
+		password = uuid.New()
+		userIDs  = map[string]string{}
+		tokens   = map[string]string{}
+		policyID string
+	)
+
+	BeforeEach(func() {
+		policyID = postPolicies()
+		for _, role := range []string{cce.RoleViewer, cce.RoleOperator} {
+			username := role + "-" + uuid.New()
+			userIDs[role] = postUser(username, password, role)
+			tokens[role] = userToken(username, password)
+		}
+	})
+
+	DescribeTable("Permissions of the roles",
+		func(role, method, path, body string, expected int) {
+			path = strings.Replace(path, "{policy_id}", policyID, 1)
+			Expect(statusCode(tokens[role], method, path, body)).To(Equal(expected))
+		},
+		Entry("viewer GET /apps", cce.RoleViewer, "GET", "/apps", "", http.StatusOK),
+		Entry("viewer POST /apps", cce.RoleViewer, "POST", "/apps", appReq, http.StatusForbidden),
+		Entry("viewer POST /policies/{policy_id}/analyze",
+			cce.RoleViewer, "POST", "/policies/{policy_id}/analyze", "{}", http.StatusOK),
+		Entry("viewer DELETE /policies/{policy_id}",
+			cce.RoleViewer, "DELETE", "/policies/{policy_id}", "", http.StatusForbidden),
+		Entry("viewer GET /users", cce.RoleViewer, "GET", "/users", "", http.StatusForbidden),
+		Entry("operator POST /apps", cce.RoleOperator, "POST", "/apps", appReq, http.StatusCreated),
+		Entry("operator GET /users", cce.RoleOperator, "GET", "/users", "", http.StatusForbidden),
+		Entry("operator POST /tokens", cce.RoleOperator, "POST", "/tokens", `{"name": "ci"}`, http.StatusForbidden),
+		Entry("operator GET /audit", cce.RoleOperator, "GET", "/audit", "", http.StatusForbidden),
+		Entry("operator GET /audit/export", cce.RoleOperator, "GET", "/audit/export", "", http.StatusForbidden),
+	)
+
+	Describe("JSON Web Tokens", func() {
+		It("Should authenticate with the current roles of the user", func() {
+			Expect(statusCode(tokens[cce.RoleOperator], "POST", "/apps", appReq)).To(Equal(http.StatusCreated))
+
+			By("Sending a PATCH /users/{user_id} request")
+			resp, err := apiCli.Patch(
+				"http://127.0.0.1:8080/users/"+userIDs[cce.RoleOperator],
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"roles": ["%s"]}`, cce.RoleViewer)))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(statusCode(tokens[cce.RoleOperator], "GET", "/apps", "")).To(Equal(http.StatusOK))
+			Expect(statusCode(tokens[cce.RoleOperator], "POST", "/apps", appReq)).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should reject the token of a deleted user", func() {
+			Expect(statusCode(tokens[cce.RoleViewer], "GET", "/apps", "")).To(Equal(http.StatusOK))
+
+			By("Sending a DELETE /users/{user_id} request")
+			resp, err := apiCli.Delete("http://127.0.0.1:8080/users/" + userIDs[cce.RoleViewer])
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(statusCode(tokens[cce.RoleViewer], "GET", "/apps", "")).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("API tokens", func() {
+		It("Should authenticate with the roles of the user of the token", func() {
+			secret := postToken(userIDs[cce.RoleViewer])
+
+			Expect(statusCode(secret, "GET", "/apps", "")).To(Equal(http.StatusOK))
+			Expect(statusCode(secret, "POST", "/apps", appReq)).To(Equal(http.StatusForbidden))
+			Expect(statusCode(secret, "GET", "/tokens", "")).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should reject an unknown API token", func() {
+			Expect(statusCode(cce.APITokenPrefix+"unknown", "GET", "/apps", "")).To(
+				Equal(http.StatusUnauthorized))
+		})
+
+		It("Should reject a deleted API token", func() {
+			secret := postToken(userIDs[cce.RoleOperator])
+			Expect(statusCode(secret, "GET", "/apps", "")).To(Equal(http.StatusOK))
+
+			By("Deleting the API token")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/tokens?user_id=" + userIDs[cce.RoleOperator])
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			var list swagger.TokenList
+			Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+			Expect(list.Tokens).To(HaveLen(1))
+			resp, err = apiCli.Delete("http://127.0.0.1:8080/tokens/" + list.Tokens[0].ID)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(statusCode(secret, "GET", "/apps", "")).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
@@ -57,6 +57,11 @@ func (cli apiClient) Delete(url string) (*http.Response, error) {
 	return new(http.Client).Do(cli.injectToken(req))
 }
 
+// Do sends a HTTP request with a token and returns an HTTP response.
+func (cli apiClient) Do(req *http.Request) (*http.Response, error) {
+	return new(http.Client).Do(cli.injectToken(req))
+}
+
 func (cli apiClient) injectToken(r *http.Request) *http.Request {
 	r.Header.Add("Authorization", fmt.Sprintf("Bearer %s", cli.Token))
 	return r
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	cceGRPC "github.com/open-ness/edgecontroller/grpc"
+	authpb "github.com/open-ness/edgecontroller/pb/auth"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// parseCredentials parses the certificate and the CA chain of node
+// credentials.
+func parseCredentials(creds *authpb.Credentials) (cert *x509.Certificate, chain []*x509.Certificate) {
+	block, _ := pem.Decode([]byte(creds.Certificate))
+	Expect(block).ToNot(BeNil())
+	cert, err := x509.ParseCertificate(block.Bytes)
+	Expect(err).ToNot(HaveOccurred())
+
+	for _, ca := range creds.CaChain {
+		block, _ = pem.Decode([]byte(ca))
+		Expect(block).ToNot(BeNil())
+		caCert, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		chain = append(chain, caCert)
+	}
+
+	return cert, chain
+}
+
+// renewCredentials calls RenewCredentials on the controller gRPC port
+// authenticating with the credentials of the node, and returns the renewed
+// credentials and the status code of the call.
+func renewCredentials(
+	grpcPort string,
+	nodeCfg *nodeConfig,
+	creds *authpb.Credentials,
+) (*authpb.Credentials, codes.Code) {
+	cert, _ := parseCredentials(creds)
+	caPool := x509.NewCertPool()
+	for _, ca := range creds.CaPool {
+		Expect(caPool.AppendCertsFromPEM([]byte(ca))).To(BeTrue())
+	}
+
+	conn, err := grpc.Dial(
+		net.JoinHostPort("127.0.0.1", grpcPort),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  nodeCfg.key,
+				Leaf:        cert,
+			}},
+			RootCAs:    caPool,
+			ServerName: cceGRPC.SNI,
+		})))
+	Expect(err).ToNot(HaveOccurred())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	By("Calling RenewCredentials with the node certificate")
+	renewed, err := authpb.NewAuthServiceClient(conn).RenewCredentials(
+		ctx, &authpb.Identity{Csr: nodeCSR(nodeCfg)})
+
+	return renewed, status.Code(err)
+}
+
+// renewalCode calls RenewCredentials on the controller and returns the status
+// code of the call.
+func renewalCode(nodeCfg *nodeConfig, creds *authpb.Credentials) codes.Code {
+	_, code := renewCredentials("8081", nodeCfg, creds)
+	return code
+}
+
+// getNodeCredentials returns the credentials issued to a node by serial
+// number.
+func getNodeCredentials(nodeID string) map[string]swagger.CredentialsSummary {
+	By("Sending a GET /nodes/{node_id}/credentials request")
+	resp, err := apiCli.Get(fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/credentials", nodeID))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var list swagger.CredentialsList
+	Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+
+	bySerial := make(map[string]swagger.CredentialsSummary)
+	for _, c := range list.Credentials {
+		bySerial[c.SerialNumber] = c
+	}
+
+	return bySerial
+}
+
+// nodeCSR returns a PEM-encoded certificate signing request of the node key.
+func nodeCSR(nodeCfg *nodeConfig) string {
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, nodeCfg.key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+}
+
+var _ = Describe("Node Credentials", func() {
+	var nodeCfg *nodeConfig
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	Describe("DELETE /nodes/{node_id}/credentials", func() {
+		It("Should reject the TLS handshake of the revoked node", func() {
+			By("Verifying the node authenticates before the revocation")
+			Expect(renewalCode(nodeCfg, nodeCfg.creds)).To(Equal(codes.FailedPrecondition))
+
+			By("Sending a DELETE /nodes/{node_id}/credentials request")
+			resp, err := apiCli.Delete(fmt.Sprintf(
+				"http://127.0.0.1:8080/nodes/%s/credentials", nodeCfg.nodeID))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			By("Verifying the TLS handshake is rejected")
+			Expect(renewalCode(nodeCfg, nodeCfg.creds)).To(Equal(codes.Unavailable))
+		})
+	})
+
+	Describe("POST /nodes/{node_id}/reenroll", func() {
+		It("Should allow the node to request credentials again", func() {
+			By("Verifying an enrolled node cannot request credentials")
+			_, err := authSvcCli.RequestCredentials(
+				context.TODO(), &authpb.Identity{Csr: nodeCSR(nodeCfg)})
+			Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+
+			By("Sending a POST /nodes/{node_id}/reenroll request")
+			resp, err := apiCli.Post(fmt.Sprintf(
+				"http://127.0.0.1:8080/nodes/%s/reenroll", nodeCfg.nodeID), "application/json", nil)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			By("Requesting fresh credentials")
+			creds, err := authSvcCli.RequestCredentials(
+				context.TODO(), &authpb.Identity{Csr: nodeCSR(nodeCfg)})
+			Expect(err).ToNot(HaveOccurred())
+
+			oldCert, _ := parseCredentials(nodeCfg.creds)
+			newCert, _ := parseCredentials(creds)
+			Expect(newCert.SerialNumber).ToNot(Equal(oldCert.SerialNumber))
+			Expect(newCert.Subject.CommonName).To(Equal(nodeCfg.nodeID))
+
+			By("Verifying only the fresh credentials authenticate")
+			Expect(renewalCode(nodeCfg, nodeCfg.creds)).To(Equal(codes.Unavailable))
+			Expect(renewalCode(nodeCfg, creds)).To(Equal(codes.FailedPrecondition))
+		})
+	})
+
+	Describe("GET /crl", func() {
+		It("Should list the revoked certificates", func() {
+			By("Sending a DELETE /nodes/{node_id}/credentials request")
+			resp, err := apiCli.Delete(fmt.Sprintf(
+				"http://127.0.0.1:8080/nodes/%s/credentials", nodeCfg.nodeID))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			By("Sending a GET /crl request without authentication")
+			resp, err = http.Get("http://127.0.0.1:8080/crl")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/pkix-crl"))
+			der, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Parsing the certificate revocation list")
+			crl, err := x509.ParseCRL(der)
+			Expect(err).ToNot(HaveOccurred())
+			cert, chain := parseCredentials(nodeCfg.creds)
+			Expect(chain[0].CheckCRLSignature(crl)).To(Succeed())
+			Expect(crl.HasExpired(time.Now())).To(BeFalse())
+
+			By("Verifying the CRL contains the revoked serial number")
+			var serials []string
+			for _, c := range crl.TBSCertList.RevokedCertificates {
+				serials = append(serials, c.SerialNumber.Text(16))
+			}
+			Expect(serials).To(ContainElement(cert.SerialNumber.Text(16)))
+		})
+	})
+
+	Describe("RenewCredentials", func() {
+		It("Should refuse to renew a certificate outside the renewal window", func() {
+			Expect(renewalCode(nodeCfg, nodeCfg.creds)).To(Equal(codes.FailedPrecondition))
+		})
+
+		Describe("Inside the renewal window", func() {
+			var renewCtl *gexec.Session
+
+			BeforeEach(func() {
+				// The renewal window is longer than the validity of the CA, so
+				// that the certificates can be renewed at once
+				renewCtl = startController(8095,
+					"-node-cert-lifetime", "1h",
+					"-node-cert-renewal-window", "40000h")
+			})
+
+			AfterEach(func() {
+				renewCtl.Kill()
+				Eventually(renewCtl).Should(gexec.Exit())
+			})
+
+			It("Should renew the certificate and revoke the superseded one", func() {
+				renewed, code := renewCredentials("8096", nodeCfg, nodeCfg.creds)
+				Expect(code).To(Equal(codes.OK))
+
+				oldCert, _ := parseCredentials(nodeCfg.creds)
+				newCert, _ := parseCredentials(renewed)
+				Expect(newCert.SerialNumber).ToNot(Equal(oldCert.SerialNumber))
+				Expect(newCert.Subject.CommonName).To(Equal(nodeCfg.nodeID))
+				Expect(newCert.NotAfter).To(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+
+				By("Verifying the superseded credentials are kept as history")
+				history := getNodeCredentials(nodeCfg.nodeID)
+				Expect(history).To(HaveLen(2))
+				Expect(history[oldCert.SerialNumber.Text(16)].Superseded).To(BeTrue())
+				Expect(history[newCert.SerialNumber.Text(16)].Superseded).To(BeFalse())
+
+				By("Verifying the superseded certificate is revoked")
+				_, code = renewCredentials("8096", nodeCfg, nodeCfg.creds)
+				Expect(code).To(Equal(codes.Unavailable))
+
+				By("Verifying the renewed certificate can be renewed")
+				_, code = renewCredentials("8096", nodeCfg, renewed)
+				Expect(code).To(Equal(codes.OK))
+			})
+
+			It("Should revoke the superseded and the renewed certificates", func() {
+				renewed, code := renewCredentials("8096", nodeCfg, nodeCfg.creds)
+				Expect(code).To(Equal(codes.OK))
+
+				By("Sending a DELETE /nodes/{node_id}/credentials request")
+				resp, err := apiCli.Delete(fmt.Sprintf(
+					"http://127.0.0.1:8095/nodes/%s/credentials", nodeCfg.nodeID))
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+				By("Verifying the TLS handshake is rejected with either certificate")
+				_, code = renewCredentials("8096", nodeCfg, nodeCfg.creds)
+				Expect(code).To(Equal(codes.Unavailable))
+				_, code = renewCredentials("8096", nodeCfg, renewed)
+				Expect(code).To(Equal(codes.Unavailable))
+			})
+		})
+
+		It("Should refuse to renew revoked credentials", func() {
+			By("Sending a DELETE /nodes/{node_id}/credentials request")
+			resp, err := apiCli.Delete(fmt.Sprintf(
+				"http://127.0.0.1:8080/nodes/%s/credentials", nodeCfg.nodeID))
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNoContent))
+
+			Expect(renewalCode(nodeCfg, nodeCfg.creds)).To(Equal(codes.Unavailable))
+
+			cert, _ := parseCredentials(nodeCfg.creds)
+			history := getNodeCredentials(nodeCfg.nodeID)
+			Expect(history).To(HaveLen(1))
+			Expect(history[cert.SerialNumber.Text(16)].Revoked).To(BeTrue())
+		})
+	})
+})
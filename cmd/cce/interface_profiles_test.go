@@ -0,0 +1,188 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("/interface_profiles", func() {
+	var nodeCfg *nodeConfig
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	postInterfaceProfiles := func(req string) *http.Response {
+		By("Sending a POST /interface_profiles request")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/interface_profiles",
+			"application/json",
+			strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	readBody := func(resp *http.Response) string {
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	// createProfile creates a profile putting interface if1 of the node in
+	// VLAN 100 and returns its ID.
+	createProfile := func() string {
+		resp := postInterfaceProfiles(fmt.Sprintf(`
+			{
+				"name": "vlan 100",
+				"match": {"description": "interface1"},
+				"set": {"vlan": 100},
+				"node_ids": ["%s"]
+			}`, nodeCfg.nodeID))
+		defer resp.Body.Close()
+
+		By("Verifying a 201 Created response")
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		var profile swagger.BaseResource
+		Expect(json.Unmarshal([]byte(readBody(resp)), &profile)).To(Succeed())
+		return profile.ID
+	}
+
+	applyProfile := func(id string, dryRun bool) *swagger.InterfaceProfileApplyResult {
+		By("Sending a POST /interface_profiles/{profile_id}/apply request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/interface_profiles/%s/apply", id),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"dry_run": %t}`, dryRun)))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var result swagger.InterfaceProfileApplyResult
+		Expect(json.Unmarshal([]byte(readBody(resp)), &result)).To(Succeed())
+		return &result
+	}
+
+	vlanOf := func(ifaceID string) int {
+		for _, ni := range getNodeInterfaces(nodeCfg.nodeID).Interfaces {
+			if ni.ID == ifaceID {
+				return ni.VLAN
+			}
+		}
+		Fail(fmt.Sprintf("interface %s not found", ifaceID))
+		return 0
+	}
+
+	Describe("POST /interface_profiles", func() {
+		It("Should create an interface profile", func() {
+			id := createProfile()
+
+			By("Sending a GET /interface_profiles/{profile_id} request")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/interface_profiles/" + id)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var profile swagger.InterfaceProfileDetail
+			Expect(json.Unmarshal([]byte(readBody(resp)), &profile)).To(Succeed())
+			Expect(profile.Name).To(Equal("vlan 100"))
+			Expect(profile.Match).To(Equal(cce.InterfaceMatch{Description: "interface1"}))
+			Expect(profile.NodeIDs).To(Equal([]string{nodeCfg.nodeID}))
+		})
+
+		It("Should return 400 Bad Request without target nodes", func() {
+			resp := postInterfaceProfiles(`{"name": "vlan 100", "match": {"driver": "kernel"}, "set": {"vlan": 100}}`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(readBody(resp)).To(Equal("Validation failed: node_ids or selector is required"))
+		})
+
+		It("Should return 422 Unprocessable Entity for a nonexistent node", func() {
+			resp := postInterfaceProfiles(`
+				{
+					"name": "vlan 100",
+					"match": {"driver": "kernel"},
+					"set": {"vlan": 100},
+					"node_ids": ["123"]
+				}`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(readBody(resp)).To(Equal("node 123 not found"))
+		})
+	})
+
+	Describe("POST /interface_profiles/{profile_id}/apply", func() {
+		It("Should only report the changes of a dry run", func() {
+			id := createProfile()
+
+			result := applyProfile(id, true)
+			Expect(result.DryRun).To(BeTrue())
+			Expect(result.Nodes).To(Equal([]swagger.InterfaceProfileNodeResult{
+				{
+					NodeID: nodeCfg.nodeID,
+					Changes: []cce.InterfaceChange{
+						{InterfaceID: "if1", Field: "vlan", From: "1", To: "100"},
+					},
+				},
+			}))
+			Expect(vlanOf("if1")).To(Equal(1))
+		})
+
+		It("Should apply the profile to the interfaces of the node", func() {
+			id := createProfile()
+
+			result := applyProfile(id, false)
+			Expect(result.Nodes).To(HaveLen(1))
+			Expect(result.Nodes[0].Error).To(BeEmpty())
+			Expect(result.Nodes[0].Changes).To(HaveLen(1))
+			Expect(vlanOf("if1")).To(Equal(100))
+			Expect(vlanOf("if0")).To(Equal(0))
+
+			By("Applying the profile again")
+			result = applyProfile(id, false)
+			Expect(result.Nodes[0].Changes).To(BeEmpty())
+		})
+
+		It("Should return 404 Not Found for a nonexistent profile", func() {
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/interface_profiles/123/apply",
+				"application/json",
+				strings.NewReader(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("DELETE /interface_profiles/{profile_id}", func() {
+		It("Should delete the interface profile", func() {
+			id := createProfile()
+
+			By("Sending a DELETE /interface_profiles/{profile_id} request")
+			resp, err := apiCli.Delete("http://127.0.0.1:8080/interface_profiles/" + id)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			resp, err = apiCli.Get("http://127.0.0.1:8080/interface_profiles/" + id)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	cce "github.com/open-ness/edgecontroller"
+	cceGRPC "github.com/open-ness/edgecontroller/grpc"
+	authpb "github.com/open-ness/edgecontroller/pb/auth"
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+// newCACert creates a CA certificate for the key signed by the parent, or a
+// self-signed root CA if parent is nil.
+func newCACert(
+	name string,
+	key crypto.Signer,
+	parent *x509.Certificate,
+	parentKey crypto.Signer,
+) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+
+	return cert
+}
+
+var _ = Describe("Intermediate CA", func() {
+	var (
+		caDir, keyDir string
+		rootCert      *x509.Certificate
+		intCert       *x509.Certificate
+		intCtl        *gexec.Session
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		By("Creating an offline root CA and an intermediate CA")
+		rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		rootCert = newCACert("Test Root CA", rootKey, nil, nil)
+		intKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		intCert = newCACert("Test Intermediate CA", intKey, rootCert, rootKey)
+
+		By("Storing the intermediate CA key apart from its certificate")
+		caDir, err = ioutil.TempDir("", "intermediate-ca")
+		Expect(err).ToNot(HaveOccurred())
+		keyDir, err = ioutil.TempDir("", "intermediate-ca-key")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pki.StoreCertificate(filepath.Join(caDir, "cert.pem"), intCert, rootCert)).To(Succeed())
+		Expect(pki.StoreKey(intKey, filepath.Join(keyDir, "signing-key.pem"))).To(Succeed())
+
+		intCtl = startController(8100,
+			"-intermediate-ca-dir", caDir,
+			"-intermediate-ca-key", filepath.Join(keyDir, "signing-key.pem"))
+	})
+
+	AfterEach(func() {
+		intCtl.Kill()
+		Eventually(intCtl).Should(gexec.Exit())
+		Expect(os.RemoveAll(caDir)).To(Succeed())
+		Expect(os.RemoveAll(keyDir)).To(Succeed())
+	})
+
+	It("Should issue node certificates with the key from the key file", func() {
+		By("Generating node private key")
+		key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+		Expect(err).ToNot(HaveOccurred())
+		certReq, err := x509.ParseCertificateRequest(csrDER)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Pre-approving Node by serial")
+		postNodesSerial(cce.NodeSerial(certReq.RawSubjectPublicKeyInfo))
+
+		By("Connecting to the enrollment endpoint trusting only the root CA")
+		roots := x509.NewCertPool()
+		roots.AddCert(rootCert)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(
+			ctx,
+			net.JoinHostPort("127.0.0.1", "8101"),
+			grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(roots, cceGRPC.EnrollmentSNI)),
+			grpc.WithBlock())
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		By("Requesting credentials")
+		creds, err := authpb.NewAuthServiceClient(conn).RequestCredentials(
+			ctx,
+			&authpb.Identity{Csr: string(pem.EncodeToMemory(
+				&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))})
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Verifying the certificate was issued by the intermediate CA")
+		cert, chain := parseCredentials(creds)
+		Expect(chain).To(Equal([]*x509.Certificate{intCert, rootCert}))
+		Expect(cert.CheckSignatureFrom(intCert)).To(Succeed())
+	})
+})
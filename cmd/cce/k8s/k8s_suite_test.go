@@ -11,11 +11,9 @@ import (
 
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/md5" //nolint:gosec
 	"crypto/rand"
 	"crypto/x509"
 	"database/sql"
-	"encoding/base64"
 
 	"encoding/json"
 	"encoding/pem"
@@ -45,6 +43,7 @@ import (
 	"google.golang.org/grpc/grpclog"
 
 	_ "github.com/go-sql-driver/mysql" // provides the mysql driver
+	cce "github.com/open-ness/edgecontroller"
 	cceGRPC "github.com/open-ness/edgecontroller/grpc"
 	"github.com/open-ness/edgecontroller/k8s"
 	authpb "github.com/open-ness/edgecontroller/pb/auth"
@@ -324,8 +323,7 @@ func createAndRegisterNode() *nodeConfig {
 		})
 
 	By("Pre-approving Node by serial")
-	hash := md5.Sum(certReq.RawSubjectPublicKeyInfo) //nolint:gosec
-	serial := base64.RawURLEncoding.EncodeToString(hash[:])
+	serial := cce.NodeSerial(certReq.RawSubjectPublicKeyInfo)
 	nodeID := postNodesSerial(serial)
 
 	By("Resetting the node")
@@ -400,8 +398,8 @@ func postNodeApps(nodeID, appID string) {
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 OK response")
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	By("Waiting for the app to be deployed")
+	Expect(waitForOperation(resp).Status).To(Equal("succeeded"))
 
 	By("Verifying app deployment success")
 	count := 0
@@ -419,6 +417,31 @@ func postNodeApps(nodeID, appID string) {
 	))
 }
 
+// waitForOperation waits for the operation accepted by the response to end
+// and returns it.
+func waitForOperation(resp *http.Response) *swagger.OperationDetail {
+	By("Verifying a 202 Accepted response")
+	Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	location := resp.Header.Get("Location")
+	Expect(location).To(HavePrefix("/operations/"))
+
+	var op *swagger.OperationDetail
+	Eventually(func() string {
+		By(fmt.Sprintf("Sending a GET %s request", location))
+		resp, err := apiCli.Get("http://127.0.0.1:8080" + location)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(body, &op)).To(Succeed())
+		return op.Status
+	}, 30*time.Second, 100*time.Millisecond).Should(Or(Equal("succeeded"), Equal("failed"), Equal("canceled")))
+
+	return op
+}
+
 func deployApp(nodeID, appID string) {
 	By("Getting the current user")
 	u, err := user.Current()
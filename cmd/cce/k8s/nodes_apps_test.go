@@ -6,7 +6,6 @@ package k8s_test
 import (
 	"fmt"
 	"github.com/open-ness/edgecontroller/swagger"
-	"io/ioutil"
 	"net/http"
 	"os/exec"
 	"runtime"
@@ -60,7 +59,7 @@ var _ = Describe("/nodes/{node_id}/apps for k8s", func() {
 	})
 
 	Describe("POST /nodes/{node_id}/apps", func() {
-		DescribeTable("200 OK",
+		DescribeTable("202 Accepted",
 			func() {
 				By("Sending a POST /nodes/{node_id}/apps request")
 				resp, err := apiCli.Post(
@@ -74,12 +73,8 @@ var _ = Describe("/nodes/{node_id}/apps for k8s", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
-
-				By("Reading the response body")
-				_, err = ioutil.ReadAll(resp.Body)
-				Expect(err).ToNot(HaveOccurred())
+				By("Waiting for the app to be deployed")
+				Expect(waitForOperation(resp).Status).To(Equal("succeeded"))
 			},
 			Entry("POST /nodes/{node_id}/apps"),
 		)
@@ -187,7 +182,7 @@ var _ = Describe("/nodes/{node_id}/apps for k8s", func() {
 			postNodeApps(nodeID, appID)
 		})
 
-		DescribeTable("200 OK",
+		DescribeTable("202 Accepted",
 			func(reqStr string, expectedNodeAppFull *swagger.NodeAppDetail) {
 				By("Sending a PATCH /nodes/{node_id}/apps/{app_id} request")
 				resp, err := apiCli.Patch(
@@ -202,9 +197,8 @@ var _ = Describe("/nodes/{node_id}/apps for k8s", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 OK response")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				By("Waiting for the command to run")
+				Expect(waitForOperation(resp).Status).To(Equal("succeeded"))
 
 				By("Verifying the node was updated")
 				expectedNodeAppFull.NodeAppSummary = swagger.NodeAppSummary{
@@ -5,6 +5,7 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -17,8 +18,10 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/handlers"
+	bbolt "go.etcd.io/bbolt"
 	"golang.org/x/sync/errgroup"
 
 	"net"
@@ -31,14 +34,20 @@ import (
 	logger "github.com/open-ness/common/log"
 	"github.com/open-ness/common/proxy/progutil"
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
 	"github.com/open-ness/edgecontroller/gorilla"
 	"github.com/open-ness/edgecontroller/grpc"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/health"
 	"github.com/open-ness/edgecontroller/http"
 	"github.com/open-ness/edgecontroller/jose"
 	"github.com/open-ness/edgecontroller/k8s"
 	"github.com/open-ness/edgecontroller/mysql"
+	"github.com/open-ness/edgecontroller/oidc"
 	"github.com/open-ness/edgecontroller/pki"
+	"github.com/open-ness/edgecontroller/reconcile"
 	"github.com/open-ness/edgecontroller/telemetry"
+	"github.com/open-ness/edgecontroller/uuid"
 )
 
 const certsDir = "./certificates"
@@ -60,11 +69,39 @@ var (
 	statsdOut  string
 	orchMode   string
 	k8sClient  k8s.Client
+
+	tokenLifetime    time.Duration
+	tokenKeysDir     string
+	tokenKeyRotation time.Duration
+	tokenKeyGrace    time.Duration
+
+	idp           oidc.Provider
+	oidcScopes    string
+	oidcGroupRole string
+
+	credsPolicy       cce.CredentialsPolicy
+	intermediateCADir string
+	intermediateCAKey string
+	pendingApproval   bool
+	legacyNodeSerials bool
+
+	enforcePolicyAnalysis bool
+
+	nodeProbeInterval        time.Duration
+	nodeProbeDegradedLatency time.Duration
+	nodeConnIdleTimeout      time.Duration
+	reconcileInterval        time.Duration
+	reconcileWorkers         int
 )
 
+// revokedTokensPruneInterval is how often expired tokens are deleted from the
+// revocation list.
+const revokedTokensPruneInterval = time.Hour
+
 func init() {
-	flag.StringVar(&dsn, "dsn", "", "Data source name")
-	flag.StringVar(&adminPass, "adminPass", "", "Admin user password")
+	flag.StringVar(&dsn, "dsn", "", "Data source name. "+
+		"options [mysql://<mysql dsn>, bolt://<file path>], no scheme defaults to mysql")
+	flag.StringVar(&adminPass, "adminPass", "", "Admin user password, applied to the admin user on every start")
 	flag.StringVar(&logLevel, "log-level", "info", "Syslog level")
 	flag.IntVar(&httpPort, "httpPort", 8080, "Controller HTTP port")
 	flag.IntVar(&grpcPort, "grpcPort", 8081, "Controller gRPC port")
@@ -74,6 +111,59 @@ func init() {
 	flag.IntVar(&statsdPort, "statsdPort", 8125, "Telemetry ingress port for statsd")
 	flag.StringVar(&syslogOut, "syslog-path", "./syslog.log", "Syslog output file path")
 	flag.StringVar(&statsdOut, "statsd-path", "./statsd.log", "StatsD output file path")
+	flag.DurationVar(&tokenLifetime, "token-lifetime", jose.DefaultTokenLifetime,
+		"Period during which an auth token is valid")
+	flag.StringVar(&tokenKeysDir, "token-keys-dir", filepath.Join(certsDir, "jwt"),
+		"Directory storing the auth token signing keys")
+	flag.DurationVar(&tokenKeyRotation, "token-key-rotation", 30*24*time.Hour,
+		"Period after which the auth token signing key is rotated, 0 to disable rotation")
+	flag.DurationVar(&tokenKeyGrace, "token-key-grace", 24*time.Hour,
+		"Period during which tokens signed by a rotated key remain valid, at least the token lifetime")
+
+	// node certificates
+	flag.StringVar(&intermediateCADir, "intermediate-ca-dir", "", "Directory with the intermediate CA "+
+		"certificate followed by its chain in cert.pem and its key in key.pem, empty to use a self-signed root CA")
+	flag.StringVar(&intermediateCAKey, "intermediate-ca-key", "", "Intermediate CA key file, e.g. on a "+
+		"separately mounted secret volume, or URI of a key of a registered signer, e.g. pkcs11:object=ca, "+
+		"empty to load key.pem from the intermediate CA directory")
+	flag.DurationVar(&credsPolicy.Lifetime, "node-cert-lifetime", 0,
+		"Period during which a node certificate is valid, 0 for until the CA expires")
+	flag.DurationVar(&credsPolicy.RenewalWindow, "node-cert-renewal-window", 30*24*time.Hour,
+		"Period before its expiry during which a node certificate can be renewed, 0 for any time")
+	flag.BoolVar(&pendingApproval, "pending-approval", false,
+		"Queue the enrollment requests of unknown nodes for approval instead of only rejecting them")
+	flag.BoolVar(&legacyNodeSerials, "legacy-node-serials", false,
+		"Accept nodes approved by their legacy MD5-based serial, rewriting it on enrollment")
+
+	// traffic policies
+	flag.BoolVar(&enforcePolicyAnalysis, "enforce-policy-analysis", false,
+		"Reject attaching a traffic policy to a node when its analysis reports shadowed, unreachable or conflicting rules")
+
+	// node health
+	flag.DurationVar(&nodeProbeInterval, "node-probe-interval", health.DefaultInterval,
+		"Period between two probes of the connectivity of the nodes, 0 to disable probing")
+	flag.DurationVar(&nodeProbeDegradedLatency, "node-probe-degraded-latency", health.DefaultDegradedLatency,
+		"Latency above which a reachable node is reported as degraded")
+	flag.DurationVar(&nodeConnIdleTimeout, "node-conn-idle-timeout", node.DefaultIdleTimeout,
+		"Period after which an unused connection to a node is closed, 0 to connect for every request")
+	flag.DurationVar(&reconcileInterval, "reconcile-interval", reconcile.DefaultInterval,
+		"Period between two reconciliations of the configuration of the nodes, 0 to disable reconciliation")
+	flag.IntVar(&reconcileWorkers, "reconcile-workers", reconcile.DefaultWorkers,
+		"Maximum number of nodes reconciled at once")
+
+	// OpenID Connect identity provider
+	flag.StringVar(&idp.Issuer, "oidc-issuer", "", "OpenID Connect identity provider issuer URL, "+
+		"empty to disable logging in with an identity provider")
+	flag.StringVar(&idp.ClientID, "oidc-client-id", "", "OpenID Connect client ID")
+	flag.StringVar(&idp.ClientSecret, "oidc-client-secret", "", "OpenID Connect client secret")
+	flag.StringVar(&idp.RedirectURL, "oidc-redirect-url", "",
+		"OpenID Connect redirect URL, the /auth/oidc/callback endpoint of the Controller")
+	flag.StringVar(&oidcScopes, "oidc-scopes", "profile,email",
+		"Comma-separated OpenID Connect scopes requested in addition to openid")
+	flag.StringVar(&idp.GroupsClaim, "oidc-groups-claim", oidc.DefaultGroupsClaim,
+		"ID token claim listing the groups of the user")
+	flag.StringVar(&oidcGroupRole, "oidc-group-roles", "",
+		"Comma-separated group=role mappings granting Controller roles to identity provider groups")
 
 	// application orchestration mode
 	flag.StringVar(&orchMode, "orchestration-mode", "native", "Orchestration mode."+
@@ -109,6 +199,15 @@ func setupOrchestrator() (cce.OrchestrationMode, error) {
 }
 
 func main() {
+	// Run the migrate subcommand without starting the controller
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Alertf("Error migrating db: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.Parse()
 
 	// Validate flags
@@ -120,7 +219,7 @@ func main() {
 	// Set log level
 	lvl, err := logger.ParseLevel(logLevel)
 	if err != nil {
-		log.Alert("Bad log level %q: %v", logLevel, err)
+		log.Alertf("Bad log level %q: %v", logLevel, err)
 		os.Exit(1)
 	}
 	log.Infof("Setting log level to: %s", logLevel)
@@ -136,10 +235,16 @@ func main() {
 	}
 
 	// Connect to the db and verify
-	db := connectDB(dsn)
+	persistenceService := connectPersistence(dsn)
+
+	// Create the admin user or update its password from the flag
+	if err = ensureAdminUser(context.Background(), persistenceService, adminPass); err != nil {
+		log.Alertf("Error initializing admin user: %v", err)
+		os.Exit(1)
+	}
 
-	// Initialize self-signed root CA
-	rootCA, err := pki.InitRootCA(filepath.Join(certsDir, "ca"))
+	// Initialize the CA issuing the node and controller certificates
+	ca, err := getCA()
 	if err != nil {
 		log.Alertf("Error initializing Controller CA: %v", err)
 		os.Exit(1)
@@ -148,22 +253,48 @@ func main() {
 
 	// TODO: Replace printing to STDERR with writing to a file or making the
 	// certificate available via an HTTP endpoint.
-	log.Infof("Root CA:\n%s", encodeCA(rootCA))
+	log.Infof("Root CA:\n%s", encodeCA(ca))
+
+	// Rewrite the legacy serials of the enrolled nodes
+	migrated, err := cce.MigrateNodeSerials(context.Background(), persistenceService)
+	if err != nil {
+		log.Alertf("Error migrating node serials: %v", err)
+		os.Exit(1)
+	}
+	if migrated > 0 {
+		log.Infof("Rewrote the legacy serials of %d enrolled nodes", migrated)
+	}
+
+	// Load the revoked node certificates rejected by the TLS servers
+	revokedCerts, err := loadRevokedCertificates(persistenceService)
+	if err != nil {
+		log.Alertf("Error loading revoked certificates: %v", err)
+		os.Exit(1)
+	}
 
 	// Define controller service
 	controller := &cce.Controller{
-		PersistenceService: &mysql.PersistenceService{DB: db},
-		AuthorityService:   rootCA,
-		TokenService:       getTokenSigner(),
+		PersistenceService:    persistenceService,
+		AuthorityService:      ca,
+		RevokedCertificates:   revokedCerts,
+		TokenService:          getTokenSigner(),
+		IdentityProvider:      getIdentityProvider(),
+		CredentialsPolicy:     credsPolicy,
+		PendingApproval:       pendingApproval,
+		LegacyNodeSerials:     legacyNodeSerials,
+		EnforcePolicyAnalysis: enforcePolicyAnalysis,
 		AdminCreds: &cce.AuthCreds{
 			Username: "admin",
 			Password: adminPass,
 		},
-		OrchestrationMode: orchestrationMode,
-		KubernetesClient:  &k8sClient,
-		ELAPort:           strconv.Itoa(elaPort),
-		EVAPort:           strconv.Itoa(evaPort),
-		EdgeNodeCreds:     newClientTLSConf(rootCA, "controller.openness"),
+		OrchestrationMode:   orchestrationMode,
+		KubernetesClient:    &k8sClient,
+		ELAPort:             strconv.Itoa(elaPort),
+		EVAPort:             strconv.Itoa(evaPort),
+		EdgeNodeCreds:       newClientTLSConf(ca, "controller.openness"),
+		NodeConnIdleTimeout: nodeConnIdleTimeout,
+		ReconcileInterval:   reconcileInterval,
+		ReconcileWorkers:    reconcileWorkers,
 	}
 
 	// Create an error group to manage server goroutines
@@ -190,9 +321,23 @@ func main() {
 	syslogAddr := fmt.Sprintf(":%d", syslogPort)
 	statsdAddr := fmt.Sprintf(":%d", statsdPort)
 	eg.Go(serveHTTP(ctx, controller, httpAddr))
-	eg.Go(serveGRPC(ctx, controller, grpcAddr, getGRPCTLS(rootCA)))
-	eg.Go(serveTelemetry(ctx, syslogOut, syslogAddr, newTLSConf(rootCA, telemetry.SyslogSNI)))
-	eg.Go(serveTelemetry(ctx, statsdOut, statsdAddr, newTLSConf(rootCA, telemetry.StatsdSNI)))
+	eg.Go(serveGRPC(ctx, controller, grpcAddr, getGRPCTLS(ca, revokedCerts)))
+	eg.Go(serveTelemetry(ctx, syslogOut, syslogAddr, newTLSConf(ca, revokedCerts, telemetry.SyslogSNI)))
+	eg.Go(serveTelemetry(ctx, statsdOut, statsdAddr, newTLSConf(ca, revokedCerts, telemetry.StatsdSNI)))
+
+	// Rotate the token signing keys and prune the token revocation list
+	eg.Go(func() error { return controller.TokenService.Keys.Run(ctx) })
+	eg.Go(func() error { pruneRevokedTokens(ctx, persistenceService); return nil })
+
+	// Probe the connectivity of the nodes
+	if nodeProbeInterval > 0 {
+		prober := &health.Prober{
+			Controller:      controller,
+			Interval:        nodeProbeInterval,
+			DegradedLatency: nodeProbeDegradedLatency,
+		}
+		eg.Go(func() error { prober.Run(ctx); return nil })
+	}
 
 	log.Info("Controller CE ready")
 
@@ -206,6 +351,90 @@ func main() {
 	}
 }
 
+// Create the admin user on first start. The -adminPass flag remains the
+// password of the admin user, so the password is updated when the flag
+// changes.
+func ensureAdminUser(ctx context.Context, ps cce.PersistenceService, password string) error {
+	users, err := ps.Filter(ctx, &cce.User{}, []cce.Filter{{Field: "username", Value: "admin"}})
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		admin := &cce.User{
+			ID:       uuid.New(),
+			Username: "admin",
+			Roles:    []string{cce.RoleAdmin},
+		}
+		if err = admin.SetPassword(password); err != nil {
+			return err
+		}
+		log.Info("Creating admin user")
+		return ps.Create(ctx, admin)
+	}
+
+	admin := users[0].(*cce.User)
+	if admin.CheckPassword(password) {
+		return nil
+	}
+	if err = admin.SetPassword(password); err != nil {
+		return err
+	}
+	log.Info("Updating admin user password")
+	return ps.BulkUpdate(ctx, []cce.Persistable{admin})
+}
+
+// Delete revoked tokens from the revocation list once they have expired, as
+// they are rejected for their expiry from then on.
+func pruneRevokedTokens(ctx context.Context, ps cce.PersistenceService) {
+	ticker := time.NewTicker(revokedTokensPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		revoked, err := ps.ReadAll(ctx, &cce.RevokedToken{})
+		if err != nil {
+			log.Errf("Error reading revoked tokens: %v", err)
+			continue
+		}
+		for _, t := range revoked {
+			if !t.(*cce.RevokedToken).Expired() {
+				continue
+			}
+			if _, err = ps.Delete(ctx, t.GetID(), &cce.RevokedToken{}); err != nil {
+				log.Errf("Error deleting expired revoked token %s: %v", t.GetID(), err)
+			}
+		}
+	}
+}
+
+// loadRevokedCertificates loads the serial numbers of the revoked node
+// certificates into a revocation list.
+func loadRevokedCertificates(ps cce.PersistenceService) (*pki.RevocationList, error) {
+	revoked, err := ps.ReadAll(context.Background(), &cce.RevokedCertificate{})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &pki.RevocationList{}
+	for _, c := range revoked {
+		serial, ok := c.(*cce.RevokedCertificate).Serial()
+		if !ok {
+			log.Warningf("Ignoring revoked certificate %s with invalid serial number", c.GetID())
+			continue
+		}
+		list.Revoke(serial)
+	}
+	log.Infof("Loaded %d revoked certificates", len(revoked))
+
+	return list, nil
+}
+
 func registerAllNodes(ctx context.Context, ps cce.PersistenceService) {
 	persisted, err := ps.ReadAll(ctx, &cce.Node{})
 	if err == nil {
@@ -217,6 +446,32 @@ func registerAllNodes(ctx context.Context, ps cce.PersistenceService) {
 	}
 }
 
+// Connect to the persistence backend selected by the DSN scheme. A DSN
+// without a scheme is a mysql DSN.
+func connectPersistence(dsn string) cce.PersistenceService {
+	switch {
+	case strings.HasPrefix(dsn, "bolt://"):
+		return &bolt.PersistenceService{DB: connectBolt(strings.TrimPrefix(dsn, "bolt://"))}
+	default:
+		return &mysql.PersistenceService{DB: connectDB(strings.TrimPrefix(dsn, "mysql://"))}
+	}
+}
+
+// Open an embedded BoltDB file, creating it if it does not exist.
+func connectBolt(path string) *bbolt.DB {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		log.Alertf("Error creating directory for db %q: %v", path, err)
+		os.Exit(1)
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		log.Alertf("Error opening db: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("DB opened: %s", path)
+	return db
+}
+
 // Connect to a mysql DB and ping it for readiness.
 func connectDB(dsn string) *sql.DB {
 	db, err := sql.Open("mysql", dsn)
@@ -231,36 +486,115 @@ func connectDB(dsn string) *sql.DB {
 		os.Exit(1)
 	}
 	log.Info("DB connection established")
+
+	// Apply pending schema migrations, refusing to run against a schema
+	// migrated by a newer controller
+	if err = (&mysql.Migrator{DB: db}).Up(context.Background()); err != nil {
+		log.Alertf("DB migration failed: %v", err)
+		os.Exit(1)
+	}
+	log.Infof("DB schema at version %d", mysql.LatestVersion())
 	return db
 }
 
 // Encode self-signed Controller CA. This is used to manually configure the
 // Appliance by adding the Controller to its trust anchor pool for TLS
 // connections.
-func encodeCA(rootCA *pki.RootCA) string {
+func encodeCA(ca certificateAuthority) string {
+	chain, err := ca.CAChain()
+	if err != nil || len(chain) == 0 {
+		return ""
+	}
+
 	return string(pem.EncodeToMemory(
 		&pem.Block{
 			Type:  "CERTIFICATE",
-			Bytes: rootCA.Cert.Raw,
+			Bytes: chain[len(chain)-1].Raw,
 		},
 	))
 }
 
-// Generate a key for signing authentication tokens. The key is only stored
-// in memory and will be re-generated upon Controller restart.
-//
-// TODO: Persist the key to avoid having API/UI users have to login and get a
-// new token every time the Controller is restarted.
-func getTokenSigner() *jose.JWSTokenIssuer {
-	joseKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+// certificateAuthority issues the node certificates and the TLS certificates
+// of the controller.
+type certificateAuthority interface {
+	cce.AuthorityService
+	NewTLSClientCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error)
+	NewTLSServerCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error)
+}
+
+// Load the intermediate CA if one is configured, otherwise initialize the
+// self-signed root CA, generating it on first start.
+func getCA() (certificateAuthority, error) {
+	if intermediateCADir == "" {
+		return pki.InitRootCA(filepath.Join(certsDir, "ca"))
+	}
+
+	// The key is loaded from key.pem in the intermediate CA directory unless
+	// another key file or the URI of a key of a registered signer is set
+	var signer crypto.Signer
+	if intermediateCAKey != "" {
+		var err error
+		if signer, err = pki.OpenSigner(intermediateCAKey); err != nil {
+			return nil, fmt.Errorf("unable to load intermediate CA key: %v", err)
+		}
+	}
+
+	ca, err := pki.LoadIntermediateCA(intermediateCADir, signer)
 	if err != nil {
-		log.Alertf("error generating token signing key: %v", err)
+		return nil, err
+	}
+	log.Infof("Loaded intermediate CA %q issued by %q", ca.Cert.Subject, ca.Cert.Issuer)
+
+	return ca, nil
+}
+
+// Load the keys for signing authentication tokens, generating a key on first
+// start. The keys are persisted so that API/UI users do not have to login and
+// get a new token every time the Controller is restarted.
+func getTokenSigner() *jose.JWSTokenIssuer {
+	keys := &jose.KeyRing{
+		Dir:            tokenKeysDir,
+		RotationPeriod: tokenKeyRotation,
+		GracePeriod:    tokenKeyGrace,
+	}
+	if err := keys.Load(); err != nil {
+		log.Alertf("error loading token signing keys: %v", err)
 		os.Exit(1)
 	}
+	if tokenKeyRotation > 0 && tokenKeyGrace < tokenLifetime {
+		log.Warningf("Token key grace period %v is shorter than the token lifetime %v: "+
+			"tokens will be rejected before they expire after a key rotation", tokenKeyGrace, tokenLifetime)
+	}
 	return &jose.JWSTokenIssuer{
-		Key:          joseKey,
-		KeyAlgorithm: "ES384",
+		Keys:     keys,
+		Lifetime: tokenLifetime,
+	}
+}
+
+// Configure the OpenID Connect identity provider from the flags, if an issuer
+// is set.
+func getIdentityProvider() *oidc.Provider {
+	if idp.Issuer == "" {
+		return nil
+	}
+
+	if oidcScopes != "" {
+		idp.Scopes = strings.Split(oidcScopes, ",")
 	}
+	idp.GroupRoles = make(map[string][]string)
+	if oidcGroupRole != "" {
+		for _, mapping := range strings.Split(oidcGroupRole, ",") {
+			groupRole := strings.SplitN(mapping, "=", 2)
+			if len(groupRole) != 2 || !cce.IsValidRole(groupRole[1]) {
+				log.Alertf("Invalid OIDC group role mapping %q", mapping)
+				os.Exit(1)
+			}
+			idp.GroupRoles[groupRole[0]] = append(idp.GroupRoles[groupRole[0]], groupRole[1])
+		}
+	}
+	log.Infof("Logging in with OIDC identity provider %s enabled", idp.Issuer)
+
+	return &idp
 }
 
 func serveHTTP(ctx context.Context, controller *cce.Controller, addr string) func() error {
@@ -276,12 +610,14 @@ func serveHTTP(ctx context.Context, controller *cce.Controller, addr string) fun
 	// handler must be applied at the top-level router.
 	cors := handlers.CORS(
 		handlers.AllowedOrigins([]string{"*"}),
-		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "ContentType"}),
+		handlers.AllowedHeaders([]string{"Authorization", "Content-Type", "ContentType", "If-Match"}),
 		handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
+		handlers.ExposedHeaders([]string{"ETag"}),
 	)
 
 	// Configure http server
 	koko := gorilla.NewGorilla(controller)
+	go koko.Run(ctx)
 
 	httpServer := http.NewServer(cors(koko))
 
@@ -401,14 +737,14 @@ func serveTelemetry(ctx context.Context, outfile, addr string, conf *tls.Config)
 //
 // In the gRPC server the servername will be considered for the particular RPCs
 // authorized to the client.
-func getGRPCTLS(rootCA *pki.RootCA) *tls.Config {
+func getGRPCTLS(ca certificateAuthority, revoked *pki.RevocationList) *tls.Config {
 	// Generate server TLS config for post-enrollment
-	serverConf := newTLSConf(rootCA, grpc.SNI)
+	serverConf := newTLSConf(ca, revoked, grpc.SNI)
 	serverConf.NextProtos = []string{"h2"}
 	serverConf.ClientAuth = tls.RequireAndVerifyClientCert
 
 	// Generate server TLS config for enrollment
-	enrollmentConf := newTLSConf(rootCA, grpc.EnrollmentSNI)
+	enrollmentConf := newTLSConf(ca, revoked, grpc.EnrollmentSNI)
 	enrollmentConf.NextProtos = []string{"h2"}
 	enrollmentConf.ClientAuth = tls.NoClientCert
 
@@ -429,20 +765,20 @@ func getGRPCTLS(rootCA *pki.RootCA) *tls.Config {
 	}
 }
 
-// Generate a new TLS key/cert pair from a root CA for use in a TLS server with
-// some server name.
-func newTLSConf(rootCA *pki.RootCA, sni string) *tls.Config {
+// Generate a new TLS key/cert pair from a CA for use in a TLS server with some
+// server name. Peer certificates in the revocation list are rejected.
+func newTLSConf(ca certificateAuthority, revoked *pki.RevocationList, sni string) *tls.Config {
 	tlsKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	if err != nil {
 		log.Alertf("error generating TLS key for server %q: %v", sni, err)
 		os.Exit(1)
 	}
-	tlsCert, err := rootCA.NewTLSServerCert(tlsKey, sni)
+	tlsCert, err := ca.NewTLSServerCert(tlsKey, sni)
 	if err != nil {
 		log.Alertf("error generating TLS cert for server %q: %v", sni, err)
 		os.Exit(1)
 	}
-	tlsCAChain, err := rootCA.CAChain()
+	tlsCAChain, err := ca.CAChain()
 	if err != nil {
 		log.Alertf("error getting TLS CA chain for server %q: %v", sni, err)
 		os.Exit(1)
@@ -459,26 +795,27 @@ func newTLSConf(rootCA *pki.RootCA, sni string) *tls.Config {
 			PrivateKey:  tlsKey,
 			Leaf:        tlsCert,
 		}},
-		ClientCAs:    tlsRoots,
-		MinVersion:   tls.VersionTLS12,
-		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		ClientCAs:             tlsRoots,
+		VerifyPeerCertificate: revoked.VerifyPeerCertificate,
+		MinVersion:            tls.VersionTLS12,
+		CipherSuites:          []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
 	}
 }
 
-// Generate a new TLS key/cert pair from a root CA for use in a TLS server with
-// some server name.
-func newClientTLSConf(rootCA *pki.RootCA, sni string) *tls.Config {
+// Generate a new TLS key/cert pair from a CA for use in a TLS server with some
+// server name.
+func newClientTLSConf(ca certificateAuthority, sni string) *tls.Config {
 	tlsKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	if err != nil {
 		log.Alertf("error generating TLS key for server %q: %v", sni, err)
 		os.Exit(1)
 	}
-	tlsCert, err := rootCA.NewTLSClientCert(tlsKey, sni)
+	tlsCert, err := ca.NewTLSClientCert(tlsKey, sni)
 	if err != nil {
 		log.Alertf("error generating TLS cert for server %q: %v", sni, err)
 		os.Exit(1)
 	}
-	tlsCAChain, err := rootCA.CAChain()
+	tlsCAChain, err := ca.CAChain()
 	if err != nil {
 		log.Alertf("error getting TLS CA chain for server %q: %v", sni, err)
 		os.Exit(1)
@@ -8,12 +8,10 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/md5" //nolint:gosec
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
@@ -25,6 +23,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"testing"
@@ -39,6 +38,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/grpclog"
 
+	cce "github.com/open-ness/edgecontroller"
 	cceGRPC "github.com/open-ness/edgecontroller/grpc"
 	authpb "github.com/open-ness/edgecontroller/pb/auth"
 	"github.com/open-ness/edgecontroller/pki"
@@ -49,10 +49,11 @@ var (
 	adminPass string
 	dbPass    string
 
-	cmd    *exec.Cmd
-	ctrl   *gexec.Session
-	node   *gexec.Session
-	nodeIn io.WriteCloser
+	cmd     *exec.Cmd
+	ctrlExe string
+	ctrl    *gexec.Session
+	node    *gexec.Session
+	nodeIn  io.WriteCloser
 
 	authSvcCli authpb.AuthServiceClient
 	apiCli     *apiClient
@@ -103,6 +104,7 @@ func startup() {
 	By("Building the controller")
 	exe, err := gexec.Build("github.com/open-ness/edgecontroller/cmd/cce")
 	Expect(err).ToNot(HaveOccurred(), "Problem building service")
+	ctrlExe = exe
 
 	By("Creating a temp dir for telemetry output files")
 	tmpdir, err := ioutil.TempDir(".", "telemetry")
@@ -131,6 +133,9 @@ func startup() {
 		"-statsdPort", "8125",
 		"-syslog-path", filepath.Join(telemDir, "syslog.log"),
 		"-statsd-path", filepath.Join(telemDir, "statsd.log"),
+		"-legacy-node-serials",
+		"-node-probe-interval", "0",
+		"-reconcile-interval", "0",
 		"-adminPass", adminPass)
 	ctrl, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
 	Expect(err).ToNot(HaveOccurred(), "Problem starting service")
@@ -171,6 +176,35 @@ func startup() {
 	Expect(err).ToNot(HaveOccurred(), "Problem starting node")
 }
 
+// startController starts another controller with additional flags. It
+// listens for HTTP requests on the port and for gRPC requests and telemetry on
+// the ports after it, and is returned once it is ready.
+func startController(httpPort int, args ...string) *gexec.Session {
+	By("Starting another controller")
+	args = append([]string{
+		"-log-level", "debug",
+		"-dsn", fmt.Sprintf("root:%s@tcp(:8083)/controller_ce", dbPass),
+		"-httpPort", strconv.Itoa(httpPort),
+		"-grpcPort", strconv.Itoa(httpPort + 1),
+		"-syslogPort", strconv.Itoa(httpPort + 2),
+		"-statsdPort", strconv.Itoa(httpPort + 3),
+		"-syslog-path", filepath.Join(telemDir, fmt.Sprintf("syslog-%d.log", httpPort)),
+		"-statsd-path", filepath.Join(telemDir, fmt.Sprintf("statsd-%d.log", httpPort)),
+		"-legacy-node-serials",
+		"-node-probe-interval", "0",
+		"-reconcile-interval", "0",
+		"-adminPass", adminPass,
+	}, args...)
+	session, err := gexec.Start(exec.Command(ctrlExe, args...), GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred(), "Problem starting service")
+
+	Eventually(session.Err, 3).Should(gbytes.Say(
+		"Controller CE ready"),
+		"Service did not start in time")
+
+	return session
+}
+
 func shutdown() {
 	if ctrl != nil {
 		By("Stopping the controller service")
@@ -216,11 +250,16 @@ func clearGRPCTargetsTable() {
 }
 
 func authToken() string {
+	return userToken("admin", adminPass)
+}
+
+// userToken logs in a user and returns the auth token.
+func userToken(username, password string) string {
 	payload, err := json.Marshal(
 		struct {
 			Username string `json:"username"`
 			Password string `json:"password"`
-		}{"admin", adminPass})
+		}{username, password})
 	Expect(err).ToNot(HaveOccurred())
 
 	req, err := http.NewRequest(
@@ -403,6 +442,12 @@ type nodeConfig struct {
 }
 
 func createAndRegisterNode() *nodeConfig {
+	return createAndRegisterLabeledNode("")
+}
+
+// createAndRegisterLabeledNode pre-approves a node with the labels, a JSON
+// object, and enrolls it. The labels are omitted if empty.
+func createAndRegisterLabeledNode(labels string) *nodeConfig {
 	By("Generating node private key")
 	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
 	Expect(err).ToNot(HaveOccurred())
@@ -427,9 +472,8 @@ func createAndRegisterNode() *nodeConfig {
 		})
 
 	By("Pre-approving Node by serial")
-	hash := md5.Sum(certReq.RawSubjectPublicKeyInfo) //nolint:gosec
-	serial := base64.RawURLEncoding.EncodeToString(hash[:])
-	nodeID := postNodesSerial(serial)
+	serial := cce.NodeSerial(certReq.RawSubjectPublicKeyInfo)
+	nodeID := postLabeledNodesSerial(serial, labels)
 
 	By("Resetting the node")
 	Expect(cmd.Process.Signal(syscall.SIGABRT)).To(Succeed(), "Problem resetting node")
@@ -461,6 +505,17 @@ func createAndRegisterNode() *nodeConfig {
 }
 
 func postNodesSerial(serial string) (id string) {
+	return postLabeledNodesSerial(serial, "")
+}
+
+// postLabeledNodesSerial pre-approves a node with the labels, a JSON object.
+// The labels are omitted if empty.
+func postLabeledNodesSerial(serial, labels string) (id string) {
+	if labels != "" {
+		labels = fmt.Sprintf(`,
+				"labels": %s`, labels)
+	}
+
 	By("Sending a POST /nodes request")
 	resp, err := apiCli.Post(
 		"http://127.0.0.1:8080/nodes",
@@ -469,8 +524,8 @@ func postNodesSerial(serial string) (id string) {
 			{
 				"name": "Test Node 1",
 				"location": "Localhost port 42101",
-				"serial": "%s"
-			}`, serial)))
+				"serial": "%s"%s
+			}`, serial, labels)))
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
@@ -733,8 +788,33 @@ func postNodeApps(nodeID, appID string) {
 	Expect(err).ToNot(HaveOccurred())
 	defer resp.Body.Close()
 
-	By("Verifying a 200 Created response")
-	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	By("Waiting for the app to be deployed")
+	Expect(waitForOperation(resp).Status).To(Equal("succeeded"))
+}
+
+// waitForOperation waits for the operation accepted by the response to end
+// and returns it.
+func waitForOperation(resp *http.Response) *swagger.OperationDetail {
+	By("Verifying a 202 Accepted response")
+	Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+	location := resp.Header.Get("Location")
+	Expect(location).To(HavePrefix("/operations/"))
+
+	var op *swagger.OperationDetail
+	Eventually(func() string {
+		By(fmt.Sprintf("Sending a GET %s request", location))
+		resp, err := apiCli.Get("http://127.0.0.1:8080" + location)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(body, &op)).To(Succeed())
+		return op.Status
+	}, 30*time.Second, 100*time.Millisecond).Should(Or(Equal("succeeded"), Equal("failed"), Equal("canceled")))
+
+	return op
 }
 
 func getNodeApps(nodeID string) *swagger.NodeAppList {
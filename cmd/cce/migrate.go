@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/mysql"
+)
+
+// runMigrate runs the migrate subcommand, which applies the mysql schema
+// migrations without starting the controller:
+//
+//     cce migrate -dsn <dsn> [-version <version>] [-status]
+//
+// Without -version the database is migrated to the latest version.
+func runMigrate(args []string) error {
+	var (
+		migrateDSN string
+		target     int
+		status     bool
+	)
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.StringVar(&migrateDSN, "dsn", "", "Data source name of the mysql database")
+	fs.IntVar(&target, "version", mysql.LatestVersion(), "Schema version to migrate up or down to")
+	fs.BoolVar(&status, "status", false, "Print the current and latest schema versions and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if strings.HasPrefix(migrateDSN, "bolt://") {
+		return fmt.Errorf("migrations only apply to mysql databases")
+	}
+
+	db, err := sql.Open("mysql", strings.TrimPrefix(migrateDSN, "mysql://"))
+	if err != nil {
+		return fmt.Errorf("error opening db: %v", err)
+	}
+	defer db.Close()
+
+	m := &mysql.Migrator{DB: db}
+	ctx := context.Background()
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if status {
+		fmt.Fprintf(os.Stdout, "current: %d\nlatest: %d\n", current, mysql.LatestVersion())
+		return nil
+	}
+
+	if err = m.MigrateTo(ctx, target); err != nil {
+		return err
+	}
+	log.Infof("Migrated schema from version %d to %d", current, target)
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os/exec"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+
+	"github.com/open-ness/edgecontroller/mysql"
+)
+
+var _ = Describe("Migrate subcommand", func() {
+	const database = "controller_ce_migrate"
+
+	var server *sql.DB
+
+	BeforeEach(func() {
+		var err error
+		server, err = sql.Open("mysql", fmt.Sprintf("root:%s@tcp(:8083)/", dbPass))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server.Exec("CREATE DATABASE " + database)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		_, err := server.Exec("DROP DATABASE IF EXISTS " + database)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(server.Close()).To(Succeed())
+	})
+
+	migrate := func(args ...string) *gexec.Session {
+		args = append([]string{"migrate", "-dsn",
+			fmt.Sprintf("root:%s@tcp(:8083)/%s", dbPass, database)}, args...)
+		session, err := gexec.Start(exec.Command(ctrlExe, args...), GinkgoWriter, GinkgoWriter)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(session, 30).Should(gexec.Exit())
+		return session
+	}
+
+	It("Should print the schema versions", func() {
+		session := migrate("-status")
+		Expect(session.ExitCode()).To(Equal(0))
+		Expect(string(session.Out.Contents())).To(ContainSubstring(
+			fmt.Sprintf("current: 0\nlatest: %d\n", mysql.LatestVersion())))
+	})
+
+	It("Should migrate up and down to a version", func() {
+		Expect(migrate("-version", "3").ExitCode()).To(Equal(0))
+		Expect(string(migrate("-status").Out.Contents())).To(HavePrefix("current: 3\n"))
+
+		By("Migrating to the latest version")
+		Expect(migrate().ExitCode()).To(Equal(0))
+		Expect(string(migrate("-status").Out.Contents())).To(HavePrefix(
+			fmt.Sprintf("current: %d\n", mysql.LatestVersion())))
+
+		By("Migrating down to version 2")
+		Expect(migrate("-version", "2").ExitCode()).To(Equal(0))
+		Expect(string(migrate("-status").Out.Contents())).To(HavePrefix("current: 2\n"))
+	})
+
+	It("Should fail for an unknown version", func() {
+		Expect(migrate("-version", fmt.Sprint(mysql.LatestVersion()+1)).ExitCode()).ToNot(Equal(0))
+		Expect(string(migrate("-status").Out.Contents())).To(HavePrefix("current: 0\n"))
+	})
+})
@@ -88,6 +88,49 @@ var _ = Describe("Node Auth Service", func() {
 				Expect(json.NewDecoder(resp.Body).Decode(&nodeResp)).To(Succeed())
 				Expect(nodeResp.Serial).To(Equal(nodeCfg.serial))
 			})
+
+			It("Should accept a node pre-approved by its legacy serial", func() {
+				clearGRPCTargetsTable()
+
+				By("Generating node private key")
+				key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+				Expect(err).ToNot(HaveOccurred())
+
+				By("Creating a certificate signing request with private key")
+				csrDER, err := x509.CreateCertificateRequest(
+					rand.Reader,
+					&x509.CertificateRequest{},
+					key,
+				)
+				Expect(err).ToNot(HaveOccurred())
+				certReq, err := x509.ParseCertificateRequest(csrDER)
+				Expect(err).ToNot(HaveOccurred())
+
+				By("Pre-approving Node by legacy serial")
+				nodeID := postNodesSerial(cce.LegacyNodeSerial(certReq.RawSubjectPublicKeyInfo))
+
+				By("Requesting credentials from auth service")
+				credentials, err := authSvcCli.RequestCredentials(
+					context.TODO(),
+					&authpb.Identity{
+						Csr: string(pem.EncodeToMemory(
+							&pem.Block{
+								Type:  "CERTIFICATE REQUEST",
+								Bytes: csrDER,
+							})),
+					},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(credentials.Certificate).ToNot(BeEmpty())
+
+				By("Verifying the Node's serial was rewritten")
+				resp, err := apiCli.Get("http://127.0.0.1:8080/nodes/" + nodeID)
+				Expect(err).ToNot(HaveOccurred())
+				defer resp.Body.Close()
+				var nodeResp cce.Node
+				Expect(json.NewDecoder(resp.Body).Decode(&nodeResp)).To(Succeed())
+				Expect(nodeResp.Serial).To(Equal(cce.NodeSerial(certReq.RawSubjectPublicKeyInfo)))
+			})
 		})
 	})
 
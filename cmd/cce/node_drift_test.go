@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/onsi/gomega/gexec"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// getNodeDrift returns the drift report of the node from
+// GET /nodes/{node_id}/drift.
+func getNodeDrift(nodeID string) *swagger.NodeDrift {
+	By("Sending a GET /nodes/{node_id}/drift request")
+	resp, err := apiCli.Get(fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/drift", nodeID))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	By("Verifying a 200 OK response")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	By("Reading the response body")
+	body, err := ioutil.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var drift swagger.NodeDrift
+
+	By("Unmarshaling the response")
+	Expect(json.Unmarshal(body, &drift)).To(Succeed())
+
+	return &drift
+}
+
+var _ = Describe("GET /nodes/{node_id}/drift", func() {
+	var nodeCfg *nodeConfig
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	It("Should return an empty report for a node that was never reconciled", func() {
+		drift := getNodeDrift(nodeCfg.nodeID)
+		Expect(drift.ReconciledAt).To(BeNil())
+		Expect(drift.Items).To(BeEmpty())
+	})
+
+	It("Should return 404 for a nonexistent node", func() {
+		By("Sending a GET /nodes/{node_id}/drift request")
+		resp, err := apiCli.Get(fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/drift", uuid.New()))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 404 Not Found response")
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	Describe("With reconciliation enabled", func() {
+		var reconcileCtl *gexec.Session
+
+		BeforeEach(func() {
+			reconcileCtl = startController(8115,
+				"-reconcile-interval", "500ms",
+				"-reconcile-workers", "2")
+		})
+
+		AfterEach(func() {
+			reconcileCtl.Kill()
+			Eventually(reconcileCtl).Should(gexec.Exit())
+		})
+
+		It("Should return the report of the last reconciliation", func() {
+			By("Waiting for the node to be reconciled")
+			Eventually(func() interface{} {
+				return getNodeDrift(nodeCfg.nodeID).ReconciledAt
+			}, 5).ShouldNot(BeNil())
+
+			drift := getNodeDrift(nodeCfg.nodeID)
+			Expect(drift.ReconciledAt.IsZero()).To(BeFalse())
+			Expect(drift.Items).ToNot(BeNil())
+		})
+	})
+})
@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Node groups", func() {
+	var (
+		appID  string
+		region string
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		appID = postApps("container")
+		region = uuid.New()
+	})
+
+	patchNodeLabels := func(nodeID string, labels string) {
+		By("Sending a PATCH /nodes/{node_id} request")
+		resp, err := apiCli.Patch(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s", nodeID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`
+				{
+					"name": "Test Node 1",
+					"location": "Localhost port 42101",
+					"serial": "%s",
+					"labels": %s
+				}`, getNode(nodeID).Serial, labels)))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	}
+
+	postNodeGroups := func(req string) *http.Response {
+		By("Sending a POST /node_groups request")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/node_groups",
+			"application/json",
+			strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	getNodeList := func(path string) *swagger.NodeList {
+		By(fmt.Sprintf("Sending a GET %s request", path))
+		resp, err := apiCli.Get("http://127.0.0.1:8080" + path)
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var list swagger.NodeList
+		Expect(json.Unmarshal(body, &list)).To(Succeed())
+		return &list
+	}
+
+	Describe("GET /nodes?selector", func() {
+		It("Should list the nodes matching the selector", func() {
+			nodeCfg := createAndRegisterNode()
+			patchNodeLabels(nodeCfg.nodeID, fmt.Sprintf(`{"region": "%s", "tier": "far-edge"}`, region))
+			Expect(getNode(nodeCfg.nodeID).Labels).To(Equal(map[string]string{
+				"region": region,
+				"tier":   "far-edge",
+			}))
+
+			nodes := getNodeList("/nodes?selector=" + url.QueryEscape("region="+region+",tier"))
+			Expect(nodes.Nodes).To(HaveLen(1))
+			Expect(nodes.Nodes[0].ID).To(Equal(nodeCfg.nodeID))
+
+			nodes = getNodeList("/nodes?selector=" + url.QueryEscape("region="+region+",!tier"))
+			Expect(nodes.Nodes).To(BeEmpty())
+		})
+
+		It("Should return 400 Bad Request for an invalid selector", func() {
+			resp, err := apiCli.Get("http://127.0.0.1:8080/nodes?selector=" + url.QueryEscape("region=e u"))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("POST /node_groups", func() {
+		It("Should deploy the apps of the group to the nodes of the group", func() {
+			nodeCfg := createAndRegisterNode()
+			patchNodeLabels(nodeCfg.nodeID, fmt.Sprintf(`{"region": "%s"}`, region))
+
+			resp := postNodeGroups(fmt.Sprintf(`
+				{
+					"name": "test-group",
+					"selector": "region=%s",
+					"apps": ["%s"]
+				}`, region, appID))
+			defer resp.Body.Close()
+
+			By("Verifying a 201 Created response")
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			var rb respBody
+			Expect(json.Unmarshal(body, &rb)).To(Succeed())
+
+			By("Verifying the app is deployed to the node")
+			Eventually(func() []swagger.NodeAppSummary {
+				return getNodeApps(nodeCfg.nodeID).NodeApps
+			}, 15*time.Second, 100*time.Millisecond).Should(Equal([]swagger.NodeAppSummary{
+				{ID: appID},
+			}))
+
+			By("Verifying the node is in the group")
+			nodes := getNodeList(fmt.Sprintf("/node_groups/%s/nodes", rb.ID))
+			Expect(nodes.Nodes).To(HaveLen(1))
+			Expect(nodes.Nodes[0].ID).To(Equal(nodeCfg.nodeID))
+		})
+
+		It("Should deploy the apps of the group to a node once it enrolls", func() {
+			resp := postNodeGroups(fmt.Sprintf(`
+				{
+					"name": "test-group",
+					"selector": "region=%s",
+					"apps": ["%s"]
+				}`, region, appID))
+			defer resp.Body.Close()
+
+			By("Verifying a 201 Created response")
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+			nodeCfg := createAndRegisterLabeledNode(fmt.Sprintf(`{"region": "%s"}`, region))
+
+			By("Verifying the app is deployed to the node")
+			Eventually(func() []swagger.NodeAppSummary {
+				return getNodeApps(nodeCfg.nodeID).NodeApps
+			}, 15*time.Second, 100*time.Millisecond).Should(Equal([]swagger.NodeAppSummary{
+				{ID: appID},
+			}))
+		})
+
+		It("Should return 400 Bad Request for an empty selector", func() {
+			resp := postNodeGroups(`{"name": "test-group", "selector": ""}`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal("Validation failed: selector cannot be empty"))
+		})
+
+		It("Should return 422 Unprocessable Entity for a nonexistent app", func() {
+			id := uuid.New()
+			resp := postNodeGroups(fmt.Sprintf(`
+				{
+					"name": "test-group",
+					"selector": "region=%s",
+					"apps": ["%s"]
+				}`, region, id))
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal(fmt.Sprintf("apps %s not found", id)))
+		})
+	})
+})
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/onsi/gomega/gexec"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// listNodeBySerial returns the summary of the node with the serial from
+// GET /nodes.
+func listNodeBySerial(serial string) *swagger.NodeSummary {
+	By("Sending a GET /nodes request")
+	resp, err := apiCli.Get(fmt.Sprintf("http://127.0.0.1:8080/nodes?serial=%s", serial))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	By("Verifying a 200 OK response")
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	By("Reading the response body")
+	body, err := ioutil.ReadAll(resp.Body)
+	Expect(err).ToNot(HaveOccurred())
+
+	var nodes swagger.NodeList
+
+	By("Unmarshaling the response")
+	Expect(json.Unmarshal(body, &nodes)).To(Succeed())
+	Expect(nodes.Nodes).To(HaveLen(1))
+
+	return &nodes.Nodes[0]
+}
+
+var _ = Describe("Node health", func() {
+	var (
+		nodeCfg  *nodeConfig
+		probeCtl *gexec.Session
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	It("Should omit the health of a node that was never probed", func() {
+		Expect(listNodeBySerial(nodeCfg.serial).Health).To(BeNil())
+		Expect(getNode(nodeCfg.nodeID).Health).To(BeNil())
+	})
+
+	Describe("With probing enabled", func() {
+		BeforeEach(func() {
+			probeCtl = startController(8110, "-node-probe-interval", "500ms")
+		})
+
+		AfterEach(func() {
+			probeCtl.Kill()
+			Eventually(probeCtl).Should(gexec.Exit())
+		})
+
+		It("Should include the health in GET /nodes and GET /nodes/{id}", func() {
+			By("Waiting for the node to be probed")
+			Eventually(func() *swagger.NodeHealth {
+				return listNodeBySerial(nodeCfg.serial).Health
+			}, 5).ShouldNot(BeNil())
+
+			summary := listNodeBySerial(nodeCfg.serial).Health
+			Expect(summary.Status).To(SatisfyAny(
+				Equal(cce.NodeStatusOnline), Equal(cce.NodeStatusDegraded), Equal(cce.NodeStatusOffline)))
+			Expect(summary.CheckedAt).ToNot(BeZero())
+
+			By("Verifying GET /nodes/{id} includes the health")
+			detail := getNode(nodeCfg.nodeID).Health
+			Expect(detail).ToNot(BeNil())
+			Expect(detail.Status).To(SatisfyAny(
+				Equal(cce.NodeStatusOnline), Equal(cce.NodeStatusDegraded), Equal(cce.NodeStatusOffline)))
+		})
+	})
+})
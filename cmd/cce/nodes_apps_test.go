@@ -28,7 +28,7 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 	})
 
 	Describe("POST /nodes/{node_id}/apps", func() {
-		DescribeTable("200 OK",
+		DescribeTable("202 Accepted",
 			func() {
 				nodeCfg := createAndRegisterNode()
 
@@ -45,8 +45,11 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 response")
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				By("Waiting for the app to be deployed")
+				op := waitForOperation(resp)
+				Expect(op.Status).To(Equal("succeeded"))
+				Expect(op.Kind).To(Equal("deploy_app"))
+				Expect(op.Result).To(Equal(fmt.Sprintf("/nodes/%s/apps/%s", nodeCfg.nodeID, appID)))
 			},
 			Entry(
 				"POST /nodes/{node_id}/apps"),
@@ -205,7 +208,7 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 	})
 
 	Describe("PATCH /nodes/{node_id}/apps/{app_id}", func() {
-		DescribeTable("200 OK",
+		DescribeTable("202 Accepted",
 			func(reqStr string, expectedNodeAppResp *swagger.NodeAppDetail) {
 				nodeCfg := createAndRegisterNode()
 				postNodeApps(nodeCfg.nodeID, appID)
@@ -218,9 +221,8 @@ var _ = Describe("/nodes/{node_id}/apps", func() {
 				Expect(err).ToNot(HaveOccurred())
 				defer resp.Body.Close()
 
-				By("Verifying a 200 OK response")
-				Expect(err).ToNot(HaveOccurred())
-				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+				By("Waiting for the command to run")
+				Expect(waitForOperation(resp).Status).To(Equal("succeeded"))
 
 				By("Getting the updated node")
 				updatedNodeAppResp := getNodeApp(nodeCfg.nodeID, appID)
@@ -109,6 +109,35 @@ var _ = Describe("/nodes", func() {
 				}`,
 				"Validation failed: serial cannot be empty"),
 		)
+
+		It("Should return 422 for a duplicate serial", func() {
+			serial := uuid.New()
+			postNodesSerial(serial)
+
+			By("Sending a POST /nodes request with the same serial")
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/nodes",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`
+				{
+					"name": "node123",
+					"location": "smart edge lab",
+					"serial": "%s"
+				}`, serial)))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying a 422 Unprocessable Entity response")
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+			By("Reading the response body")
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Verifying the response body")
+			Expect(string(body)).To(Equal(
+				fmt.Sprintf("duplicate record in nodes detected for serial %s", serial)))
+		})
 	})
 
 	Describe("GET /nodes", func() {
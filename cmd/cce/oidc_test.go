@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// mockIdentityProvider is a local OpenID Connect identity provider. Its
+// authorization endpoint redirects back to the controller at once with an
+// authorization code, which is exchanged for an ID token of the configured
+// user.
+type mockIdentityProvider struct {
+	*httptest.Server
+	key jose.JSONWebKey
+
+	mu       sync.Mutex
+	subject  string
+	username string
+	groups   []string
+	nonce    string
+}
+
+func newMockIdentityProvider() *mockIdentityProvider {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	m := &mockIdentityProvider{key: jose.JSONWebKey{Key: priv, KeyID: "test-key", Algorithm: "ES256", Use: "sig"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		m.writeJSON(w, map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		m.writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{m.key.Public()}})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.nonce = r.FormValue("nonce")
+		m.mu.Unlock()
+
+		callback, err := url.Parse(r.FormValue("redirect_uri"))
+		Expect(err).ToNot(HaveOccurred())
+		callback.RawQuery = url.Values{
+			"code":  {"test-code"},
+			"state": {r.FormValue("state")},
+		}.Encode()
+		http.Redirect(w, r, callback.String(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "test-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			m.writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		m.mu.Lock()
+		claims := map[string]interface{}{
+			"iss":                m.URL,
+			"sub":                m.subject,
+			"aud":                "test-client",
+			"iat":                time.Now().Unix(),
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"nonce":              m.nonce,
+			"preferred_username": m.username,
+			"groups":             m.groups,
+		}
+		m.mu.Unlock()
+
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: m.key},
+			new(jose.SignerOptions).WithType("JWT"))
+		Expect(err).ToNot(HaveOccurred())
+		idToken, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		Expect(err).ToNot(HaveOccurred())
+
+		m.writeJSON(w, map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+
+	return m
+}
+
+// setUser sets the user the identity provider authenticates.
+func (m *mockIdentityProvider) setUser(subject, username string, groups ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subject = subject
+	m.username = username
+	m.groups = groups
+}
+
+func (m *mockIdentityProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	Expect(json.NewEncoder(w).Encode(v)).To(Succeed())
+}
+
+var _ = Describe("OpenID Connect Login", func() {
+	const (
+		oidcURL      = "http://127.0.0.1:8090"
+		loginURL     = oidcURL + "/auth/oidc/login"
+		callbackPath = "/auth/oidc/callback"
+	)
+
+	var (
+		idp     *mockIdentityProvider
+		oidcCtl *gexec.Session
+		subject string
+	)
+
+	BeforeEach(func() {
+		idp = newMockIdentityProvider()
+		subject = uuid.New()
+		idp.setUser(subject, "oidc-"+subject, "netops")
+
+		oidcCtl = startController(8090,
+			"-oidc-issuer", idp.URL,
+			"-oidc-client-id", "test-client",
+			"-oidc-client-secret", "test-secret",
+			"-oidc-redirect-url", oidcURL+callbackPath,
+			"-oidc-group-roles", "netops=operator,auditors=viewer")
+	})
+
+	AfterEach(func() {
+		oidcCtl.Kill()
+		Eventually(oidcCtl).Should(gexec.Exit())
+		idp.Close()
+	})
+
+	// login logs in through the identity provider and returns the response
+	// of the callback.
+	login := func() *http.Response {
+		jar, err := cookiejar.New(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Sending a GET /auth/oidc/login request")
+		resp, err := (&http.Client{Jar: jar}).Get(loginURL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resp.Request.URL.Path).To(Equal(callbackPath))
+
+		return resp
+	}
+
+	// loginToken logs in through the identity provider and returns the
+	// controller token.
+	loginToken := func() string {
+		resp := login()
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		var auth struct {
+			Token string `json:"token"`
+		}
+		Expect(json.NewDecoder(resp.Body).Decode(&auth)).To(Succeed())
+		Expect(auth.Token).ToNot(BeEmpty())
+
+		return auth.Token
+	}
+
+	Describe("GET /auth/oidc/login", func() {
+		It("Should redirect to the identity provider", func() {
+			client := &http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			resp, err := client.Get(loginURL)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusFound))
+
+			location, err := resp.Location()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(location.String()).To(HavePrefix(idp.URL + "/authorize?"))
+			Expect(location.Query().Get("client_id")).To(Equal("test-client"))
+			Expect(location.Query().Get("redirect_uri")).To(Equal(oidcURL + callbackPath))
+			Expect(location.Query().Get("state")).ToNot(BeEmpty())
+			Expect(location.Query().Get("nonce")).ToNot(BeEmpty())
+
+			Expect(resp.Cookies()).To(HaveLen(1))
+			Expect(resp.Cookies()[0].HttpOnly).To(BeTrue())
+		})
+
+		It("Should not log in without an identity provider", func() {
+			resp, err := http.Get("http://127.0.0.1:8080/auth/oidc/login")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("GET /auth/oidc/callback", func() {
+		It("Should issue a token with the roles of the groups", func() {
+			token := loginToken()
+
+			Expect(statusCode(token, "GET", "/apps", "")).To(Equal(http.StatusOK))
+			Expect(statusCode(token, "GET", "/users", "")).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should update the roles of the user on every login", func() {
+			loginToken()
+
+			idp.setUser(subject, "oidc-"+subject, "auditors")
+			token := loginToken()
+
+			Expect(statusCode(token, "GET", "/apps", "")).To(Equal(http.StatusOK))
+			Expect(statusCode(token, "POST", "/apps", "{}")).To(Equal(http.StatusForbidden))
+
+			By("Verifying a single user was created")
+			resp, err := apiCli.Get("http://127.0.0.1:8080/users?username=oidc-" + subject)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var users swagger.UserList
+			Expect(json.NewDecoder(resp.Body).Decode(&users)).To(Succeed())
+			Expect(users.Users).To(HaveLen(1))
+			Expect(users.Users[0].Roles).To(Equal([]string{cce.RoleViewer}))
+		})
+
+		It("Should reject a user whose groups grant no role", func() {
+			idp.setUser(subject, "oidc-"+subject, "guests")
+
+			resp := login()
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		})
+
+		It("Should reject a username taken by a local user", func() {
+			username := "local-" + subject
+			postUser(username, uuid.New(), cce.RoleViewer)
+			idp.setUser(subject, username, "netops")
+
+			resp := login()
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+		})
+
+		It("Should reject a callback without a login in progress", func() {
+			resp, err := http.Get(oidcURL + callbackPath + "?code=test-code&state=test-state")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("Should reject a callback with another state", func() {
+			client := &http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+
+			resp, err := client.Get(loginURL)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.Cookies()).To(HaveLen(1))
+
+			req, err := http.NewRequest(http.MethodGet,
+				oidcURL+callbackPath+"?code=test-code&state=other-state", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req.AddCookie(resp.Cookies()[0])
+
+			resp, err = client.Do(req)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("Should reject a failed login", func() {
+			resp, err := http.Get(oidcURL + callbackPath + "?error=access_denied")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		})
+	})
+})
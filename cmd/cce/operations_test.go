@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("/operations", func() {
+	var (
+		appID string
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		appID = postApps("container")
+	})
+
+	postNodeAppsAsync := func(nodeID string) *http.Response {
+		By("Sending a POST /nodes/{node_id}/apps request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps", nodeID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, appID)))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	Describe("GET /operations/{operation_id}", func() {
+		It("Should return an operation that failed", func() {
+			By("Deploying an app to a node that is not connected")
+			nodeCfg := createAndRegisterNode()
+			clearGRPCTargetsTable()
+			resp := postNodeAppsAsync(nodeCfg.nodeID)
+			defer resp.Body.Close()
+
+			op := waitForOperation(resp)
+			Expect(op.Status).To(Equal("failed"))
+			Expect(op.Error).ToNot(BeEmpty())
+			Expect(op.Result).To(BeEmpty())
+
+			By("Verifying the node app was not created")
+			resp2, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/apps/%s", nodeCfg.nodeID, appID))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp2.Body.Close()
+			Expect(resp2.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Should return 404 Not Found for a nonexistent operation", func() {
+			resp, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/operations/%s", uuid.New()))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("POST /operations/{operation_id}/cancel", func() {
+		It("Should return 409 Conflict for an operation that ended", func() {
+			nodeCfg := createAndRegisterNode()
+			resp := postNodeAppsAsync(nodeCfg.nodeID)
+			defer resp.Body.Close()
+			op := waitForOperation(resp)
+			Expect(op.Status).To(Equal("succeeded"))
+
+			By("Sending a POST /operations/{operation_id}/cancel request")
+			resp2, err := apiCli.Post(
+				fmt.Sprintf("http://127.0.0.1:8080/operations/%s/cancel", op.ID),
+				"application/json",
+				strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp2.Body.Close()
+			Expect(resp2.StatusCode).To(Equal(http.StatusConflict))
+
+			body, err := ioutil.ReadAll(resp2.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal(fmt.Sprintf("operation %s already succeeded", op.ID)))
+		})
+
+		It("Should return 404 Not Found for a nonexistent operation", func() {
+			resp, err := apiCli.Post(
+				fmt.Sprintf("http://127.0.0.1:8080/operations/%s/cancel", uuid.New()),
+				"application/json",
+				strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
@@ -0,0 +1,325 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gexec"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	cce "github.com/open-ness/edgecontroller"
+	cceGRPC "github.com/open-ness/edgecontroller/grpc"
+	authpb "github.com/open-ness/edgecontroller/pb/auth"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// newNodeCSR generates a node key and returns its PEM-encoded certificate
+// signing request and the serial of the node.
+func newNodeCSR() (csrPEM, serial string) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	Expect(err).ToNot(HaveOccurred())
+	certReq, err := x509.ParseCertificateRequest(csrDER)
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})),
+		cce.NodeSerial(certReq.RawSubjectPublicKeyInfo)
+}
+
+// getPendingNodes returns the nodes pending approval with the serial.
+func getPendingNodes(serial string) []swagger.PendingNodeSummary {
+	By("Sending a GET /nodes/pending request")
+	resp, err := apiCli.Get("http://127.0.0.1:8080/nodes/pending?serial=" + url.QueryEscape(serial))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var list swagger.PendingNodeList
+	Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+
+	return list.PendingNodes
+}
+
+// getNodesBySerial returns the nodes with the serial.
+func getNodesBySerial(serial string) []swagger.NodeSummary {
+	By("Sending a GET /nodes request")
+	resp, err := apiCli.Get("http://127.0.0.1:8080/nodes?serial=" + url.QueryEscape(serial))
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var list swagger.NodeList
+	Expect(json.NewDecoder(resp.Body).Decode(&list)).To(Succeed())
+
+	return list.Nodes
+}
+
+var _ = Describe("Node Approval", func() {
+	Describe("Pending approval", func() {
+		var (
+			pendingCtl *gexec.Session
+			conn       *grpc.ClientConn
+			authCli    authpb.AuthServiceClient
+		)
+
+		BeforeEach(func() {
+			clearGRPCTargetsTable()
+			pendingCtl = startController(8105, "-pending-approval")
+
+			caPool := x509.NewCertPool()
+			Expect(caPool.AppendCertsFromPEM(controllerRootPEM)).To(BeTrue())
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			var err error
+			conn, err = grpc.DialContext(
+				ctx,
+				net.JoinHostPort("127.0.0.1", "8106"),
+				grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(caPool, cceGRPC.EnrollmentSNI)),
+				grpc.WithBlock())
+			Expect(err).ToNot(HaveOccurred())
+			authCli = authpb.NewAuthServiceClient(conn)
+		})
+
+		AfterEach(func() {
+			conn.Close()
+			pendingCtl.Kill()
+			Eventually(pendingCtl).Should(gexec.Exit())
+		})
+
+		// requestCredentials requests credentials for the CSR and returns
+		// the status code of the call.
+		requestCredentials := func(csrPEM string) codes.Code {
+			By("Requesting credentials from auth service")
+			_, err := authCli.RequestCredentials(context.TODO(), &authpb.Identity{Csr: csrPEM})
+			return status.Code(err)
+		}
+
+		It("Should queue an unknown node once", func() {
+			csrPEM, serial := newNodeCSR()
+
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.Unauthenticated))
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.Unauthenticated))
+
+			pending := getPendingNodes(serial)
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].Serial).To(Equal(serial))
+			Expect(pending[0].Address).To(Equal("127.0.0.1"))
+			Expect(pending[0].RequestedAt).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("Should enroll an approved node", func() {
+			csrPEM, serial := newNodeCSR()
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.Unauthenticated))
+			pending := getPendingNodes(serial)
+			Expect(pending).To(HaveLen(1))
+
+			By("Sending a POST /nodes/pending request")
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/nodes/pending",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(
+					`{"id": "%s", "name": "approved node", "location": "rack 1"}`, pending[0].ID)))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			var rb respBody
+			Expect(json.NewDecoder(resp.Body).Decode(&rb)).To(Succeed())
+
+			By("Verifying the node was created and is no longer pending")
+			node := getNode(rb.ID)
+			Expect(node.Name).To(Equal("approved node"))
+			Expect(node.Serial).To(Equal(serial))
+			Expect(getPendingNodes(serial)).To(BeEmpty())
+
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.OK))
+		})
+
+		It("Should not approve a missing pending node", func() {
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/nodes/pending",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"id": "%s", "name": "node", "location": "rack"}`, uuid.New())))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("Should reject a pending node and queue it again", func() {
+			csrPEM, serial := newNodeCSR()
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.Unauthenticated))
+			pending := getPendingNodes(serial)
+			Expect(pending).To(HaveLen(1))
+
+			By("Sending a DELETE /nodes/pending/{pending_id} request")
+			resp, err := apiCli.Delete("http://127.0.0.1:8080/nodes/pending/" + pending[0].ID)
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			Expect(getPendingNodes(serial)).To(BeEmpty())
+			Expect(getNodesBySerial(serial)).To(BeEmpty())
+
+			By("Verifying the node is queued again when it requests credentials")
+			Expect(requestCredentials(csrPEM)).To(Equal(codes.Unauthenticated))
+			Expect(getPendingNodes(serial)).To(HaveLen(1))
+		})
+	})
+
+	Describe("POST /nodes/import", func() {
+		// importNodes imports a manifest and returns the response status code
+		// and body.
+		importNodes := func(contentType, manifest string) (int, []byte) {
+			By("Sending a POST /nodes/import request")
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/nodes/import", contentType, strings.NewReader(manifest))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+
+			return resp.StatusCode, body
+		}
+
+		It("Should import the nodes of a CSV manifest", func() {
+			_, serial1 := newNodeCSR()
+			_, serial2 := newNodeCSR()
+
+			code, body := importNodes("text/csv", fmt.Sprintf(
+				"serial,name,location\n%s,node 1,rack 1\n%s,node 2,rack 2\n", serial1, serial2))
+			Expect(code).To(Equal(http.StatusCreated))
+
+			var list swagger.NodeList
+			Expect(json.Unmarshal(body, &list)).To(Succeed())
+			Expect(list.Nodes).To(HaveLen(2))
+			Expect(getNodesBySerial(serial1)).To(HaveLen(1))
+			Expect(getNodesBySerial(serial2)).To(HaveLen(1))
+		})
+
+		It("Should import no node of a manifest with an invalid row", func() {
+			_, serial1 := newNodeCSR()
+			_, serial3 := newNodeCSR()
+
+			code, body := importNodes("application/json", fmt.Sprintf(`[
+				{"name": "node 1", "location": "rack 1", "serial": "%s"},
+				{"name": "", "location": "rack 2", "serial": "invalid"},
+				{"name": "node 3", "location": "rack 3", "serial": "%s"}
+			]`, serial1, serial3))
+			Expect(code).To(Equal(http.StatusBadRequest))
+			Expect(string(body)).To(ContainSubstring("row 2"))
+
+			Expect(getNodesBySerial(serial1)).To(BeEmpty())
+			Expect(getNodesBySerial(serial3)).To(BeEmpty())
+		})
+
+		It("Should import no node of a manifest with an existing serial", func() {
+			_, existing := newNodeCSR()
+			postNodesSerial(existing)
+			_, serial := newNodeCSR()
+
+			code, body := importNodes("application/json", fmt.Sprintf(`[
+				{"name": "node 1", "location": "rack 1", "serial": "%s"},
+				{"name": "node 2", "location": "rack 2", "serial": "%s"}
+			]`, serial, existing))
+			Expect(code).To(Equal(http.StatusBadRequest))
+			Expect(string(body)).To(ContainSubstring(
+				fmt.Sprintf("row 2: serial %s already exists", existing)))
+
+			Expect(getNodesBySerial(serial)).To(BeEmpty())
+		})
+
+		It("Should import no node of a manifest with duplicate serials", func() {
+			_, serial := newNodeCSR()
+
+			code, _ := importNodes("application/json", fmt.Sprintf(`[
+				{"name": "node 1", "location": "rack 1", "serial": "%s"},
+				{"name": "node 2", "location": "rack 2", "serial": "%s"}
+			]`, serial, serial))
+			Expect(code).To(Equal(http.StatusBadRequest))
+
+			Expect(getNodesBySerial(serial)).To(BeEmpty())
+		})
+
+		It("Should import concurrent manifests sharing a serial entirely or not at all", func() {
+			_, shared := newNodeCSR()
+			var (
+				wg      sync.WaitGroup
+				serials = make([]string, 4)
+				codes   = make([]int, 4)
+			)
+			for i := range serials {
+				_, serials[i] = newNodeCSR()
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					codes[i], _ = importNodes("application/json", fmt.Sprintf(`[
+						{"name": "node %d", "location": "rack 1", "serial": "%s"},
+						{"name": "shared node", "location": "rack 2", "serial": "%s"}
+					]`, i, serials[i], shared))
+				}(i)
+			}
+			wg.Wait()
+
+			By("Verifying only the nodes of one manifest were imported")
+			Expect(codes).To(ContainElement(http.StatusCreated))
+			Expect(getNodesBySerial(shared)).To(HaveLen(1))
+			for i, code := range codes {
+				if code == http.StatusCreated {
+					Expect(getNodesBySerial(serials[i])).To(HaveLen(1))
+					continue
+				}
+				Expect(getNodesBySerial(serials[i])).To(BeEmpty())
+			}
+		})
+
+		It("Should dequeue the imported pending nodes", func() {
+			pendingCtl := startController(8105, "-pending-approval")
+			defer func() {
+				pendingCtl.Kill()
+				Eventually(pendingCtl).Should(gexec.Exit())
+			}()
+
+			caPool := x509.NewCertPool()
+			Expect(caPool.AppendCertsFromPEM(controllerRootPEM)).To(BeTrue())
+			conn, err := grpc.Dial(
+				net.JoinHostPort("127.0.0.1", "8106"),
+				grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(caPool, cceGRPC.EnrollmentSNI)))
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			csrPEM, serial := newNodeCSR()
+			_, err = authpb.NewAuthServiceClient(conn).RequestCredentials(
+				context.TODO(), &authpb.Identity{Csr: csrPEM})
+			Expect(status.Code(err)).To(Equal(codes.Unauthenticated))
+			Expect(getPendingNodes(serial)).To(HaveLen(1))
+
+			code, _ := importNodes("application/json", fmt.Sprintf(
+				`[{"name": "node 1", "location": "rack 1", "serial": "%s"}]`, serial))
+			Expect(code).To(Equal(http.StatusCreated))
+			Expect(getPendingNodes(serial)).To(BeEmpty())
+		})
+	})
+})
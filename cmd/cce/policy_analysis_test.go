@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/onsi/gomega/gexec"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /policies/{policy_id}/analyze", func() {
+	// rule returns a rule matching the TCP traffic to the destination subnet.
+	rule := func(priority int, address string, mask int, action string) string {
+		return fmt.Sprintf(`
+			{
+				"priority": %d,
+				"destination": {
+					"ip_filter": {
+						"address": "%s",
+						"mask": %d,
+						"protocol": "tcp"
+					}
+				},
+				"target": {
+					"action": "%s"
+				}
+			}`, priority, address, mask, action)
+	}
+
+	postAnalyze := func(policyID string, req string) *http.Response {
+		By("Sending a POST /policies/{policy_id}/analyze request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/policies/%s/analyze", policyID),
+			"application/json",
+			strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	analyze := func(policyID string, req string) *swagger.PolicyAnalysis {
+		resp := postAnalyze(policyID, req)
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var analysis swagger.PolicyAnalysis
+		Expect(json.Unmarshal(body, &analysis)).To(Succeed())
+		return &analysis
+	}
+
+	It("Should report no findings for a policy with a single rule", func() {
+		policyID := postPolicies()
+
+		analysis := analyze(policyID, `{}`)
+		Expect(analysis.PolicyID).To(Equal(policyID))
+		Expect(analysis.Findings).To(BeEmpty())
+	})
+
+	It("Should analyze the rules of the request", func() {
+		policyID := postPolicies()
+
+		analysis := analyze(policyID, fmt.Sprintf(`{"traffic_rules": [%s, %s]}`,
+			rule(1, "10.0.0.0", 16, "drop"),
+			rule(2, "10.0.1.0", 24, "accept")))
+		Expect(analysis.Findings).To(HaveLen(1))
+		Expect(analysis.Findings[0].Kind).To(Equal(cce.PolicyFindingShadowed))
+		Expect(analysis.Findings[0].Message).To(Equal("rules[1] is shadowed by rules[0] with action drop"))
+
+		By("Verifying the policy is unchanged")
+		Expect(getPolicy(policyID).Rules).To(HaveLen(1))
+	})
+
+	It("Should report conflicts with the policies of the node", func() {
+		clearGRPCTargetsTable()
+		nodeCfg := createAndRegisterNode()
+		appID := postApps("container")
+		postNodeApps(nodeCfg.nodeID, appID)
+		patchNodesAppsPolicy(nodeCfg.nodeID, appID, postPolicies())
+
+		policyID := postPolicies("policy-2")
+		analysis := analyze(policyID, fmt.Sprintf(`{"node_id": "%s", "traffic_rules": [%s]}`,
+			nodeCfg.nodeID, rule(1, "64.1.0.0", 16, "drop")))
+		Expect(analysis.NodeID).To(Equal(nodeCfg.nodeID))
+		Expect(analysis.Findings).To(HaveLen(1))
+		Expect(analysis.Findings[0].Kind).To(Equal(cce.PolicyFindingConflict))
+	})
+
+	It("Should return 400 Bad Request for invalid rules", func() {
+		policyID := postPolicies()
+
+		resp := postAnalyze(policyID, fmt.Sprintf(`{"traffic_rules": [%s]}`,
+			rule(0, "10.0.0.0", 16, "drop")))
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("Should return 404 Not Found for a nonexistent policy", func() {
+		resp := postAnalyze("123", `{}`)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+	})
+
+	It("Should return 422 Unprocessable Entity for a nonexistent node", func() {
+		resp := postAnalyze(postPolicies(), `{"node_id": "123"}`)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	Describe("With the analysis enforced", func() {
+		var enforceCtl *gexec.Session
+
+		BeforeEach(func() {
+			enforceCtl = startController(8120, "-enforce-policy-analysis")
+		})
+
+		AfterEach(func() {
+			enforceCtl.Kill()
+			Eventually(enforceCtl).Should(gexec.Exit())
+		})
+
+		It("Should reject attaching a policy conflicting with the policies of the node", func() {
+			clearGRPCTargetsTable()
+			nodeCfg := createAndRegisterNode()
+			appID := postApps("container")
+			postNodeApps(nodeCfg.nodeID, appID)
+			patchNodesAppsPolicy(nodeCfg.nodeID, appID, postPolicies())
+			otherAppID := postApps("container")
+			postNodeApps(nodeCfg.nodeID, otherAppID)
+
+			By("Sending a POST /policies request with a conflicting rule")
+			resp, err := apiCli.Post(
+				"http://127.0.0.1:8080/policies",
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"name": "policy-2", "traffic_rules": [%s]}`,
+					rule(1, "64.1.0.0", 16, "drop"))))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			var rb respBody
+			Expect(json.Unmarshal(body, &rb)).To(Succeed())
+
+			By("Sending a PATCH /nodes/{node_id}/apps/{app_id}/policy request")
+			resp, err = apiCli.Patch(
+				fmt.Sprintf("http://127.0.0.1:8120/nodes/%s/apps/%s/policy", nodeCfg.nodeID, otherAppID),
+				"application/json",
+				strings.NewReader(fmt.Sprintf(`{"id": "%s"}`, rb.ID)))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying a 422 Unprocessable Entity response")
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			body, err = ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(HavePrefix(fmt.Sprintf("traffic policy %s failed analysis: ", rb.ID)))
+		})
+	})
+})
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/swagger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /nodes/{node_id}/policies/simulate", func() {
+	var (
+		nodeCfg *nodeConfig
+		appID   string
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+		appID = postApps("container")
+		postNodeApps(nodeCfg.nodeID, appID)
+	})
+
+	// postPolicy creates a policy dropping the TCP traffic to 64.1.0.0/16,
+	// except to the ports 1000-1012 redirected to 123.2.3.4:1600.
+	postPolicy := func() string {
+		By("Sending a POST /policies request")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/policies",
+			"application/json",
+			strings.NewReader(`
+			{
+				"name": "simulated-policy",
+				"traffic_rules": [{
+					"description": "drop-subnet",
+					"priority": 2,
+					"destination": {
+						"ip_filter": {"address": "64.1.0.0", "mask": 16, "protocol": "tcp"}
+					},
+					"target": {"action": "drop"}
+				}, {
+					"description": "redirect-service",
+					"priority": 1,
+					"destination": {
+						"ip_filter": {
+							"address": "64.1.0.0",
+							"mask": 16,
+							"begin_port": 1000,
+							"end_port": 1012,
+							"protocol": "tcp"
+						}
+					},
+					"target": {
+						"action": "accept",
+						"ip_modifier": {"address": "123.2.3.4", "port": 1600}
+					}
+				}]
+			}`))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 201 Created response")
+		Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var rb respBody
+		Expect(json.Unmarshal(body, &rb)).To(Succeed())
+		return rb.ID
+	}
+
+	postSimulate := func(req string) *http.Response {
+		By("Sending a POST /nodes/{node_id}/policies/simulate request")
+		resp, err := apiCli.Post(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/policies/simulate", nodeCfg.nodeID),
+			"application/json",
+			strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	simulate := func(port int) *swagger.PolicySimulation {
+		resp := postSimulate(fmt.Sprintf(`
+			{
+				"packet": {
+					"source": {"ip": "10.0.0.7", "port": 40000},
+					"destination": {"ip": "64.1.1.1", "port": %d},
+					"protocol": "tcp"
+				}
+			}`, port))
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var simulation swagger.PolicySimulation
+		Expect(json.Unmarshal(body, &simulation)).To(Succeed())
+		return &simulation
+	}
+
+	It("Should return the rule of the app policy matching the packet", func() {
+		policyID := postPolicy()
+		patchNodesAppsPolicy(nodeCfg.nodeID, appID, policyID)
+
+		simulation := simulate(1001)
+		Expect(simulation.NodeID).To(Equal(nodeCfg.nodeID))
+		Expect(simulation.Results).To(HaveLen(1))
+		result := simulation.Results[0]
+		Expect(result.AppID).To(Equal(appID))
+		Expect(result.PolicyID).To(Equal(policyID))
+		Expect(result.Matched).To(BeTrue())
+		Expect(*result.Rule).To(Equal(1))
+		Expect(result.TrafficRule.Target.Action).To(Equal("accept"))
+		Expect(result.TrafficRule.Target.IP.Address).To(Equal("123.2.3.4"))
+
+		By("Simulating a packet to another port")
+		result = simulate(22).Results[0]
+		Expect(*result.Rule).To(Equal(0))
+		Expect(result.TrafficRule.Target.Action).To(Equal("drop"))
+	})
+
+	It("Should return no results without policies", func() {
+		Expect(simulate(1001).Results).To(BeEmpty())
+	})
+
+	It("Should return 400 Bad Request for an invalid packet", func() {
+		resp := postSimulate(`{"packet": {"protocol": "all"}}`)
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("Validation failed: protocol must be one of [tcp, udp, icmp, sctp]"))
+	})
+})
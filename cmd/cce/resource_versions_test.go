@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// sendIfMatch sends a request with an If-Match header and returns the status
+// code and the ETag of the response.
+func sendIfMatch(method, url, etag string, body io.Reader) (statusCode int, newETag string) {
+	req, err := http.NewRequest(method, url, body)
+	Expect(err).ToNot(HaveOccurred())
+	req.Header.Set("Content-Type", "application/json")
+	if etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	resp, err := apiCli.Do(req)
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+
+	return resp.StatusCode, resp.Header.Get("ETag")
+}
+
+// getETag sends a GET request and returns the ETag of the response.
+func getETag(url string) string {
+	resp, err := apiCli.Get(url)
+	Expect(err).ToNot(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	return resp.Header.Get("ETag")
+}
+
+// sendIfMatchConcurrently sends the same request with an If-Match header n
+// times concurrently and returns the status codes of the responses.
+func sendIfMatchConcurrently(n int, method, url, etag, body string) []int {
+	var (
+		wg    sync.WaitGroup
+		codes = make([]int, n)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer GinkgoRecover()
+			defer wg.Done()
+			codes[i], _ = sendIfMatch(method, url, etag, strings.NewReader(body))
+		}(i)
+	}
+	wg.Wait()
+
+	return codes
+}
+
+var _ = Describe("Resource versions", func() {
+	var nodeCfg *nodeConfig
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	It("Should reject a stale If-Match header of a node", func() {
+		url := fmt.Sprintf("http://127.0.0.1:8080/nodes/%s", nodeCfg.nodeID)
+		req := fmt.Sprintf(`{"name": "node-etag", "location": "lab", "serial": "%s"}`, nodeCfg.serial)
+
+		etag := getETag(url)
+		Expect(etag).ToNot(BeEmpty())
+
+		By("Updating the node with the current ETag")
+		code, newETag := sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(newETag).ToNot(Equal(etag))
+		Expect(getETag(url)).To(Equal(newETag))
+
+		By("Updating the node with the stale ETag")
+		code, _ = sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+
+		By("Deleting the node with the stale ETag")
+		code, _ = sendIfMatch(http.MethodDelete, url, etag, nil)
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("Should reject a stale If-Match header of a DNS configuration", func() {
+		url := fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/dns", nodeCfg.nodeID)
+		req := `{"name": "dns-etag", "records": {"a": [{"name": "app.demosite.com", "values": ["192.168.1.5"]}]}}`
+
+		By("Creating the DNS configuration")
+		code, etag := sendIfMatch(http.MethodPatch, url, "", strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(etag).To(Equal(`"1"`))
+		Expect(getETag(url)).To(Equal(etag))
+
+		By("Replacing the DNS configuration with the current ETag")
+		code, newETag := sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(newETag).To(Equal(`"2"`))
+
+		By("Replacing the DNS configuration with the stale ETag")
+		code, _ = sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+
+		By("Deleting the DNS configuration with the stale ETag")
+		code, _ = sendIfMatch(http.MethodDelete, url, etag, nil)
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+
+		By("Deleting the DNS configuration with the current ETag")
+		code, _ = sendIfMatch(http.MethodDelete, url, newETag, nil)
+		Expect(code).To(Equal(http.StatusNoContent))
+	})
+
+	It("Should reject a stale If-Match header of an interface policy", func() {
+		url := fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/interfaces/if0/policy", nodeCfg.nodeID)
+		req := fmt.Sprintf(`{"id": "%s"}`, postPolicies())
+
+		etag := getETag(url)
+		Expect(etag).ToNot(BeEmpty())
+
+		By("Setting the policy with the current ETag")
+		code, newETag := sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(newETag).ToNot(Equal(etag))
+		Expect(getETag(url)).To(Equal(newETag))
+
+		By("Setting the policy with the stale ETag")
+		code, _ = sendIfMatch(http.MethodPatch, url, etag, strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+
+		By("Deleting the policy with the stale ETag")
+		code, _ = sendIfMatch(http.MethodDelete, url, etag, nil)
+		Expect(code).To(Equal(http.StatusPreconditionFailed))
+
+		By("Deleting the policy with the current ETag")
+		code, _ = sendIfMatch(http.MethodDelete, url, newETag, nil)
+		Expect(code).To(Equal(http.StatusNoContent))
+	})
+
+	It("Should apply one of concurrent DNS configuration replacements with the same ETag", func() {
+		url := fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/dns", nodeCfg.nodeID)
+		req := `{"name": "dns-etag", "records": {"a": [{"name": "app.demosite.com", "values": ["192.168.1.5"]}]}}`
+
+		By("Creating the DNS configuration")
+		code, etag := sendIfMatch(http.MethodPatch, url, "", strings.NewReader(req))
+		Expect(code).To(Equal(http.StatusOK))
+
+		By("Replacing the DNS configuration concurrently with the current ETag")
+		codes := sendIfMatchConcurrently(4, http.MethodPatch, url, etag, req)
+		Expect(codes).To(ConsistOf(
+			http.StatusOK,
+			http.StatusPreconditionFailed,
+			http.StatusPreconditionFailed,
+			http.StatusPreconditionFailed))
+		Expect(getETag(url)).To(Equal(`"2"`))
+	})
+
+	It("Should apply one of concurrent interface policy changes with the same ETag", func() {
+		url := fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/interfaces/if0/policy", nodeCfg.nodeID)
+		req := fmt.Sprintf(`{"id": "%s"}`, postPolicies())
+
+		etag := getETag(url)
+		Expect(etag).ToNot(BeEmpty())
+
+		By("Setting the policy concurrently with the current ETag")
+		codes := sendIfMatchConcurrently(4, http.MethodPatch, url, etag, req)
+		Expect(codes).To(ConsistOf(
+			http.StatusOK,
+			http.StatusPreconditionFailed,
+			http.StatusPreconditionFailed,
+			http.StatusPreconditionFailed))
+	})
+})
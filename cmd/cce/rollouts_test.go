@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("/rollouts", func() {
+	var (
+		previousAppID string
+		appID         string
+	)
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		previousAppID = postApps("container")
+		appID = postApps("container")
+	})
+
+	postRollouts := func(req string) *http.Response {
+		By("Sending a POST /rollouts request")
+		resp, err := apiCli.Post(
+			"http://127.0.0.1:8080/rollouts",
+			"application/json",
+			strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	// waitForRollout waits for the rollout accepted by the response to end and
+	// returns it.
+	waitForRollout := func(resp *http.Response) *swagger.RolloutDetail {
+		By("Verifying a 202 Accepted response")
+		Expect(resp.StatusCode).To(Equal(http.StatusAccepted))
+		location := resp.Header.Get("Location")
+		Expect(location).To(HavePrefix("/rollouts/"))
+
+		var ro *swagger.RolloutDetail
+		Eventually(func() string {
+			By(fmt.Sprintf("Sending a GET %s request", location))
+			resp, err := apiCli.Get("http://127.0.0.1:8080" + location)
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(body, &ro)).To(Succeed())
+			return ro.Status
+		}, 30*time.Second, 100*time.Millisecond).Should(
+			Or(Equal("succeeded"), Equal("rolled_back"), Equal("failed")))
+
+		return ro
+	}
+
+	Describe("POST /rollouts", func() {
+		It("Should replace the previous app on the nodes", func() {
+			nodeCfg := createAndRegisterNode()
+			postNodeApps(nodeCfg.nodeID, previousAppID)
+
+			resp := postRollouts(fmt.Sprintf(`
+				{
+					"app_id": "%s",
+					"previous_app_id": "%s",
+					"node_ids": ["%s"],
+					"wave_size": 1,
+					"max_unavailable": 1,
+					"max_failures": 0
+				}`, appID, previousAppID, nodeCfg.nodeID))
+			defer resp.Body.Close()
+
+			ro := waitForRollout(resp)
+			Expect(ro.Status).To(Equal("succeeded"))
+			Expect(ro.Nodes).To(Equal([]swagger.RolloutNode{
+				{NodeID: nodeCfg.nodeID, Status: "upgraded"},
+			}))
+
+			By("Verifying the app replaced the previous app")
+			Expect(getNodeApps(nodeCfg.nodeID).NodeApps).To(Equal([]swagger.NodeAppSummary{
+				{ID: appID},
+			}))
+			Expect(getNodeApp(nodeCfg.nodeID, appID).Status).To(Equal("running"))
+		})
+
+		It("Should return 400 Bad Request for an invalid wave size", func() {
+			nodeCfg := createAndRegisterNode()
+
+			resp := postRollouts(fmt.Sprintf(`
+				{
+					"app_id": "%s",
+					"node_ids": ["%s"],
+					"wave_size": 0,
+					"max_unavailable": 1
+				}`, appID, nodeCfg.nodeID))
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal("Validation failed: wave_size must be at least 1"))
+		})
+
+		It("Should return 422 Unprocessable Entity for a nonexistent node", func() {
+			nodeID := uuid.New()
+
+			resp := postRollouts(fmt.Sprintf(`
+				{
+					"app_id": "%s",
+					"node_ids": ["%s"],
+					"wave_size": 1,
+					"max_unavailable": 1
+				}`, appID, nodeID))
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal(fmt.Sprintf("node %s not found", nodeID)))
+		})
+	})
+
+	Describe("GET /rollouts", func() {
+		It("Should list the rollouts of an app", func() {
+			nodeCfg := createAndRegisterNode()
+			resp := postRollouts(fmt.Sprintf(`
+				{
+					"app_id": "%s",
+					"node_ids": ["%s"],
+					"wave_size": 1,
+					"max_unavailable": 1
+				}`, appID, nodeCfg.nodeID))
+			defer resp.Body.Close()
+			ro := waitForRollout(resp)
+
+			By("Sending a GET /rollouts request")
+			resp2, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/rollouts?app_id=%s", appID))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp2.Body.Close()
+			Expect(resp2.StatusCode).To(Equal(http.StatusOK))
+
+			var list swagger.RolloutList
+			body, err := ioutil.ReadAll(resp2.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(body, &list)).To(Succeed())
+			Expect(list.Rollouts).To(Equal([]swagger.RolloutSummary{ro.RolloutSummary}))
+		})
+	})
+
+	Describe("GET /rollouts/{rollout_id}", func() {
+		It("Should return 404 Not Found for a nonexistent rollout", func() {
+			resp, err := apiCli.Get(
+				fmt.Sprintf("http://127.0.0.1:8080/rollouts/%s", uuid.New()))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
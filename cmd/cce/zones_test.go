@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/swagger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("/nodes/{node_id}/zones", func() {
+	var nodeCfg *nodeConfig
+
+	BeforeEach(func() {
+		clearGRPCTargetsTable()
+		nodeCfg = createAndRegisterNode()
+	})
+
+	zonesURL := func(path string) string {
+		return fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/zones%s", nodeCfg.nodeID, path)
+	}
+
+	postZones := func(req string) *http.Response {
+		By("Sending a POST /nodes/{node_id}/zones request")
+		resp, err := apiCli.Post(zonesURL(""), "application/json", strings.NewReader(req))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	getZones := func() *swagger.ZoneList {
+		By("Sending a GET /nodes/{node_id}/zones request")
+		resp, err := apiCli.Get(zonesURL(""))
+		Expect(err).ToNot(HaveOccurred())
+		defer resp.Body.Close()
+
+		By("Verifying a 200 OK response")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var zones swagger.ZoneList
+		Expect(json.Unmarshal(body, &zones)).To(Succeed())
+		return &zones
+	}
+
+	// patchInterfaceZones sets the zones of the first interface of the node.
+	patchInterfaceZones := func(zones string) *http.Response {
+		By("Sending a PATCH /nodes/{node_id}/interfaces request")
+		ifaces := []string{}
+		for i := 0; i < 4; i++ {
+			z := "null"
+			if i == 0 {
+				z = zones
+			}
+			ifaces = append(ifaces, fmt.Sprintf(`
+				{
+					"id": "if%d",
+					"description": "interface%d",
+					"driver": "kernel",
+					"type": "none",
+					"mac_address": "mac%d",
+					"vlan": %d,
+					"zones": %s,
+					"fallback_interface": ""
+				}`, i, i, i, i, z))
+		}
+		resp, err := apiCli.Patch(
+			fmt.Sprintf("http://127.0.0.1:8080/nodes/%s/interfaces", nodeCfg.nodeID),
+			"application/json",
+			strings.NewReader(fmt.Sprintf(`{"interfaces": [%s]}`, strings.Join(ifaces, ","))))
+		Expect(err).ToNot(HaveOccurred())
+		return resp
+	}
+
+	readBody := func(resp *http.Response) string {
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		return string(body)
+	}
+
+	Describe("POST /nodes/{node_id}/zones", func() {
+		It("Should create a zone", func() {
+			resp := postZones(`{"id": "far-edge", "description": "far edge zone"}`)
+			defer resp.Body.Close()
+
+			By("Verifying a 201 Created response")
+			Expect(resp.StatusCode).To(Equal(http.StatusCreated))
+			Expect(readBody(resp)).To(Equal(`{"id":"far-edge"}`))
+
+			Expect(getZones().Zones).To(Equal([]swagger.ZoneSummary{
+				{ID: "far-edge", Description: "far edge zone"},
+			}))
+		})
+
+		It("Should return 400 Bad Request for an invalid ID", func() {
+			resp := postZones(`{"id": "far edge"}`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(readBody(resp)).To(Equal(`Validation failed: id "far edge" is invalid`))
+		})
+
+		It("Should return 409 Conflict for an existing zone", func() {
+			resp := postZones(`{"id": "far-edge"}`)
+			resp.Body.Close()
+			resp = postZones(`{"id": "far-edge"}`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+			Expect(readBody(resp)).To(Equal("zone far-edge already exists"))
+		})
+	})
+
+	Describe("PATCH /nodes/{node_id}/zones/{zone_id}", func() {
+		It("Should update the description of the zone", func() {
+			resp := postZones(`{"id": "far-edge"}`)
+			resp.Body.Close()
+
+			By("Sending a PATCH /nodes/{node_id}/zones/{zone_id} request")
+			resp, err := apiCli.Patch(
+				zonesURL("/far-edge"),
+				"application/json",
+				strings.NewReader(`{"description": "updated zone"}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			By("Sending a GET /nodes/{node_id}/zones/{zone_id} request")
+			resp, err = apiCli.Get(zonesURL("/far-edge"))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+			var zone swagger.ZoneDetail
+			Expect(json.Unmarshal([]byte(readBody(resp)), &zone)).To(Succeed())
+			Expect(zone.Description).To(Equal("updated zone"))
+		})
+
+		It("Should return 404 Not Found for a nonexistent zone", func() {
+			resp, err := apiCli.Patch(
+				zonesURL("/far-edge"),
+				"application/json",
+				strings.NewReader(`{"description": "updated zone"}`))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+
+	Describe("Interface zones", func() {
+		It("Should only put the interfaces in existing zones", func() {
+			resp := patchInterfaceZones(`["far-edge"]`)
+			defer resp.Body.Close()
+
+			By("Verifying a 422 Unprocessable Entity response")
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(readBody(resp)).To(Equal("zone far-edge of network interface if0 not found"))
+
+			resp = postZones(`{"id": "far-edge"}`)
+			resp.Body.Close()
+			resp = patchInterfaceZones(`["far-edge"]`)
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+	})
+
+	Describe("DELETE /nodes/{node_id}/zones/{zone_id}", func() {
+		It("Should not delete a zone in use", func() {
+			resp := postZones(`{"id": "far-edge"}`)
+			resp.Body.Close()
+			resp = patchInterfaceZones(`["far-edge"]`)
+			resp.Body.Close()
+
+			By("Sending a DELETE /nodes/{node_id}/zones/{zone_id} request")
+			resp, err := apiCli.Delete(zonesURL("/far-edge"))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			By("Verifying a 422 Unprocessable Entity response")
+			Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+			Expect(readBody(resp)).To(Equal(
+				"cannot delete zone far-edge: zone in use by network interface if0"))
+
+			By("Removing the interface from the zone")
+			resp = patchInterfaceZones("null")
+			resp.Body.Close()
+
+			resp, err = apiCli.Delete(zonesURL("/far-edge"))
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(getZones().Zones).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+// Command nodeimportcli imports a manifest of nodes into the controller so
+// that the nodes are approved to enroll:
+//
+//     nodeimportcli -controller http://localhost:8080 -token <token> -manifest nodes.csv
+//
+// The manifest is a JSON array of objects, or a CSV file with a header row,
+// with the name, location and serial of each node.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+)
+
+func main() {
+	controller := flag.String("controller", "http://localhost:8080", "Controller REST API URL")
+	token := flag.String("token", os.Getenv("CCE_TOKEN"),
+		"API token or access token of a user with write permission, defaults to $CCE_TOKEN")
+	manifest := flag.String("manifest", "", "Path to the CSV or JSON manifest of the nodes")
+
+	flag.Parse()
+
+	if *manifest == "" || *token == "" {
+		fmt.Println("No 'manifest' or 'token' specified. Please use -h or -help")
+		os.Exit(-1)
+	}
+
+	if err := importNodes(*controller, *token, *manifest); err != nil {
+		fmt.Printf("Import failed: %v\n", err)
+		os.Exit(-1)
+	}
+}
+
+// importNodes posts the manifest to the controller and prints the nodes it
+// created.
+func importNodes(controller, token, manifest string) error {
+	body, err := ioutil.ReadFile(filepath.Clean(manifest))
+	if err != nil {
+		return err
+	}
+
+	// The manifest format is given by its extension
+	mediaType := cce.NodeManifestJSON
+	if strings.EqualFold(filepath.Ext(manifest), ".csv") {
+		mediaType = cce.NodeManifestCSV
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		strings.TrimSuffix(controller, "/")+"/nodes/import", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mediaType)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%s: %s", resp.Status, respBody)
+	}
+
+	fmt.Println(string(respBody))
+	return nil
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+// Command policysimcli evaluates a packet against the traffic policies of a
+// node and prints the rule of each interface and app policy matching it:
+//
+//     policysimcli -token <token> -node <node_id> -protocol tcp \
+//         -dst-ip 64.1.1.1 -dst-port 1001 -src-gtp 10.6.7.2 -imsi 310150123456789
+//
+// With -interface or -app only the policy of the interface or app is
+// evaluated.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+func main() {
+	controller := flag.String("controller", "http://localhost:8080", "Controller REST API URL")
+	token := flag.String("token", os.Getenv("CCE_TOKEN"),
+		"API token or access token of a user, defaults to $CCE_TOKEN")
+	nodeID := flag.String("node", "", "ID of the node")
+
+	var req swagger.PolicySimulationReq
+	flag.StringVar(&req.InterfaceID, "interface", "", "Only evaluate the policy of the interface")
+	flag.StringVar(&req.AppID, "app", "", "Only evaluate the policy of the app")
+	flag.StringVar(&req.Packet.Protocol, "protocol", "tcp", "Protocol of the packet: tcp, udp, icmp or sctp")
+	flag.StringVar(&req.Packet.IMSI, "imsi", "", "IMSI of the subscriber of GTP traffic")
+	endpointFlags(&req.Packet.Source, "src", "source")
+	endpointFlags(&req.Packet.Destination, "dst", "destination")
+
+	flag.Parse()
+
+	if *nodeID == "" || *token == "" {
+		fmt.Println("No 'node' or 'token' specified. Please use -h or -help")
+		os.Exit(-1)
+	}
+
+	if err := simulate(*controller, *token, *nodeID, &req); err != nil {
+		fmt.Printf("Simulation failed: %v\n", err)
+		os.Exit(-1)
+	}
+}
+
+// endpointFlags defines the flags of the source or destination of the packet.
+func endpointFlags(e *cce.PacketEndpoint, prefix, name string) {
+	flag.StringVar(&e.MAC, prefix+"-mac", "", "MAC address of the "+name)
+	flag.StringVar(&e.IP, prefix+"-ip", "", "IP address of the "+name)
+	flag.IntVar(&e.Port, prefix+"-port", 0, "Port of the "+name)
+	flag.StringVar(&e.GTPAddress, prefix+"-gtp", "", "Address of the GTP tunnel endpoint of the "+name)
+}
+
+// simulate posts the packet to the controller and prints the rules matching
+// it.
+func simulate(controller, token, nodeID string, simReq *swagger.PolicySimulationReq) error {
+	body, err := json.Marshal(simReq)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		fmt.Sprintf("%s/nodes/%s/policies/simulate", strings.TrimSuffix(controller, "/"), nodeID),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, respBody)
+	}
+
+	var simulation swagger.PolicySimulation
+	if err = json.Unmarshal(respBody, &simulation); err != nil {
+		return err
+	}
+	if len(simulation.Results) == 0 {
+		fmt.Println("No traffic policy attached")
+	}
+	for i := range simulation.Results {
+		printResult(&simulation.Results[i])
+	}
+
+	return nil
+}
+
+func printResult(result *swagger.PolicySimulationResult) {
+	attachment := "interface " + result.InterfaceID
+	if result.AppID != "" {
+		attachment = "app " + result.AppID
+	}
+	if !result.Matched {
+		fmt.Printf("%s, policy %s: no rule matched\n", attachment, result.PolicyID)
+		return
+	}
+
+	rule := result.TrafficRule
+	fmt.Printf("%s, policy %s: rules[%d] %q (priority %d) matched, action %s\n",
+		attachment, result.PolicyID, *result.Rule, rule.Description, rule.Priority, rule.Target.Action)
+	if rule.Target.MAC != nil {
+		fmt.Printf("    MAC address set to %s\n", rule.Target.MAC.MACAddress)
+	}
+	if rule.Target.IP != nil {
+		fmt.Printf("    IP address set to %s, port %d\n", rule.Target.IP.Address, rule.Target.IP.Port)
+	}
+}
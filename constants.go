@@ -13,6 +13,10 @@ const MaxBodySize = 64 * 1024
 // MaxHTTPRequestTime is the maximum time to request HTTP data before timing out
 const MaxHTTPRequestTime = 2 * time.Minute
 
+// MaxOperationTime is the maximum time an operation on a node may run before
+// timing out
+const MaxOperationTime = 30 * time.Minute
+
 // MaxDBRequestTime is the maximum time to request database data before timing out
 const MaxDBRequestTime = 10 * time.Second
 
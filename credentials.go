@@ -4,23 +4,31 @@
 package cce
 
 import (
-	"crypto/md5" //nolint:gosec
+	"context"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
 )
 
 // Credentials defines a response for a request to obtain authentication
 // credentials. These credentials may be used to further communicate with
 // endpoint(s) that are protected by a form of authentication.
+//
+// Credentials are issued to a node on enrollment and each renewal. The
+// credentials issued before are kept as history and marked as superseded.
 type Credentials struct {
-	// ID is the base64-encoded MD5 hash of the certificate's public key.
-	ID string `json:"id"`
-	// Certificate is a PEM-encoded X.509 certificate.
+	ID     string `json:"id"`
+	NodeID string `json:"node_id"`
+	// Certificate is a PEM-encoded X.509 certificate issued to the node.
 	Certificate string `json:"certificate"`
+	// Superseded is set once the credentials are replaced by renewal or
+	// re-enrollment.
+	Superseded bool `json:"superseded"`
 }
 
 // GetTableName returns the name of the table this entity is saved in.
@@ -40,45 +48,111 @@ func (c *Credentials) SetID(id string) {
 
 // Validate validates the model.
 func (c *Credentials) Validate() error {
-	if c.ID == "" {
-		return errors.New("id cannot be empty")
+	if !uuid.IsValid(c.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(c.NodeID) {
+		return errors.New("node_id not a valid uuid")
 	}
 	if c.Certificate == "" {
 		return errors.New("certificate cannot be empty")
 	}
 
-	block, _ := pem.Decode([]byte(c.Certificate))
-	if block == nil {
-		return errors.New("certificate not PEM-encoded")
+	cert, err := c.ParseCertificate()
+	if err != nil {
+		return err
 	}
 
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return errors.New("certificate not a valid certificate")
+	if cert.Subject.CommonName != c.NodeID {
+		return errors.New("certificate not issued to node_id")
 	}
 
-	pubKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
-	if err != nil {
-		return errors.New("certificate public key not a valid public key")
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*Credentials) FilterFields() []string {
+	return []string{
+		"node_id",
 	}
+}
 
-	// gosec: not hashing user input/passwords
-	hash := md5.Sum(pubKey) //nolint:gosec
+// ParseCertificate parses the PEM-encoded certificate.
+func (c *Credentials) ParseCertificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(c.Certificate))
+	if block == nil {
+		return nil, errors.New("certificate not PEM-encoded")
+	}
 
-	if c.ID != base64.RawURLEncoding.EncodeToString(hash[:]) {
-		return errors.New("id not derived from certificate public key")
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.New("certificate not a valid certificate")
 	}
 
-	return nil
+	return cert, nil
 }
 
 func (c *Credentials) String() string {
 	return fmt.Sprintf(strings.TrimSpace(`
 Credentials[
     ID: %s
+    NodeID: %s
     Certificate: %s
+    Superseded: %t
 ]`),
 		c.ID,
+		c.NodeID,
 		c.Certificate,
+		c.Superseded,
 	)
 }
+
+// CurrentCredentials returns the credentials of the node that are not
+// superseded, or nil if the node is not enrolled.
+func CurrentCredentials(ctx context.Context, ps PersistenceService, nodeID string) (*Credentials, error) {
+	persisted, err := ps.Filter(ctx, &Credentials{}, []Filter{{
+		Field: "node_id",
+		Value: nodeID,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range persisted {
+		if creds := e.(*Credentials); !creds.Superseded {
+			return creds, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// CredentialsPolicy is the policy for the lifetime and renewal of the
+// certificates issued to nodes.
+type CredentialsPolicy struct {
+	// Lifetime is the maximum validity of an issued certificate. If zero
+	// certificates are valid until the CA expires.
+	Lifetime time.Duration
+	// RenewalWindow is how long before it expires a certificate can be
+	// renewed. If zero certificates can be renewed at any time.
+	RenewalWindow time.Duration
+}
+
+// NotAfter returns the expiry of a certificate issued at now, or the zero time
+// if certificates are valid until the CA expires.
+func (p CredentialsPolicy) NotAfter(now time.Time) time.Time {
+	if p.Lifetime == 0 {
+		return time.Time{}
+	}
+
+	return now.Add(p.Lifetime)
+}
+
+// CanRenew returns whether the certificate can be renewed at now.
+func (p CredentialsPolicy) CanRenew(cert *x509.Certificate, now time.Time) bool {
+	if now.After(cert.NotAfter) {
+		return false
+	}
+
+	return p.RenewalWindow == 0 || cert.NotAfter.Sub(now) <= p.RenewalWindow
+}
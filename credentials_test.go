@@ -4,7 +4,9 @@
 package cce_test
 
 import (
+	"crypto/x509"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -18,13 +20,13 @@ var _ = Describe("Entities: Credentials", func() {
 
 	var testCertificate = strings.TrimSpace(`
 -----BEGIN CERTIFICATE-----
-MIIBNzCB3qADAgECAggUBcLhJDUGvDAKBggqhkjOPQQDAjAfMR0wGwYDVQQKExRD
+MIIBQDCB6KADAgECAggUBcLhJDUGvDAKBggqhkjOPQQDAjAfMR0wGwYDVQQKExRD
 b250cm9sbGVyIEF1dGhvcml0eTAeFw0xOTA1MDkyMzM1MThaFw0yMjA1MDYxNjQ3
-NTVaMCExHzAdBgNVBAMMFmNKbF8wWF91Tld2TUdpWU5TNF9QU0EwWTATBgcqhkjO
-PQIBBggqhkjOPQMBBwNCAARtIFmun7tXRkXNEo+Z5jLm9k3Oo3i1OJoyZPXf/cI2
-Sc5R/5l3+ydZ+M1J19moUjIPGLpU1pr5Ln4c5H+L3bd9owIwADAKBggqhkjOPQQD
-AgNIADBFAiBePo41cBQAYZFCcJJfrOlPbzXTAONrnJt/NN1h16krJwIhANRhVgcl
-HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
+NTVaMC8xLTArBgNVBAMTJDlkNzQwYjVlLTJmNWEtNGQ4Yi04ZDFiLTM2YTBiNGRm
+YTJjMzBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABOLX/gx9K2zEX7TWBgOLOXIV
+pX8omN7CVgZO+W9o03SB382mJDiXZ0sZiBYGZhtuAzDqieiZhqatjjXmD8rQx/cw
+CgYIKoZIzj0EAwIDRwAwRAIgFKWxSVJ4BrGpSJUFTS9faEEqHHF91S7+9Dw2x0v7
+DKgCIHUwbMwmnluh9t9Qiv6L9b8FNkAKooaKF0DZleDwFznI
 -----END CERTIFICATE-----`)
 
 	var testInvalidCertificate = strings.TrimSpace(`
@@ -34,7 +36,8 @@ HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
 
 	BeforeEach(func() {
 		creds = &cce.Credentials{
-			ID:          "ysRMvw8aMviv9qPcXIlAsA",
+			ID:          "3a4a0f2b-5d1c-4e8f-9b7a-6c2d1e0f9a8b",
+			NodeID:      "9d740b5e-2f5a-4d8b-8d1b-36a0b4dfa2c3",
 			Certificate: testCertificate,
 		}
 	})
@@ -48,7 +51,7 @@ HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
 	Describe("GetID", func() {
 		It("Should return the ID", func() {
 			Expect(creds.GetID()).To(Equal(
-				"ysRMvw8aMviv9qPcXIlAsA"))
+				"3a4a0f2b-5d1c-4e8f-9b7a-6c2d1e0f9a8b"))
 		})
 	})
 
@@ -63,10 +66,16 @@ HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
 	})
 
 	Describe("Validate", func() {
-		It("Should return an error if ID is empty", func() {
-			creds.ID = ""
+		It("Should return an error if ID is not a UUID", func() {
+			creds.ID = "123"
+			Expect(creds.Validate()).To(MatchError(
+				"id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			creds.NodeID = "123"
 			Expect(creds.Validate()).To(MatchError(
-				"id cannot be empty"))
+				"node_id not a valid uuid"))
 		})
 
 		It("Should return an error if Certificate is empty", func() {
@@ -87,10 +96,36 @@ HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
 				"certificate not a valid certificate"))
 		})
 
-		It("Should return an error if ID is not derived from Certificate public key", func() {
-			creds.ID = "123"
+		It("Should return an error if Certificate was not issued to NodeID", func() {
+			creds.NodeID = "1b5a7e22-7c43-4b1e-a3d6-0f8e2c9b4d11"
 			Expect(creds.Validate()).To(MatchError(
-				"id not derived from certificate public key"))
+				"certificate not issued to node_id"))
+		})
+
+		It("Should return no error if valid", func() {
+			Expect(creds.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(creds.FilterFields()).To(Equal([]string{
+				"node_id",
+			}))
+		})
+	})
+
+	Describe("ParseCertificate", func() {
+		It("Should return the certificate", func() {
+			cert, err := creds.ParseCertificate()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.Subject.CommonName).To(Equal("9d740b5e-2f5a-4d8b-8d1b-36a0b4dfa2c3"))
+		})
+
+		It("Should return an error if Certificate is not PEM-encoded", func() {
+			creds.Certificate = "123"
+			_, err := creds.ParseCertificate()
+			Expect(err).To(MatchError("certificate not PEM-encoded"))
 		})
 	})
 
@@ -98,18 +133,58 @@ HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
 		It("Should return the string value", func() {
 			Expect(creds.String()).To(Equal(strings.TrimSpace(`
 Credentials[
-    ID: ysRMvw8aMviv9qPcXIlAsA
+    ID: 3a4a0f2b-5d1c-4e8f-9b7a-6c2d1e0f9a8b
+    NodeID: 9d740b5e-2f5a-4d8b-8d1b-36a0b4dfa2c3
     Certificate: -----BEGIN CERTIFICATE-----
-MIIBNzCB3qADAgECAggUBcLhJDUGvDAKBggqhkjOPQQDAjAfMR0wGwYDVQQKExRD
+MIIBQDCB6KADAgECAggUBcLhJDUGvDAKBggqhkjOPQQDAjAfMR0wGwYDVQQKExRD
 b250cm9sbGVyIEF1dGhvcml0eTAeFw0xOTA1MDkyMzM1MThaFw0yMjA1MDYxNjQ3
-NTVaMCExHzAdBgNVBAMMFmNKbF8wWF91Tld2TUdpWU5TNF9QU0EwWTATBgcqhkjO
-PQIBBggqhkjOPQMBBwNCAARtIFmun7tXRkXNEo+Z5jLm9k3Oo3i1OJoyZPXf/cI2
-Sc5R/5l3+ydZ+M1J19moUjIPGLpU1pr5Ln4c5H+L3bd9owIwADAKBggqhkjOPQQD
-AgNIADBFAiBePo41cBQAYZFCcJJfrOlPbzXTAONrnJt/NN1h16krJwIhANRhVgcl
-HjqhNuXFDM1RVkkcBuAD0lQZHQJdXJGmqNju
+NTVaMC8xLTArBgNVBAMTJDlkNzQwYjVlLTJmNWEtNGQ4Yi04ZDFiLTM2YTBiNGRm
+YTJjMzBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABOLX/gx9K2zEX7TWBgOLOXIV
+pX8omN7CVgZO+W9o03SB382mJDiXZ0sZiBYGZhtuAzDqieiZhqatjjXmD8rQx/cw
+CgYIKoZIzj0EAwIDRwAwRAIgFKWxSVJ4BrGpSJUFTS9faEEqHHF91S7+9Dw2x0v7
+DKgCIHUwbMwmnluh9t9Qiv6L9b8FNkAKooaKF0DZleDwFznI
 -----END CERTIFICATE-----
+    Superseded: false
 ]`,
 			)))
 		})
 	})
+
+	Describe("CredentialsPolicy", func() {
+		var (
+			now  = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			cert = &x509.Certificate{NotAfter: now.Add(10 * 24 * time.Hour)}
+		)
+
+		Describe("NotAfter", func() {
+			It("Should return the zero time without a lifetime", func() {
+				Expect(cce.CredentialsPolicy{}.NotAfter(now).IsZero()).To(BeTrue())
+			})
+
+			It("Should return the expiry after the lifetime", func() {
+				policy := cce.CredentialsPolicy{Lifetime: time.Hour}
+				Expect(policy.NotAfter(now)).To(Equal(now.Add(time.Hour)))
+			})
+		})
+
+		Describe("CanRenew", func() {
+			It("Should allow renewal at any time without a renewal window", func() {
+				Expect(cce.CredentialsPolicy{}.CanRenew(cert, now)).To(BeTrue())
+			})
+
+			It("Should allow renewal within the renewal window", func() {
+				policy := cce.CredentialsPolicy{RenewalWindow: 10 * 24 * time.Hour}
+				Expect(policy.CanRenew(cert, now)).To(BeTrue())
+			})
+
+			It("Should not allow renewal before the renewal window", func() {
+				policy := cce.CredentialsPolicy{RenewalWindow: 24 * time.Hour}
+				Expect(policy.CanRenew(cert, now)).To(BeFalse())
+			})
+
+			It("Should not allow renewal of an expired certificate", func() {
+				Expect(cce.CredentialsPolicy{}.CanRenew(cert, cert.NotAfter.Add(time.Second))).To(BeFalse())
+			})
+		})
+	})
 })
@@ -18,6 +18,8 @@ type DNSConfig struct {
 	Name       string          `json:"name"`
 	ARecords   []*DNSARecord   `json:"a_records"`
 	Forwarders []*DNSForwarder `json:"forwarders"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
 }
 
 // GetTableName returns the name of the persistence table.
@@ -35,6 +37,16 @@ func (cfg *DNSConfig) SetID(id string) {
 	cfg.ID = id
 }
 
+// GetResourceVersion gets the resource version.
+func (cfg *DNSConfig) GetResourceVersion() uint64 {
+	return cfg.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (cfg *DNSConfig) SetResourceVersion(v uint64) {
+	cfg.ResourceVersion = v
+}
+
 // Validate validates the model.
 func (cfg *DNSConfig) Validate() error {
 	if !uuid.IsValid(cfg.ID) {
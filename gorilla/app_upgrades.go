@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// upgradeNodeApps submits an operation upgrading the app on every node
+// running it.
+func (g *Gorilla) upgradeNodeApps(ctx context.Context, app *cce.App) error {
+	ctrl := getController(ctx)
+
+	nodeApps, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.NodeApp{},
+		[]cce.Filter{{Field: "app_id", Value: app.ID}})
+	if err != nil {
+		return err
+	}
+
+	for _, na := range nodeApps {
+		op := &cce.Operation{
+			Kind:   cce.OperationUpgradeApp,
+			NodeID: na.(*cce.NodeApp).NodeID,
+			AppID:  app.ID,
+		}
+		if err = g.ops.submit(ctx, op); err != nil {
+			return err
+		}
+		log.Infof("Upgrading app %s to revision %d on node %s by operation %s",
+			app.ID, app.Revision, op.NodeID, op.ID)
+	}
+
+	return nil
+}
+
+// Used for GET /apps/{app_id}/nodes endpoint
+//
+// The nodes running the app are listed with the revision of the app they run
+// and the last upgrade of the app on them.
+func (g *Gorilla) swagGETAppNodes(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	appID := mux.Vars(r)["app_id"]
+
+	app, err := ctrl.PersistenceService.Read(r.Context(), appID, &cce.App{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if app == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	nodeApps, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeApp{},
+		[]cce.Filter{{Field: "app_id", Value: appID}})
+	if err != nil {
+		log.Errf("Error filtering node apps: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ops, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.Operation{},
+		[]cce.Filter{{Field: "app_id", Value: appID}})
+	if err != nil {
+		log.Errf("Error filtering operations: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Find the last upgrade on every node
+	upgrades := make(map[string]*cce.Operation)
+	for _, e := range ops {
+		op := e.(*cce.Operation)
+		if op.Kind != cce.OperationUpgradeApp {
+			continue
+		}
+		if last, ok := upgrades[op.NodeID]; !ok || op.CreatedAt.After(last.CreatedAt) {
+			upgrades[op.NodeID] = op
+		}
+	}
+
+	// Construct the response object
+	list := swagger.AppNodeList{Nodes: []swagger.AppNode{}}
+	for _, e := range nodeApps {
+		nodeApp := e.(*cce.NodeApp)
+		node := swagger.AppNode{
+			NodeID:   nodeApp.NodeID,
+			Revision: nodeApp.Revision,
+			UpToDate: nodeApp.Revision == app.(*cce.App).Revision,
+		}
+		if op, ok := upgrades[nodeApp.NodeID]; ok {
+			detail := operationDetail(op)
+			node.Upgrade = &detail
+		}
+		list.Nodes = append(list.Nodes, node)
+	}
+	sort.Slice(list.Nodes, func(i, j int) bool {
+		return list.Nodes[i].NodeID < list.Nodes[j].NodeID
+	})
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
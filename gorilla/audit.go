@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// auditResponseWriter records the status code and body of a response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// auditEntities are the entities snapshotted from persistence before and
+// after a request, by the route template of their collection. The
+// configuration held by the nodes is not snapshotted, as reading it would
+// call the nodes.
+var auditEntities = map[string]cce.Persistable{
+	"/apps":               &cce.App{},
+	"/interface_profiles": &cce.InterfaceProfile{},
+	"/kube_ovn/policies":  &cce.TrafficPolicyKubeOVN{},
+	"/node_groups":        &cce.NodeGroup{},
+	"/nodes":              &cce.Node{},
+	"/nodes/pending":      &cce.PendingNode{},
+	"/policies":           &cce.TrafficPolicy{},
+	"/rollouts":           &cce.Rollout{},
+	"/tokens":             &cce.APIToken{},
+	"/users":              &cce.User{},
+}
+
+// auditHandler records an audit event for every POST, PATCH and DELETE
+// request, including those rejected for missing or invalid credentials. The
+// actor of the event is cce.AuditActorAnonymous until it is set by the
+// authentication with setAuditActor.
+func (g *Gorilla) auditHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST", "PATCH", "DELETE":
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		route := r.URL.Path
+		if tpl, err := mux.CurrentRoute(r).GetPathTemplate(); err == nil {
+			route = tpl
+		}
+		event := cce.NewAuditEvent(cce.AuditActorAnonymous, r.Method, route)
+		event.Path = r.URL.Path
+		event.EntityID = entityID(r, route)
+
+		rec := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), contextKey("auditEvent"), event)))
+
+		// A handler writing no response responds with 200 OK
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		event.Status = rec.status
+		event.Result = cce.AuditResultSuccess
+		if rec.status >= http.StatusBadRequest {
+			event.Result = cce.AuditResultFailure
+		}
+
+		ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+		if err := ctrl.PersistenceService.Create(r.Context(), event); err != nil {
+			log.Errf("Error recording audit event %s: %v", event, err)
+		}
+	})
+}
+
+// auditSnapshotHandler represents the entity of an audited request of an
+// authenticated user in its audit event by its persisted state before and
+// after the request, if the route is of a persisted entity.
+func (g *Gorilla) auditSnapshotHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, ok := r.Context().Value(contextKey("auditEvent")).(*cce.AuditEvent)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok = r.Context().Value(contextKey("principal")).(*principal); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The collection of the entity of a PATCH or DELETE route is the
+		// route up to the entity ID variable
+		collection := event.Route
+		if r.Method != "POST" {
+			collection = ""
+			if strings.HasSuffix(event.Route, "}") {
+				collection = event.Route[:strings.LastIndex(event.Route, "/")]
+			}
+			event.Before = snapshot(r, collection, event.EntityID)
+		}
+
+		rec := &auditResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status >= http.StatusBadRequest {
+			return
+		}
+
+		switch r.Method {
+		case "POST":
+			// The ID of a created entity is returned in the response
+			var created struct {
+				ID string `json:"id"`
+			}
+			if json.Unmarshal(rec.body.Bytes(), &created) == nil && created.ID != "" {
+				event.EntityID = created.ID
+				event.After = snapshot(r, collection, created.ID)
+			}
+		case "PATCH":
+			event.After = snapshot(r, collection, event.EntityID)
+		}
+	})
+}
+
+// setAuditActor sets the actor of the audit event of the request, if the
+// request is audited.
+func setAuditActor(r *http.Request, actor string) {
+	if event, ok := r.Context().Value(contextKey("auditEvent")).(*cce.AuditEvent); ok {
+		event.Actor = actor
+	}
+}
+
+// entityID returns the value of the last variable of the route template.
+func entityID(r *http.Request, route string) string {
+	start := strings.LastIndex(route, "{")
+	end := strings.LastIndex(route, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+
+	return mux.Vars(r)[route[start+1:end]]
+}
+
+// snapshot returns the persisted entity of the collection with the ID, or nil
+// if the collection is not of a persisted entity or the entity does not exist.
+// Secrets are removed from the entity.
+func snapshot(r *http.Request, collection, id string) json.RawMessage {
+	zv, ok := auditEntities[collection]
+	if !ok || id == "" {
+		return nil
+	}
+
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	e, err := ctrl.PersistenceService.Read(r.Context(), id, zv)
+	if err != nil {
+		log.Errf("Error reading audited entity %s: %v", id, err)
+		return nil
+	}
+	if e == nil {
+		return nil
+	}
+
+	switch e := e.(type) {
+	case *cce.User:
+		e.PasswordHash = ""
+	case *cce.APIToken:
+		e.Hash = ""
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Errf("Error marshaling audited entity %s: %v", id, err)
+		return nil
+	}
+
+	return b
+}
+
+// Used for GET /audit endpoint
+func (g *Gorilla) swagGETAudit(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of audit events from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.AuditEvent{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	events := swagger.AuditEventList{Events: []*cce.AuditEvent{}, Next: nextLink(r, next)}
+	for _, e := range persisted {
+		events.Events = append(events.Events, e.(*cce.AuditEvent))
+	}
+
+	// Marshal the response object to JSON
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(eventsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /audit/export endpoint
+//
+// The audit events matching the filters are exported in JSON lines format,
+// one event per line, reading all pages from persistence.
+func (g *Gorilla) swagGETAuditExport(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+	q.Limit = maxListLimit
+
+	enc := json.NewEncoder(w)
+	for first := true; first || q.PageToken != ""; first = false {
+		persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.AuditEvent{}, q)
+		if err != nil {
+			if first {
+				writeListError(w, err)
+			} else {
+				log.Errf("Error exporting audit events: %v", err)
+			}
+			return
+		}
+		if first {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+		}
+
+		for _, e := range persisted {
+			if err = enc.Encode(e); err != nil {
+				log.Errf("Error writing response: %v", err)
+				return
+			}
+		}
+		q.PageToken = next
+	}
+}
@@ -4,13 +4,57 @@
 package gorilla
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/gorilla/mux"
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
 )
 
+// jwksPath is the path of the JSON Web Key Set verifying the auth tokens.
+const jwksPath = "/.well-known/jwks.json"
+
+// Paths of the OpenID Connect login with an external identity provider.
+const (
+	oidcLoginPath    = "/auth/oidc/login"
+	oidcCallbackPath = "/auth/oidc/callback"
+)
+
+// publicPaths are the paths that do not require an auth token, besides
+// POST /auth.
+var publicPaths = map[string]bool{
+	jwksPath:         true,
+	crlPath:          true,
+	oidcLoginPath:    true,
+	oidcCallbackPath: true,
+}
+
+// oidcCookie holds the state and nonce of an OpenID Connect login between the
+// redirect to the identity provider and the callback.
+const (
+	oidcCookie       = "cce_oidc_login"
+	oidcCookieMaxAge = 10 * 60 // 10 minutes
+)
+
+// principal is the authenticated user of a request.
+type principal struct {
+	UserID string
+	Roles  []string
+
+	// TokenID and TokenExpiry identify the JSON Web Token the user
+	// authenticated with. They are empty for API tokens.
+	TokenID     string
+	TokenExpiry time.Time
+}
+
 func authenticate(w http.ResponseWriter, r *http.Request) {
 	var (
 		ctrl = r.Context().Value(contextKey("controller")).(*cce.Controller)
@@ -23,28 +67,308 @@ func authenticate(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	setAuditActor(r, cce.AuditActorLoginPrefix+u.Username)
 
 	// Verify the user name and password
-	if u.Username != ctrl.AdminCreds.Username {
+	users, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.User{},
+		[]cce.Filter{{Field: "username", Value: u.Username}})
+	if err != nil {
+		log.Errf("Error reading users: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(users) != 1 {
 		log.Debugf("Unsuccessful login attempt for user '%s'", u.Username)
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
-	if u.Password != ctrl.AdminCreds.Password {
+	user := users[0].(*cce.User)
+	if !user.CheckPassword(u.Password) {
 		log.Debugf("Unsuccessful login attempt for user '%s'", u.Username)
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 	log.Debugf("Successfully authenticated user: %s", u.Username)
+	setAuditActor(r, user.ID)
 
 	// Create an auth token
-	token, err := ctrl.TokenService.Issue()
+	token, err := ctrl.TokenService.Issue(user.ID, user.Roles)
 	if err != nil {
 		log.Debugf("Error signing authentication token: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	writeToken(w, token)
+}
+
+// oidcLogin redirects the user to the external identity provider to log in
+// with the OpenID Connect authorization code flow.
+func oidcLogin(w http.ResponseWriter, r *http.Request) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	if ctrl.IdentityProvider == nil {
+		http.Error(w, "No identity provider is configured", http.StatusNotFound)
+		return
+	}
+
+	state, err := randomString()
+	if err != nil {
+		log.Errf("Error generating OIDC state: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	nonce, err := randomString()
+	if err != nil {
+		log.Errf("Error generating OIDC nonce: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	url, err := ctrl.IdentityProvider.AuthCodeURL(r.Context(), state, nonce)
+	if err != nil {
+		log.Errf("Error building OIDC authorization URL: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    state + "." + nonce,
+		Path:     oidcCallbackPath,
+		MaxAge:   oidcCookieMaxAge,
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// oidcCallback completes an OpenID Connect login. The identity provider
+// redirects the user back with an authorization code, which is exchanged for
+// the identity of the user. The user is created on first login and its roles
+// are updated from its identity provider groups on every login.
+func oidcCallback(w http.ResponseWriter, r *http.Request) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	idp := ctrl.IdentityProvider
+
+	if idp == nil {
+		http.Error(w, "No identity provider is configured", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	if e := query.Get("error"); e != "" {
+		log.Debugf("OIDC login failed: %s: %s", e, query.Get("error_description"))
+		http.Error(w, "Login with identity provider failed", http.StatusUnauthorized)
+		return
+	}
+
+	// Check the callback belongs to the login started by this user agent
+	cookie, err := r.Cookie(oidcCookie)
+	if err != nil {
+		http.Error(w, "No login in progress", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCookie, Path: oidcCallbackPath, MaxAge: -1})
+
+	stateNonce := strings.SplitN(cookie.Value, ".", 2)
+	if len(stateNonce) != 2 ||
+		subtle.ConstantTimeCompare([]byte(stateNonce[0]), []byte(query.Get("state"))) != 1 {
+		http.Error(w, "Login state does not match", http.StatusBadRequest)
+		return
+	}
+
+	identity, err := idp.Exchange(r.Context(), query.Get("code"), stateNonce[1])
+	if err != nil {
+		log.Debugf("Unsuccessful OIDC login: %v", err)
+		http.Error(w, "Login with identity provider failed", http.StatusUnauthorized)
+		return
+	}
+	if len(identity.Roles) == 0 {
+		log.Debugf("OIDC user '%s' with groups %v has no roles", identity.Username, identity.Groups)
+		http.Error(w, "User has no roles", http.StatusForbidden)
+		return
+	}
+
+	user, err := provisionExternalUser(r.Context(), ctrl.PersistenceService,
+		idp.Issuer+" "+identity.Subject, identity.Username, identity.Roles)
+	if err == errUsernameTaken {
+		log.Debugf("OIDC user '%s' collides with an existing user", identity.Username)
+		http.Error(w, "Username is already taken by another user", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Errf("Error provisioning OIDC user '%s': %v", identity.Username, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Debugf("Successfully authenticated OIDC user: %s", user.Username)
+
+	token, err := ctrl.TokenService.Issue(user.ID, user.Roles)
+	if err != nil {
+		log.Debugf("Error signing authentication token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeToken(w, token)
+}
+
+// errUsernameTaken is returned when an external user is created with the
+// username of another user.
+var errUsernameTaken = errors.New("username is already taken")
+
+// provisionExternalUser creates the user with the external ID or updates its
+// roles. The username is only set when the user is created, as it is
+// immutable, and must not be taken by a local or another external user.
+func provisionExternalUser(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	externalID string,
+	username string,
+	roles []string,
+) (*cce.User, error) {
+	users, err := ps.Filter(ctx, &cce.User{}, []cce.Filter{{Field: "external_id", Value: externalID}})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		if users, err = ps.Filter(
+			ctx, &cce.User{}, []cce.Filter{{Field: "username", Value: username}},
+		); err != nil {
+			return nil, err
+		}
+		if len(users) != 0 {
+			return nil, errUsernameTaken
+		}
+
+		user := &cce.User{
+			ID:         uuid.New(),
+			Username:   username,
+			Roles:      roles,
+			ExternalID: externalID,
+		}
+		if err = user.Validate(); err != nil {
+			return nil, err
+		}
+		log.Infof("Creating user '%s' for identity provider subject", username)
+		return user, ps.Create(ctx, user)
+	}
+
+	user := users[0].(*cce.User)
+	if !equalStrings(user.Roles, roles) {
+		user.Roles = roles
+		if err = ps.BulkUpdate(ctx, []cce.Persistable{user}); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// refreshToken issues a new auth token with the current roles of the user and
+// revokes the token the request was authenticated with.
+func refreshToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctrl = r.Context().Value(contextKey("controller")).(*cce.Controller)
+		p    = getPrincipal(r.Context())
+	)
+
+	if p.TokenID == "" {
+		http.Error(w, "Only JSON Web Tokens can be refreshed", http.StatusBadRequest)
+		return
+	}
+
+	user, err := ctrl.PersistenceService.Read(r.Context(), p.UserID, &cce.User{})
+	if err != nil {
+		log.Errf("Error reading user: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	token, err := ctrl.TokenService.Issue(p.UserID, user.(*cce.User).Roles)
+	if err != nil {
+		log.Debugf("Error signing authentication token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err = revokeToken(r.Context(), ctrl.PersistenceService, p); err != nil {
+		log.Errf("Error revoking authentication token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Debugf("Refreshed authentication token for user %s", p.UserID)
+
+	writeToken(w, token)
+}
+
+// logout revokes the token the request was authenticated with.
+func logout(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctrl = r.Context().Value(contextKey("controller")).(*cce.Controller)
+		p    = getPrincipal(r.Context())
+	)
+
+	if p.TokenID == "" {
+		http.Error(w, "API tokens are revoked by deleting them", http.StatusBadRequest)
+		return
+	}
+
+	if err := revokeToken(r.Context(), ctrl.PersistenceService, p); err != nil {
+		log.Errf("Error revoking authentication token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Debugf("Logged out user %s", p.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func revokeToken(ctx context.Context, ps cce.PersistenceService, p *principal) error {
+	if err := ps.Create(ctx, &cce.RevokedToken{
+		ID:        p.TokenID,
+		UserID:    p.UserID,
+		ExpiresAt: p.TokenExpiry,
+	}); err != nil {
+		return err
+	}
+	ctx.Value(contextKey("revocations")).(*revocations).revoke(p.TokenID, p.TokenExpiry)
+
+	return nil
+}
+
+func writeToken(w http.ResponseWriter, token string) {
 	// Wrap auth token in JSON
 	bytes, err := json.Marshal(
 		struct {
@@ -68,8 +392,27 @@ func authenticate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// jwks publishes the public keys verifying auth tokens so that other services
+// can validate tokens without calling the controller.
+func jwks(w http.ResponseWriter, r *http.Request) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	bytes, err := json.Marshal(ctrl.TokenService.Keys.JWKS())
+	if err != nil {
+		log.Errf("Error marshaling JWKS: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(bytes); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
 // requireAuthHandler is a handler that only allows HTTP requests with a valid
-// JSON Web Token issued by the Controller Token Authentication service.
+// JSON Web Token issued by the Controller Token Authentication service or a
+// valid API token, whose roles grant the permission required by the route.
 func requireAuthHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
@@ -89,12 +432,142 @@ func requireAuthHandler(next http.Handler) http.Handler {
 		}
 
 		// Validate the auth token
-		err := ctrl.TokenService.Validate(bearer[1])
+		var (
+			p   *principal
+			err error
+		)
+		if strings.HasPrefix(bearer[1], cce.APITokenPrefix) {
+			p, err = authenticateAPIToken(r.Context(), ctrl.PersistenceService, bearer[1])
+		} else {
+			p, err = authenticateJWT(r.Context(), ctrl, bearer[1])
+		}
 		if err != nil {
+			log.Debugf("Invalid auth token: %v", err)
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		setAuditActor(r, p.UserID)
+
+		// Check the roles grant the permission required by the route
+		required := routePermission(r)
+		if !cce.HasPermission(p.Roles, required) {
+			log.Debugf("User %s with roles %v denied %s permission for %s %s",
+				p.UserID, p.Roles, required, r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), contextKey("principal"), p)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// authenticateJWT validates a JSON Web Token and checks it has not been
+// revoked. The roles are those of the user at the time of the request, so
+// that changing the roles of a user or deleting it applies to the tokens
+// already issued.
+func authenticateJWT(ctx context.Context, ctrl *cce.Controller, token string) (*principal, error) {
+	claims, err := ctrl.TokenService.Validate(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := ctx.Value(contextKey("revocations")).(*revocations).isRevoked(
+		ctx, ctrl.PersistenceService, claims.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	user, err := ctrl.PersistenceService.Read(ctx, claims.Subject, &cce.User{})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("token user does not exist")
+	}
+
+	return &principal{
+		UserID:      claims.Subject,
+		Roles:       user.(*cce.User).Roles,
+		TokenID:     claims.ID,
+		TokenExpiry: claims.Expiry.Time(),
+	}, nil
+}
+
+// authenticateAPIToken looks up an API token by the hash of its secret. The
+// roles are those of the user at the time of the request.
+func authenticateAPIToken(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	secret string,
+) (*principal, error) {
+	tokens, err := ps.Filter(
+		ctx,
+		&cce.APIToken{},
+		[]cce.Filter{{Field: "hash", Value: cce.HashAPIToken(secret)}})
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) != 1 {
+		return nil, errors.New("unknown or revoked API token")
+	}
+
+	user, err := ps.Read(ctx, tokens[0].(*cce.APIToken).UserID, &cce.User{})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("API token user does not exist")
+	}
+
+	return &principal{UserID: user.GetID(), Roles: user.(*cce.User).Roles}, nil
+}
+
+// routePermissions are the permissions required by the routes, named by their
+// method and path template, that do not require the default permission of
+// their method.
+var routePermissions = map[string]cce.Permission{
+	"POST /auth/refresh": cce.PermissionRead,
+	"POST /auth/logout":  cce.PermissionRead,
+
+	"POST /policies/{policy_id}/analyze":      cce.PermissionRead,
+	"POST /nodes/{node_id}/policies/simulate": cce.PermissionRead,
+
+	"GET /users":              cce.PermissionAdmin,
+	"POST /users":             cce.PermissionAdmin,
+	"GET /users/{user_id}":    cce.PermissionAdmin,
+	"PATCH /users/{user_id}":  cce.PermissionAdmin,
+	"DELETE /users/{user_id}": cce.PermissionAdmin,
+
+	"GET /tokens":               cce.PermissionAdmin,
+	"POST /tokens":              cce.PermissionAdmin,
+	"DELETE /tokens/{token_id}": cce.PermissionAdmin,
+
+	"GET /audit":        cce.PermissionAdmin,
+	"GET /audit/export": cce.PermissionAdmin,
+}
+
+// routePermission returns the permission required to call the matched route.
+// Managing users and API tokens and reading the audit log requires
+// PermissionAdmin, refreshing and revoking the own auth token, analyzing and
+// simulating traffic policies and other GET requests require PermissionRead
+// and all other requests require PermissionWrite.
+func routePermission(r *http.Request) cce.Permission {
+	if route := mux.CurrentRoute(r); route != nil {
+		if p, ok := routePermissions[route.GetName()]; ok {
+			return p
+		}
+	}
+	if r.Method == http.MethodGet {
+		return cce.PermissionRead
+	}
+
+	return cce.PermissionWrite
+}
+
+func getPrincipal(ctx context.Context) *principal {
+	return ctx.Value(contextKey("principal")).(*principal)
+}
@@ -15,6 +15,9 @@ func handleCreateNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 	if err != nil {
 		return fmt.Errorf("Error fetching app from DB: %v", err)
 	}
+	if app == nil {
+		return fmt.Errorf("App %s not found", e.(*cce.NodeApp).AppID)
+	}
 
 	log.Debugf("Loaded app %s\n%+v", app.GetID(), app)
 
@@ -27,6 +30,7 @@ func handleCreateNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 	if err != nil {
 		return fmt.Errorf("Error connecting to node: %v", err)
 	}
+	defer disconnectNode(nodeCC)
 
 	if err := nodeCC.AppDeploySvcCli.Deploy(ctx, app.(*cce.App)); err != nil {
 		return err
@@ -45,8 +49,6 @@ func handleCreateNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 
 	log.Infof("App %s deployed to node", app.GetID())
 
-	disconnectNode(nodeCC)
-
 	return nil
 }
 
@@ -70,6 +72,7 @@ func handleCreateNodesDNSConfigs(
 	if err != nil {
 		return err
 	}
+	defer disconnectNode(nodeCC)
 
 	for _, aRecord := range dnsConfig.(*cce.DNSConfig).ARecords {
 		if err := nodeCC.DNSSvcCli.SetA(ctx, aRecord); err != nil {
@@ -96,6 +99,7 @@ func handleCreateNodesDNSConfigsWithAliases(
 	if err != nil {
 		return err
 	}
+	defer disconnectNode(nodeCC)
 
 	for _, alias := range dnsAliases {
 		record := &cce.DNSARecord{
@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// crlPath is the path of the certificate revocation list of the node
+// certificates.
+const crlPath = "/crl"
+
+// crlValidity is how long a client may cache the certificate revocation list.
+const crlValidity = 24 * time.Hour
+
+// Used for GET /nodes/{node_id}/credentials endpoint
+//
+// The credentials issued to the node are listed without their certificates.
+func (g *Gorilla) swagGETNodeCredentials(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	persisted, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.Credentials{},
+		[]cce.Filter{{Field: "node_id", Value: mux.Vars(r)["node_id"]}})
+	if err != nil {
+		log.Errf("Error reading credentials: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	list := swagger.CredentialsList{Credentials: []swagger.CredentialsSummary{}}
+	for _, e := range persisted {
+		creds := e.(*cce.Credentials)
+		cert, err := creds.ParseCertificate()
+		if err != nil {
+			log.Errf("Error parsing credentials %s: %v", creds.ID, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		list.Credentials = append(list.Credentials, swagger.CredentialsSummary{
+			ID:           creds.ID,
+			SerialNumber: cert.SerialNumber.Text(16),
+			NotBefore:    cert.NotBefore,
+			NotAfter:     cert.NotAfter,
+			Superseded:   creds.Superseded,
+			Revoked:      ctrl.RevokedCertificates.IsRevoked(cert.SerialNumber),
+		})
+	}
+	sort.Slice(list.Credentials, func(i, j int) bool {
+		return list.Credentials[i].NotBefore.After(list.Credentials[j].NotBefore)
+	})
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for DELETE /nodes/{node_id}/credentials endpoint
+//
+// The unexpired certificates of the node are revoked. Its credentials are kept
+// so that the node cannot enroll again until its re-enrollment is requested.
+func (g *Gorilla) swagDELETENodeCredentials(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	issued, err := revokeNodeCredentials(r.Context(), ctrl, mux.Vars(r)["node_id"])
+	if err != nil {
+		log.Errf("Error revoking credentials: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(issued) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Used for POST /nodes/{node_id}/reenroll endpoint
+//
+// The unexpired certificates of the node are revoked, its credentials are
+// superseded and its address is deleted so that the node can enroll again.
+func (g *Gorilla) swagPOSTNodeReenroll(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	nodeID := mux.Vars(r)["node_id"]
+
+	node, err := ctrl.PersistenceService.Read(r.Context(), nodeID, &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	issued, err := revokeNodeCredentials(r.Context(), ctrl, nodeID)
+	if err != nil {
+		log.Errf("Error revoking credentials: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var superseded []cce.Persistable
+	for _, creds := range issued {
+		if !creds.Superseded {
+			creds.Superseded = true
+			superseded = append(superseded, creds)
+		}
+	}
+	if len(superseded) > 0 {
+		if err = ctrl.PersistenceService.BulkUpdate(r.Context(), superseded); err != nil {
+			log.Errf("Error superseding credentials: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	targets, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeGRPCTarget{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		log.Errf("Error reading node addresses: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, t := range targets {
+		if _, err = ctrl.PersistenceService.Delete(r.Context(), t.GetID(), &cce.NodeGRPCTarget{}); err != nil {
+			log.Errf("Error deleting node address: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	g.invalidateNodeConns(nodeID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Used for GET /crl endpoint
+//
+// The certificate revocation list is DER-encoded and signed by the CA that
+// issues the node certificates.
+func crl(w http.ResponseWriter, r *http.Request) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.RevokedCertificate{})
+	if err != nil {
+		log.Errf("Error reading revoked certificates: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var revoked []pkix.RevokedCertificate
+	for _, e := range persisted {
+		c := e.(*cce.RevokedCertificate)
+		serial, ok := c.Serial()
+		if !ok {
+			log.Warningf("Omitting revoked certificate %s with invalid serial number from CRL", c.ID)
+			continue
+		}
+		revoked = append(revoked, pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: c.RevokedAt,
+		})
+	}
+
+	der, err := ctrl.AuthorityService.CreateCRL(revoked, time.Now().Add(crlValidity))
+	if err != nil {
+		log.Errf("Error creating CRL: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	if _, err = w.Write(der); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// revokeNodeCredentials revokes the certificates of the credentials issued to
+// the node that have not expired yet, the superseded ones included, and
+// returns the credentials.
+func revokeNodeCredentials(ctx context.Context, ctrl *cce.Controller, nodeID string) ([]*cce.Credentials, error) {
+	persisted, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.Credentials{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var issued []*cce.Credentials
+	for _, e := range persisted {
+		creds := e.(*cce.Credentials)
+		issued = append(issued, creds)
+
+		cert, err := creds.ParseCertificate()
+		if err != nil {
+			return nil, err
+		}
+		if now.After(cert.NotAfter) {
+			continue
+		}
+		if err = revokeCredentials(ctx, ctrl, creds); err != nil {
+			return nil, err
+		}
+	}
+
+	return issued, nil
+}
+
+// revokeCredentials revokes the certificate of the credentials, unless it was
+// already revoked.
+func revokeCredentials(ctx context.Context, ctrl *cce.Controller, creds *cce.Credentials) error {
+	cert, err := creds.ParseCertificate()
+	if err != nil {
+		return err
+	}
+	serial := cert.SerialNumber.Text(16)
+
+	revoked, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.RevokedCertificate{},
+		[]cce.Filter{{Field: "serial_number", Value: serial}})
+	if err != nil {
+		return err
+	}
+	if len(revoked) == 0 {
+		if err = ctrl.PersistenceService.Create(ctx, &cce.RevokedCertificate{
+			ID:           uuid.New(),
+			NodeID:       creds.NodeID,
+			SerialNumber: serial,
+			RevokedAt:    time.Now().UTC(),
+		}); err != nil {
+			return err
+		}
+		log.Infof("Revoked certificate %s of node %s", serial, creds.NodeID)
+	}
+
+	if ctrl.RevokedCertificates != nil {
+		ctrl.RevokedCertificates.Revoke(cert.SerialNumber)
+	}
+
+	return nil
+}
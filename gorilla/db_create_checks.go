@@ -11,6 +11,36 @@ import (
 	cce "github.com/open-ness/edgecontroller"
 )
 
+func checkDBCreateNodes(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	e cce.Persistable,
+) (statusCode int, err error) {
+	var es []cce.Persistable
+
+	if es, err = ps.Filter(
+		ctx,
+		&cce.Node{},
+		[]cce.Filter{
+			{
+				Field: "serial",
+				Value: e.(*cce.Node).Serial,
+			},
+		},
+	); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if len(es) != 0 {
+		return http.StatusUnprocessableEntity, fmt.Errorf(
+			"duplicate record in %s detected for serial %s",
+			e.(*cce.Node).GetTableName(),
+			e.(*cce.Node).Serial)
+	}
+
+	return 0, nil
+}
+
 func checkDBCreateNodesApps(
 	ctx context.Context,
 	ps cce.PersistenceService,
@@ -111,3 +141,51 @@ func checkDBCreateNodesDNSConfigs(
 
 	return 0, nil
 }
+
+func checkDBCreateUsers(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	e cce.Persistable,
+) (statusCode int, err error) {
+	var es []cce.Persistable
+
+	if es, err = ps.Filter(
+		ctx,
+		&cce.User{},
+		[]cce.Filter{
+			{
+				Field: "username",
+				Value: e.(*cce.User).Username,
+			},
+		},
+	); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if len(es) != 0 {
+		return http.StatusUnprocessableEntity, fmt.Errorf(
+			"duplicate record in %s detected for username %s",
+			e.(*cce.User).GetTableName(),
+			e.(*cce.User).Username)
+	}
+
+	return 0, nil
+}
+
+func checkDBCreateAPITokens(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	e cce.Persistable,
+) (statusCode int, err error) {
+	user, err := ps.Read(ctx, e.(*cce.APIToken).UserID, &cce.User{})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if user == nil {
+		return http.StatusUnprocessableEntity, fmt.Errorf(
+			"user %s does not exist", e.(*cce.APIToken).UserID)
+	}
+
+	return 0, nil
+}
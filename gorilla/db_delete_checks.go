@@ -104,7 +104,14 @@ func checkDBDeleteApps(
 			id)
 	}
 
-	return 0, nil
+	return checkDBDeleteNodeGroupRefs(ctx, ps, "app_id", id, func(g *cce.NodeGroup) bool {
+		for _, appID := range g.Apps {
+			if appID == id {
+				return true
+			}
+		}
+		return false
+	})
 }
 
 func checkDBDeleteNodesApps(
@@ -163,7 +170,14 @@ func checkDBDeleteTrafficPolicies(
 			id)
 	}
 
-	return 0, nil
+	return checkDBDeleteNodeGroupRefs(ctx, ps, "traffic_policy_id", id, func(g *cce.NodeGroup) bool {
+		for _, p := range g.TrafficPolicies {
+			if p.TrafficPolicyID == id {
+				return true
+			}
+		}
+		return false
+	})
 }
 
 func checkDBDeleteDNSConfigs(
@@ -213,5 +227,32 @@ func checkDBDeleteDNSConfigs(
 			id)
 	}
 
+	return checkDBDeleteNodeGroupRefs(ctx, ps, "dns_config_id", id, func(g *cce.NodeGroup) bool {
+		return g.DNSConfigID == id
+	})
+}
+
+// checkDBDeleteNodeGroupRefs checks that no node group refers to the entity.
+// The references are not filterable fields, so every group is read.
+func checkDBDeleteNodeGroupRefs(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	field string,
+	id string,
+	refers func(*cce.NodeGroup) bool,
+) (statusCode int, err error) {
+	es, err := ps.ReadAll(ctx, &cce.NodeGroup{})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	for _, e := range es {
+		if refers(e.(*cce.NodeGroup)) {
+			return http.StatusUnprocessableEntity, fmt.Errorf(
+				"cannot delete %s %s: record in use in node_groups",
+				field, id)
+		}
+	}
+
 	return 0, nil
 }
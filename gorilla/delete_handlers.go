@@ -27,6 +27,7 @@ func handleDeleteNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 	if err != nil {
 		return err
 	}
+	defer disconnectNode(nodeCC)
 
 	// if kubernetes un-deploy application
 	if ctrl.OrchestrationMode == cce.OrchestrationModeKubernetes ||
@@ -40,10 +41,7 @@ func handleDeleteNodesApps(ctx context.Context, ps cce.PersistenceService, e cce
 		}
 	}
 
-	err = nodeCC.AppDeploySvcCli.Undeploy(ctx, app.GetID())
-	disconnectNode(nodeCC)
-
-	return err
+	return nodeCC.AppDeploySvcCli.Undeploy(ctx, app.GetID())
 }
 
 func handleDeleteNodesDNSConfigs(
@@ -66,6 +64,7 @@ func handleDeleteNodesDNSConfigs(
 	if err != nil {
 		return err
 	}
+	defer disconnectNode(nodeCC)
 
 	for _, aRecord := range dnsConfig.(*cce.DNSConfig).ARecords {
 		if err := nodeCC.DNSSvcCli.DeleteA(ctx, aRecord); err != nil {
@@ -92,6 +91,7 @@ func handleDeleteNodesDNSConfigsWithAliases(
 	if err != nil {
 		return err
 	}
+	defer disconnectNode(nodeCC)
 
 	for _, alias := range dnsAliases {
 		record := &cce.DNSARecord{
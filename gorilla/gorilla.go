@@ -8,11 +8,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"runtime/debug"
+	"sort"
 	"strings"
 
 	"github.com/gorilla/mux"
 	logger "github.com/open-ness/common/log"
 	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/reconcile"
+	"github.com/open-ness/edgecontroller/rollout"
 )
 
 var log = logger.DefaultLogger.WithField("pkg", "gorilla")
@@ -22,6 +26,21 @@ type Gorilla struct {
 	// router
 	router *mux.Router
 
+	// nodeConns pools the connections to the nodes, nil if they are not pooled
+	nodeConns *node.Pool
+
+	// ops runs the operations on the nodes
+	ops *operations
+
+	// rollouts runs the rollouts of apps to the nodes
+	rollouts *rollout.Runner
+
+	// reconciler assigns the configuration of the node groups to the nodes
+	reconciler *reconcile.Reconciler
+
+	// revocations caches the revocation list of the auth tokens
+	revocations *revocations
+
 	// TODO: Check if these handlers are still necessary
 	// entity routes handlers
 	nodesHandler                  *handler
@@ -44,11 +63,14 @@ func NewGorilla( //nolint:gocyclo
 		// router
 		router: mux.NewRouter(),
 
+		revocations: newRevocations(),
+
 		// entity routes handlers
 		nodesHandler: &handler{
 			model:    &cce.Node{},
 			reqModel: &cce.NodeReq{},
 
+			checkDBCreate: checkDBCreateNodes,
 			checkDBDelete: checkDBDeleteNodes,
 
 			handleGet:    handleGetNodes,
@@ -98,12 +120,29 @@ func NewGorilla( //nolint:gocyclo
 		},
 	}
 
+	if controller.NodeConnIdleTimeout > 0 {
+		g.nodeConns = &node.Pool{
+			TLS:         controller.EdgeNodeCreds,
+			IdleTimeout: controller.NodeConnIdleTimeout,
+		}
+	}
+	g.ops = newOperations(controller, g.nodeConns)
+	g.rollouts = newRollouts(controller, g.nodeConns)
+	g.reconciler = &reconcile.Reconciler{
+		Controller: controller,
+		Interval:   controller.ReconcileInterval,
+		Workers:    controller.ReconcileWorkers,
+		Connect:    nodeConnector(controller, g.nodeConns),
+	}
+	controller.NodeEnrolled = func(nodeID string) { g.reconcileNodes(nodeID) }
+
 	nativePoliciesHandlers := map[string]http.HandlerFunc{
-		"GET      /policies":             g.swagGETPolicies,
-		"POST     /policies":             g.swagPOSTPolicies,
-		"GET      /policies/{policy_id}": g.swagGETPolicyByID,
-		"PATCH    /policies/{policy_id}": g.swagPATCHPolicyByID,
-		"DELETE   /policies/{policy_id}": g.swagDELETEPolicyByID,
+		"GET      /policies":                     g.swagGETPolicies,
+		"POST     /policies":                     g.swagPOSTPolicies,
+		"GET      /policies/{policy_id}":         g.swagGETPolicyByID,
+		"PATCH    /policies/{policy_id}":         g.swagPATCHPolicyByID,
+		"DELETE   /policies/{policy_id}":         g.swagDELETEPolicyByID,
+		"POST     /policies/{policy_id}/analyze": g.swagPOSTPolicyAnalyze,
 
 		"GET      /nodes/{node_id}/interfaces/{interface_id}/policy": g.swagGETNodeInterfacePolicy,
 		"PATCH    /nodes/{node_id}/interfaces/{interface_id}/policy": g.swagPATCHNodeInterfacePolicy,
@@ -112,6 +151,8 @@ func NewGorilla( //nolint:gocyclo
 		"GET      /nodes/{node_id}/apps/{app_id}/policy": g.swagGETNodeAppPolicy,
 		"PATCH    /nodes/{node_id}/apps/{app_id}/policy": g.swagPATCHNodeAppPolicy,
 		"DELETE   /nodes/{node_id}/apps/{app_id}/policy": g.swagDELETENodeAppPolicy,
+
+		"POST     /nodes/{node_id}/policies/simulate": g.swagPOSTNodePoliciesSimulate,
 	}
 
 	kubeOVNPoliciesHandlers := map[string]http.HandlerFunc{
@@ -127,7 +168,15 @@ func NewGorilla( //nolint:gocyclo
 	}
 
 	routes := map[string]http.HandlerFunc{
-		"POST     /auth": authenticate,
+		"POST     /auth":         authenticate,
+		"POST     /auth/refresh": refreshToken,
+		"POST     /auth/logout":  logout,
+
+		"GET      " + oidcLoginPath:    oidcLogin,
+		"GET      " + oidcCallbackPath: oidcCallback,
+
+		"GET      " + jwksPath: jwks,
+		"GET      " + crlPath:  crl,
 
 		"GET      /nodes":           g.swagGETNodes,
 		"POST     /nodes":           g.swagPOSTNodes,
@@ -135,16 +184,57 @@ func NewGorilla( //nolint:gocyclo
 		"PATCH    /nodes/{node_id}": g.swagPATCHNodeByID,
 		"DELETE   /nodes/{node_id}": g.swagDELETENodeByID,
 
+		"POST     /nodes/import":               g.swagPOSTNodesImport,
+		"GET      /nodes/pending":              g.swagGETPendingNodes,
+		"POST     /nodes/pending":              g.swagPOSTPendingNodes,
+		"DELETE   /nodes/pending/{pending_id}": g.swagDELETEPendingNodeByID,
+
+		"GET      /nodes/{node_id}/credentials": g.swagGETNodeCredentials,
+		"DELETE   /nodes/{node_id}/credentials": g.swagDELETENodeCredentials,
+		"POST     /nodes/{node_id}/reenroll":    g.swagPOSTNodeReenroll,
+
+		"GET      /nodes/{node_id}/drift":         g.swagGETNodeDrift,
+		"GET      /nodes/{node_id}/status_events": g.swagGETNodeStatusEvents,
+
+		"GET      /operations/{operation_id}":        g.swagGETOperationByID,
+		"POST     /operations/{operation_id}/cancel": g.swagPOSTOperationCancel,
+
+		"GET      /interface_profiles":                    g.swagGETInterfaceProfiles,
+		"POST     /interface_profiles":                    g.swagPOSTInterfaceProfiles,
+		"GET      /interface_profiles/{profile_id}":       g.swagGETInterfaceProfileByID,
+		"PATCH    /interface_profiles/{profile_id}":       g.swagPATCHInterfaceProfileByID,
+		"DELETE   /interface_profiles/{profile_id}":       g.swagDELETEInterfaceProfileByID,
+		"POST     /interface_profiles/{profile_id}/apply": g.swagPOSTInterfaceProfileApply,
+
+		"GET      /node_groups":                  g.swagGETNodeGroups,
+		"POST     /node_groups":                  g.swagPOSTNodeGroups,
+		"GET      /node_groups/{group_id}":       g.swagGETNodeGroupByID,
+		"PATCH    /node_groups/{group_id}":       g.swagPATCHNodeGroupByID,
+		"DELETE   /node_groups/{group_id}":       g.swagDELETENodeGroupByID,
+		"GET      /node_groups/{group_id}/nodes": g.swagGETNodeGroupNodes,
+
+		"GET      /rollouts":              g.swagGETRollouts,
+		"POST     /rollouts":              g.swagPOSTRollouts,
+		"GET      /rollouts/{rollout_id}": g.swagGETRolloutByID,
+
 		"GET      /apps":          g.swagGETApps,
 		"POST     /apps":          g.swagPOSTApps,
 		"GET      /apps/{app_id}": g.swagGETAppByID,
 		"PATCH    /apps/{app_id}": g.swagPATCHAppByID,
 		"DELETE   /apps/{app_id}": g.swagDELETEAppByID,
 
+		"GET      /apps/{app_id}/nodes": g.swagGETAppNodes,
+
 		"GET      /nodes/{node_id}/dns": g.swagGETNodeDNS,
 		"PATCH    /nodes/{node_id}/dns": g.swagPATCHNodeDNS,
 		"DELETE   /nodes/{node_id}/dns": g.swagDELETENodeDNS,
 
+		"GET      /nodes/{node_id}/zones":           g.swagGETZones,
+		"POST     /nodes/{node_id}/zones":           g.swagPOSTZones,
+		"GET      /nodes/{node_id}/zones/{zone_id}": g.swagGETZoneByID,
+		"PATCH    /nodes/{node_id}/zones/{zone_id}": g.swagPATCHZoneByID,
+		"DELETE   /nodes/{node_id}/zones/{zone_id}": g.swagDELETEZoneByID,
+
 		"GET      /nodes/{node_id}/interfaces":                g.swagGETInterfaces,
 		"PATCH    /nodes/{node_id}/interfaces":                g.swagPATCHInterfaces,
 		"GET      /nodes/{node_id}/interfaces/{interface_id}": g.swagGETInterfaceByID,
@@ -154,6 +244,19 @@ func NewGorilla( //nolint:gocyclo
 		"GET      /nodes/{node_id}/apps/{app_id}": g.swagGETNodeAppsByID,
 		"PATCH    /nodes/{node_id}/apps/{app_id}": g.swagPATCHNodeAppsByID,
 		"DELETE   /nodes/{node_id}/apps/{app_id}": g.swagDELETENodeAppByID,
+
+		"GET      /users":           g.swagGETUsers,
+		"POST     /users":           g.swagPOSTUsers,
+		"GET      /users/{user_id}": g.swagGETUserByID,
+		"PATCH    /users/{user_id}": g.swagPATCHUserByID,
+		"DELETE   /users/{user_id}": g.swagDELETEUserByID,
+
+		"GET      /tokens":            g.swagGETTokens,
+		"POST     /tokens":            g.swagPOSTTokens,
+		"DELETE   /tokens/{token_id}": g.swagDELETETokenByID,
+
+		"GET      /audit":        g.swagGETAudit,
+		"GET      /audit/export": g.swagGETAuditExport,
 	}
 
 	if controller.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
@@ -166,9 +269,19 @@ func NewGorilla( //nolint:gocyclo
 		}
 	}
 
-	for endpoint, handlerFunc := range routes {
+	// Register the routes by path so that literal paths such as /nodes/pending
+	// are matched before templates such as /nodes/{node_id}. Routes are named
+	// by their method and path template to look up their permission.
+	endpoints := make([]string, 0, len(routes))
+	for endpoint := range routes {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return strings.Fields(endpoints[i])[1] < strings.Fields(endpoints[j])[1]
+	})
+	for _, endpoint := range endpoints {
 		split := strings.Fields(endpoint)
-		g.router.HandleFunc(split[1], handlerFunc).Methods(split[0])
+		g.router.HandleFunc(split[1], routes[endpoint]).Methods(split[0]).Name(split[0] + " " + split[1])
 	}
 
 	// Catch panics
@@ -202,21 +315,28 @@ func NewGorilla( //nolint:gocyclo
 		})
 	})
 
-	// Inject the controller
+	// Inject the controller, the node connection pool and the token
+	// revocations
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := context.WithValue(
 				r.Context(),
 				contextKey("controller"),
 				controller)
+			ctx = context.WithValue(ctx, contextKey("nodeConns"), g.nodeConns)
+			ctx = context.WithValue(ctx, contextKey("revocations"), g.revocations)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	})
 
-	// Require auth token for all endpoints except POST /auth
+	// Record an audit event for every call changing the state of the
+	// controller, including the calls failing authentication
+	g.router.Use(g.auditHandler)
+
+	// Require auth token for all endpoints except logging in and the JWKS
 	g.router.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if r.RequestURI == "/auth" {
+			if r.RequestURI == "/auth" || publicPaths[r.URL.Path] {
 				next.ServeHTTP(w, r)
 			} else {
 				requireAuthHandler(next).ServeHTTP(w, r)
@@ -238,14 +358,10 @@ func NewGorilla( //nolint:gocyclo
 
 				ctx := context.WithValue(r.Context(), contextKey("body"), body)
 
-				// Scrub for the body payload for potentially sensitive authentication data
-				// (this only affects logging, not the actual request body)
-				// TODO: Log the JSON payload here but with the password field scrubbed
-				if r.URL.Path == "/auth" {
-					body = []byte("***** REDACTED *****")
-				}
-
-				log.Debugf("Injected body: %s", string(body))
+				// Scrub the passwords from the body payload, like those of POST
+				// /auth and the /users routes (this only affects logging, not
+				// the actual request body)
+				log.Debugf("Injected body: %s", string(scrubBody(body)))
 				next.ServeHTTP(w, r.WithContext(ctx))
 			default:
 				next.ServeHTTP(w, r)
@@ -253,6 +369,10 @@ func NewGorilla( //nolint:gocyclo
 		})
 	})
 
+	// Record the entities changed by the authenticated calls in their audit
+	// events
+	g.router.Use(g.auditSnapshotHandler)
+
 	return g
 }
 
@@ -262,6 +382,29 @@ func (c contextKey) String() string {
 	return "controller-ce context key " + string(c)
 }
 
+// Run resumes the operations and rollouts interrupted by the last shutdown,
+// reconciles the nodes every ReconcileInterval, if set, and evicts the idle
+// pooled connections to the nodes until the context is done, then closes the
+// pooled connections. The operations and rollouts are interrupted when the
+// context is done.
+func (g *Gorilla) Run(ctx context.Context) {
+	g.ops.resume(ctx)
+	g.rollouts.Resume(ctx)
+	if g.reconciler.Interval > 0 {
+		go g.reconciler.Run(ctx)
+	}
+	if g.nodeConns != nil {
+		g.nodeConns.Run(ctx)
+	}
+}
+
+// invalidateNodeConns closes the pooled connections to the node.
+func (g *Gorilla) invalidateNodeConns(nodeID string) {
+	if g.nodeConns != nil {
+		g.nodeConns.Invalidate(nodeID)
+	}
+}
+
 // ServeHTTP wraps mux.ServeHTTP.
 func (g *Gorilla) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	g.router.ServeHTTP(w, req)
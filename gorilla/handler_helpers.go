@@ -6,7 +6,14 @@ package gorilla
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
 
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/open-ness/edgecontroller/grpc/node"
@@ -17,6 +24,12 @@ import (
 const (
 	defaultELAPort = "42101"
 	defaultEVAPort = "42102"
+
+	// maxListLimit is the maximum page size of the list endpoints
+	maxListLimit = 1000
+
+	// redacted replaces sensitive data in the logs
+	redacted = "***** REDACTED *****"
 )
 
 func connectNode(
@@ -52,6 +65,16 @@ func connectNode(
 
 	log.Debugf("connectNode(%v): connecting to %v", e.GetNodeID(), target)
 
+	// Reuse a pooled connection if possible
+	if pool, ok := ctx.Value(contextKey("nodeConns")).(*node.Pool); ok && pool != nil {
+		nodeCC, err := pool.Get(ctx, e.GetNodeID(), addr, port)
+		if err != nil {
+			log.Noticef("Could not connect to node: %v", err)
+			return nil, errors.Wrap(err, "could not connect to node")
+		}
+		return nodeCC, nil
+	}
+
 	nodeCC := node.ClientConn{Addr: addr, Port: port, TLS: conf}
 	if err := nodeCC.Connect(ctx); err != nil {
 		log.Noticef("Could not connect to node: %v", err)
@@ -62,11 +85,150 @@ func connectNode(
 	return &nodeCC, nil
 }
 
+// disconnectNode closes the connection to the node, or releases it to the pool
+// if it is pooled.
 func disconnectNode(nodeCC *node.ClientConn) {
 	log.Debugf("Disconnecting %v", nodeCC)
 	nodeCC.Disconnect()
 }
 
+// setETag sets the ETag of the response to the resource version of an entity.
+func setETag(w http.ResponseWriter, v cce.Versioned) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, v.GetResourceVersion()))
+}
+
+// ifMatch returns the resource version required by the If-Match header of the
+// request, or 0 if the header is absent or matches any version. If the header
+// is invalid a 400 Bad Request is written and ok is false.
+func ifMatch(w http.ResponseWriter, r *http.Request) (version uint64, ok bool) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, true
+	}
+
+	version, err := strconv.ParseUint(strings.Trim(h, `"`), 10, 64)
+	if err != nil || version == 0 {
+		log.Debugf("Invalid If-Match header %q", h)
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err = w.Write([]byte(fmt.Sprintf("Invalid If-Match header %q", h))); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return 0, false
+	}
+
+	return version, true
+}
+
+// nodeIfMatch reads the node of a request changing the traffic policy of one
+// of its interfaces or apps and checks the If-Match header of the request
+// against the resource version of the node. These policies have no resource
+// version of their own and are versioned by their node, like the interfaces
+// of the node. The resource version of the node is incremented before the
+// policy is changed, so that of concurrent requests with the same If-Match
+// header only the first one changes the policy, and the ETag of the response
+// is set. If the node is not found or the header is invalid or stale, the
+// response is written and ok is false.
+func nodeIfMatch(w http.ResponseWriter, r *http.Request, ps cce.PersistenceService) (ok bool) {
+	persisted, err := ps.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return false
+	}
+	n := persisted.(*cce.Node)
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return false
+	}
+	n.ResourceVersion = version
+	if err = ps.BulkUpdate(r.Context(), []cce.Persistable{n}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return false
+		}
+		log.Errf("Error updating entities: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	setETag(w, n)
+
+	return true
+}
+
+// listQuery parses the query parameters of a list request. The limit,
+// page_token and sort parameters select the page, the selector parameter
+// selects entities by their labels and any other parameter filters on the
+// field of the same name. If the parameters are invalid a 400 Bad Request is
+// written and ok is false.
+func listQuery(w http.ResponseWriter, r *http.Request) (q cce.Query, ok bool) {
+	for k, vs := range r.URL.Query() {
+		var err error
+		switch {
+		case len(vs) != 1:
+			err = fmt.Errorf("parameter %q must be specified once", k)
+		case k == "limit":
+			q.Limit, err = strconv.Atoi(vs[0])
+			if err != nil || q.Limit < 1 || q.Limit > maxListLimit {
+				err = fmt.Errorf("limit must be between 1 and %d", maxListLimit)
+			}
+		case k == "page_token":
+			q.PageToken = vs[0]
+		case k == "sort":
+			q.Sort = vs[0]
+		case k == "selector":
+			q.Selector, err = cce.ParseSelector(vs[0])
+		default:
+			q.Filters = append(q.Filters, cce.Filter{Field: k, Value: vs[0]})
+		}
+		if err != nil {
+			log.Debugf("Invalid list query %q: %v", r.URL.RawQuery, err)
+			w.WriteHeader(http.StatusBadRequest)
+			if _, err = w.Write([]byte(err.Error())); err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return cce.Query{}, false
+		}
+	}
+
+	return q, true
+}
+
+// writeListError writes the response for an error from
+// PersistenceService.FilterPage.
+func writeListError(w http.ResponseWriter, err error) {
+	if errors.Cause(err) == cce.ErrInvalidQuery {
+		log.Debugf("Invalid list query: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err = w.Write([]byte(err.Error())); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	log.Errf("Error filtering entities: %v", err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// nextLink returns the link to the page of a list request with the page token,
+// or an empty string if there is no next page.
+func nextLink(r *http.Request, pageToken string) string {
+	if pageToken == "" {
+		return ""
+	}
+
+	params := r.URL.Query()
+	params.Set("page_token", pageToken)
+	next := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+
+	return next.String()
+}
+
 func getController(ctx context.Context) *cce.Controller {
 	return ctx.Value(contextKey("controller")).(*cce.Controller)
 }
@@ -81,10 +243,50 @@ func toK8SApp(app *cce.App) k8s.App {
 	}
 
 	return k8s.App{
-		ID:     app.ID,
-		Image:  app.ID + ":latest",
-		Cores:  app.Cores,
-		Memory: app.Memory,
-		Ports:  ports,
+		ID:       app.ID,
+		Image:    app.ID + ":latest",
+		Cores:    app.Cores,
+		Memory:   app.Memory,
+		Ports:    ports,
+		Revision: app.Revision,
+	}
+}
+
+// scrubBody returns the JSON body of a request with the values of its password
+// fields redacted, for logging. A body that is not JSON is redacted as a whole.
+func scrubBody(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return []byte(redacted)
+	}
+	scrubPasswords(v)
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return []byte(redacted)
+	}
+
+	return scrubbed
+}
+
+// scrubPasswords redacts the values of the password fields of the decoded
+// JSON value and of the values it contains.
+func scrubPasswords(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if strings.EqualFold(k, "password") {
+				v[k] = redacted
+			} else {
+				scrubPasswords(e)
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			scrubPasswords(e)
+		}
 	}
 }
@@ -0,0 +1,372 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Used for GET /interface_profiles endpoint
+func (g *Gorilla) swagGETInterfaceProfiles(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of interface profiles from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.InterfaceProfile{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	profiles := swagger.InterfaceProfileList{
+		InterfaceProfiles: []swagger.InterfaceProfileSummary{},
+		Next:              nextLink(r, next),
+	}
+	for _, e := range persisted {
+		profiles.InterfaceProfiles = append(profiles.InterfaceProfiles, swagger.InterfaceProfileSummary{
+			ID:   e.(*cce.InterfaceProfile).ID,
+			Name: e.(*cce.InterfaceProfile).Name,
+		})
+	}
+
+	// Marshal the response object to JSON
+	profilesJSON, err := json.Marshal(profiles)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(profilesJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /interface_profiles endpoint
+func (g *Gorilla) swagPOSTInterfaceProfiles(w http.ResponseWriter, r *http.Request) {
+	// Load the payload
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	profile := swagger.InterfaceProfileDetail{}
+	if err := json.Unmarshal(body, &profile); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if profile.ID != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := w.Write([]byte("Validation failed: id cannot be specified in POST request")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Convert it to a persistable object
+	persisted := toInterfaceProfile(uuid.New(), &profile)
+
+	if !storeInterfaceProfile(w, r, persisted, false) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"id":"%s"}`, persisted.ID))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /interface_profiles/{profile_id} endpoint
+func (g *Gorilla) swagGETInterfaceProfileByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(
+		r.Context(), mux.Vars(r)["profile_id"], &cce.InterfaceProfile{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	p := persisted.(*cce.InterfaceProfile)
+
+	// Construct the response object
+	profile := swagger.InterfaceProfileDetail{
+		InterfaceProfileSummary: swagger.InterfaceProfileSummary{
+			ID:   p.ID,
+			Name: p.Name,
+		},
+		Match:    p.Match,
+		Set:      p.Set,
+		NodeIDs:  p.NodeIDs,
+		Selector: p.Selector,
+	}
+
+	// Marshal the response object to JSON
+	profileJSON, err := json.Marshal(profile)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(profileJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /interface_profiles/{profile_id} endpoint
+func (g *Gorilla) swagPATCHInterfaceProfileByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	profile := swagger.InterfaceProfileDetail{}
+	if err := json.Unmarshal(body, &profile); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Check that the entity is there
+	e, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["profile_id"], &cce.InterfaceProfile{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	storeInterfaceProfile(w, r, toInterfaceProfile(e.GetID(), &profile), true)
+}
+
+// Used for DELETE /interface_profiles/{profile_id} endpoint
+//
+// The interfaces of the nodes keep the settings of the profile.
+func (g *Gorilla) swagDELETEInterfaceProfileByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["profile_id"], &cce.InterfaceProfile{})
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
+
+// Used for POST /interface_profiles/{profile_id}/apply endpoint
+//
+// The profile is applied to the interfaces of every node it is attached to.
+// With dry_run the changes each node would make are only reported. A node
+// that cannot be reached, or whose interfaces would be put in zones it does
+// not have, is reported with an error and left unchanged.
+func (g *Gorilla) swagPOSTInterfaceProfileApply(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	var req swagger.InterfaceProfileApplyReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the profile and the nodes it is attached to
+	e, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["profile_id"], &cce.InterfaceProfile{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	profile := e.(*cce.InterfaceProfile)
+
+	nodes, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading nodes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Apply the profile to the nodes concurrently
+	result := swagger.InterfaceProfileApplyResult{DryRun: req.DryRun, Nodes: []swagger.InterfaceProfileNodeResult{}}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, n := range nodes {
+		if !profile.Targets(n.(*cce.Node)) {
+			continue
+		}
+		wg.Add(1)
+		go func(n *cce.Node) {
+			defer wg.Done()
+			res := swagger.InterfaceProfileNodeResult{NodeID: n.ID}
+			changes, err := applyInterfaceProfile(r.Context(), ctrl, profile, n, req.DryRun)
+			if err != nil {
+				log.Noticef("Could not apply interface profile %s to node %s: %v", profile.ID, n.ID, err)
+				res.Error = err.Error()
+			}
+			res.Changes = changes
+			if res.Changes == nil {
+				res.Changes = []cce.InterfaceChange{}
+			}
+
+			mu.Lock()
+			result.Nodes = append(result.Nodes, res)
+			mu.Unlock()
+		}(n.(*cce.Node))
+	}
+	wg.Wait()
+	sort.Slice(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].NodeID < result.Nodes[j].NodeID
+	})
+
+	// Marshal the response object to JSON
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(resultJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// applyInterfaceProfile applies the profile to the interfaces of the node and
+// returns the changes. With dryRun the changes are only computed.
+func applyInterfaceProfile(
+	ctx context.Context,
+	ctrl *cce.Controller,
+	profile *cce.InterfaceProfile,
+	n *cce.Node,
+	dryRun bool,
+) ([]cce.InterfaceChange, error) {
+	nodePort := ctrl.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+	nodeCC, err := connectNode(ctx, ctrl.PersistenceService, n, nodePort, ctrl.EdgeNodeCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer disconnectNode(nodeCC)
+
+	ifaces, err := nodeCC.IfaceSvcCli.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updated, changes := profile.Apply(ifaces)
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	// The zones of the interfaces must exist on the node
+	if _, err = checkInterfaceZones(ctx, nodeCC, updated); err != nil {
+		return changes, err
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	return changes, nodeCC.IfaceSvcCli.BulkUpdate(ctx, updated)
+}
+
+// storeInterfaceProfile validates the profile, checks the nodes it is attached
+// to exist and creates or updates it. It returns whether the profile was
+// stored; otherwise the response is written.
+func storeInterfaceProfile(
+	w http.ResponseWriter,
+	r *http.Request,
+	profile *cce.InterfaceProfile,
+	update bool,
+) bool {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Validate the object
+	if err := profile.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", profile, err)
+		writeValidationError(w, err)
+		return false
+	}
+
+	// Check the nodes are there
+	for _, id := range profile.NodeIDs {
+		n, err := ctrl.PersistenceService.Read(r.Context(), id, &cce.Node{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return false
+		}
+		if n == nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			if _, err = w.Write([]byte(fmt.Sprintf("node %s not found", id))); err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return false
+		}
+	}
+
+	// Persist the object
+	var err error
+	if update {
+		err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{profile})
+	} else {
+		err = ctrl.PersistenceService.Create(r.Context(), profile)
+	}
+	if err != nil {
+		log.Errf("Error storing entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+func toInterfaceProfile(id string, profile *swagger.InterfaceProfileDetail) *cce.InterfaceProfile {
+	return &cce.InterfaceProfile{
+		ID:       id,
+		Name:     profile.Name,
+		Match:    profile.Match,
+		Set:      profile.Set,
+		NodeIDs:  profile.NodeIDs,
+		Selector: profile.Selector,
+	}
+}
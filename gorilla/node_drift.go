@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// Used for GET /nodes/{node_id}/drift endpoint
+//
+// The drift found by the last reconciliation of the node is returned, with no
+// items if the node was never reconciled.
+func (g *Gorilla) swagGETNodeDrift(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	nodeID := mux.Vars(r)["node_id"]
+
+	node, err := ctrl.PersistenceService.Read(r.Context(), nodeID, &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	persisted, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeDrift{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		log.Errf("Error reading node drift: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	resp := swagger.NodeDrift{Items: []swagger.NodeDriftItem{}}
+	if len(persisted) > 0 {
+		drift := persisted[0].(*cce.NodeDrift)
+		resp.ReconciledAt = &drift.ReconciledAt
+		resp.Error = drift.Error
+		for _, item := range drift.Items {
+			resp.Items = append(resp.Items, swagger.NodeDriftItem{
+				Kind:     item.Kind,
+				ID:       item.ID,
+				Detail:   item.Detail,
+				Repaired: item.Repaired,
+			})
+		}
+	}
+
+	// Marshal the response object to JSON
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(respJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
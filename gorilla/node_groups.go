@@ -0,0 +1,369 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Used for GET /node_groups endpoint
+func (g *Gorilla) swagGETNodeGroups(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of node groups from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.NodeGroup{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	groups := swagger.NodeGroupList{NodeGroups: []swagger.NodeGroupSummary{}, Next: nextLink(r, next)}
+	for _, e := range persisted {
+		groups.NodeGroups = append(groups.NodeGroups, nodeGroupSummary(e.(*cce.NodeGroup)))
+	}
+
+	// Marshal the response object to JSON
+	groupsJSON, err := json.Marshal(groups)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(groupsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /node_groups endpoint
+//
+// The configuration of the group is assigned to the nodes of the group in the
+// background.
+func (g *Gorilla) swagPOSTNodeGroups(w http.ResponseWriter, r *http.Request) {
+	// Load the payload
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	group := swagger.NodeGroupDetail{}
+	if err := json.Unmarshal(body, &group); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if group.ID != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := w.Write([]byte("Validation failed: id cannot be specified in POST request")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Convert it to a persistable object
+	persisted := toNodeGroup(uuid.New(), &group)
+
+	if !g.storeNodeGroup(w, r, persisted, false) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"id":"%s"}`, persisted.ID))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /node_groups/{group_id} endpoint
+func (g *Gorilla) swagGETNodeGroupByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["group_id"], &cce.NodeGroup{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	group := persisted.(*cce.NodeGroup)
+
+	// Construct the response object
+	detail := swagger.NodeGroupDetail{
+		NodeGroupSummary: nodeGroupSummary(group),
+		Apps:             group.Apps,
+		DNSConfigID:      group.DNSConfigID,
+		TrafficPolicies:  []swagger.NodeGroupTrafficPolicy{},
+	}
+	if detail.Apps == nil {
+		detail.Apps = []string{}
+	}
+	for _, p := range group.TrafficPolicies {
+		detail.TrafficPolicies = append(detail.TrafficPolicies, swagger.NodeGroupTrafficPolicy{
+			AppID:           p.AppID,
+			TrafficPolicyID: p.TrafficPolicyID,
+		})
+	}
+
+	// Marshal the response object to JSON
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(detailJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /node_groups/{group_id} endpoint
+//
+// The configuration of the group is assigned to the nodes of the group in the
+// background. The configuration removed from the group, and that of the nodes
+// leaving the group, stays on the nodes.
+func (g *Gorilla) swagPATCHNodeGroupByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	group := swagger.NodeGroupDetail{}
+	if err := json.Unmarshal(body, &group); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Check that the entity is there
+	e, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["group_id"], &cce.NodeGroup{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	g.storeNodeGroup(w, r, toNodeGroup(e.GetID(), &group), true)
+}
+
+// Used for DELETE /node_groups/{group_id} endpoint
+//
+// The configuration of the group stays on the nodes of the group.
+func (g *Gorilla) swagDELETENodeGroupByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["group_id"], &cce.NodeGroup{})
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
+
+// Used for GET /node_groups/{group_id}/nodes endpoint
+func (g *Gorilla) swagGETNodeGroupNodes(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	group, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["group_id"], &cce.NodeGroup{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if group == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	nodes, err := groupNodes(r.Context(), ctrl, group.(*cce.NodeGroup))
+	if err != nil {
+		log.Errf("Error reading nodes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	list := swagger.NodeList{Nodes: []swagger.NodeSummary{}}
+	for _, n := range nodes {
+		list.Nodes = append(list.Nodes, swagger.NodeSummary{
+			ID:       n.ID,
+			Name:     n.Name,
+			Location: n.Location,
+			Serial:   n.Serial,
+			Labels:   n.Labels,
+		})
+	}
+	sort.Slice(list.Nodes, func(i, j int) bool {
+		return list.Nodes[i].ID < list.Nodes[j].ID
+	})
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// storeNodeGroup validates the group, checks the apps, DNS configuration and
+// traffic policies of the group exist, creates or updates the group and
+// assigns its configuration to its nodes. It returns whether the group was
+// stored; otherwise the response is written.
+func (g *Gorilla) storeNodeGroup( //nolint:gocyclo
+	w http.ResponseWriter,
+	r *http.Request,
+	group *cce.NodeGroup,
+	update bool,
+) bool {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Validate the object
+	if err := group.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", group, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return false
+	}
+
+	// Check the referenced entities are there
+	refs := map[string]cce.Persistable{}
+	for _, id := range group.Apps {
+		refs[id] = &cce.App{}
+	}
+	if group.DNSConfigID != "" {
+		refs[group.DNSConfigID] = &cce.DNSConfig{}
+	}
+	for _, p := range group.TrafficPolicies {
+		refs[p.TrafficPolicyID] = &cce.TrafficPolicy{}
+	}
+	for id, model := range refs {
+		e, err := ctrl.PersistenceService.Read(r.Context(), id, model)
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return false
+		}
+		if e == nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err = w.Write([]byte(fmt.Sprintf("%s %s not found", model.GetTableName(), id)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return false
+		}
+	}
+
+	// Persist the object
+	var err error
+	if update {
+		err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{group})
+	} else {
+		err = ctrl.PersistenceService.Create(r.Context(), group)
+	}
+	if err != nil {
+		log.Errf("Error storing entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+
+	// Assign the configuration of the group to its nodes
+	nodes, err := groupNodes(r.Context(), ctrl, group)
+	if err != nil {
+		log.Errf("Error reading nodes of node group %s: %v", group.ID, err)
+		return true
+	}
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	g.reconcileNodes(ids...)
+
+	return true
+}
+
+// groupNodes returns the nodes of the group.
+func groupNodes(ctx context.Context, ctrl *cce.Controller, group *cce.NodeGroup) ([]*cce.Node, error) {
+	persisted, err := ctrl.PersistenceService.ReadAll(ctx, &cce.Node{})
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*cce.Node
+	for _, e := range persisted {
+		if group.Matches(e.(*cce.Node)) {
+			nodes = append(nodes, e.(*cce.Node))
+		}
+	}
+
+	return nodes, nil
+}
+
+// reconcileNodes reconciles the nodes in the background so that they get the
+// configuration of the node groups they are in. At most ReconcileWorkers
+// nodes are reconciled at once, the periodic reconciliation included.
+func (g *Gorilla) reconcileNodes(nodeIDs ...string) {
+	g.reconciler.Start(g.ops.baseContext(), nodeIDs...)
+}
+
+func toNodeGroup(id string, group *swagger.NodeGroupDetail) *cce.NodeGroup {
+	persisted := &cce.NodeGroup{
+		ID:          id,
+		Name:        group.Name,
+		Selector:    group.Selector,
+		Apps:        group.Apps,
+		DNSConfigID: group.DNSConfigID,
+	}
+	for _, p := range group.TrafficPolicies {
+		persisted.TrafficPolicies = append(persisted.TrafficPolicies, cce.NodeGroupTrafficPolicy{
+			AppID:           p.AppID,
+			TrafficPolicyID: p.TrafficPolicyID,
+		})
+	}
+
+	return persisted
+}
+
+func nodeGroupSummary(group *cce.NodeGroup) swagger.NodeGroupSummary {
+	return swagger.NodeGroupSummary{
+		ID:       group.ID,
+		Name:     group.Name,
+		Selector: group.Selector,
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// Used for GET /nodes/{node_id}/status_events endpoint
+func (g *Gorilla) swagGETNodeStatusEvents(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	nodeID := mux.Vars(r)["node_id"]
+
+	node, err := ctrl.PersistenceService.Read(r.Context(), nodeID, &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	persisted, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeStatusEvent{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		log.Errf("Error reading node status events: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	list := swagger.NodeStatusEventList{Events: []swagger.NodeStatusEventSummary{}}
+	for _, e := range persisted {
+		list.Events = append(list.Events, swagger.NodeStatusEventSummary{
+			ID:        e.(*cce.NodeStatusEvent).ID,
+			From:      e.(*cce.NodeStatusEvent).From,
+			To:        e.(*cce.NodeStatusEvent).To,
+			Timestamp: e.(*cce.NodeStatusEvent).Timestamp,
+		})
+	}
+	sort.Slice(list.Events, func(i, j int) bool {
+		return list.Events[i].Timestamp.After(list.Events[j].Timestamp)
+	})
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// readNodeHealth returns the health of the nodes by node ID. Each node is
+// looked up on the unique node_id key, so a page of nodes never reads the
+// health of the whole fleet.
+func readNodeHealth(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	nodes []cce.Persistable,
+) (map[string]*swagger.NodeHealth, error) {
+	health := make(map[string]*swagger.NodeHealth, len(nodes))
+	for _, n := range nodes {
+		h, err := readNodeHealthByID(ctx, ps, n.GetID())
+		if err != nil {
+			return nil, err
+		}
+		if h != nil {
+			health[n.GetID()] = h
+		}
+	}
+
+	return health, nil
+}
+
+// readNodeHealthByID returns the health of the node, or nil if it was never
+// probed.
+func readNodeHealthByID(ctx context.Context, ps cce.PersistenceService, nodeID string) (*swagger.NodeHealth, error) {
+	persisted, err := ps.Filter(ctx, &cce.NodeHealth{}, []cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil || len(persisted) == 0 {
+		return nil, err
+	}
+
+	return nodeHealthSummary(persisted[0].(*cce.NodeHealth)), nil
+}
+
+func nodeHealthSummary(h *cce.NodeHealth) *swagger.NodeHealth {
+	summary := &swagger.NodeHealth{
+		Status:    h.Status,
+		LatencyMS: float64(h.Latency.Microseconds()) / 1000,
+		CheckedAt: h.CheckedAt,
+	}
+	if !h.LastSeen.IsZero() {
+		lastSeen := h.LastSeen
+		summary.LastSeen = &lastSeen
+	}
+
+	return summary
+}
@@ -0,0 +1,368 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// operations runs the operations on the nodes in the background.
+type operations struct {
+	controller *cce.Controller
+	nodeConns  *node.Pool
+
+	mu sync.Mutex
+	// ctx is the context the operations run in, canceled when the controller
+	// shuts down
+	ctx context.Context
+	// cancels are the cancel functions of the running operations
+	cancels map[string]context.CancelFunc
+	// canceled are the running operations whose cancellation was requested
+	canceled map[string]bool
+}
+
+func newOperations(controller *cce.Controller, nodeConns *node.Pool) *operations {
+	return &operations{
+		controller: controller,
+		nodeConns:  nodeConns,
+		ctx:        context.Background(),
+		cancels:    make(map[string]context.CancelFunc),
+		canceled:   make(map[string]bool),
+	}
+}
+
+// resume runs the operations in the context from now on and restarts the
+// operations that did not end before the controller last shut down.
+func (o *operations) resume(ctx context.Context) {
+	o.mu.Lock()
+	o.ctx = ctx
+	o.mu.Unlock()
+
+	for _, status := range []string{cce.OperationPending, cce.OperationRunning} {
+		persisted, err := o.controller.PersistenceService.Filter(
+			ctx,
+			&cce.Operation{},
+			[]cce.Filter{{Field: "status", Value: status}})
+		if err != nil {
+			log.Errf("Error reading %s operations: %v", status, err)
+			continue
+		}
+		for _, e := range persisted {
+			log.Infof("Resuming operation %s", e.GetID())
+			o.start(e.(*cce.Operation))
+		}
+	}
+}
+
+// baseContext returns the context the operations run in.
+func (o *operations) baseContext() context.Context {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.ctx
+}
+
+// submit stores a new pending operation and starts it.
+func (o *operations) submit(ctx context.Context, op *cce.Operation) error {
+	now := time.Now().UTC()
+	op.ID = uuid.New()
+	op.Status = cce.OperationPending
+	op.CreatedAt = now
+	op.UpdatedAt = now
+	if err := op.Validate(); err != nil {
+		return err
+	}
+
+	if err := o.controller.PersistenceService.Create(ctx, op); err != nil {
+		return errors.Wrap(err, "error storing operation")
+	}
+	o.start(op)
+
+	return nil
+}
+
+// start runs a copy of the operation in the background, unless it is already
+// running.
+func (o *operations) start(pending *cce.Operation) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.cancels[pending.ID]; ok {
+		return
+	}
+	op := *pending
+
+	base := o.ctx
+	ctx, cancel := context.WithTimeout(base, cce.MaxOperationTime)
+	ctx = context.WithValue(ctx, contextKey("controller"), o.controller)
+	ctx = context.WithValue(ctx, contextKey("nodeConns"), o.nodeConns)
+	o.cancels[op.ID] = cancel
+
+	go func() {
+		defer cancel()
+		err := o.run(ctx, &op)
+
+		o.mu.Lock()
+		canceled := o.canceled[op.ID]
+		delete(o.cancels, op.ID)
+		delete(o.canceled, op.ID)
+		o.mu.Unlock()
+
+		switch {
+		case err == nil:
+			op.Status = cce.OperationSucceeded
+			op.Result = fmt.Sprintf("/nodes/%s/apps/%s", op.NodeID, op.AppID)
+		case canceled:
+			op.Status = cce.OperationCanceled
+		case base.Err() != nil:
+			// The controller is shutting down: the operation is resumed
+			// when it starts again
+			log.Infof("Interrupted operation %s: %v", op.ID, err)
+			return
+		default:
+			op.Status = cce.OperationFailed
+			op.Error = err.Error()
+		}
+		op.Progress = ""
+		log.Infof("Operation %s %s", op.ID, op.Status)
+		o.update(&op)
+	}()
+}
+
+// run runs the operation.
+func (o *operations) run(ctx context.Context, op *cce.Operation) error {
+	ps := o.controller.PersistenceService
+
+	op.Status = cce.OperationRunning
+	switch op.Kind {
+	case cce.OperationDeployApp:
+		op.Progress = "deploying app"
+		o.update(op)
+
+		nodeApp := &cce.NodeApp{ID: uuid.New(), NodeID: op.NodeID, AppID: op.AppID}
+		app, err := ps.Read(ctx, op.AppID, &cce.App{})
+		if err != nil {
+			return err
+		}
+		if app != nil {
+			nodeApp.Revision = app.(*cce.App).Revision
+		}
+		if err = handleCreateNodesApps(ctx, ps, nodeApp); err != nil {
+			return err
+		}
+
+		op.Progress = "storing node app"
+		o.update(op)
+		return ps.Create(ctx, nodeApp)
+
+	case cce.OperationUpdateApp:
+		op.Progress = fmt.Sprintf("running %s command", op.Cmd)
+		o.update(op)
+
+		nodeApps, err := ps.Filter(
+			ctx,
+			&cce.NodeApp{},
+			[]cce.Filter{
+				{Field: "node_id", Value: op.NodeID},
+				{Field: "app_id", Value: op.AppID},
+			})
+		if err != nil {
+			return err
+		}
+		if len(nodeApps) != 1 {
+			return errors.New("app not deployed to node")
+		}
+
+		_, err = handleUpdateNodesApps(ctx, ps, &cce.NodeAppReq{
+			NodeApp: *nodeApps[0].(*cce.NodeApp),
+			Cmd:     op.Cmd,
+		})
+		return err
+
+	case cce.OperationUpgradeApp:
+		op.Progress = "redeploying app"
+		o.update(op)
+
+		nodeApps, err := ps.Filter(
+			ctx,
+			&cce.NodeApp{},
+			[]cce.Filter{
+				{Field: "node_id", Value: op.NodeID},
+				{Field: "app_id", Value: op.AppID},
+			})
+		if err != nil {
+			return err
+		}
+		if len(nodeApps) != 1 {
+			return errors.New("app not deployed to node")
+		}
+		nodeApp := nodeApps[0].(*cce.NodeApp)
+
+		// The current revision is redeployed even if the app was updated
+		// again since the operation was submitted
+		app, err := ps.Read(ctx, op.AppID, &cce.App{})
+		if err != nil {
+			return err
+		}
+		if app == nil {
+			return errors.Errorf("app %s not found", op.AppID)
+		}
+		if err = handleUpgradeNodesApps(ctx, ps, nodeApp, app.(*cce.App)); err != nil {
+			return err
+		}
+
+		op.Progress = "storing node app"
+		o.update(op)
+		nodeApp.Revision = app.(*cce.App).Revision
+		return ps.BulkUpdate(ctx, []cce.Persistable{nodeApp})
+	}
+
+	return errors.Errorf("unknown operation kind %s", op.Kind)
+}
+
+// update stores the operation. It is stored even if the operation's context
+// is done so that its end is recorded.
+func (o *operations) update(op *cce.Operation) {
+	ctx, cancel := context.WithTimeout(context.Background(), cce.MaxDBRequestTime)
+	defer cancel()
+
+	op.UpdatedAt = time.Now().UTC()
+	if err := o.controller.PersistenceService.BulkUpdate(ctx, []cce.Persistable{op}); err != nil {
+		log.Errf("Error storing operation %s: %v", op.ID, err)
+	}
+}
+
+// errOperationDone is returned when canceling an operation that ended.
+var errOperationDone = errors.New("operation already ended")
+
+// cancel requests the cancellation of the operation. A running operation is
+// canceled once the node call in progress returns.
+func (o *operations) cancel(ctx context.Context, op *cce.Operation) error {
+	if op.Done() {
+		return errOperationDone
+	}
+
+	o.mu.Lock()
+	cancel, ok := o.cancels[op.ID]
+	if ok {
+		o.canceled[op.ID] = true
+		cancel()
+	}
+	o.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	// The operation is not running in this controller
+	op.Status = cce.OperationCanceled
+	op.Progress = ""
+	op.UpdatedAt = time.Now().UTC()
+	return o.controller.PersistenceService.BulkUpdate(ctx, []cce.Persistable{op})
+}
+
+// Used for GET /operations/{operation_id} endpoint
+func (g *Gorilla) swagGETOperationByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	op, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["operation_id"], &cce.Operation{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if op == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeOperation(w, http.StatusOK, op.(*cce.Operation))
+}
+
+// Used for POST /operations/{operation_id}/cancel endpoint
+//
+// The cancellation is asynchronous: the operation is canceled once the call
+// to the node in progress returns.
+func (g *Gorilla) swagPOSTOperationCancel(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	op, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["operation_id"], &cce.Operation{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if op == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = g.ops.cancel(r.Context(), op.(*cce.Operation))
+	switch {
+	case err == errOperationDone:
+		w.WriteHeader(http.StatusConflict)
+		if _, err = w.Write([]byte(fmt.Sprintf("operation %s already %s", op.GetID(),
+			op.(*cce.Operation).Status))); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	case err != nil:
+		log.Errf("Error canceling operation: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// writeAcceptedOperation answers a request that started the operation.
+func writeAcceptedOperation(w http.ResponseWriter, op *cce.Operation) {
+	w.Header().Set("Location", "/operations/"+op.ID)
+	writeOperation(w, http.StatusAccepted, op)
+}
+
+func writeOperation(w http.ResponseWriter, statusCode int, op *cce.Operation) {
+	// Construct the response object
+	detail := operationDetail(op)
+
+	// Marshal the response object to JSON
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err = w.Write(detailJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+func operationDetail(op *cce.Operation) swagger.OperationDetail {
+	return swagger.OperationDetail{
+		ID:        op.ID,
+		Kind:      op.Kind,
+		NodeID:    op.NodeID,
+		AppID:     op.AppID,
+		Command:   op.Cmd,
+		Status:    op.Status,
+		Progress:  op.Progress,
+		Result:    op.Result,
+		Error:     op.Error,
+		CreatedAt: op.CreatedAt,
+		UpdatedAt: op.UpdatedAt,
+	}
+}
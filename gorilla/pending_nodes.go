@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Used for POST /nodes/import endpoint
+//
+// The manifest is a JSON array or a CSV file of the name, location and serial
+// of the nodes. Either all nodes of the manifest are created or none is.
+func (g *Gorilla) swagPOSTNodesImport(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Parse the manifest according to its content type
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = cce.NodeManifestJSON
+	}
+	nodes, err := cce.ParseNodeManifest(bytes.NewReader(body), mediaType)
+	if err != nil {
+		log.Debugf("Error parsing node manifest: %v", err)
+		writeValidationError(w, err)
+		return
+	}
+
+	// Validate every node before creating any of them
+	existing, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading nodes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	serials := make(map[string]bool)
+	for _, e := range existing {
+		serials[e.(*cce.Node).Serial] = true
+	}
+	for i, node := range nodes {
+		node.ID = uuid.New()
+		if err = node.Validate(); err != nil {
+			writeValidationError(w, fmt.Errorf("row %d: %v", i+1, err))
+			return
+		}
+		if serials[node.Serial] {
+			writeValidationError(w, fmt.Errorf("row %d: serial %s already exists", i+1, node.Serial))
+			return
+		}
+		serials[node.Serial] = true
+	}
+
+	// Persist the nodes in one transaction so that a failure creates none
+	creates := make([]cce.Persistable, 0, len(nodes))
+	for _, node := range nodes {
+		creates = append(creates, node)
+	}
+	if err = ctrl.PersistenceService.BulkWrite(r.Context(), creates, nil); err != nil {
+		log.Errf("Error creating entities: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Remove the nodes from the pending approval queue. The nodes are created
+	// already, so a failure only leaves their stale requests in the queue.
+	list := swagger.NodeList{Nodes: []swagger.NodeSummary{}}
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if err = deletePendingNodes(r, ctrl, node.Serial); err != nil {
+			log.Errf("Error deleting pending node: %v", err)
+		}
+		list.Nodes = append(list.Nodes, swagger.NodeSummary{
+			ID:       node.ID,
+			Name:     node.Name,
+			Location: node.Location,
+			Serial:   node.Serial,
+		})
+		ids = append(ids, node.ID)
+	}
+	log.Infof("Imported %d nodes", len(nodes))
+
+	// Assign the configuration of the node groups the nodes are in
+	g.reconcileNodes(ids...)
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /nodes/pending endpoint
+func (g *Gorilla) swagGETPendingNodes(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of pending nodes from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.PendingNode{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	list := swagger.PendingNodeList{PendingNodes: []swagger.PendingNodeSummary{}, Next: nextLink(r, next)}
+	for _, e := range persisted {
+		list.PendingNodes = append(list.PendingNodes, swagger.PendingNodeSummary{
+			ID:          e.(*cce.PendingNode).ID,
+			Serial:      e.(*cce.PendingNode).Serial,
+			Address:     e.(*cce.PendingNode).Address,
+			RequestedAt: e.(*cce.PendingNode).RequestedAt,
+		})
+	}
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /nodes/pending endpoint
+//
+// The pending node is approved by creating a node with its serial. The node
+// is enrolled the next time it requests credentials.
+func (g *Gorilla) swagPOSTPendingNodes(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	approval := swagger.PendingNodeApproval{}
+	if err := json.Unmarshal(body, &approval); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if approval.ID == "" {
+		writeValidationError(w, fmt.Errorf("id cannot be empty"))
+		return
+	}
+
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), approval.ID, &cce.PendingNode{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	pending := persisted.(*cce.PendingNode)
+
+	// Validate the node to create
+	node := &cce.Node{
+		ID:       uuid.New(),
+		Name:     approval.Name,
+		Location: approval.Location,
+		Serial:   pending.Serial,
+	}
+	if err = node.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", node, err)
+		writeValidationError(w, err)
+		return
+	}
+
+	approved, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.Node{},
+		[]cce.Filter{{Field: "serial", Value: node.Serial}})
+	if err != nil {
+		log.Errf("Error reading nodes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(approved) > 0 {
+		writeValidationError(w, fmt.Errorf("serial %s already exists", node.Serial))
+		return
+	}
+
+	// Persist the node and remove it from the pending approval queue
+	if err = ctrl.PersistenceService.Create(r.Context(), node); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err = deletePendingNodes(r, ctrl, node.Serial); err != nil {
+		log.Errf("Error deleting pending node: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Approved pending node %s as node %s", node.Serial, node.ID)
+
+	// Assign the configuration of the node groups the node is in
+	g.reconcileNodes(node.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write([]byte(fmt.Sprintf(`{"id":"%s"}`, node.ID))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for DELETE /nodes/pending/{pending_id} endpoint
+//
+// The pending node is rejected. It is queued again if it requests credentials
+// again.
+func (g *Gorilla) swagDELETEPendingNodeByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["pending_id"], &cce.PendingNode{})
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
+
+// deletePendingNodes removes the node of the serial from the pending approval
+// queue.
+func deletePendingNodes(r *http.Request, ctrl *cce.Controller, serial string) error {
+	pending, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.PendingNode{},
+		[]cce.Filter{{Field: "serial", Value: serial}})
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if _, err = ctrl.PersistenceService.Delete(r.Context(), p.GetID(), &cce.PendingNode{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeValidationError writes a bad request response for the validation error.
+func writeValidationError(w http.ResponseWriter, err error) {
+	w.WriteHeader(http.StatusBadRequest)
+	if _, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
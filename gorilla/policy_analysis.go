@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// Used for POST /policies/{policy_id}/analyze endpoint
+//
+// The rules of the policy, or the rules of the request if any, are analyzed
+// without changing the policy. With a node_id the rules are also compared with
+// the policies attached to the interfaces and apps of the node.
+func (g *Gorilla) swagPOSTPolicyAnalyze(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	var req swagger.PolicyAnalyzeReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the policy from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicy{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	policy := *persisted.(*cce.TrafficPolicy)
+
+	// Validate the rules to analyze instead
+	if req.Rules != nil {
+		policy.Rules = req.Rules
+		if err = policy.Validate(); err != nil {
+			log.Debugf("Validation failed for %#v: %v", req, err)
+			writeValidationError(w, err)
+			return
+		}
+	}
+
+	// Fetch the policies of the node
+	var nodePolicies []*cce.TrafficPolicy
+	if req.NodeID != "" {
+		node, err := ctrl.PersistenceService.Read(r.Context(), req.NodeID, &cce.Node{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if node == nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			if _, err = w.Write([]byte(fmt.Sprintf("node %s not found", req.NodeID))); err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+
+		if nodePolicies, err = nodeTrafficPolicies(r.Context(), ctrl, req.NodeID, "", ""); err != nil {
+			log.Errf("Error reading node traffic policies: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Construct the response object
+	analysis := swagger.PolicyAnalysis{
+		PolicyID: policy.ID,
+		NodeID:   req.NodeID,
+		Findings: cce.AnalyzeTrafficPolicy(&policy, nodePolicies),
+	}
+
+	// Marshal the response object to JSON
+	analysisJSON, err := json.Marshal(analysis)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(analysisJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// policyAttachment is a traffic policy attached to an interface or an app of a
+// node.
+type policyAttachment struct {
+	interfaceID string
+	appID       string
+	policyID    string
+}
+
+// nodePolicyAttachments returns the traffic policies attached to the
+// interfaces and apps of the node.
+func nodePolicyAttachments(ctx context.Context, ctrl *cce.Controller, nodeID string) ([]policyAttachment, error) {
+	var attachments []policyAttachment
+
+	ifacePolicies, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range ifacePolicies {
+		attachments = append(attachments, policyAttachment{
+			interfaceID: e.(*cce.NodeInterfaceTrafficPolicy).NetworkInterfaceID,
+			policyID:    e.(*cce.NodeInterfaceTrafficPolicy).TrafficPolicyID,
+		})
+	}
+
+	nodeApps, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.NodeApp{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, err
+	}
+	for _, na := range nodeApps {
+		appPolicies, err := ctrl.PersistenceService.Filter(
+			ctx,
+			&cce.NodeAppTrafficPolicy{},
+			[]cce.Filter{{Field: "nodes_apps_id", Value: na.GetID()}})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range appPolicies {
+			attachments = append(attachments, policyAttachment{
+				appID:    na.(*cce.NodeApp).AppID,
+				policyID: e.(*cce.NodeAppTrafficPolicy).TrafficPolicyID,
+			})
+		}
+	}
+
+	return attachments, nil
+}
+
+// nodeTrafficPolicies returns the traffic policies attached to the interfaces
+// and apps of the node. The policy attached to the interface ifaceID or to the
+// app appID, which is being replaced, is left out.
+func nodeTrafficPolicies(
+	ctx context.Context,
+	ctrl *cce.Controller,
+	nodeID string,
+	ifaceID string,
+	appID string,
+) ([]*cce.TrafficPolicy, error) {
+	attachments, err := nodePolicyAttachments(ctx, ctrl, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*cce.TrafficPolicy
+	read := make(map[string]bool)
+	for _, a := range attachments {
+		if read[a.policyID] ||
+			(a.interfaceID != "" && a.interfaceID == ifaceID) ||
+			(a.appID != "" && a.appID == appID) {
+			continue
+		}
+		read[a.policyID] = true
+
+		policy, err := ctrl.PersistenceService.Read(ctx, a.policyID, &cce.TrafficPolicy{})
+		if err != nil {
+			return nil, err
+		}
+		if policy != nil {
+			policies = append(policies, policy.(*cce.TrafficPolicy))
+		}
+	}
+
+	return policies, nil
+}
+
+// checkPolicyAnalysis analyzes the policy about to be attached to the
+// interface ifaceID or the app appID of the node if the controller enforces
+// the analysis. It returns a non-zero status code if the policy cannot be
+// attached.
+func checkPolicyAnalysis(
+	ctx context.Context,
+	ctrl *cce.Controller,
+	policy *cce.TrafficPolicy,
+	nodeID string,
+	ifaceID string,
+	appID string,
+) (statusCode int, err error) {
+	if !ctrl.EnforcePolicyAnalysis {
+		return 0, nil
+	}
+
+	nodePolicies, err := nodeTrafficPolicies(ctx, ctrl, nodeID, ifaceID, appID)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	findings := cce.AnalyzeTrafficPolicy(policy, nodePolicies)
+	if len(findings) == 0 {
+		return 0, nil
+	}
+	msgs := make([]string, 0, len(findings))
+	for _, f := range findings {
+		msgs = append(msgs, f.Message)
+	}
+
+	return http.StatusUnprocessableEntity, errors.Errorf(
+		"traffic policy %s failed analysis: %s", policy.ID, strings.Join(msgs, "; "))
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// Used for POST /nodes/{node_id}/policies/simulate endpoint
+//
+// The packet is evaluated against the traffic policy of every interface and
+// app of the node, or of the interface or app of the request. The rule of each
+// policy matching the packet first is returned with its action and modifiers.
+func (g *Gorilla) swagPOSTNodePoliciesSimulate(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+	nodeID := mux.Vars(r)["node_id"]
+
+	// Unmarshal the payload
+	var req swagger.PolicySimulationReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Validate the packet
+	if err := req.Packet.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", req, err)
+		writeValidationError(w, err)
+		return
+	}
+
+	// Fetch the node from persistence and check if it's there
+	node, err := ctrl.PersistenceService.Read(r.Context(), nodeID, &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	attachments, err := nodePolicyAttachments(r.Context(), ctrl, nodeID)
+	if err != nil {
+		log.Errf("Error reading node traffic policies: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Evaluate the packet against the policies
+	simulation := swagger.PolicySimulation{NodeID: nodeID, Results: []swagger.PolicySimulationResult{}}
+	for _, a := range attachments {
+		if (req.InterfaceID != "" && a.interfaceID != req.InterfaceID) ||
+			(req.AppID != "" && a.appID != req.AppID) {
+			continue
+		}
+
+		policy, err := ctrl.PersistenceService.Read(r.Context(), a.policyID, &cce.TrafficPolicy{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if policy == nil {
+			continue
+		}
+
+		result := swagger.PolicySimulationResult{
+			InterfaceID: a.interfaceID,
+			AppID:       a.appID,
+			PolicyID:    a.policyID,
+		}
+		if rule, i := policy.(*cce.TrafficPolicy).Match(&req.Packet); rule != nil {
+			result.Matched = true
+			result.Rule = &i
+			result.TrafficRule = rule
+		}
+		simulation.Results = append(simulation.Results, result)
+	}
+
+	// List the interfaces before the apps
+	sort.Slice(simulation.Results, func(i, j int) bool {
+		a, b := simulation.Results[i], simulation.Results[j]
+		if (a.InterfaceID == "") != (b.InterfaceID == "") {
+			return a.InterfaceID != ""
+		}
+		return a.InterfaceID+a.AppID < b.InterfaceID+b.AppID
+	})
+
+	// Marshal the response object to JSON
+	simulationJSON, err := json.Marshal(simulation)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(simulationJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cce "github.com/open-ness/edgecontroller"
+)
+
+// revocationCheckInterval is how long a token found not to be revoked is
+// trusted before the revocation list is read again. Tokens revoked by this
+// controller are rejected at once, tokens revoked by another controller
+// sharing the database within this interval.
+const revocationCheckInterval = 10 * time.Second
+
+// revocations caches the revocation list of the JSON Web Tokens so that
+// authenticating a request does not read the list every time.
+type revocations struct {
+	mu sync.Mutex
+	// revoked are the expiry times of the revoked tokens by token ID
+	revoked map[string]time.Time
+	// checked are the times the tokens were found not to be revoked by token
+	// ID
+	checked map[string]time.Time
+	pruned  time.Time
+}
+
+func newRevocations() *revocations {
+	return &revocations{
+		revoked: make(map[string]time.Time),
+		checked: make(map[string]time.Time),
+		pruned:  time.Now(),
+	}
+}
+
+// isRevoked returns whether the token with the ID has been revoked.
+func (c *revocations) isRevoked(ctx context.Context, ps cce.PersistenceService, id string) (bool, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	c.prune(now)
+	_, revoked := c.revoked[id]
+	checked, ok := c.checked[id]
+	c.mu.Unlock()
+	if revoked {
+		return true, nil
+	}
+	if ok && now.Sub(checked) < revocationCheckInterval {
+		return false, nil
+	}
+
+	e, err := ps.Read(ctx, id, &cce.RevokedToken{})
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e != nil {
+		c.revoked[id] = e.(*cce.RevokedToken).ExpiresAt
+		delete(c.checked, id)
+		return true, nil
+	}
+	c.checked[id] = now
+
+	return false, nil
+}
+
+// revoke records a token revoked by this controller.
+func (c *revocations) revoke(id string, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.revoked[id] = expiresAt
+	delete(c.checked, id)
+}
+
+// prune forgets the expired revoked tokens and the outdated checks once per
+// check interval. It must be called with the lock held.
+func (c *revocations) prune(now time.Time) {
+	if now.Sub(c.pruned) < revocationCheckInterval {
+		return
+	}
+	c.pruned = now
+
+	for id, expiresAt := range c.revoked {
+		if !now.Before(expiresAt) {
+			delete(c.revoked, id)
+		}
+	}
+	for id, checked := range c.checked {
+		if now.Sub(checked) >= revocationCheckInterval {
+			delete(c.checked, id)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/rollout"
+	"github.com/open-ness/edgecontroller/swagger"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+func newRollouts(controller *cce.Controller, nodeConns *node.Pool) *rollout.Runner {
+	return &rollout.Runner{
+		Controller: controller,
+		Connect:    nodeConnector(controller, nodeConns),
+	}
+}
+
+// nodeConnector returns a function connecting to the port of a node through
+// the node connection pool.
+func nodeConnector(
+	controller *cce.Controller,
+	nodeConns *node.Pool,
+) func(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+	return func(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+		ctx = context.WithValue(ctx, contextKey("nodeConns"), nodeConns)
+		return connectNode(
+			ctx,
+			controller.PersistenceService,
+			&cce.NodeApp{NodeID: nodeID},
+			port,
+			controller.EdgeNodeCreds)
+	}
+}
+
+// Used for GET /rollouts endpoint
+func (g *Gorilla) swagGETRollouts(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of rollouts from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.Rollout{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	rollouts := swagger.RolloutList{Rollouts: []swagger.RolloutSummary{}, Next: nextLink(r, next)}
+	for _, e := range persisted {
+		rollouts.Rollouts = append(rollouts.Rollouts, rolloutSummary(e.(*cce.Rollout)))
+	}
+
+	// Marshal the response object to JSON
+	rolloutsJSON, err := json.Marshal(rollouts)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(rolloutsJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /rollouts endpoint
+//
+// The rollout runs in the background. The nodes are upgraded in waves of
+// wave_size nodes, max_unavailable of them at a time. Once more than
+// max_failures nodes failed the rollout halts and the upgraded nodes are
+// rolled back to the previous app.
+func (g *Gorilla) swagPOSTRollouts(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	var req swagger.RolloutReq
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Error unmarshaling json: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// The apps of Kubernetes are deployed by the orchestrator
+	if ctrl.OrchestrationMode == cce.OrchestrationModeKubernetes ||
+		ctrl.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if _, err := w.Write([]byte("rollouts are not supported in Kubernetes mode")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	nodeIDs, status, err := rolloutNodes(r.Context(), ctrl, &req)
+	if err != nil {
+		log.Errf("Error reading nodes: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if status != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if _, err = w.Write([]byte(status)); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Construct the rollout
+	now := time.Now().UTC()
+	ro := &cce.Rollout{
+		ID:             uuid.New(),
+		AppID:          req.AppID,
+		PreviousAppID:  req.PreviousAppID,
+		WaveSize:       req.WaveSize,
+		MaxUnavailable: req.MaxUnavailable,
+		MaxFailures:    req.MaxFailures,
+		Status:         cce.RolloutPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	for _, id := range nodeIDs {
+		ro.Nodes = append(ro.Nodes, cce.RolloutNode{NodeID: id, Status: cce.RolloutNodePending})
+	}
+
+	// Validate the object
+	if err = ro.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", req, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check the apps are there
+	for _, id := range []string{ro.AppID, ro.PreviousAppID} {
+		if id == "" {
+			continue
+		}
+		app, err := ctrl.PersistenceService.Read(r.Context(), id, &cce.App{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if app == nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			if _, err = w.Write([]byte(fmt.Sprintf("app %s not found", id))); err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+	}
+
+	if err = ctrl.PersistenceService.Create(r.Context(), ro); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	g.rollouts.Start(ro)
+
+	w.Header().Set("Location", "/rollouts/"+ro.ID)
+	writeRollout(w, http.StatusAccepted, ro)
+}
+
+// rolloutNodes returns the IDs of the nodes targeted by the rollout request.
+// If the request does not target existing nodes the reason is returned.
+func rolloutNodes(
+	ctx context.Context,
+	ctrl *cce.Controller,
+	req *swagger.RolloutReq,
+) (ids []string, status string, err error) {
+	if len(req.NodeIDs) > 0 {
+		for _, id := range req.NodeIDs {
+			n, err := ctrl.PersistenceService.Read(ctx, id, &cce.Node{})
+			if err != nil {
+				return nil, "", err
+			}
+			if n == nil {
+				return nil, fmt.Sprintf("node %s not found", id), nil
+			}
+		}
+		return req.NodeIDs, "", nil
+	}
+
+	if req.Selector != "" {
+		sel, err := cce.ParseSelector(req.Selector)
+		if err != nil {
+			return nil, err.Error(), nil
+		}
+		nodes, err := ctrl.PersistenceService.ReadAll(ctx, &cce.Node{})
+		if err != nil {
+			return nil, "", err
+		}
+		for _, n := range nodes {
+			if sel.Matches(n.(*cce.Node).Labels) {
+				ids = append(ids, n.GetID())
+			}
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Sprintf("no node matches selector %s", req.Selector), nil
+		}
+		return ids, "", nil
+	}
+
+	if req.Location == "" {
+		return nil, "node_ids, selector or location is required", nil
+	}
+	nodes, err := ctrl.PersistenceService.Filter(
+		ctx,
+		&cce.Node{},
+		[]cce.Filter{{Field: "location", Value: req.Location}})
+	if err != nil {
+		return nil, "", err
+	}
+	for _, n := range nodes {
+		ids = append(ids, n.GetID())
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Sprintf("no node at location %s", req.Location), nil
+	}
+
+	return ids, "", nil
+}
+
+// Used for GET /rollouts/{rollout_id} endpoint
+func (g *Gorilla) swagGETRolloutByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	ro, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["rollout_id"], &cce.Rollout{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if ro == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	writeRollout(w, http.StatusOK, ro.(*cce.Rollout))
+}
+
+func rolloutSummary(ro *cce.Rollout) swagger.RolloutSummary {
+	return swagger.RolloutSummary{
+		ID:            ro.ID,
+		AppID:         ro.AppID,
+		PreviousAppID: ro.PreviousAppID,
+		Status:        ro.Status,
+		CreatedAt:     ro.CreatedAt,
+		UpdatedAt:     ro.UpdatedAt,
+	}
+}
+
+func writeRollout(w http.ResponseWriter, statusCode int, ro *cce.Rollout) {
+	// Construct the response object
+	detail := swagger.RolloutDetail{
+		RolloutSummary: rolloutSummary(ro),
+		WaveSize:       ro.WaveSize,
+		MaxUnavailable: ro.MaxUnavailable,
+		MaxFailures:    ro.MaxFailures,
+		Wave:           ro.Wave,
+		Error:          ro.Error,
+		Nodes:          []swagger.RolloutNode{},
+	}
+	for _, n := range ro.Nodes {
+		detail.Nodes = append(detail.Nodes, swagger.RolloutNode{
+			NodeID: n.NodeID,
+			Status: n.Status,
+			Error:  n.Error,
+		})
+	}
+
+	// Marshal the response object to JSON
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err = w.Write(detailJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
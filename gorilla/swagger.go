@@ -26,6 +26,7 @@ import (
 	cce "github.com/open-ness/edgecontroller"
 	"github.com/open-ness/edgecontroller/swagger"
 	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
 )
 
 // The following handlers are compliant to our published Swagger (OpenAPI 3.0) schema.
@@ -35,21 +36,37 @@ func (g *Gorilla) swagGETNodes(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.Node{})
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of nodes from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.Node{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Fetch the health of the nodes in the page
+	health, err := readNodeHealth(r.Context(), ctrl.PersistenceService, persisted)
 	if err != nil {
+		log.Errf("Error reading node health: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	// Construct the response object
-	nodes := swagger.NodeList{Nodes: []swagger.NodeSummary{}}
+	nodes := swagger.NodeList{Nodes: []swagger.NodeSummary{}, Next: nextLink(r, next)}
 	for _, n := range persisted {
 		node := swagger.NodeSummary{
 			ID:       n.(*cce.Node).ID,
 			Name:     n.(*cce.Node).Name,
 			Location: n.(*cce.Node).Location,
 			Serial:   n.(*cce.Node).Serial,
+			Labels:   n.(*cce.Node).Labels,
+			Health:   health[n.(*cce.Node).ID],
 		}
 		nodes.Nodes = append(nodes.Nodes, node)
 	}
@@ -87,6 +104,14 @@ func (g *Gorilla) swagGETNodeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch the health of the node
+	health, err := readNodeHealthByID(r.Context(), ctrl.PersistenceService, persisted.GetID())
+	if err != nil {
+		log.Errf("Error reading node health: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	// Construct the response object
 	node := swagger.NodeDetail{
 		NodeSummary: swagger.NodeSummary{
@@ -94,6 +119,8 @@ func (g *Gorilla) swagGETNodeByID(w http.ResponseWriter, r *http.Request) {
 			Name:     persisted.(*cce.Node).Name,
 			Location: persisted.(*cce.Node).Location,
 			Serial:   persisted.(*cce.Node).Serial,
+			Labels:   persisted.(*cce.Node).Labels,
+			Health:   health,
 		},
 	}
 
@@ -103,6 +130,7 @@ func (g *Gorilla) swagGETNodeByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, persisted.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(nodeJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -129,7 +157,15 @@ func (g *Gorilla) swagPATCHNodeByID(w http.ResponseWriter, r *http.Request) {
 		Name:     node.Name,
 		Location: node.Location,
 		Serial:   node.Serial,
+		Labels:   node.Labels,
+	}
+
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
 	}
+	persisted.ResourceVersion = version
 
 	// Validate the object
 	if err := persisted.Validate(); err != nil {
@@ -144,10 +180,21 @@ func (g *Gorilla) swagPATCHNodeByID(w http.ResponseWriter, r *http.Request) {
 
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		log.Errf("Error updating entities: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, &persisted)
+
+	// Assign the configuration of the node groups the node joined
+	if len(persisted.Labels) > 0 {
+		g.reconcileNodes(persisted.ID)
+	}
 }
 
 // Used for DELETE /nodes/{node_id} endpoint
@@ -182,7 +229,19 @@ func (g *Gorilla) swagDELETENodeByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	// Only delete the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err = ctrl.PersistenceService.Delete(
+		r.Context(), mux.Vars(r)["node_id"], &cce.Node{ResourceVersion: version})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -194,6 +253,8 @@ func (g *Gorilla) swagDELETENodeByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	g.invalidateNodeConns(mux.Vars(r)["node_id"])
 }
 
 // Used for GET /apps endpoint
@@ -201,15 +262,21 @@ func (g *Gorilla) swagGETApps(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.App{})
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of apps from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.App{}, q)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeListError(w, err)
 		return
 	}
 
 	// Construct the response object
-	apps := swagger.AppList{Apps: []swagger.AppSummary{}}
+	apps := swagger.AppList{Apps: []swagger.AppSummary{}, Next: nextLink(r, next)}
 	for _, a := range persisted {
 		app := swagger.AppSummary{
 			ID:          a.(*cce.App).ID,
@@ -270,6 +337,7 @@ func (g *Gorilla) swagGETAppByID(w http.ResponseWriter, r *http.Request) {
 		Source:      persisted.(*cce.App).Source,
 		Ports:       persisted.(*cce.App).Ports,
 		EPAFeatures: persisted.(*cce.App).EPAFeatures,
+		Revision:    persisted.(*cce.App).Revision,
 	}
 
 	// Marshal the response object to JSON
@@ -278,6 +346,7 @@ func (g *Gorilla) swagGETAppByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, persisted.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(appJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -285,7 +354,10 @@ func (g *Gorilla) swagGETAppByID(w http.ResponseWriter, r *http.Request) {
 }
 
 // Used for PATCH /apps/{app_id} endpoint
-func (g *Gorilla) swagPATCHAppByID(w http.ResponseWriter, r *http.Request) {
+//
+// If the update changes how the app is deployed its revision is incremented
+// and the app is upgraded in the background on the nodes running it.
+func (g *Gorilla) swagPATCHAppByID(w http.ResponseWriter, r *http.Request) { //nolint:gocyclo
 	// Load the controller to access the persistence and the payload
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 	body := r.Context().Value(contextKey("body")).([]byte)
@@ -313,6 +385,13 @@ func (g *Gorilla) swagPATCHAppByID(w http.ResponseWriter, r *http.Request) {
 		EPAFeatures: app.EPAFeatures,
 	}
 
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	persisted.ResourceVersion = version
+
 	// Validate the object
 	if err := persisted.Validate(); err != nil {
 		log.Debugf("Validation failed for %#v: %v", persisted, err)
@@ -324,12 +403,42 @@ func (g *Gorilla) swagPATCHAppByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Increment the revision if the nodes must redeploy the app
+	current, err := ctrl.PersistenceService.Read(r.Context(), persisted.ID, &cce.App{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	upgrade := false
+	if current != nil {
+		persisted.Revision = current.(*cce.App).Revision
+		if current.(*cce.App).RedeployRequired(&persisted) {
+			persisted.Revision++
+			upgrade = true
+		}
+	}
+
 	// Persist the object
-	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
+	if err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		log.Errf("Error updating entities: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	if upgrade {
+		if err = g.upgradeNodeApps(r.Context(), &persisted); err != nil {
+			log.Errf("Error starting app upgrades: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	setETag(w, &persisted)
 }
 
 // Used for DELETE /apps/{app_id} endpoint
@@ -364,7 +473,19 @@ func (g *Gorilla) swagDELETEAppByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["app_id"], &cce.App{})
+	// Only delete the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err = ctrl.PersistenceService.Delete(
+		r.Context(), mux.Vars(r)["app_id"], &cce.App{ResourceVersion: version})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -383,15 +504,21 @@ func (g *Gorilla) swagGETPolicies(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.TrafficPolicy{})
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of policies from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.TrafficPolicy{}, q)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeListError(w, err)
 		return
 	}
 
 	// Construct the response object
-	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}}
+	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}, Next: nextLink(r, next)}
 	for _, a := range persisted {
 		policy := swagger.PolicySummary{
 			ID:   a.(*cce.TrafficPolicy).ID,
@@ -448,6 +575,7 @@ func (g *Gorilla) swagGETPolicyByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, persisted.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(policyJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -475,6 +603,13 @@ func (g *Gorilla) swagPATCHPolicyByID(w http.ResponseWriter, r *http.Request) {
 		Rules: policy.Rules,
 	}
 
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	persisted.ResourceVersion = version
+
 	// Validate the object
 	if err := persisted.Validate(); err != nil {
 		log.Debugf("Validation failed for %#v: %v", persisted, err)
@@ -488,10 +623,16 @@ func (g *Gorilla) swagPATCHPolicyByID(w http.ResponseWriter, r *http.Request) {
 
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		log.Errf("Error updating entities: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, &persisted)
 }
 
 // Used for DELETE /policies/{policy_id}
@@ -529,7 +670,19 @@ func (g *Gorilla) swagDELETEPolicyByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicy{})
+	// Only delete the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err = ctrl.PersistenceService.Delete(
+		r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicy{ResourceVersion: version})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -548,16 +701,21 @@ func (g *Gorilla) swagGETKubeOVNPolicies(w http.ResponseWriter, r *http.Request)
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the nodes from persistence
-	persisted, err := ctrl.PersistenceService.ReadAll(r.Context(), &cce.TrafficPolicyKubeOVN{})
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of policies from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.TrafficPolicyKubeOVN{}, q)
 	if err != nil {
-		log.Errf("Failed to fetch the nodes from persistence: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeListError(w, err)
 		return
 	}
 
 	// Construct the response object
-	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}}
+	policies := swagger.PolicyList{Policies: []swagger.PolicySummary{}, Next: nextLink(r, next)}
 	for _, a := range persisted {
 		policy := swagger.PolicySummary{
 			ID:   a.(*cce.TrafficPolicyKubeOVN).ID,
@@ -616,6 +774,7 @@ func (g *Gorilla) swagGETKubeOVNPolicyByID(w http.ResponseWriter, r *http.Reques
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, persisted.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(policyJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -644,6 +803,13 @@ func (g *Gorilla) swagPATCHKubeOVNPolicyByID(w http.ResponseWriter, r *http.Requ
 		Egress:  policy.EgressRules,
 	}
 
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	persisted.ResourceVersion = version
+
 	// Validate the object
 	if err := persisted.Validate(); err != nil {
 		log.Debugf("Validation failed for %#v: %v", persisted, err)
@@ -657,10 +823,16 @@ func (g *Gorilla) swagPATCHKubeOVNPolicyByID(w http.ResponseWriter, r *http.Requ
 
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&persisted}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		log.Errf("Error updating entities: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, &persisted)
 }
 
 // Used for DELETE /kube_ovn/policies/{policy_id}
@@ -698,7 +870,19 @@ func (g *Gorilla) swagDELETEKubeOVNPolicyByID(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicyKubeOVN{})
+	// Only delete the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err = ctrl.PersistenceService.Delete(
+		r.Context(), mux.Vars(r)["policy_id"], &cce.TrafficPolicyKubeOVN{ResourceVersion: version})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
 	if err != nil {
 		log.Errf("Error deleting entity: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -724,6 +908,7 @@ func (g *Gorilla) swagGETNodeDNS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch the entity from persistence
+	var persistedConfig cce.Persistable
 	persistedNode, err := ctrl.PersistenceService.Filter(
 		r.Context(),
 		&cce.NodeDNSConfig{},
@@ -735,7 +920,6 @@ func (g *Gorilla) swagGETNodeDNS(w http.ResponseWriter, r *http.Request) {
 	}
 	if len(persistedNode) != 0 {
 		// Fetch the DNS config from persistence
-		var persistedConfig cce.Persistable
 		persistedConfig, err = ctrl.PersistenceService.Read(
 			r.Context(),
 			persistedNode[0].(*cce.NodeDNSConfig).DNSConfigID,
@@ -807,6 +991,9 @@ func (g *Gorilla) swagGETNodeDNS(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	if persistedConfig != nil {
+		setETag(w, persistedConfig.(cce.Versioned))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(dnsJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -829,6 +1016,12 @@ func (g *Gorilla) swagPATCHNodeDNS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only update the DNS configuration version the client has seen
+	version, ok := g.swagDNSIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	// Delete the old persisted data
 	if err := g.swagDNSDeleteHelper(w, r); err != nil {
 		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
@@ -839,7 +1032,7 @@ func (g *Gorilla) swagPATCHNodeDNS(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create the new requested data
-	if err := g.swagDNSCreateHelper(w, r); err != nil {
+	if err := g.swagDNSCreateHelper(w, r, version+1); err != nil {
 		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
 		if err != nil {
 			log.Errf("Error writing response: %v", err)
@@ -864,6 +1057,11 @@ func (g *Gorilla) swagDELETENodeDNS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only delete the DNS configuration version the client has seen
+	if _, ok := g.swagDNSIfMatch(w, r); !ok {
+		return
+	}
+
 	// Delete the old persisted data
 	if err := g.swagDNSDeleteHelper(w, r); err != nil {
 		_, err = w.Write([]byte(fmt.Sprintf("DNS call failed mid operation: %v", err)))
@@ -876,7 +1074,7 @@ func (g *Gorilla) swagDELETENodeDNS(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) error { //nolint:gocyclo
+func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request, version uint64) error { //nolint:gocyclo
 	// Load the controller to access the persistence and the payload
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 	body := r.Context().Value(contextKey("body")).([]byte)
@@ -897,8 +1095,9 @@ func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) er
 
 	// Create the new persistable entity for the DNS config
 	newConfig := &cce.DNSConfig{
-		ID:   uuid.New(),
-		Name: requested.Name,
+		ID:              uuid.New(),
+		Name:            requested.Name,
+		ResourceVersion: version,
 	}
 
 	// Create the new persistable association
@@ -983,10 +1182,97 @@ func (g *Gorilla) swagDNSCreateHelper(w http.ResponseWriter, r *http.Request) er
 		w.WriteHeader(http.StatusInternalServerError)
 		return err
 	}
+	setETag(w, newConfig)
 
 	return nil
 }
 
+// swagDNSIfMatch returns the resource version of the DNS configuration of the
+// node, or 0 if it has none, and checks the If-Match header of the request
+// against it. The version is carried over to the configuration replacing it.
+// The configuration is claimed by incrementing its version before it is
+// replaced or deleted, so that of concurrent requests with the same If-Match
+// header only the first one proceeds. If the header is invalid or stale the
+// response is written and ok is false.
+func (g *Gorilla) swagDNSIfMatch(w http.ResponseWriter, r *http.Request) (current uint64, ok bool) { //nolint:gocyclo
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	// Fetch the DNS config of the node from persistence
+	persistedNode, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.NodeDNSConfig{},
+		[]cce.Filter{{Field: "node_id", Value: mux.Vars(r)["node_id"]}},
+	)
+	if err != nil {
+		log.Errf("Error filtering entities: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return 0, false
+	}
+	var config *cce.DNSConfig
+	if len(persistedNode) != 0 {
+		persistedConfig, err := ctrl.PersistenceService.Read(
+			r.Context(),
+			persistedNode[0].(*cce.NodeDNSConfig).DNSConfigID,
+			&cce.DNSConfig{},
+		)
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return 0, false
+		}
+		if persistedConfig != nil {
+			config = persistedConfig.(*cce.DNSConfig)
+			current = config.ResourceVersion
+		}
+	}
+
+	if version != 0 && version != current {
+		log.Debugf("Precondition failed: DNS config of node %s is at version %d, not %d",
+			mux.Vars(r)["node_id"], current, version)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return 0, false
+	}
+	if config == nil {
+		return current, true
+	}
+
+	// Claim the config, unless another request changed it since it was read
+	config.ResourceVersion = version
+	err = ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{config})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return 0, false
+	}
+	if err != nil {
+		log.Errf("Error updating entities: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return 0, false
+	}
+	if version != 0 {
+		// A config deleted since it was read is not updated either
+		claimed, err := ctrl.PersistenceService.Read(r.Context(), config.ID, &cce.DNSConfig{})
+		if err != nil {
+			log.Errf("Error reading entity: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return 0, false
+		}
+		if claimed == nil {
+			log.Debugf("Precondition failed: DNS config of node %s was deleted", mux.Vars(r)["node_id"])
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return 0, false
+		}
+	}
+
+	return current, true
+}
+
 func (g *Gorilla) swagDNSDeleteHelper(w http.ResponseWriter, r *http.Request) error {
 	// Load the controller to access the persistence and the payload
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
@@ -1107,6 +1393,7 @@ func (g *Gorilla) swagGETInterfaces(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, persisted.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(ifacesJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -1138,6 +1425,16 @@ func (g *Gorilla) swagPATCHInterfaces(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	if version != 0 && version != persisted.(*cce.Node).ResourceVersion {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
 	// Convert it to a persistable object
 	requested := cce.NodeReq{
 		Node:              *persisted.(*cce.Node),
@@ -1183,10 +1480,16 @@ func (g *Gorilla) swagPATCHInterfaces(w http.ResponseWriter, r *http.Request) {
 
 	// Persist the object
 	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{&requested}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
 		log.Errf("Error updating entities: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, &requested)
 }
 
 // Used for GET /nodes/{node_id}/interfaces/{interface_id} endpoint
@@ -1254,6 +1557,19 @@ func (g *Gorilla) swagGETNodeInterfacePolicy(w http.ResponseWriter, r *http.Requ
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Fetch the node from persistence and check if it's there, the policy is
+	// versioned by its node
+	node, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	// Construct the response object
 	baseResource := swagger.BaseResource{}
 
@@ -1289,6 +1605,7 @@ func (g *Gorilla) swagGETNodeInterfacePolicy(w http.ResponseWriter, r *http.Requ
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, node.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(baseResourceJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -1309,14 +1626,9 @@ func (g *Gorilla) swagPATCHNodeInterfacePolicy(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Fetch the nodes from persistence and check if it's there
-	node, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if node == nil {
-		w.WriteHeader(http.StatusNotFound)
+	// Fetch the node from persistence and only update the resource version
+	// the client has seen
+	if !nodeIfMatch(w, r, ctrl.PersistenceService) {
 		return
 	}
 
@@ -1338,6 +1650,27 @@ func (g *Gorilla) swagPATCHNodeInterfacePolicy(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Analyze the policy against the other policies of the node
+	code, err := checkPolicyAnalysis(
+		r.Context(),
+		ctrl,
+		policy.(*cce.TrafficPolicy),
+		mux.Vars(r)["node_id"],
+		mux.Vars(r)["interface_id"],
+		"")
+	if code != 0 {
+		if code == http.StatusUnprocessableEntity {
+			log.Debugf("Policy analysis failed: %v", err)
+		} else {
+			log.Errf("Error analyzing policy: %v", err)
+		}
+		w.WriteHeader(code)
+		if _, err = w.Write([]byte(err.Error())); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Construct the update object to dial to the node
 	requested := cce.NodeReq{
 		Node: cce.Node{
@@ -1352,7 +1685,7 @@ func (g *Gorilla) swagPATCHNodeInterfacePolicy(w http.ResponseWriter, r *http.Re
 	}
 
 	// Update the remote node
-	code, err := handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
+	code, err = handleUpdateNodes(r.Context(), ctrl.PersistenceService, &requested)
 	switch {
 	case code != 0:
 		log.Errf("Error updating remote entities: %v", err)
@@ -1420,14 +1753,9 @@ func (g *Gorilla) swagDELETENodeInterfacePolicy(w http.ResponseWriter, r *http.R
 	// Load the controller to access the persistence and the payload
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
-	// Fetch the nodes from persistence and check if it's there
-	node, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if node == nil {
-		w.WriteHeader(http.StatusNotFound)
+	// Fetch the node from persistence and only update the resource version
+	// the client has seen
+	if !nodeIfMatch(w, r, ctrl.PersistenceService) {
 		return
 	}
 	// TODO: Verify the interface ID is valid
@@ -1511,24 +1839,25 @@ func (g *Gorilla) swagGETNodeApps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Construct the response object
-	nodeApps := swagger.NodeAppList{NodeApps: []swagger.NodeAppSummary{}}
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+	q.Filters = append(q.Filters, cce.Filter{
+		Field: "node_id",
+		Value: mux.Vars(r)["node_id"],
+	})
 
 	// Filter nodes_apps to get the node_app_id
-	persisted, err := ctrl.PersistenceService.Filter(
-		r.Context(),
-		&cce.NodeApp{},
-		[]cce.Filter{
-			{
-				Field: "node_id",
-				Value: mux.Vars(r)["node_id"],
-			},
-		})
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.NodeApp{}, q)
 	if err != nil {
-		log.Errf("Error filtering node_apps: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeListError(w, err)
 		return
 	}
+
+	// Construct the response object
+	nodeApps := swagger.NodeAppList{NodeApps: []swagger.NodeAppSummary{}, Next: nextLink(r, next)}
 	for _, a := range persisted {
 		nodeApps.NodeApps = append(nodeApps.NodeApps, swagger.NodeAppSummary{
 			ID: a.(*cce.NodeApp).AppID,
@@ -1601,6 +1930,39 @@ func (g *Gorilla) swagPOSTNodeApp(w http.ResponseWriter, r *http.Request) { //no
 		return
 	}
 
+	// Check the app is not being deployed to the node
+	ops, err := ctrl.PersistenceService.Filter(
+		r.Context(),
+		&cce.Operation{},
+		[]cce.Filter{
+			{
+				Field: "node_id",
+				Value: mux.Vars(r)["node_id"],
+			},
+			{
+				Field: "app_id",
+				Value: baseResource.ID,
+			},
+		})
+	if err != nil {
+		log.Errf("Error filtering operations: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, op := range ops {
+		if op.(*cce.Operation).Kind == cce.OperationDeployApp && !op.(*cce.Operation).Done() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err = w.Write([]byte(fmt.Sprintf(
+				"app %s is being deployed to node %s by operation %s",
+				baseResource.ID, mux.Vars(r)["node_id"], op.GetID(),
+			)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+	}
+
 	// Fetch the entity from persistence and check if it's there
 	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
 	if err != nil {
@@ -1641,20 +2003,20 @@ func (g *Gorilla) swagPOSTNodeApp(w http.ResponseWriter, r *http.Request) { //no
 		return
 	}
 
-	// Create the remote node app
-	err = handleCreateNodesApps(r.Context(), ctrl.PersistenceService, &nodeApp)
-	if err != nil {
-		log.Errf("Error creating node app: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	// Deploy the app in the background: the node app is persisted once the
+	// app is deployed
+	op := &cce.Operation{
+		Kind:   cce.OperationDeployApp,
+		NodeID: nodeApp.NodeID,
+		AppID:  nodeApp.AppID,
 	}
-
-	// Persist the object
-	if err := ctrl.PersistenceService.Create(r.Context(), &nodeApp); err != nil {
-		log.Errf("Error creating entity: %v", err)
+	if err = g.ops.submit(r.Context(), op); err != nil {
+		log.Errf("Error starting operation: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	writeAcceptedOperation(w, op)
 }
 
 // Used for GET /nodes/{node_id}/apps/{app_id} endpoint
@@ -1721,6 +2083,9 @@ func (g *Gorilla) swagGETNodeAppsByID(w http.ResponseWriter, r *http.Request) {
 }
 
 // Used for PATCH /nodes/{node_id}/apps/{app_id} endpoint
+//
+// The command starts, stops or restarts the app on the node in an operation
+// and does not change the node app, so the If-Match header does not apply.
 func (g *Gorilla) swagPATCHNodeAppsByID(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence and the payload
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
@@ -1784,17 +2149,20 @@ func (g *Gorilla) swagPATCHNodeAppsByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	code, err := handleUpdateNodesApps(r.Context(), ctrl.PersistenceService, &requested)
-	switch {
-	case code != 0:
-		log.Errf("Error updating remote entities: %v", err)
-		w.WriteHeader(code)
-		_, err = w.Write([]byte("error updating remote entity"))
-		if err != nil {
-			log.Errf("Error writing response: %v", err)
-		}
+	// Run the command in the background
+	op := &cce.Operation{
+		Kind:   cce.OperationUpdateApp,
+		NodeID: requested.NodeID,
+		AppID:  requested.AppID,
+		Cmd:    requested.Cmd,
+	}
+	if err = g.ops.submit(r.Context(), op); err != nil {
+		log.Errf("Error starting operation: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+
+	writeAcceptedOperation(w, op)
 }
 
 // Used for DELETE /nodes/{node_id}/apps/{app_id} endpoint
@@ -1875,6 +2243,19 @@ func (g *Gorilla) swagGETNodeAppPolicy(w http.ResponseWriter, r *http.Request) {
 	// Load the controller to access the persistence
 	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
 
+	// Fetch the node from persistence and check if it's there, the policy is
+	// versioned by its node
+	node, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if node == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	// Filter nodes_apps to get the node_app_id
 	nodeApps, err := ctrl.PersistenceService.Filter(
 		r.Context(),
@@ -1940,6 +2321,7 @@ func (g *Gorilla) swagGETNodeAppPolicy(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	setETag(w, node.(cce.Versioned))
 	w.Header().Set("Content-Type", "application/json")
 	if _, err = w.Write(baseResourceJSON); err != nil {
 		log.Errf("Error writing response: %v", err)
@@ -1989,7 +2371,13 @@ func (g *Gorilla) swagPATCHNodeAppPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Query traffic_policies to verify the baseResourceID is valid
+	// Fetch the node from persistence and only update the resource version
+	// the client has seen
+	if !nodeIfMatch(w, r, ctrl.PersistenceService) {
+		return
+	}
+
+	// Query traffic_policies to verify the baseResourceID is valid
 	policy, err := ctrl.PersistenceService.Read(r.Context(), baseResource.ID, &cce.TrafficPolicy{})
 	if err != nil {
 		log.Errf("Error reading traffic_policies: %v", err)
@@ -2001,6 +2389,27 @@ func (g *Gorilla) swagPATCHNodeAppPolicy(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Analyze the policy against the other policies of the node
+	code, err := checkPolicyAnalysis(
+		r.Context(),
+		ctrl,
+		policy.(*cce.TrafficPolicy),
+		mux.Vars(r)["node_id"],
+		"",
+		mux.Vars(r)["app_id"])
+	if code != 0 {
+		if code == http.StatusUnprocessableEntity {
+			log.Debugf("Policy analysis failed: %v", err)
+		} else {
+			log.Errf("Error analyzing policy: %v", err)
+		}
+		w.WriteHeader(code)
+		if _, err = w.Write([]byte(err.Error())); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
 	// Connect to node
 	nodePort := ctrl.ELAPort
 	if nodePort == "" {
@@ -2017,6 +2426,7 @@ func (g *Gorilla) swagPATCHNodeAppPolicy(w http.ResponseWriter, r *http.Request)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer disconnectNode(nodeCC)
 
 	// Make gRPC call to node to set the policy
 	if err = nodeCC.AppPolicySvcCli.Set(
@@ -2109,6 +2519,12 @@ func (g *Gorilla) swagDELETENodeAppPolicy(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Fetch the node from persistence and only update the resource version
+	// the client has seen
+	if !nodeIfMatch(w, r, ctrl.PersistenceService) {
+		return
+	}
+
 	// Filter nodes_apps_traffic_policies to get the ID
 	nodeAppPolicies, err := ctrl.PersistenceService.Filter(
 		r.Context(),
@@ -2141,6 +2557,7 @@ func (g *Gorilla) swagDELETENodeAppPolicy(w http.ResponseWriter, r *http.Request
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	defer disconnectNode(nodeCC)
 
 	// Make gRPC call to node to delete the policy
 	if err = nodeCC.AppPolicySvcCli.Delete(
@@ -2432,3 +2849,408 @@ func (g *Gorilla) swagDELETENodeAppKubeOVNPolicy(w http.ResponseWriter, r *http.
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Used for GET /users endpoint
+func (g *Gorilla) swagGETUsers(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of users from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.User{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	users := swagger.UserList{Users: []swagger.UserSummary{}, Next: nextLink(r, next)}
+	for _, u := range persisted {
+		users.Users = append(users.Users, swagger.UserSummary{
+			ID:       u.(*cce.User).ID,
+			Username: u.(*cce.User).Username,
+			Roles:    u.(*cce.User).Roles,
+		})
+	}
+
+	// Marshal the response object to JSON
+	usersJSON, err := json.Marshal(users)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(usersJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /users endpoint
+func (g *Gorilla) swagPOSTUsers(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	user := swagger.UserDetail{}
+	if err := json.Unmarshal(body, &user); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if user.ID != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := w.Write([]byte("Validation failed: id cannot be specified in POST request")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Convert it to a persistable object
+	persisted := cce.User{
+		ID:       uuid.New(),
+		Username: user.Username,
+		Roles:    user.Roles,
+	}
+
+	// Hash the password and validate the object
+	err := persisted.SetPassword(user.Password)
+	if err == nil {
+		err = persisted.Validate()
+	}
+	if err != nil {
+		log.Debugf("Validation failed for user %s: %v", persisted.Username, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the username is not taken
+	if statusCode, err := checkDBCreateUsers(r.Context(), ctrl.PersistenceService, &persisted); err != nil {
+		log.Errf("Error checking DB create: %v", err)
+		w.WriteHeader(statusCode)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err := ctrl.PersistenceService.Create(r.Context(), &persisted); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err := w.Write([]byte(fmt.Sprintf(`{"id":"%s"}`, persisted.ID))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /users/{user_id} endpoint
+func (g *Gorilla) swagGETUserByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["user_id"], &cce.User{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Construct the response object
+	user := swagger.UserDetail{
+		UserSummary: swagger.UserSummary{
+			ID:       persisted.(*cce.User).ID,
+			Username: persisted.(*cce.User).Username,
+			Roles:    persisted.(*cce.User).Roles,
+		},
+	}
+
+	// Marshal the response object to JSON
+	userJSON, err := json.Marshal(user)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, persisted.(cce.Versioned))
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(userJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /users/{user_id} endpoint
+func (g *Gorilla) swagPATCHUserByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	user := swagger.UserDetail{}
+	if err := json.Unmarshal(body, &user); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the entity from persistence and check if it's there
+	e, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["user_id"], &cce.User{})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	persisted := e.(*cce.User)
+
+	// Only update the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+	persisted.ResourceVersion = version
+
+	// Update the roles, and the password if one is given
+	if user.Username != "" && user.Username != persisted.Username {
+		err = errors.New("username cannot be changed")
+	}
+	if err == nil {
+		persisted.Roles = user.Roles
+		if user.Password != "" {
+			err = persisted.SetPassword(user.Password)
+		}
+	}
+	if err == nil {
+		err = persisted.Validate()
+	}
+	if err != nil {
+		log.Debugf("Validation failed for user %s: %v", persisted.Username, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err := ctrl.PersistenceService.BulkUpdate(r.Context(), []cce.Persistable{persisted}); err != nil {
+		if errors.Cause(err) == cce.ErrVersionConflict {
+			log.Debugf("Precondition failed: %v", err)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		log.Errf("Error updating entities: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	setETag(w, persisted)
+}
+
+// Used for DELETE /users/{user_id} endpoint
+func (g *Gorilla) swagDELETEUserByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Users cannot lock themselves out
+	if mux.Vars(r)["user_id"] == getPrincipal(r.Context()).UserID {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if _, err := w.Write([]byte("cannot delete the authenticated user")); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Fetch the entity from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["user_id"], &cce.User{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Only delete the resource version the client has seen
+	version, ok := ifMatch(w, r)
+	if !ok {
+		return
+	}
+
+	// Deleting the user cascades to its API tokens
+	ok, err = ctrl.PersistenceService.Delete(
+		r.Context(), mux.Vars(r)["user_id"], &cce.User{ResourceVersion: version})
+	if errors.Cause(err) == cce.ErrVersionConflict {
+		log.Debugf("Precondition failed: %v", err)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// we just fetched the entity, so if !ok then something went wrong
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Used for GET /tokens endpoint
+func (g *Gorilla) swagGETTokens(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Parse the pagination, sorting and filtering parameters
+	q, ok := listQuery(w, r)
+	if !ok {
+		return
+	}
+
+	// Fetch the page of tokens from persistence
+	persisted, next, err := ctrl.PersistenceService.FilterPage(r.Context(), &cce.APIToken{}, q)
+	if err != nil {
+		writeListError(w, err)
+		return
+	}
+
+	// Construct the response object
+	tokens := swagger.TokenList{Tokens: []swagger.TokenSummary{}, Next: nextLink(r, next)}
+	for _, t := range persisted {
+		tokens.Tokens = append(tokens.Tokens, swagger.TokenSummary{
+			ID:        t.(*cce.APIToken).ID,
+			UserID:    t.(*cce.APIToken).UserID,
+			Name:      t.(*cce.APIToken).Name,
+			CreatedAt: t.(*cce.APIToken).CreatedAt,
+		})
+	}
+
+	// Marshal the response object to JSON
+	tokensJSON, err := json.Marshal(tokens)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(tokensJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /tokens endpoint
+func (g *Gorilla) swagPOSTTokens(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	token := swagger.TokenDetail{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Tokens are issued for the authenticated user unless another is given
+	if token.UserID == "" {
+		token.UserID = getPrincipal(r.Context()).UserID
+	}
+
+	// Generate the token secret and a persistable object
+	persisted, secret, err := cce.NewAPIToken(token.UserID, token.Name)
+	if err != nil {
+		log.Errf("Error generating API token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Validate the object
+	if err = persisted.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", persisted, err)
+		w.WriteHeader(http.StatusBadRequest)
+		_, err = w.Write([]byte(fmt.Sprintf("Validation failed: %v", err)))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Check that the user exists
+	if statusCode, err := checkDBCreateAPITokens(r.Context(), ctrl.PersistenceService, persisted); err != nil {
+		log.Errf("Error checking DB create: %v", err)
+		w.WriteHeader(statusCode)
+		_, err = w.Write([]byte(err.Error()))
+		if err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+
+	// Persist the object
+	if err = ctrl.PersistenceService.Create(r.Context(), persisted); err != nil {
+		log.Errf("Error creating entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Return the token secret, which cannot be retrieved again
+	tokenJSON, err := json.Marshal(swagger.TokenDetail{
+		TokenSummary: swagger.TokenSummary{
+			ID:        persisted.ID,
+			UserID:    persisted.UserID,
+			Name:      persisted.Name,
+			CreatedAt: persisted.CreatedAt,
+		},
+		Token: secret,
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write(tokenJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for DELETE /tokens/{token_id} endpoint
+func (g *Gorilla) swagDELETETokenByID(w http.ResponseWriter, r *http.Request) {
+	// Load the controller to access the persistence and the payload
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Deleting the token revokes it
+	ok, err := ctrl.PersistenceService.Delete(r.Context(), mux.Vars(r)["token_id"], &cce.APIToken{})
+	if err != nil {
+		log.Errf("Error deleting entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+}
@@ -27,8 +27,14 @@ func handleUpdateNodes(
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	defer disconnectNode(nodeCC)
 
 	if e.(*cce.NodeReq).NetworkInterfaces != nil {
+		// The zones of the interfaces must exist on the node
+		if statusCode, err = checkInterfaceZones(ctx, nodeCC, e.(*cce.NodeReq).NetworkInterfaces); err != nil {
+			return statusCode, err
+		}
+
 		if err := nodeCC.IfaceSvcCli.BulkUpdate(ctx, e.(*cce.NodeReq).NetworkInterfaces); err != nil {
 			if s, ok := status.FromError(errors.Cause(err)); ok {
 				if s.Code() == codes.NotFound {
@@ -56,6 +62,42 @@ func handleUpdateNodes(
 	return 0, nil
 }
 
+// handleUpgradeNodesApps redeploys the app to the node. As when the app is
+// deployed, the node fetches the app in every mode and the deployment of the
+// app is then rolled out in the Kubernetes modes.
+func handleUpgradeNodesApps(
+	ctx context.Context,
+	ps cce.PersistenceService,
+	nodeApp *cce.NodeApp,
+	app *cce.App,
+) error {
+	ctrl := getController(ctx)
+	nodePort := ctrl.EVAPort
+	if nodePort == "" {
+		nodePort = defaultEVAPort
+	}
+	nodeCC, err := connectNode(ctx, ps, nodeApp, nodePort, ctrl.EdgeNodeCreds)
+	if err != nil {
+		return err
+	}
+	defer disconnectNode(nodeCC)
+
+	if err = nodeCC.AppDeploySvcCli.Redeploy(ctx, app); err != nil {
+		return err
+	}
+
+	if ctrl.OrchestrationMode == cce.OrchestrationModeKubernetes ||
+		ctrl.OrchestrationMode == cce.OrchestrationModeKubernetesOVN {
+		if err = ctrl.KubernetesClient.Upgrade(ctx, nodeApp.NodeID, toK8SApp(app)); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("App %s upgraded to revision %d on node %s", app.ID, app.Revision, nodeApp.NodeID)
+
+	return nil
+}
+
 func handleUpdateNodesApps( //nolint: gocyclo
 	ctx context.Context,
 	ps cce.PersistenceService,
@@ -70,6 +112,7 @@ func handleUpdateNodesApps( //nolint: gocyclo
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	defer disconnectNode(nodeCC)
 
 	switch ctrl.OrchestrationMode {
 	case cce.OrchestrationModeNative:
@@ -0,0 +1,313 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package gorilla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	elapb "github.com/open-ness/edgecontroller/pb/ela"
+	"github.com/open-ness/edgecontroller/swagger"
+)
+
+// Used for GET /nodes/{node_id}/zones endpoint
+func (g *Gorilla) swagGETZones(w http.ResponseWriter, r *http.Request) {
+	nodeCC, ok := connectZoneNode(w, r)
+	if !ok {
+		return
+	}
+	defer disconnectNode(nodeCC)
+
+	zones, err := nodeZones(r.Context(), nodeCC)
+	if err != nil {
+		log.Errf("Error getting zones: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	list := swagger.ZoneList{Zones: []swagger.ZoneSummary{}}
+	for _, z := range zones {
+		list.Zones = append(list.Zones, swagger.ZoneSummary{
+			ID:          z.ID,
+			Description: z.Description,
+		})
+	}
+
+	// Marshal the response object to JSON
+	listJSON, err := json.Marshal(list)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(listJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for POST /nodes/{node_id}/zones endpoint
+func (g *Gorilla) swagPOSTZones(w http.ResponseWriter, r *http.Request) {
+	// Load the payload
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	req := swagger.ZoneDetail{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Validate the object
+	zone := cce.NetworkZone{ID: req.ID, Description: req.Description}
+	if err := zone.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", zone, err)
+		writeValidationError(w, err)
+		return
+	}
+
+	nodeCC, ok := connectZoneNode(w, r)
+	if !ok {
+		return
+	}
+	defer disconnectNode(nodeCC)
+
+	err := nodeCC.ZoneSvcCli.Create(r.Context(), &elapb.NetworkZone{
+		Id:          zone.ID,
+		Description: zone.Description,
+	})
+	if status.Code(errors.Cause(err)) == codes.AlreadyExists {
+		w.WriteHeader(http.StatusConflict)
+		if _, err = w.Write([]byte(fmt.Sprintf("zone %s already exists", zone.ID))); err != nil {
+			log.Errf("Error writing response: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		log.Errf("Error creating zone: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if _, err = w.Write([]byte(fmt.Sprintf(`{"id":"%s"}`, zone.ID))); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for GET /nodes/{node_id}/zones/{zone_id} endpoint
+func (g *Gorilla) swagGETZoneByID(w http.ResponseWriter, r *http.Request) {
+	nodeCC, ok := connectZoneNode(w, r)
+	if !ok {
+		return
+	}
+	defer disconnectNode(nodeCC)
+
+	zone, err := nodeCC.ZoneSvcCli.Get(r.Context(), mux.Vars(r)["zone_id"])
+	if status.Code(errors.Cause(err)) == codes.NotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errf("Error getting zone: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// Construct the response object
+	detail := swagger.ZoneDetail{
+		ZoneSummary: swagger.ZoneSummary{
+			ID:          zone.Id,
+			Description: zone.Description,
+		},
+	}
+
+	// Marshal the response object to JSON
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err = w.Write(detailJSON); err != nil {
+		log.Errf("Error writing response: %v", err)
+	}
+}
+
+// Used for PATCH /nodes/{node_id}/zones/{zone_id} endpoint
+func (g *Gorilla) swagPATCHZoneByID(w http.ResponseWriter, r *http.Request) {
+	// Load the payload
+	body := r.Context().Value(contextKey("body")).([]byte)
+
+	// Unmarshal the payload
+	req := swagger.ZoneDetail{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Errf("Error unmarshaling json: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Validate the object
+	zone := cce.NetworkZone{ID: mux.Vars(r)["zone_id"], Description: req.Description}
+	if req.ID != "" && req.ID != zone.ID {
+		writeValidationError(w, errors.New("id cannot be changed"))
+		return
+	}
+	if err := zone.Validate(); err != nil {
+		log.Debugf("Validation failed for %#v: %v", zone, err)
+		writeValidationError(w, err)
+		return
+	}
+
+	nodeCC, ok := connectZoneNode(w, r)
+	if !ok {
+		return
+	}
+	defer disconnectNode(nodeCC)
+
+	err := nodeCC.ZoneSvcCli.Update(r.Context(), &elapb.NetworkZone{
+		Id:          zone.ID,
+		Description: zone.Description,
+	})
+	if status.Code(errors.Cause(err)) == codes.NotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errf("Error updating zone: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// Used for DELETE /nodes/{node_id}/zones/{zone_id} endpoint
+//
+// A zone that network interfaces of the node are in cannot be deleted.
+func (g *Gorilla) swagDELETEZoneByID(w http.ResponseWriter, r *http.Request) {
+	nodeCC, ok := connectZoneNode(w, r)
+	if !ok {
+		return
+	}
+	defer disconnectNode(nodeCC)
+
+	zoneID := mux.Vars(r)["zone_id"]
+
+	// Check that no interface is in the zone
+	ifaces, err := nodeCC.IfaceSvcCli.GetAll(r.Context())
+	if err != nil {
+		log.Errf("Error getting interfaces: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for _, ni := range ifaces {
+		for _, id := range ni.Zones {
+			if id != zoneID {
+				continue
+			}
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, err = w.Write([]byte(fmt.Sprintf(
+				"cannot delete zone %s: zone in use by network interface %s", zoneID, ni.ID)))
+			if err != nil {
+				log.Errf("Error writing response: %v", err)
+			}
+			return
+		}
+	}
+
+	err = nodeCC.ZoneSvcCli.Delete(r.Context(), zoneID)
+	if status.Code(errors.Cause(err)) == codes.NotFound {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errf("Error deleting zone: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// connectZoneNode connects to the ELA of the node of the request. If the node
+// is not found or cannot be reached the response is written and ok is false.
+func connectZoneNode(w http.ResponseWriter, r *http.Request) (nodeCC *node.ClientConn, ok bool) {
+	ctrl := r.Context().Value(contextKey("controller")).(*cce.Controller)
+
+	// Fetch the node from persistence and check if it's there
+	persisted, err := ctrl.PersistenceService.Read(r.Context(), mux.Vars(r)["node_id"], &cce.Node{})
+	if err != nil {
+		log.Errf("Error reading entity: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if persisted == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return nil, false
+	}
+
+	nodePort := ctrl.ELAPort
+	if nodePort == "" {
+		nodePort = defaultELAPort
+	}
+	nodeCC, err = connectNode(r.Context(), ctrl.PersistenceService, persisted.(*cce.Node), nodePort,
+		ctrl.EdgeNodeCreds)
+	if err != nil {
+		log.Errf("Error connecting to node: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return nodeCC, true
+}
+
+// nodeZones returns the network zones of the node.
+func nodeZones(ctx context.Context, nodeCC *node.ClientConn) ([]*cce.NetworkZone, error) {
+	pbZones, err := nodeCC.ZoneSvcCli.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := []*cce.NetworkZone{}
+	for _, z := range pbZones.NetworkZones {
+		zones = append(zones, &cce.NetworkZone{ID: z.Id, Description: z.Description})
+	}
+
+	return zones, nil
+}
+
+// checkInterfaceZones checks that the zones of the network interfaces exist
+// on the node. The zones of the node are only fetched if an interface is in a
+// zone.
+func checkInterfaceZones(
+	ctx context.Context,
+	nodeCC *node.ClientConn,
+	ifaces []*cce.NetworkInterface,
+) (statusCode int, err error) {
+	inZone := false
+	for _, ni := range ifaces {
+		inZone = inZone || len(ni.Zones) > 0
+	}
+	if !inZone {
+		return 0, nil
+	}
+
+	zones, err := nodeZones(ctx, nodeCC)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if err = cce.CheckInterfaceZones(ifaces, zones); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+
+	return 0, nil
+}
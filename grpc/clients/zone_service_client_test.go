@@ -52,7 +52,29 @@ var _ = Describe("Network Zone Service Client", func() {
 			})
 		})
 
-		Describe("Errors", func() {})
+		Describe("Errors", func() {
+			It("Should return an error if the ID already exists", func() {
+				By("Passing an existing ID")
+				err := zoneSvcCli.Create(ctx, &elapb.NetworkZone{Id: zoneID})
+
+				By("Verifying an AlreadyExists response")
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Cause(err)).To(Equal(
+					status.Errorf(codes.AlreadyExists,
+						"Network Zone %s already exists", zoneID)))
+			})
+
+			It("Should return an error if the ID is empty", func() {
+				By("Passing an empty ID")
+				err := zoneSvcCli.Create(ctx, &elapb.NetworkZone{})
+
+				By("Verifying an InvalidArgument response")
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Cause(err)).To(Equal(
+					status.Error(codes.InvalidArgument,
+						"Network Zone ID cannot be empty")))
+			})
+		})
 	})
 
 	Describe("Update", func() {
@@ -273,6 +295,27 @@ var _ = Describe("Network Zone Service Client", func() {
 					status.Errorf(codes.NotFound,
 						"Network Zone %s not found", badID)))
 			})
+
+			It("Should return an error if the zone is used by an interface", func() {
+				By("Adding the first interface to the zone")
+				ni, err := interfaceSvcCli.Get(ctx, "if0")
+				Expect(err).ToNot(HaveOccurred())
+				ni.Zones = []string{zoneID}
+				Expect(interfaceSvcCli.Update(ctx, ni)).To(Succeed())
+				defer func() {
+					ni.Zones = nil
+					Expect(interfaceSvcCli.Update(ctx, ni)).To(Succeed())
+				}()
+
+				By("Deleting the zone")
+				err = zoneSvcCli.Delete(ctx, zoneID)
+
+				By("Verifying a FailedPrecondition response")
+				Expect(err).To(HaveOccurred())
+				Expect(errors.Cause(err)).To(Equal(
+					status.Errorf(codes.FailedPrecondition,
+						"Network Zone %s is used by Network Interface if0", zoneID)))
+			})
 		})
 	})
 })
@@ -20,6 +20,11 @@ type ClientConn struct {
 	TLS  *tls.Config
 
 	conn *grpc.ClientConn
+	// opts are the additional dial options.
+	opts []ggrpc.DialOption
+	// pool is set if the connection is owned by a Pool.
+	pool   *Pool
+	pooled *pooledConn
 
 	AppDeploySvcCli   *gclients.ApplicationDeploymentServiceClient
 	AppLifeSvcCli     *gclients.ApplicationLifecycleServiceClient
@@ -34,11 +39,16 @@ type ClientConn struct {
 func (cc *ClientConn) Connect(ctx context.Context) error {
 	var err error
 
+	// Copy the dial options, so that concurrent connections do not append
+	// to a shared array
+	opts := make([]ggrpc.DialOption, len(cc.opts), len(cc.opts)+1)
+	copy(opts, cc.opts)
+
 	if cc.Port == "42102" { // XXX use the actual variable with this!
 		// OP-1742: ContextDialler not supported by Gateway
 		//nolint:staticcheck
 		cc.conn, err = grpc.Dial(ctx, cc.Addr, cc.TLS,
-			ggrpc.WithDialer(cce.PrefaceLis.DialEva))
+			append(opts, ggrpc.WithDialer(cce.PrefaceLis.DialEva))...)
 
 		// EVA
 		cc.AppDeploySvcCli = gclients.NewApplicationDeploymentServiceClient(cc.conn)
@@ -47,7 +57,7 @@ func (cc *ClientConn) Connect(ctx context.Context) error {
 		// OP-1742: ContextDialler not supported by Gateway
 		//nolint:staticcheck
 		cc.conn, err = grpc.Dial(ctx, cc.Addr, cc.TLS,
-			ggrpc.WithDialer(cce.PrefaceLis.DialEla))
+			append(opts, ggrpc.WithDialer(cce.PrefaceLis.DialEla))...)
 
 		// ELA
 		cc.AppPolicySvcCli = gclients.NewApplicationPolicyServiceClient(cc.conn)
@@ -61,6 +71,18 @@ func (cc *ClientConn) Connect(ctx context.Context) error {
 	return err
 }
 
+// Disconnect closes the connection, unless it is owned by a Pool, to which
+// it is released instead.
 func (cc *ClientConn) Disconnect() {
-	cc.conn.Close()
+	if cc.pool != nil {
+		cc.pool.release(cc.pooled)
+		return
+	}
+	cc.close()
+}
+
+func (cc *ClientConn) close() {
+	if cc.conn != nil {
+		cc.conn.Close()
+	}
 }
@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package node_test
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/common/proxy/progutil"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+func TestNode(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Node Suite")
+}
+
+var lis net.Listener
+
+var _ = BeforeSuite(func() {
+	// The nodes are dialed through the connections they open to the
+	// controller, of which there are none
+	var err error
+	lis, err = net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	cce.PrefaceLis = progutil.NewPrefaceListener(lis)
+})
+
+var _ = AfterSuite(func() {
+	Expect(lis.Close()).To(Succeed())
+})
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package node
+
+import (
+	"context"
+	"crypto/tls"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/keepalive"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "node")
+
+// Defaults of the pool settings.
+const (
+	DefaultIdleTimeout = 5 * time.Minute
+
+	// The nodes' gRPC servers reject keepalive pings more frequent than every
+	// 5 minutes by default.
+	keepaliveTime    = 5 * time.Minute
+	keepaliveTimeout = 20 * time.Second
+	maxBackoffDelay  = 30 * time.Second
+)
+
+// Pool keeps long-lived connections to the nodes so that they are reused
+// across requests, keyed by node ID and port. A connection is kept alive and
+// reconnects with backoff when it breaks. It is closed once it has not been
+// used for the idle timeout, when the address of its node changes or when it
+// is invalidated, but never while a caller still holds it.
+type Pool struct {
+	// TLS is the configuration for connecting to the nodes. The server name is
+	// overridden with the node ID.
+	TLS *tls.Config
+	// IdleTimeout is how long an unused connection is kept. If zero
+	// DefaultIdleTimeout is used.
+	IdleTimeout time.Duration
+
+	mu    sync.Mutex
+	conns map[poolKey]*pooledConn
+}
+
+type poolKey struct {
+	nodeID string
+	port   string
+}
+
+type pooledConn struct {
+	addr string
+	cc   *ClientConn
+	// dialed is closed once the connection is established or dialing failed,
+	// in which case err is set.
+	dialed chan struct{}
+	err    error
+	// users is the number of callers holding the connection.
+	users    int
+	lastUsed time.Time
+	// retired is set once the connection is removed from the pool. It is
+	// closed when its last user releases it.
+	retired bool
+}
+
+// Get returns a connection to the port of the node at the address, connecting
+// if there is no usable connection yet. The caller must release the
+// connection with its Disconnect method once done, which leaves it open for
+// reuse.
+func (p *Pool) Get(ctx context.Context, nodeID, addr, port string) (*ClientConn, error) {
+	key := poolKey{nodeID: nodeID, port: port}
+
+	p.mu.Lock()
+	for {
+		pc, ok := p.conns[key]
+		if !ok {
+			break
+		}
+
+		select {
+		case <-pc.dialed:
+		default:
+			// Wait for the connection being dialed by another caller
+			p.mu.Unlock()
+			select {
+			case <-pc.dialed:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			p.mu.Lock()
+			if pc.err != nil && pc.addr == addr {
+				p.mu.Unlock()
+				return nil, pc.err
+			}
+			continue
+		}
+
+		if pc.addr == addr && pc.cc.conn.GetState() != connectivity.Shutdown {
+			pc.users++
+			pc.lastUsed = time.Now()
+			p.mu.Unlock()
+			return pc.cc, nil
+		}
+
+		// The node moved or the connection was shut down
+		log.Debugf("Replacing connection to node %s at %s:%s", nodeID, pc.addr, port)
+		p.retire(key, pc)
+	}
+
+	// Dial outside of the lock so that connecting to a node does not block
+	// the connections to the other nodes
+	pc := &pooledConn{addr: addr, dialed: make(chan struct{}), users: 1}
+	if p.conns == nil {
+		p.conns = make(map[poolKey]*pooledConn)
+	}
+	p.conns[key] = pc
+	p.mu.Unlock()
+
+	cc, err := p.dial(ctx, nodeID, addr, port, pc)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	close(pc.dialed)
+	if err != nil {
+		pc.err = err
+		if p.conns[key] == pc {
+			delete(p.conns, key)
+		}
+		return nil, err
+	}
+	pc.cc = cc
+	pc.lastUsed = time.Now()
+	log.Debugf("Pooled connection to node %s at %s:%s", nodeID, addr, port)
+
+	return cc, nil
+}
+
+func (p *Pool) dial(ctx context.Context, nodeID, addr, port string, pc *pooledConn) (*ClientConn, error) {
+	conf := p.TLS
+	if conf != nil {
+		conf = conf.Clone()
+		conf.ServerName = nodeID
+	}
+	cc := &ClientConn{
+		Addr: addr,
+		Port: port,
+		TLS:  conf,
+		opts: []ggrpc.DialOption{
+			ggrpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:    keepaliveTime,
+				Timeout: keepaliveTimeout,
+			}),
+			ggrpc.WithBackoffMaxDelay(maxBackoffDelay),
+		},
+		pool:   p,
+		pooled: pc,
+	}
+	if err := cc.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+// release returns a connection acquired with Get to the pool.
+func (p *Pool) release(pc *pooledConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc.users--
+	pc.lastUsed = time.Now()
+	if pc.retired && pc.users == 0 {
+		pc.cc.close()
+	}
+}
+
+// retire removes the connection from the pool and closes it, or leaves it to
+// be closed by its last user.
+func (p *Pool) retire(key poolKey, pc *pooledConn) {
+	delete(p.conns, key)
+	pc.retired = true
+	if pc.users == 0 && pc.cc != nil {
+		pc.cc.close()
+	}
+}
+
+// Invalidate closes the connections to the node, e.g. once it is deleted or
+// re-enrolled. The connections held by callers are closed once released, so
+// that the calls in flight complete.
+func (p *Pool) Invalidate(nodeID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, pc := range p.conns {
+		if key.nodeID == nodeID {
+			p.retire(key, pc)
+		}
+	}
+}
+
+// EvictIdle closes the connections that no caller holds and that have not
+// been used for the idle timeout.
+func (p *Pool) EvictIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, pc := range p.conns {
+		if pc.users == 0 && time.Since(pc.lastUsed) >= p.idleTimeout() {
+			log.Debugf("Closing idle connection to node %s:%s", key.nodeID, key.port)
+			p.retire(key, pc)
+		}
+	}
+}
+
+// Len returns the number of pooled connections.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.conns)
+}
+
+// Run evicts the idle connections until the context is done, then closes all
+// connections.
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.idleTimeout() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.Close()
+			return
+		case <-ticker.C:
+			p.EvictIdle()
+		}
+	}
+}
+
+// Close closes all connections, including the ones held by callers.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, pc := range p.conns {
+		if pc.cc != nil {
+			pc.cc.close()
+		}
+		pc.retired = true
+		delete(p.conns, key)
+	}
+}
+
+func (p *Pool) idleTimeout() time.Duration {
+	if p.IdleTimeout <= 0 {
+		return DefaultIdleTimeout
+	}
+
+	return p.IdleTimeout
+}
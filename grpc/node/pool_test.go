@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package node_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = Describe("Pool", func() {
+	var (
+		ctx  = context.Background()
+		pool *node.Pool
+	)
+
+	BeforeEach(func() {
+		pool = &node.Pool{IdleTimeout: time.Hour}
+	})
+
+	AfterEach(func() {
+		pool.Close()
+	})
+
+	It("Should reuse the connection to the port of a node", func() {
+		cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cc.IfaceSvcCli).ToNot(BeNil())
+
+		By("Disconnecting the pooled connection")
+		cc.Disconnect()
+
+		reused, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reused).To(BeIdenticalTo(cc))
+		Expect(pool.Len()).To(Equal(1))
+	})
+
+	It("Should keep a connection per node and port", func() {
+		ela, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		eva, err := pool.Get(ctx, "node-1", "127.0.0.1", "42102")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(eva.AppDeploySvcCli).ToNot(BeNil())
+		other, err := pool.Get(ctx, "node-2", "127.0.0.2", "42101")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ela).ToNot(BeIdenticalTo(eva))
+		Expect(ela).ToNot(BeIdenticalTo(other))
+		Expect(pool.Len()).To(Equal(3))
+	})
+
+	It("Should replace the connection once the address of the node changes", func() {
+		cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+
+		moved, err := pool.Get(ctx, "node-1", "127.0.0.2", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(moved).ToNot(BeIdenticalTo(cc))
+		Expect(moved.Addr).To(Equal("127.0.0.2"))
+		Expect(pool.Len()).To(Equal(1))
+	})
+
+	It("Should close the connections to an invalidated node", func() {
+		_, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = pool.Get(ctx, "node-1", "127.0.0.1", "42102")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = pool.Get(ctx, "node-2", "127.0.0.2", "42101")
+		Expect(err).ToNot(HaveOccurred())
+
+		pool.Invalidate("node-1")
+		Expect(pool.Len()).To(Equal(1))
+	})
+
+	It("Should evict the idle connections", func() {
+		pool.IdleTimeout = 50 * time.Millisecond
+		cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		cc.Disconnect()
+
+		pool.EvictIdle()
+		Expect(pool.Len()).To(Equal(1))
+
+		time.Sleep(pool.IdleTimeout)
+		pool.EvictIdle()
+		Expect(pool.Len()).To(BeZero())
+	})
+
+	It("Should not evict a connection while it is held", func() {
+		pool.IdleTimeout = 50 * time.Millisecond
+		cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+
+		time.Sleep(pool.IdleTimeout)
+		pool.EvictIdle()
+		Expect(pool.Len()).To(Equal(1))
+		Expect(callCode(cc)).ToNot(Equal(codes.Canceled))
+
+		By("Releasing the connection")
+		cc.Disconnect()
+		pool.EvictIdle()
+		Expect(pool.Len()).To(Equal(1))
+
+		time.Sleep(pool.IdleTimeout)
+		pool.EvictIdle()
+		Expect(pool.Len()).To(BeZero())
+		Expect(callCode(cc)).To(Equal(codes.Canceled))
+	})
+
+	It("Should close an invalidated connection once it is released", func() {
+		cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		shared, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(shared).To(BeIdenticalTo(cc))
+
+		pool.Invalidate("node-1")
+		Expect(pool.Len()).To(BeZero())
+
+		By("Verifying the calls in flight are not cut")
+		shared.Disconnect()
+		Expect(callCode(cc)).ToNot(Equal(codes.Canceled))
+
+		By("Verifying the connection is closed by its last user")
+		cc.Disconnect()
+		Expect(callCode(cc)).To(Equal(codes.Canceled))
+
+		By("Verifying a new connection is dialed")
+		reconnected, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reconnected).ToNot(BeIdenticalTo(cc))
+	})
+
+	It("Should dial a connection once for concurrent callers", func() {
+		ccs := make(chan *node.ClientConn, 10)
+		for i := 0; i < cap(ccs); i++ {
+			go func() {
+				defer GinkgoRecover()
+				cc, err := pool.Get(ctx, "node-1", "127.0.0.1", "42101")
+				Expect(err).ToNot(HaveOccurred())
+				ccs <- cc
+			}()
+		}
+
+		first := <-ccs
+		for i := 1; i < cap(ccs); i++ {
+			Expect(<-ccs).To(BeIdenticalTo(first))
+		}
+		Expect(pool.Len()).To(Equal(1))
+	})
+})
+
+// callCode returns the code of a call over the connection. There is no node
+// at the other end, so the call fails, with codes.Canceled if the connection
+// is closed.
+func callCode(cc *node.ClientConn) codes.Code {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := cc.IfaceSvcCli.GetAll(ctx)
+	return status.Code(errors.Cause(err))
+}
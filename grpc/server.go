@@ -5,14 +5,14 @@ package grpc
 
 import (
 	"context"
-	"crypto/md5" //nolint:gosec
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/base64"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"net"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -24,6 +24,7 @@ import (
 	cce "github.com/open-ness/edgecontroller"
 	authpb "github.com/open-ness/edgecontroller/pb/auth"
 	evapb "github.com/open-ness/edgecontroller/pb/eva"
+	"github.com/open-ness/edgecontroller/pki"
 	"github.com/open-ness/edgecontroller/uuid"
 )
 
@@ -42,6 +43,10 @@ const (
 	// receive a certificate. It is similar to how a REST app may require a
 	// session token for API paths other than /login.
 	enrollmentMethod = "/openness.auth.AuthService/RequestCredentials"
+
+	// renewalMethod is the gRPC full RPC path for renewing the credentials of
+	// an enrolled node.
+	renewalMethod = "/openness.auth.AuthService/RenewCredentials"
 )
 
 // Server wraps grpc.Server
@@ -65,7 +70,7 @@ func NewServer(controller *cce.Controller, conf *tls.Config) *Server {
 				) (resp interface{}, err error) {
 					// apply checkAuth middleware
 					if err := checkAuth(ctx,
-						info.FullMethod); err != nil {
+						controller.RevokedCertificates, info.FullMethod); err != nil {
 						return nil, err
 					}
 					return handler(ctx, req)
@@ -80,7 +85,7 @@ func NewServer(controller *cce.Controller, conf *tls.Config) *Server {
 				) error {
 					// apply checkAuth middleware
 					if err := checkAuth(ss.Context(),
-						info.FullMethod); err != nil {
+						controller.RevokedCertificates, info.FullMethod); err != nil {
 						return err
 					}
 					return handler(srv, ss)
@@ -97,8 +102,10 @@ func NewServer(controller *cce.Controller, conf *tls.Config) *Server {
 
 // checkAuth is a middleware, applied inside the unary and stream interceptors,
 // to ensure that if the enrollment server config was used (i.e. no client cert
-// was provided) that only the enrollment endpoint is authorized.
-func checkAuth(ctx context.Context, method string) error {
+// was provided) that only the enrollment endpoint is authorized. Connections
+// with a client certificate are rejected once the certificate is revoked, as
+// they may have been established before.
+func checkAuth(ctx context.Context, revoked *pki.RevocationList, method string) error {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
 		return fmt.Errorf("expected peer info in gRPC context")
@@ -115,6 +122,10 @@ func checkAuth(ctx context.Context, method string) error {
 		}
 		return nil
 	case SNI:
+		certs := tlsInfo.State.PeerCertificates
+		if len(certs) > 0 && revoked.IsRevoked(certs[0].SerialNumber) {
+			return status.Errorf(codes.Unauthenticated, "certificate %x has been revoked", certs[0].SerialNumber)
+		}
 		return nil
 	default:
 		return fmt.Errorf("unexpected server name: %s", tlsInfo.State.ServerName)
@@ -137,11 +148,262 @@ func (s *Server) Stop() {
 }
 
 // RequestCredentials requests authentication endpoint credentials.
-func (s *Server) RequestCredentials(ctx context.Context, id *authpb.Identity) ( // nolint: gocyclo
-	*authpb.Credentials,
-	error,
+func (s *Server) RequestCredentials(ctx context.Context, id *authpb.Identity) (
+	_ *authpb.Credentials,
+	err error,
 ) {
-	// Parse and validate CSR
+	// Record the enrollment attempt in the audit log
+	event := cce.NewAuditEvent(cce.AuditActorNodePrefix, "gRPC", enrollmentMethod)
+	defer func() { s.audit(ctx, event, err) }()
+
+	certReq, err := parseCSR(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Node's identity is base64-encoded (w/o padding) SHA-256 hash of the public key data
+	serial := cce.NodeSerial(certReq.RawSubjectPublicKeyInfo)
+	event.Actor = cce.AuditActorNodePrefix + serial
+
+	// Verify the Node's pre-approval by public key data
+	node, err := s.approvedNode(ctx, certReq.RawSubjectPublicKeyInfo)
+	if err != nil {
+		log.Errf("error getting node approval: %v", err)
+		return nil, status.Errorf(codes.Unauthenticated, "node %s not approved", serial)
+	}
+	if node == nil {
+		if s.controller.PendingApproval {
+			s.queuePendingNode(ctx, serial)
+			return nil, status.Errorf(codes.Unauthenticated, "node %s pending approval", serial)
+		}
+		return nil, status.Errorf(codes.Unauthenticated, "node %s not approved", serial)
+	}
+	event.EntityID = node.ID
+
+	// Refuse to replace the credentials of an enrolled node unless its
+	// re-enrollment was requested
+	enrolled, err := cce.CurrentCredentials(ctx, s.controller.PersistenceService, node.ID)
+	if err != nil {
+		log.Errf("error getting node credentials: %v", err)
+		return nil, status.Error(codes.Internal, "unable to get credentials")
+	}
+	if enrolled != nil {
+		return nil, status.Errorf(codes.AlreadyExists,
+			"node %s already enrolled, re-enrollment must be requested", serial)
+	}
+
+	creds, resp, err := s.issueCredentials(ctx, certReq, node.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the Node's IP address
+	nodeIP, err := getPeerIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store the Node's address before its credentials, as once the
+	// credentials are stored the node cannot enroll again
+	nodeWithTarget := &cce.NodeGRPCTarget{
+		ID:         uuid.New(),
+		NodeID:     node.ID,
+		GRPCTarget: nodeIP,
+	}
+	if err = s.controller.PersistenceService.Create(ctx, nodeWithTarget); err != nil {
+		log.Errf("Failed to store Node address: %v", err)
+		return nil, status.Error(codes.Internal, "unable to store node address")
+	}
+	if err = s.controller.PersistenceService.Create(ctx, creds); err != nil {
+		log.Errf("Failed to store Node credentials: %v", err)
+		if _, err = s.controller.PersistenceService.Delete(
+			ctx, nodeWithTarget.ID, &cce.NodeGRPCTarget{}); err != nil {
+			log.Errf("Failed to delete Node address %s: %v", nodeWithTarget.ID, err)
+		}
+		return nil, status.Error(codes.Internal, "unable to store credentials")
+	}
+	if event.After, err = json.Marshal(nodeWithTarget); err != nil {
+		log.Errf("Failed to marshal Node address: %v", err)
+		event.After, err = nil, nil
+	}
+	// Also let the proxy node we have a new client
+	cce.RegisterToProxy(ctx, s.controller.PersistenceService, node.ID)
+
+	if s.controller.NodeEnrolled != nil {
+		s.controller.NodeEnrolled(node.ID)
+	}
+
+	return resp, nil
+}
+
+// approvedNode returns the node approved by the serial of the public key, or
+// nil if there is none. While legacy serials are accepted, a node approved by
+// the legacy serial of the key is returned with its serial rewritten.
+func (s *Server) approvedNode(ctx context.Context, publicKeyDER []byte) (*cce.Node, error) {
+	serial := cce.NodeSerial(publicKeyDER)
+	entities, err := s.controller.PersistenceService.Filter(ctx, &cce.Node{}, []cce.Filter{{
+		Field: "serial",
+		Value: serial,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) > 0 {
+		return entities[0].(*cce.Node), nil
+	}
+	if !s.controller.LegacyNodeSerials {
+		return nil, nil
+	}
+
+	legacySerial := cce.LegacyNodeSerial(publicKeyDER)
+	if entities, err = s.controller.PersistenceService.Filter(ctx, &cce.Node{}, []cce.Filter{{
+		Field: "serial",
+		Value: legacySerial,
+	}}); err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	node := entities[0].(*cce.Node)
+	node.Serial = serial
+	if err = s.controller.PersistenceService.BulkUpdate(ctx, []cce.Persistable{node}); err != nil {
+		return nil, err
+	}
+	log.Infof("Rewrote legacy serial %s of node %s to %s", legacySerial, node.ID, serial)
+
+	return node, nil
+}
+
+// queuePendingNode records the enrollment request of a node that is not
+// approved, unless it is already queued, so that an operator can approve it.
+func (s *Server) queuePendingNode(ctx context.Context, serial string) {
+	pending, err := s.controller.PersistenceService.Filter(ctx, &cce.PendingNode{}, []cce.Filter{{
+		Field: "serial",
+		Value: serial,
+	}})
+	if err != nil {
+		log.Errf("error getting pending node: %v", err)
+		return
+	}
+	if len(pending) > 0 {
+		return
+	}
+
+	// The address is informational only, the node is identified by its serial
+	nodeIP, err := getPeerIP(ctx)
+	if err != nil {
+		log.Warningf("Queuing node %s without address: %v", serial, err)
+	}
+
+	if err = s.controller.PersistenceService.Create(ctx, &cce.PendingNode{
+		ID:          uuid.New(),
+		Serial:      serial,
+		Address:     nodeIP,
+		RequestedAt: time.Now().UTC(),
+	}); err != nil {
+		log.Errf("Failed to queue pending node %s: %v", serial, err)
+		return
+	}
+	log.Infof("Node %s is pending approval", serial)
+}
+
+// RenewCredentials renews the credentials of an enrolled node before its
+// certificate expires. The node must authenticate with its current
+// certificate and the certificate must be within the renewal window. The
+// replaced certificate is revoked.
+func (s *Server) RenewCredentials(ctx context.Context, id *authpb.Identity) (
+	_ *authpb.Credentials,
+	err error,
+) {
+	// Record the renewal attempt in the audit log
+	event := cce.NewAuditEvent(cce.AuditActorNodePrefix, "gRPC", renewalMethod)
+	defer func() { s.audit(ctx, event, err) }()
+
+	cert, err := getPeerCertificate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeID := cert.Subject.CommonName
+	event.Actor = cce.AuditActorNodePrefix + nodeID
+	event.EntityID = nodeID
+
+	certReq, err := parseCSR(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the current certificate of the node can be renewed
+	current, err := cce.CurrentCredentials(ctx, s.controller.PersistenceService, nodeID)
+	if err != nil {
+		log.Errf("error getting node credentials: %v", err)
+		return nil, status.Error(codes.Internal, "unable to get credentials")
+	}
+	if current == nil {
+		return nil, status.Errorf(codes.PermissionDenied, "node %s not enrolled", nodeID)
+	}
+	currentCert, err := current.ParseCertificate()
+	if err != nil {
+		log.Errf("Failed to parse Node credentials %s: %v", current.ID, err)
+		return nil, status.Error(codes.Internal, "unable to get credentials")
+	}
+	if currentCert.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+		return nil, status.Errorf(codes.PermissionDenied,
+			"certificate %x of node %s has been superseded", cert.SerialNumber, nodeID)
+	}
+	if !s.controller.CredentialsPolicy.CanRenew(cert, time.Now()) {
+		return nil, status.Errorf(codes.FailedPrecondition,
+			"certificate %x of node %s is not due for renewal until %s", cert.SerialNumber, nodeID,
+			cert.NotAfter.Add(-s.controller.CredentialsPolicy.RenewalWindow).UTC().Format(time.RFC3339))
+	}
+
+	creds, resp, err := s.issueCredentials(ctx, certReq, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	// Keep the replaced credentials as history and revoke their certificate,
+	// so that only the renewed certificate authenticates the node
+	current.Superseded = true
+	revoked := &cce.RevokedCertificate{
+		ID:           uuid.New(),
+		NodeID:       nodeID,
+		SerialNumber: currentCert.SerialNumber.Text(16),
+		RevokedAt:    time.Now().UTC(),
+	}
+	if err = s.controller.PersistenceService.BulkWrite(
+		ctx,
+		[]cce.Persistable{creds, revoked},
+		[]cce.Persistable{current},
+	); err != nil {
+		log.Errf("Failed to store Node credentials: %v", err)
+		return nil, status.Error(codes.Internal, "unable to store credentials")
+	}
+	if s.controller.RevokedCertificates != nil {
+		s.controller.RevokedCertificates.Revoke(currentCert.SerialNumber)
+	}
+	log.Infof("Revoked certificate %s of node %s superseded by renewal", revoked.SerialNumber, nodeID)
+
+	return resp, nil
+}
+
+// getPeerIP returns the IP address of the peer of the RPC.
+func getPeerIP(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Internal, "missing peer data from context")
+	}
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if ip == "" || err != nil {
+		return "", status.Errorf(codes.Internal, "bad remote address in peer data: %s: %v",
+			p.Addr.String(), err)
+	}
+
+	return ip, nil
+}
+
+// parseCSR parses and validates the CSR of the identity.
+func parseCSR(id *authpb.Identity) (*x509.CertificateRequest, error) {
 	csr := id.GetCsr()
 	if csr == "" {
 		return nil, status.Error(codes.InvalidArgument, "CSR cannot be empty")
@@ -163,33 +425,27 @@ func (s *Server) RequestCredentials(ctx context.Context, id *authpb.Identity) (
 		return nil, status.Errorf(codes.InvalidArgument, "error validating CSR: %v", err)
 	}
 
-	// Node's identity is base64-encoded (w/o padding) MD5 hash of the public key data
-	// gosec: not hashing user input/passwords
-	hash := md5.Sum(certReq.RawSubjectPublicKeyInfo) //nolint:gosec
-	serial := base64.RawURLEncoding.EncodeToString(hash[:])
-
-	// Verify the Node's pre-approval by public key data
-	entities, err := s.controller.PersistenceService.Filter(ctx, &cce.Node{}, []cce.Filter{{
-		Field: "serial",
-		Value: serial,
-	}})
-	if err != nil || len(entities) == 0 {
-		if err != nil {
-			log.Errf("error getting node approval: %v", err)
-		}
-		return nil, status.Errorf(codes.Unauthenticated, "node %s not approved", serial)
-	}
-	node := entities[0].(*cce.Node)
+	return certReq, nil
+}
 
+// issueCredentials signs the certificate request of the node according to the
+// credentials policy. It returns the credentials to store and the response
+// with the CA chain.
+func (s *Server) issueCredentials(
+	ctx context.Context,
+	certReq *x509.CertificateRequest,
+	nodeID string,
+) (*cce.Credentials, *authpb.Credentials, error) {
 	// Sign cert request
 	cert, err := s.controller.AuthorityService.SignCSR(
 		certReq.Raw,
 		&x509.Certificate{
-			Subject: pkix.Name{CommonName: node.ID},
+			Subject:  pkix.Name{CommonName: nodeID},
+			NotAfter: s.controller.CredentialsPolicy.NotAfter(time.Now()),
 		})
 	if err != nil {
 		log.Errf("Failed to sign CSR: %v", err)
-		return nil, status.Error(codes.Internal, "unable to sign CSR")
+		return nil, nil, status.Error(codes.Internal, "unable to sign CSR")
 	}
 	certPEM := pem.EncodeToMemory(
 		&pem.Block{
@@ -200,11 +456,11 @@ func (s *Server) RequestCredentials(ctx context.Context, id *authpb.Identity) (
 	// Get signer chain for response
 	caChain, err := s.controller.AuthorityService.CAChain()
 	if err != nil {
-		return nil, status.Error(codes.Internal, "unable to get CA chain")
+		return nil, nil, status.Error(codes.Internal, "unable to get CA chain")
 	}
 	if len(caChain) == 0 {
 		log.Errf("Failed to get CA chain: CA chain is empty")
-		return nil, status.Error(codes.Internal, "CA chain is empty")
+		return nil, nil, status.Error(codes.Internal, "CA chain is empty")
 	}
 
 	// Encode each certificate in CA chain in PEM
@@ -222,47 +478,32 @@ func (s *Server) RequestCredentials(ctx context.Context, id *authpb.Identity) (
 	// Add the root CA to the Node's CA pool
 	caPoolPEM := chainPEM[len(chainPEM)-1:]
 
-	// Store Node credentials
 	creds := &cce.Credentials{
-		ID:          node.ID,
+		ID:          uuid.New(),
+		NodeID:      nodeID,
 		Certificate: string(certPEM),
 	}
-	if err = s.controller.PersistenceService.Create(ctx, creds); err != nil {
-		log.Errf("Failed to store Node credentials: %v", err)
-		return nil, status.Error(codes.Internal, "unable to store credentials")
-	}
-
-	// Get the Node's IP address
-	p, ok := peer.FromContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Internal, "missing peer data from context")
-	}
-	nodeIP, _, err := net.SplitHostPort(p.Addr.String())
-	if nodeIP == "" || err != nil {
-		return nil, status.Errorf(codes.Internal, "bad remote address in peer data: %s: %v",
-			p.Addr.String(), err)
-	}
 
-	// Store the Node's address
-	nodeWithTarget := &cce.NodeGRPCTarget{
-		ID:         uuid.New(),
-		NodeID:     node.ID,
-		GRPCTarget: nodeIP,
-	}
-	if err := s.controller.PersistenceService.Create(ctx, nodeWithTarget); err != nil {
-		log.Errf("Failed to store Node address: %v", err)
-		return nil, status.Error(codes.Internal, "unable to store node address")
-	}
-	// Also let the proxy node we have a new client
-	cce.RegisterToProxy(ctx, s.controller.PersistenceService, node.ID)
-
-	return &authpb.Credentials{
+	return creds, &authpb.Credentials{
 		Certificate: creds.Certificate,
 		CaChain:     chainPEM,
 		CaPool:      caPoolPEM,
 	}, nil
 }
 
+// audit records the audit event of an RPC that returned err.
+func (s *Server) audit(ctx context.Context, event *cce.AuditEvent, err error) {
+	event.Status = int(status.Code(err))
+	event.Result = cce.AuditResultSuccess
+	if err != nil {
+		event.Result = cce.AuditResultFailure
+	}
+
+	if err = s.controller.PersistenceService.Create(ctx, event); err != nil {
+		log.Errf("Failed to record audit event %s: %v", event, err)
+	}
+}
+
 // GetContainerByIP retrieves info of deployed application with IP provided
 func (s *Server) GetContainerByIP(ctx context.Context, containerIP *evapb.ContainerIP) (*evapb.ContainerInfo, error) {
 	nodeID, err := getNodeID(ctx)
@@ -285,26 +526,37 @@ func (s *Server) GetContainerByIP(ctx context.Context, containerIP *evapb.Contai
 // getNodeID extracts the node info from the client TLS certificate. A context
 // from a gRPC endpoint must be passed.
 func getNodeID(ctx context.Context) (string, error) {
+	cert, err := getPeerCertificate(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cert.Subject.CommonName, nil
+}
+
+// getPeerCertificate extracts the verified client TLS certificate of a node.
+// A context from a gRPC endpoint must be passed.
+func getPeerCertificate(ctx context.Context) (*x509.Certificate, error) {
 	p, ok := peer.FromContext(ctx)
 	if !ok {
-		return "", status.Error(codes.FailedPrecondition,
+		return nil, status.Error(codes.FailedPrecondition,
 			"gRPC call missing peer context")
 	}
 	authInfo, ok := p.AuthInfo.(credentials.TLSInfo)
 	if !ok {
-		return "", status.Error(codes.FailedPrecondition,
+		return nil, status.Error(codes.FailedPrecondition,
 			"gRPC peer missing TLS auth info")
 	}
 	chains := authInfo.State.VerifiedChains
 	if len(chains) < 1 {
-		return "", status.Error(codes.Unauthenticated,
+		return nil, status.Error(codes.Unauthenticated,
 			"gRPC peer was not authenticated with a client TLS certificate")
 	}
-	nodeID := chains[0][0].Subject.CommonName
-	if nodeID == "" {
-		return "", status.Error(codes.FailedPrecondition,
+	cert := chains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, status.Error(codes.FailedPrecondition,
 			"gRPC peer connected with a client TLS cert with no Common Name")
 	}
 
-	return nodeID, nil
+	return cert, nil
 }
@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+// Package health probes the connectivity of the enrolled nodes and records
+// their status.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	ggrpc "google.golang.org/grpc"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "health")
+
+// Defaults of the prober settings.
+const (
+	DefaultInterval        = time.Minute
+	DefaultDegradedLatency = 500 * time.Millisecond
+
+	defaultELAPort = "42101"
+	defaultEVAPort = "42102"
+)
+
+// DialFunc connects to the port of the node at the address and returns once
+// the connection is established.
+type DialFunc func(ctx context.Context, nodeID, addr, port string) error
+
+// Prober periodically connects to the ELA and EVA ports of every enrolled node
+// and records the node's status, latency and when it was last seen. A change
+// of status is recorded as a NodeStatusEvent.
+type Prober struct {
+	Controller *cce.Controller
+	// Interval is the period between two probes of the nodes.
+	Interval time.Duration
+	// DegradedLatency is the latency above which a reachable node is
+	// degraded.
+	DegradedLatency time.Duration
+	// Dial connects to a node. If nil the node is dialed over gRPC with the
+	// controller's edge node credentials.
+	Dial DialFunc
+}
+
+// Run probes the nodes every interval until the context is done.
+func (p *Prober) Run(ctx context.Context) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := p.ProbeAll(ctx); err != nil {
+			log.Errf("Error probing nodes: %v", err)
+		}
+	}
+}
+
+// ProbeAll probes every enrolled node once.
+func (p *Prober) ProbeAll(ctx context.Context) error {
+	targets, err := p.Controller.PersistenceService.ReadAll(ctx, &cce.NodeGRPCTarget{})
+	if err != nil {
+		return errors.Wrap(err, "error reading node addresses")
+	}
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(target *cce.NodeGRPCTarget) {
+			defer wg.Done()
+			if err := p.probe(ctx, target); err != nil {
+				log.Errf("Error probing node %s: %v", target.NodeID, err)
+			}
+		}(t.(*cce.NodeGRPCTarget))
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// probe probes the node of the target and records its health.
+func (p *Prober) probe(ctx context.Context, target *cce.NodeGRPCTarget) error {
+	elaPort, evaPort := p.ports()
+	elaLatency, elaErr := p.dial(ctx, target, elaPort)
+	evaLatency, evaErr := p.dial(ctx, target, evaPort)
+
+	health, err := p.health(ctx, target.NodeID)
+	if err != nil {
+		return err
+	}
+	previous := health.Status
+
+	now := time.Now().UTC()
+	health.CheckedAt = now
+	switch {
+	case elaErr != nil && evaErr != nil:
+		log.Debugf("Node %s unreachable: %v; %v", target.NodeID, elaErr, evaErr)
+		health.Status = cce.NodeStatusOffline
+	case elaErr != nil || evaErr != nil:
+		log.Debugf("Node %s partially reachable: %v", target.NodeID, firstErr(elaErr, evaErr))
+		health.Status = cce.NodeStatusDegraded
+		health.LastSeen = now
+		health.Latency = maxDuration(elaLatency, evaLatency)
+	default:
+		health.Status = cce.NodeStatusOnline
+		health.LastSeen = now
+		health.Latency = maxDuration(elaLatency, evaLatency)
+		if health.Latency > p.degradedLatency() {
+			health.Status = cce.NodeStatusDegraded
+		}
+	}
+
+	if previous == "" {
+		err = p.Controller.PersistenceService.Create(ctx, health)
+	} else {
+		err = p.Controller.PersistenceService.BulkUpdate(ctx, []cce.Persistable{health})
+	}
+	if err != nil {
+		return errors.Wrap(err, "error storing node health")
+	}
+
+	if health.Status != previous {
+		log.Infof("Node %s is %s", target.NodeID, health.Status)
+		if err = p.Controller.PersistenceService.Create(ctx, &cce.NodeStatusEvent{
+			ID:        uuid.New(),
+			NodeID:    target.NodeID,
+			From:      previous,
+			To:        health.Status,
+			Timestamp: now,
+		}); err != nil {
+			return errors.Wrap(err, "error storing node status event")
+		}
+	}
+
+	return nil
+}
+
+// health returns the persisted health of the node, or a new health with an
+// empty status if the node was never probed.
+func (p *Prober) health(ctx context.Context, nodeID string) (*cce.NodeHealth, error) {
+	persisted, err := p.Controller.PersistenceService.Filter(
+		ctx,
+		&cce.NodeHealth{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node health")
+	}
+	if len(persisted) > 0 {
+		return persisted[0].(*cce.NodeHealth), nil
+	}
+
+	return &cce.NodeHealth{ID: uuid.New(), NodeID: nodeID}, nil
+}
+
+// dial connects to the port of the node and returns how long it took.
+func (p *Prober) dial(ctx context.Context, target *cce.NodeGRPCTarget, port string) (time.Duration, error) {
+	dial := p.Dial
+	if dial == nil {
+		dial = p.dialGRPC
+	}
+
+	start := time.Now()
+	if err := dial(ctx, target.NodeID, target.GRPCTarget, port); err != nil {
+		return 0, err
+	}
+
+	return time.Since(start), nil
+}
+
+// dialGRPC establishes a gRPC connection to the port of the node through the
+// connections the node opened to the controller.
+func (p *Prober) dialGRPC(ctx context.Context, nodeID, addr, port string) error {
+	conf := p.Controller.EdgeNodeCreds
+	if conf != nil {
+		conf = conf.Clone()
+		conf.ServerName = nodeID
+	}
+
+	_, evaPort := p.ports()
+	dialer := cce.PrefaceLis.DialEla
+	if port == evaPort {
+		dialer = cce.PrefaceLis.DialEva
+	}
+
+	// OP-1742: ContextDialler not supported by Gateway
+	//nolint:staticcheck
+	conn, err := grpc.Dial(ctx, addr, conf, ggrpc.WithDialer(dialer), ggrpc.WithBlock())
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+func (p *Prober) ports() (ela, eva string) {
+	ela, eva = p.Controller.ELAPort, p.Controller.EVAPort
+	if ela == "" {
+		ela = defaultELAPort
+	}
+	if eva == "" {
+		eva = defaultEVAPort
+	}
+
+	return ela, eva
+}
+
+func (p *Prober) degradedLatency() time.Duration {
+	if p.DegradedLatency <= 0 {
+		return DefaultDegradedLatency
+	}
+
+	return p.DegradedLatency
+}
+
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+
+	return b
+}
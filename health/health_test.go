@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package health_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/health"
+	"github.com/open-ness/edgecontroller/uuid"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Prober", func() {
+	var (
+		ctx    = context.Background()
+		tmpDir string
+		db     *bbolt.DB
+		ps     *bolt.PersistenceService
+		node   *cce.Node
+		prober *health.Prober
+
+		// unreachable are the ports the fake dialer fails to connect to
+		unreachable map[string]bool
+		// latency is how long the fake dialer takes to connect
+		latency time.Duration
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		By("Opening a db in a temp directory")
+		tmpDir, err = ioutil.TempDir("", "health_test")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bbolt.Open(filepath.Join(tmpDir, "controller_ce.db"), 0600, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		By("Creating an enrolled node")
+		node = &cce.Node{
+			ID:       uuid.New(),
+			Name:     "test-node",
+			Location: "test-location",
+			Serial:   "test-serial",
+		}
+		Expect(ps.Create(ctx, node)).To(Succeed())
+		Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+			ID:         uuid.New(),
+			NodeID:     node.ID,
+			GRPCTarget: "127.0.0.1",
+		})).To(Succeed())
+
+		unreachable = map[string]bool{}
+		latency = 0
+		prober = &health.Prober{
+			Controller:      &cce.Controller{PersistenceService: ps},
+			DegradedLatency: 50 * time.Millisecond,
+			Dial: func(ctx context.Context, nodeID, addr, port string) error {
+				Expect(nodeID).To(Equal(node.ID))
+				Expect(addr).To(Equal("127.0.0.1"))
+				time.Sleep(latency)
+				if unreachable[port] {
+					return errors.New("connection refused")
+				}
+				return nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	nodeHealth := func() *cce.NodeHealth {
+		persisted, err := ps.Filter(ctx, &cce.NodeHealth{}, []cce.Filter{{Field: "node_id", Value: node.ID}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(persisted).To(HaveLen(1))
+		return persisted[0].(*cce.NodeHealth)
+	}
+
+	statusEvents := func() []string {
+		persisted, err := ps.Filter(ctx, &cce.NodeStatusEvent{}, []cce.Filter{{Field: "node_id", Value: node.ID}})
+		Expect(err).ToNot(HaveOccurred())
+		var events []string
+		for _, e := range persisted {
+			events = append(events, e.(*cce.NodeStatusEvent).From+"->"+e.(*cce.NodeStatusEvent).To)
+		}
+		return events
+	}
+
+	It("Should record a reachable node as online", func() {
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+
+		h := nodeHealth()
+		Expect(h.Status).To(Equal(cce.NodeStatusOnline))
+		Expect(h.LastSeen).To(Equal(h.CheckedAt))
+		Expect(statusEvents()).To(ConsistOf("->online"))
+	})
+
+	It("Should record a node reachable on one port as degraded", func() {
+		unreachable["42102"] = true
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+
+		Expect(nodeHealth().Status).To(Equal(cce.NodeStatusDegraded))
+	})
+
+	It("Should record a slow node as degraded", func() {
+		latency = 60 * time.Millisecond
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+
+		h := nodeHealth()
+		Expect(h.Status).To(Equal(cce.NodeStatusDegraded))
+		Expect(h.Latency).To(BeNumerically(">=", latency))
+	})
+
+	It("Should record an unreachable node as offline and keep when it was last seen", func() {
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+		lastSeen := nodeHealth().LastSeen
+
+		unreachable["42101"] = true
+		unreachable["42102"] = true
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+
+		h := nodeHealth()
+		Expect(h.Status).To(Equal(cce.NodeStatusOffline))
+		Expect(h.LastSeen).To(BeTemporally("==", lastSeen))
+		Expect(h.CheckedAt).To(BeTemporally(">", lastSeen))
+		Expect(statusEvents()).To(ConsistOf("->online", "online->offline"))
+	})
+
+	It("Should not record an event if the status did not change", func() {
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+
+		Expect(statusEvents()).To(ConsistOf("->online"))
+	})
+
+	It("Should delete the health of a deleted node", func() {
+		Expect(prober.ProbeAll(ctx)).To(Succeed())
+		_, err := ps.Delete(ctx, node.ID, &cce.Node{})
+		Expect(err).ToNot(HaveOccurred())
+
+		persisted, err := ps.ReadAll(ctx, &cce.NodeHealth{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(persisted).To(BeEmpty())
+		Expect(statusEvents()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// InterfaceProfile is a template of the configuration of the network
+// interfaces of many nodes. The settings of the profile are applied to the
+// interfaces of the targeted nodes that match the profile.
+type InterfaceProfile struct {
+	ID    string            `json:"id"`
+	Name  string            `json:"name"`
+	Match InterfaceMatch    `json:"match"`
+	Set   InterfaceSettings `json:"set"`
+	// NodeIDs and Selector target the nodes the profile is attached to: the
+	// nodes listed by ID and the nodes whose labels match the selector.
+	NodeIDs  []string `json:"node_ids,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+}
+
+// InterfaceMatch matches the network interfaces whose fields match every set
+// criterion. The PCI and Description criteria are shell patterns as
+// understood by path.Match; the PCI pattern matches the ID of the interface,
+// which is its PCI address.
+type InterfaceMatch struct {
+	Driver      string `json:"driver,omitempty"`
+	Type        string `json:"type,omitempty"`
+	PCI         string `json:"pci,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// InterfaceSettings are the settings of the matched network interfaces. The
+// fields that are not set are left unchanged; an empty, non-nil Zones removes
+// the interfaces from their zones.
+type InterfaceSettings struct {
+	Driver            string   `json:"driver,omitempty"`
+	VLAN              *int     `json:"vlan,omitempty"`
+	Zones             []string `json:"zones"`
+	FallbackInterface *string  `json:"fallback_interface,omitempty"`
+}
+
+// InterfaceChange is a change of a field of a network interface.
+type InterfaceChange struct {
+	InterfaceID string `json:"interface_id"`
+	Field       string `json:"field"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*InterfaceProfile) GetTableName() string {
+	return "interface_profiles"
+}
+
+// GetID gets the ID.
+func (p *InterfaceProfile) GetID() string {
+	return p.ID
+}
+
+// SetID sets the ID.
+func (p *InterfaceProfile) SetID(id string) {
+	p.ID = id
+}
+
+// Validate validates the model.
+func (p *InterfaceProfile) Validate() error { // nolint: gocyclo
+	if !uuid.IsValid(p.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if p.Match == (InterfaceMatch{}) {
+		return errors.New("match cannot be empty")
+	}
+	if err := validateInterfaceDriver("match.driver", p.Match.Driver); err != nil {
+		return err
+	}
+	switch p.Match.Type {
+	case "", "none", "upstream", "downstream", "bidirectional", "breakout":
+	default:
+		return errors.New("match.type must be one of [none, upstream, downstream, bidirectional, breakout]")
+	}
+	if _, err := path.Match(p.Match.PCI, ""); err != nil {
+		return fmt.Errorf("match.pci pattern %q is invalid", p.Match.PCI)
+	}
+	if _, err := path.Match(p.Match.Description, ""); err != nil {
+		return fmt.Errorf("match.description pattern %q is invalid", p.Match.Description)
+	}
+	if p.Set.Driver == "" && p.Set.VLAN == nil && p.Set.Zones == nil && p.Set.FallbackInterface == nil {
+		return errors.New("set cannot be empty")
+	}
+	if err := validateInterfaceDriver("set.driver", p.Set.Driver); err != nil {
+		return err
+	}
+	if p.Set.VLAN != nil && (*p.Set.VLAN < 0 || *p.Set.VLAN > 255) {
+		return errors.New("set.vlan must be in [0..255]")
+	}
+	for i, id := range p.Set.Zones {
+		if err := (&NetworkZone{ID: id}).Validate(); err != nil {
+			return fmt.Errorf("set.zones[%d] %v", i, err)
+		}
+	}
+	for i, id := range p.NodeIDs {
+		if !uuid.IsValid(id) {
+			return fmt.Errorf("node_ids[%d] not a valid uuid", i)
+		}
+	}
+	if _, err := ParseSelector(p.Selector); err != nil {
+		return err
+	}
+	if len(p.NodeIDs) == 0 && p.Selector == "" {
+		return errors.New("node_ids or selector is required")
+	}
+
+	return nil
+}
+
+func validateInterfaceDriver(field, driver string) error {
+	switch driver {
+	case "", "kernel", "userspace":
+		return nil
+	default:
+		return fmt.Errorf("%s must be one of [kernel, userspace]", field)
+	}
+}
+
+// Targets returns whether the profile is attached to the node.
+func (p *InterfaceProfile) Targets(n *Node) bool {
+	for _, id := range p.NodeIDs {
+		if id == n.ID {
+			return true
+		}
+	}
+	if p.Selector == "" {
+		return false
+	}
+	sel, err := ParseSelector(p.Selector)
+
+	return err == nil && sel.Matches(n.Labels)
+}
+
+// Matches returns whether the network interface matches the profile.
+func (p *InterfaceProfile) Matches(ni *NetworkInterface) bool {
+	m := p.Match
+	if m.Driver != "" && m.Driver != ni.Driver {
+		return false
+	}
+	if m.Type != "" && m.Type != ni.Type {
+		return false
+	}
+	if m.PCI != "" {
+		if ok, _ := path.Match(m.PCI, ni.ID); !ok {
+			return false
+		}
+	}
+	if m.Description != "" {
+		if ok, _ := path.Match(m.Description, ni.Description); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply returns the network interfaces with the settings of the profile
+// applied to those matching it, and the changes that were made. The
+// interfaces passed in are not modified.
+func (p *InterfaceProfile) Apply(ifaces []*NetworkInterface) ([]*NetworkInterface, []InterfaceChange) {
+	updated := make([]*NetworkInterface, 0, len(ifaces))
+	changes := []InterfaceChange{}
+	for _, ni := range ifaces {
+		u := *ni
+		updated = append(updated, &u)
+		if !p.Matches(ni) {
+			continue
+		}
+
+		change := func(field, from, to string) {
+			if from != to {
+				changes = append(changes, InterfaceChange{InterfaceID: ni.ID, Field: field, From: from, To: to})
+			}
+		}
+		if p.Set.Driver != "" {
+			change("driver", u.Driver, p.Set.Driver)
+			u.Driver = p.Set.Driver
+		}
+		if p.Set.VLAN != nil {
+			change("vlan", strconv.Itoa(u.VLAN), strconv.Itoa(*p.Set.VLAN))
+			u.VLAN = *p.Set.VLAN
+		}
+		if p.Set.Zones != nil {
+			zones := append([]string(nil), p.Set.Zones...)
+			sort.Strings(zones)
+			current := append([]string(nil), u.Zones...)
+			sort.Strings(current)
+			change("zones", strings.Join(current, ","), strings.Join(zones, ","))
+			u.Zones = append([]string{}, p.Set.Zones...)
+		}
+		if p.Set.FallbackInterface != nil {
+			change("fallback_interface", u.FallbackInterface, *p.Set.FallbackInterface)
+			u.FallbackInterface = *p.Set.FallbackInterface
+		}
+	}
+
+	return updated, changes
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*InterfaceProfile) FilterFields() []string {
+	return []string{
+		"name",
+	}
+}
+
+func (p *InterfaceProfile) String() string {
+	vlan := ""
+	if p.Set.VLAN != nil {
+		vlan = strconv.Itoa(*p.Set.VLAN)
+	}
+	fallback := ""
+	if p.Set.FallbackInterface != nil {
+		fallback = *p.Set.FallbackInterface
+	}
+
+	return fmt.Sprintf(strings.TrimSpace(`
+InterfaceProfile[
+    ID: %s
+    Name: %s
+    Match: %+v
+    SetDriver: %s
+    SetVLAN: %s
+    SetZones: %v
+    SetFallbackInterface: %s
+    NodeIDs: %v
+    Selector: %s
+]`),
+		p.ID,
+		p.Name,
+		p.Match,
+		p.Set.Driver,
+		vlan,
+		p.Set.Zones,
+		fallback,
+		p.NodeIDs,
+		p.Selector)
+}
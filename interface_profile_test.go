@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: InterfaceProfile", func() {
+	var (
+		profile *cce.InterfaceProfile
+		ifaces  []*cce.NetworkInterface
+	)
+
+	BeforeEach(func() {
+		vlan := 10
+		fallback := "0000:00:09.0"
+		profile = &cce.InterfaceProfile{
+			ID:   "6a1c3e5b-7d9f-4b2a-8c4e-1f3a5c7e9b0d",
+			Name: "far-edge-upstream",
+			Match: cce.InterfaceMatch{
+				Driver: "kernel",
+				PCI:    "0000:00:08.*",
+			},
+			Set: cce.InterfaceSettings{
+				Driver:            "userspace",
+				VLAN:              &vlan,
+				Zones:             []string{"far-edge"},
+				FallbackInterface: &fallback,
+			},
+			NodeIDs:  []string{"9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e"},
+			Selector: "tier=far-edge",
+		}
+		ifaces = []*cce.NetworkInterface{
+			{
+				ID:          "0000:00:08.0",
+				Description: "upstream",
+				Driver:      "kernel",
+				Type:        "upstream",
+			},
+			{
+				ID:          "0000:00:08.1",
+				Description: "upstream",
+				Driver:      "userspace",
+				Type:        "upstream",
+			},
+			{
+				ID:          "0000:00:09.0",
+				Description: "fallback",
+				Driver:      "kernel",
+				Type:        "none",
+			},
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "interface_profiles"`, func() {
+			Expect(profile.GetTableName()).To(Equal("interface_profiles"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(profile.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			profile.ID = "123"
+			Expect(profile.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if Name is empty", func() {
+			profile.Name = ""
+			Expect(profile.Validate()).To(MatchError("name cannot be empty"))
+		})
+
+		It("Should return an error if Match is empty", func() {
+			profile.Match = cce.InterfaceMatch{}
+			Expect(profile.Validate()).To(MatchError("match cannot be empty"))
+		})
+
+		It("Should return an error if the matched driver is invalid", func() {
+			profile.Match.Driver = "dpdk"
+			Expect(profile.Validate()).To(MatchError("match.driver must be one of [kernel, userspace]"))
+		})
+
+		It("Should return an error if the matched type is invalid", func() {
+			profile.Match.Type = "sideways"
+			Expect(profile.Validate()).To(MatchError(
+				"match.type must be one of [none, upstream, downstream, bidirectional, breakout]"))
+		})
+
+		It("Should return an error if the PCI pattern is invalid", func() {
+			profile.Match.PCI = "0000:00:0[8"
+			Expect(profile.Validate()).To(MatchError(`match.pci pattern "0000:00:0[8" is invalid`))
+		})
+
+		It("Should return an error if Set is empty", func() {
+			profile.Set = cce.InterfaceSettings{}
+			Expect(profile.Validate()).To(MatchError("set cannot be empty"))
+		})
+
+		It("Should return an error if the VLAN is out of range", func() {
+			vlan := 256
+			profile.Set.VLAN = &vlan
+			Expect(profile.Validate()).To(MatchError("set.vlan must be in [0..255]"))
+		})
+
+		It("Should return an error if a zone is invalid", func() {
+			profile.Set.Zones = []string{""}
+			Expect(profile.Validate()).To(MatchError("set.zones[0] id cannot be empty"))
+		})
+
+		It("Should return an error if a node ID is not a UUID", func() {
+			profile.NodeIDs = []string{"123"}
+			Expect(profile.Validate()).To(MatchError("node_ids[0] not a valid uuid"))
+		})
+
+		It("Should return an error if the profile targets no node", func() {
+			profile.NodeIDs = nil
+			profile.Selector = ""
+			Expect(profile.Validate()).To(MatchError("node_ids or selector is required"))
+		})
+	})
+
+	Describe("Targets", func() {
+		It("Should target the listed nodes and the nodes matching the selector", func() {
+			Expect(profile.Targets(&cce.Node{ID: "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e"})).To(BeTrue())
+			Expect(profile.Targets(&cce.Node{
+				ID:     "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c",
+				Labels: map[string]string{"tier": "far-edge"},
+			})).To(BeTrue())
+			Expect(profile.Targets(&cce.Node{ID: "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c"})).To(BeFalse())
+		})
+	})
+
+	Describe("Apply", func() {
+		It("Should change the matching interfaces", func() {
+			updated, changes := profile.Apply(ifaces)
+			Expect(changes).To(Equal([]cce.InterfaceChange{
+				{InterfaceID: "0000:00:08.0", Field: "driver", From: "kernel", To: "userspace"},
+				{InterfaceID: "0000:00:08.0", Field: "vlan", From: "0", To: "10"},
+				{InterfaceID: "0000:00:08.0", Field: "zones", From: "", To: "far-edge"},
+				{InterfaceID: "0000:00:08.0", Field: "fallback_interface", From: "", To: "0000:00:09.0"},
+			}))
+			Expect(updated).To(HaveLen(3))
+			Expect(*updated[0]).To(Equal(cce.NetworkInterface{
+				ID:                "0000:00:08.0",
+				Description:       "upstream",
+				Driver:            "userspace",
+				Type:              "upstream",
+				VLAN:              10,
+				Zones:             []string{"far-edge"},
+				FallbackInterface: "0000:00:09.0",
+			}))
+			Expect(updated[1]).To(Equal(ifaces[1]))
+			Expect(updated[2]).To(Equal(ifaces[2]))
+
+			By("Checking the interfaces passed in are not modified")
+			Expect(ifaces[0].Driver).To(Equal("kernel"))
+		})
+
+		It("Should match the interfaces by description", func() {
+			profile.Match = cce.InterfaceMatch{Description: "fall*"}
+			_, changes := profile.Apply(ifaces)
+			Expect(changes).ToNot(BeEmpty())
+			for _, c := range changes {
+				Expect(c.InterfaceID).To(Equal("0000:00:09.0"))
+			}
+		})
+
+		It("Should not report interfaces already configured", func() {
+			updated, _ := profile.Apply(ifaces)
+			_, changes := profile.Apply(updated)
+			Expect(changes).To(BeEmpty())
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(profile.FilterFields()).To(Equal([]string{
+				"name",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(profile.String()).To(Equal(strings.TrimSpace(`
+InterfaceProfile[
+    ID: 6a1c3e5b-7d9f-4b2a-8c4e-1f3a5c7e9b0d
+    Name: far-edge-upstream
+    Match: {Driver:kernel Type: PCI:0000:00:08.* Description:}
+    SetDriver: userspace
+    SetVLAN: 10
+    SetZones: [far-edge]
+    SetFallbackInterface: 0000:00:09.0
+    NodeIDs: [9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e]
+    Selector: tier=far-edge
+]`,
+			)))
+		})
+	})
+})
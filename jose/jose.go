@@ -4,60 +4,103 @@
 package jose
 
 import (
-	"crypto"
 	"time"
 
+	"github.com/open-ness/edgecontroller/uuid"
 	"github.com/pkg/errors"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 )
 
+// DefaultTokenLifetime is how long tokens are valid if no lifetime is set.
+const DefaultTokenLifetime = 24 * time.Hour
+
 // JWSTokenIssuer issues and validates JSON web signature tokens.
 type JWSTokenIssuer struct {
-	Key          crypto.PrivateKey
-	KeyAlgorithm string
+	// Keys are the keys signing and verifying tokens.
+	Keys *KeyRing
+	// Lifetime is how long issued tokens are valid. Zero means
+	// DefaultTokenLifetime.
+	Lifetime time.Duration
+}
+
+// Claims are the claims of a token issued by JWSTokenIssuer.
+type Claims struct {
+	jwt.Claims
+	// Roles are the roles of the subject.
+	Roles []string `json:"roles"`
 }
 
-// Issue issues a new JWT token signed with the authority key and valid for one
-// day. The signed JWT token is returned in the RFC 7519 compact serialization
-// format.
-func (s *JWSTokenIssuer) Issue() (string, error) {
+// Issue issues a new JWT token for a subject with roles, signed with the
+// current signing key and valid for the token lifetime. Each token has a unique
+// ID in the jti claim so it can be revoked, and the ID of the signing key is
+// set in the kid header. The signed JWT token is returned in the RFC 7519 compact
+// serialization format.
+func (s *JWSTokenIssuer) Issue(subject string, roles []string) (string, error) {
+	key, err := s.Keys.signingKey()
+	if err != nil {
+		return "", err
+	}
+
 	signer, err := jose.NewSigner(
 		jose.SigningKey{
-			Key:       s.Key,
-			Algorithm: jose.SignatureAlgorithm(s.KeyAlgorithm),
+			Key:       key,
+			Algorithm: jose.SignatureAlgorithm(key.Algorithm),
 		},
 		new(jose.SignerOptions).WithType("JWT"))
 	if err != nil {
 		return "", errors.Wrap(err, "unable to create token signer")
 	}
 
-	claims := jwt.Claims{
-		Expiry: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 1 day
+	lifetime := s.Lifetime
+	if lifetime == 0 {
+		lifetime = DefaultTokenLifetime
+	}
+
+	now := time.Now()
+	claims := Claims{
+		Claims: jwt.Claims{
+			ID:       uuid.New(),
+			Subject:  subject,
+			IssuedAt: jwt.NewNumericDate(now),
+			Expiry:   jwt.NewNumericDate(now.Add(lifetime)),
+		},
+		Roles: roles,
 	}
 
 	return jwt.Signed(signer).Claims(claims).CompactSerialize()
 }
 
-// Validate validates the JWT token was signed with the authority key and has
-// not yet expired. The signed JWT token is expected to be in the RFC 7519
-// compact serialization format.
-func (s *JWSTokenIssuer) Validate(t string) error {
+// Validate validates the JWT token was signed with the current signing key or a
+// replaced key within its grace period and has not yet expired, and returns its
+// claims. The signed JWT token is expected to be in the RFC 7519 compact
+// serialization format.
+func (s *JWSTokenIssuer) Validate(t string) (*Claims, error) {
 	token, err := jwt.ParseSigned(t)
 	if err != nil {
-		return errors.Wrap(err, "unable to parse token")
+		return nil, errors.Wrap(err, "unable to parse token")
 	}
 
-	key, ok := s.Key.(crypto.Signer)
-	if !ok {
-		return errors.Wrap(err, "invalid signing key")
+	if len(token.Headers) != 1 {
+		return nil, errors.New("token must have exactly one signature")
+	}
+	key, err := s.Keys.verificationKey(token.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
 	}
 
-	var claims jwt.Claims
-	err = token.Claims(key.Public(), &claims)
+	var claims Claims
+	err = token.Claims(key, &claims)
 	if err != nil {
-		return errors.Wrap(err, "unable to deserialize token claims")
+		return nil, errors.Wrap(err, "unable to deserialize token claims")
+	}
+
+	if err = claims.Validate(jwt.Expected{Time: time.Now()}); err != nil {
+		return nil, err
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, errors.New("token is missing the jti or iat claim")
 	}
 
-	return claims.Validate(jwt.Expected{Time: time.Now()})
+	return &claims, nil
 }
@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package jose
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	"github.com/open-ness/edgecontroller/pki"
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "jose")
+
+// keyCheckInterval is how often Run checks whether the signing key is due for
+// rotation.
+const keyCheckInterval = time.Minute
+
+// KeyRing persists the keys signing tokens in a directory and rotates them.
+// Each key is stored as a PEM-encoded PKCS #8 file named after its creation
+// time in Unix nanoseconds. The newest key signs tokens and each older key
+// keeps verifying tokens for GracePeriod after the key that replaced it was
+// created, after which it is deleted.
+type KeyRing struct {
+	// Dir is the directory storing the keys.
+	Dir string
+	// RotationPeriod is how long a key signs tokens before it is replaced.
+	// Zero disables rotation.
+	RotationPeriod time.Duration
+	// GracePeriod is how long a replaced key keeps verifying tokens. It
+	// should be at least the token lifetime.
+	GracePeriod time.Duration
+
+	mu   sync.RWMutex
+	keys []*signingKey // ordered by creation time
+}
+
+type signingKey struct {
+	jwk     jose.JSONWebKey
+	path    string
+	created time.Time
+}
+
+// Load loads the keys from Dir. A key is generated if there are none or the
+// newest key is due for rotation, and keys past their grace period are
+// deleted.
+func (kr *KeyRing) Load() error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if err := os.MkdirAll(kr.Dir, 0700); err != nil {
+		return errors.Wrap(err, "unable to create key directory")
+	}
+
+	keys, err := loadSigningKeys(kr.Dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if len(keys) == 0 ||
+		kr.RotationPeriod > 0 && now.Sub(keys[len(keys)-1].created) >= kr.RotationPeriod {
+		key, err := generateSigningKey(kr.Dir, now)
+		if err != nil {
+			return err
+		}
+		log.Infof("Generated token signing key %s", key.jwk.KeyID)
+		keys = append(keys, key)
+	}
+
+	kr.keys = nil
+	for i, key := range keys {
+		if i < len(keys)-1 && now.Sub(keys[i+1].created) >= kr.GracePeriod {
+			if err := os.Remove(key.path); err != nil {
+				log.Errf("Error deleting expired token signing key %s: %v", key.jwk.KeyID, err)
+			} else {
+				log.Infof("Deleted expired token signing key %s", key.jwk.KeyID)
+			}
+			continue
+		}
+		kr.keys = append(kr.keys, key)
+	}
+
+	return nil
+}
+
+// Rotate replaces the signing key with a newly generated key. The replaced key
+// keeps verifying tokens for the grace period.
+func (kr *KeyRing) Rotate() error {
+	kr.mu.Lock()
+	key, err := generateSigningKey(kr.Dir, time.Now())
+	kr.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	log.Infof("Rotated token signing key to %s", key.jwk.KeyID)
+
+	return kr.Load()
+}
+
+// Run rotates the signing key every RotationPeriod and deletes keys past their
+// grace period until the context is canceled.
+func (kr *KeyRing) Run(ctx context.Context) error {
+	ticker := time.NewTicker(keyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := kr.Load(); err != nil {
+				log.Errf("Error rotating token signing keys: %v", err)
+			}
+		}
+	}
+}
+
+// JWKS returns the public keys verifying tokens as a JSON Web Key Set.
+func (kr *KeyRing) JWKS() jose.JSONWebKeySet {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range kr.keys {
+		set.Keys = append(set.Keys, key.jwk.Public())
+	}
+
+	return set
+}
+
+// signingKey returns the key signing new tokens.
+func (kr *KeyRing) signingKey() (jose.JSONWebKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	if len(kr.keys) == 0 {
+		return jose.JSONWebKey{}, errors.New("no token signing key loaded")
+	}
+
+	return kr.keys[len(kr.keys)-1].jwk, nil
+}
+
+// verificationKey returns the public key with the key ID, if it is still
+// within its grace period.
+func (kr *KeyRing) verificationKey(kid string) (crypto.PublicKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	for i, key := range kr.keys {
+		if key.jwk.KeyID != kid {
+			continue
+		}
+		if i < len(kr.keys)-1 && now.Sub(kr.keys[i+1].created) >= kr.GracePeriod {
+			return nil, errors.Errorf("signing key %q has expired", kid)
+		}
+		return key.jwk.Public().Key, nil
+	}
+
+	return nil, errors.Errorf("unknown signing key %q", kid)
+}
+
+func loadSigningKeys(dir string) ([]*signingKey, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read key directory")
+	}
+
+	var keys []*signingKey
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, ".pem") {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(name, ".pem"), 10, 64)
+		if err != nil {
+			log.Warningf("Ignoring token signing key file %q not named after its creation time", name)
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		priv, err := pki.LoadKey(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to load token signing key %q", name)
+		}
+		jwk, err := newJWK(priv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to load token signing key %q", name)
+		}
+
+		keys = append(keys, &signingKey{jwk: jwk, path: path, created: time.Unix(0, nanos)})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].created.Before(keys[j].created)
+	})
+
+	return keys, nil
+}
+
+func generateSigningKey(dir string, created time.Time) (*signingKey, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate token signing key")
+	}
+	jwk, err := newJWK(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%d.pem", created.UnixNano()))
+	if err = pki.StoreKey(priv, path); err != nil {
+		return nil, err
+	}
+
+	return &signingKey{jwk: jwk, path: path, created: created}, nil
+}
+
+// newJWK wraps a private key in a JSON Web Key identified by the RFC 7638
+// thumbprint of its public key.
+func newJWK(priv crypto.PrivateKey) (jose.JSONWebKey, error) {
+	alg, err := signatureAlgorithm(priv)
+	if err != nil {
+		return jose.JSONWebKey{}, err
+	}
+
+	jwk := jose.JSONWebKey{Key: priv, Algorithm: string(alg), Use: "sig"}
+	pub := jwk.Public()
+	thumbprint, err := pub.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return jose.JSONWebKey{}, errors.Wrap(err, "unable to compute key thumbprint")
+	}
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return jwk, nil
+}
+
+func signatureAlgorithm(priv crypto.PrivateKey) (jose.SignatureAlgorithm, error) {
+	switch k := priv.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jose.ES256, nil
+		case elliptic.P384():
+			return jose.ES384, nil
+		case elliptic.P521():
+			return jose.ES512, nil
+		}
+	case *rsa.PrivateKey:
+		return jose.RS256, nil
+	}
+
+	return "", errors.Errorf("unsupported token signing key type %T", priv)
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package jose_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/open-ness/edgecontroller/jose"
+)
+
+var _ = Describe("KeyRing", func() {
+	var (
+		tmpDir string
+		keys   *jose.KeyRing
+		issuer *jose.JWSTokenIssuer
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "jose_test")
+		Expect(err).ToNot(HaveOccurred())
+
+		keys = &jose.KeyRing{
+			Dir:            filepath.Join(tmpDir, "jwt"),
+			RotationPeriod: time.Hour,
+			GracePeriod:    time.Hour,
+		}
+		Expect(keys.Load()).To(Succeed())
+		issuer = &jose.JWSTokenIssuer{Keys: keys}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("Should generate and persist a key on first load", func() {
+		files, err := ioutil.ReadDir(keys.Dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(keys.JWKS().Keys).To(HaveLen(1))
+	})
+
+	It("Should validate tokens after reloading the keys", func() {
+		token, err := issuer.Issue("test-user", []string{"admin"})
+		Expect(err).ToNot(HaveOccurred())
+
+		reloaded := &jose.KeyRing{Dir: keys.Dir, RotationPeriod: time.Hour, GracePeriod: time.Hour}
+		Expect(reloaded.Load()).To(Succeed())
+
+		claims, err := (&jose.JWSTokenIssuer{Keys: reloaded}).Validate(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.Subject).To(Equal("test-user"))
+		Expect(claims.Roles).To(Equal([]string{"admin"}))
+		Expect(claims.ID).ToNot(BeEmpty())
+		Expect(claims.IssuedAt).ToNot(BeNil())
+	})
+
+	It("Should issue tokens valid for the token lifetime", func() {
+		issuer.Lifetime = time.Minute
+		token, err := issuer.Issue("test-user", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		claims, err := issuer.Validate(token)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(claims.Expiry.Time().Sub(claims.IssuedAt.Time())).To(Equal(time.Minute))
+	})
+
+	It("Should publish the public keys with their key IDs", func() {
+		key := keys.JWKS().Keys[0]
+		Expect(key.IsPublic()).To(BeTrue())
+		Expect(key.KeyID).ToNot(BeEmpty())
+		Expect(key.Algorithm).To(Equal("ES384"))
+	})
+
+	It("Should validate tokens signed by a rotated key during the grace period", func() {
+		token, err := issuer.Issue("test-user", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(keys.Rotate()).To(Succeed())
+		Expect(keys.JWKS().Keys).To(HaveLen(2))
+
+		_, err = issuer.Validate(token)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("Should reject tokens signed by a rotated key after the grace period", func() {
+		token, err := issuer.Issue("test-user", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		keys.GracePeriod = 0
+		Expect(keys.Rotate()).To(Succeed())
+		Expect(keys.JWKS().Keys).To(HaveLen(1))
+
+		files, err := ioutil.ReadDir(keys.Dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+
+		_, err = issuer.Validate(token)
+		Expect(err).To(MatchError(ContainSubstring("unknown signing key")))
+	})
+})
@@ -6,6 +6,7 @@ package k8s
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -29,6 +30,9 @@ type App struct {
 	Memory int // in MB
 	Image  string
 	Ports  []*PortProto
+	// Revision is the revision of the app, recorded in the pod template so
+	// that upgrading the app rolls out its deployment.
+	Revision int
 }
 
 // PortProto is a port and protocol tuple
@@ -42,6 +46,8 @@ const (
 	nodeIDLabelKey = "node-id"
 	// Key for the label attached to a k8s pod containing the App ID
 	appIDLabelKey = "app-id"
+	// Key for the annotation of a k8s pod template containing the App revision
+	appRevisionAnnotationKey = "app-revision"
 )
 
 // Client abstracts calls to k8s master API
@@ -160,27 +166,14 @@ func (ks *Client) checkNode(nodeID string) error {
 
 // create a kubernetes deployment
 func (ks *Client) deploy(nodeID string, app App) error {
-	protoConverter := map[string]apiV1.Protocol{
-		"tcp":  apiV1.ProtocolTCP,
-		"udp":  apiV1.ProtocolUDP,
-		"sctp": apiV1.ProtocolSCTP,
-	}
-
-	var ports []apiV1.ContainerPort
-	for _, portProt := range app.Ports {
-		proto, ok := protoConverter[portProt.Protocol]
-		if !ok {
-			return errors.New("unsupported protocol for kubernetes error")
-		}
-		ports = append(ports, apiV1.ContainerPort{
-			ContainerPort: portProt.Port,
-			Protocol:      proto,
-		})
+	ports, err := containerPorts(app)
+	if err != nil {
+		return err
 	}
 
 	// deployment client
 	deploymentsClient := ks.clientSet.AppsV1().Deployments(apiV1.NamespaceDefault)
-	_, err := deploymentsClient.Create(&appsV1.Deployment{
+	_, err = deploymentsClient.Create(&appsV1.Deployment{
 		ObjectMeta: metaV1.ObjectMeta{
 			GenerateName: "app",
 			Labels: map[string]string{
@@ -209,33 +202,14 @@ func (ks *Client) deploy(nodeID string, app App) error {
 						appIDLabelKey:  app.ID,
 						nodeIDLabelKey: nodeID,
 					},
+					Annotations: map[string]string{
+						appRevisionAnnotationKey: strconv.Itoa(app.Revision),
+					},
 				},
 				Spec: apiV1.PodSpec{
 					Containers: []apiV1.Container{
 						{
-							Resources: apiV1.ResourceRequirements{
-								Limits: apiV1.ResourceList{
-									// CPU, in cores. (500m = .5 cores)
-									apiV1.ResourceCPU: *resource.NewQuantity(
-										int64(app.Cores),
-										resource.DecimalSI,
-									),
-
-									// Memory, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
-									apiV1.ResourceMemory: *resource.NewQuantity(
-										int64(1024*1024*app.Memory),
-										resource.BinarySI,
-									),
-
-									// Volume size, in bytes (e,g. 5Gi = 5GiB = 5 * 1024 * 1024 * 1024)
-									// apiV1.ResourceStorage: resource.MustParse(d.Storage),
-
-									// Local ephemeral storage, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
-									// The resource name for ResourceEphemeralStorage is alpha and it can change
-									// across releases.
-									// apiV1.ResourceEphemeralStorage: resource.MustParse(d.EphemeralStorage),
-								},
-							},
+							Resources:       containerResources(app),
 							Name:            uuid.New(),
 							Image:           app.ID,
 							Ports:           ports,
@@ -275,6 +249,88 @@ func (ks *Client) undeploy(nodeID, appID string) error {
 	return errors.Wrap(err, "create kubernetes deployment error")
 }
 
+// Upgrade rolls out the deployment of the app with the resources, ports and
+// revision of the app.
+func (ks *Client) Upgrade(ctx context.Context, nodeID string, app App) error {
+	ks.connectOnce.Do(ks.init)
+	if ks.err != nil {
+		return ks.err
+	}
+
+	ports, err := containerPorts(app)
+	if err != nil {
+		return errors.Wrap(err, "upgrade: deployment error")
+	}
+	deployment, err := ks.getDeployment(nodeID, app.ID)
+	if err != nil {
+		return errors.Wrap(err, "upgrade: error getting deployment by ID")
+	}
+
+	template := &deployment.Spec.Template
+	if template.ObjectMeta.Annotations == nil {
+		template.ObjectMeta.Annotations = make(map[string]string)
+	}
+	template.ObjectMeta.Annotations[appRevisionAnnotationKey] = strconv.Itoa(app.Revision)
+	for i := range template.Spec.Containers {
+		template.Spec.Containers[i].Resources = containerResources(app)
+		template.Spec.Containers[i].Ports = ports
+	}
+
+	deploymentsClient := ks.clientSet.AppsV1().Deployments(apiV1.NamespaceDefault)
+	_, err = deploymentsClient.Update(deployment)
+	return errors.Wrap(err, "upgrade: error updating deployment")
+}
+
+// containerPorts returns the ports of the container of the app.
+func containerPorts(app App) ([]apiV1.ContainerPort, error) {
+	protoConverter := map[string]apiV1.Protocol{
+		"tcp":  apiV1.ProtocolTCP,
+		"udp":  apiV1.ProtocolUDP,
+		"sctp": apiV1.ProtocolSCTP,
+	}
+
+	var ports []apiV1.ContainerPort
+	for _, portProt := range app.Ports {
+		proto, ok := protoConverter[portProt.Protocol]
+		if !ok {
+			return nil, errors.New("unsupported protocol for kubernetes error")
+		}
+		ports = append(ports, apiV1.ContainerPort{
+			ContainerPort: portProt.Port,
+			Protocol:      proto,
+		})
+	}
+
+	return ports, nil
+}
+
+// containerResources returns the resource limits of the container of the app.
+func containerResources(app App) apiV1.ResourceRequirements {
+	return apiV1.ResourceRequirements{
+		Limits: apiV1.ResourceList{
+			// CPU, in cores. (500m = .5 cores)
+			apiV1.ResourceCPU: *resource.NewQuantity(
+				int64(app.Cores),
+				resource.DecimalSI,
+			),
+
+			// Memory, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
+			apiV1.ResourceMemory: *resource.NewQuantity(
+				int64(1024*1024*app.Memory),
+				resource.BinarySI,
+			),
+
+			// Volume size, in bytes (e,g. 5Gi = 5GiB = 5 * 1024 * 1024 * 1024)
+			// apiV1.ResourceStorage: resource.MustParse(d.Storage),
+
+			// Local ephemeral storage, in bytes. (500Gi = 500GiB = 500 * 1024 * 1024 * 1024)
+			// The resource name for ResourceEphemeralStorage is alpha and it can change
+			// across releases.
+			// apiV1.ResourceEphemeralStorage: resource.MustParse(d.EphemeralStorage),
+		},
+	}
+}
+
 func int32Ptr(i int32) *int32 { return &i }
 
 // Start scales up the number of replicas of kubernetes deployment to 1.
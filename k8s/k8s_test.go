@@ -64,7 +64,7 @@ var _ = AfterSuite(func() {
 
 var _ = Describe("K8S", func() {
 	Context("API calls to K8S master", func() {
-		It("Should deploy, start, stop, restart and undeploy an app from a public docker image", func() {
+		It("Should deploy, start, stop, restart, upgrade and undeploy an app from a public docker image", func() {
 			kubeConfig := path.Join(homeDir, ".kube", "config")
 			config, err := clientcmd.BuildConfigFromFlags("", kubeConfig)
 			Expect(err).NotTo(HaveOccurred())
@@ -164,6 +164,12 @@ var _ = Describe("K8S", func() {
 			defer cancel()
 			Expect(client.Restart(ctx, nodeID, appID)).To(Succeed())
 
+			app.Memory = 200
+			app.Revision = 1
+			ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			Expect(client.Upgrade(ctx, nodeID, app)).To(Succeed())
+
 			ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 			Expect(client.Undeploy(ctx, nodeID, appID)).To(Succeed())
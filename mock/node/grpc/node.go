@@ -28,7 +28,7 @@ func NewMockNode() *MockNode {
 		dnsSvc           = newDNSService()
 		interfaceSvc     = newInterfaceService()
 		ifPolicySvc      = newInterfacePolicyService(interfaceSvc)
-		zoneSvc          = &zoneService{}
+		zoneSvc          = newZoneService(interfaceSvc)
 	)
 
 	appDeployLifeSvc.appPolicyService = appPolicySvc
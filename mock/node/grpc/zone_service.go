@@ -14,6 +14,14 @@ import (
 
 type zoneService struct {
 	zones []*elapb.NetworkZone
+
+	interfaceService *interfaceService
+}
+
+func newZoneService(interfaceService *interfaceService) *zoneService {
+	return &zoneService{
+		interfaceService: interfaceService,
+	}
 }
 
 func (s *zoneService) reset() {
@@ -24,6 +32,15 @@ func (s *zoneService) Create(
 	ctx context.Context,
 	zone *elapb.NetworkZone,
 ) (*empty.Empty, error) {
+	if zone.Id == "" {
+		return nil, status.Error(
+			codes.InvalidArgument, "Network Zone ID cannot be empty")
+	}
+	if s.find(zone.Id) != nil {
+		return nil, status.Errorf(
+			codes.AlreadyExists, "Network Zone %s already exists", zone.Id)
+	}
+
 	s.zones = append(s.zones, zone)
 
 	return &empty.Empty{}, nil
@@ -95,6 +112,11 @@ func (s *zoneService) Delete(
 	i := s.findIndex(id.Id)
 
 	if i < len(s.zones) {
+		if ni := s.findInterface(id.Id); ni != nil {
+			return nil, status.Errorf(
+				codes.FailedPrecondition,
+				"Network Zone %s is used by Network Interface %s", id.Id, ni.Id)
+		}
 		s.delete(i)
 		return &empty.Empty{}, nil
 	}
@@ -123,6 +145,23 @@ func (s *zoneService) findIndex(id string) int {
 	return len(s.zones)
 }
 
+// findInterface returns a network interface in the zone, if any.
+func (s *zoneService) findInterface(id string) *elapb.NetworkInterface {
+	if s.interfaceService == nil {
+		return nil
+	}
+
+	for _, ni := range s.interfaceService.nis {
+		for _, zone := range ni.Zones {
+			if zone == id {
+				return ni
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *zoneService) delete(i int) {
 	copy(s.zones[i:], s.zones[i+1:])
 	s.zones[len(s.zones)-1] = nil
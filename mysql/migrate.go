@@ -0,0 +1,315 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	logger "github.com/open-ness/common/log"
+	"github.com/pkg/errors"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "mysql")
+
+// migrationsLock is the name of the advisory lock serializing migrations
+// between controllers sharing a database.
+const migrationsLock = "controller_ce.schema_migrations"
+
+// ErrSchemaTooNew is returned when the database schema was migrated by a newer
+// controller than this one.
+var ErrSchemaTooNew = errors.New("database schema is newer than this controller supports")
+
+// defaultLockTimeout is how long a migrator waits for the migrations of
+// another controller by default.
+const defaultLockTimeout = 60 * time.Second
+
+// Migration is a numbered schema change. Up applies the change and Down
+// reverts it. Each statement is executed on its own, so statements must not be
+// separated by semicolons. MySQL commits DDL statements implicitly, so the
+// executed statements are recorded in the schema_migration_steps table and an
+// interrupted migration resumes after the last executed statement. Check, if
+// set, is called before the up statements are executed and fails the migration
+// with an error explaining how to fix the data that cannot be migrated.
+type Migration struct {
+	Version     int
+	Description string
+	Check       func(ctx context.Context, conn *sql.Conn) error
+	Up          []string
+	Down        []string
+}
+
+// LatestVersion returns the schema version this controller expects.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Migrator applies schema migrations to a MySQL database and records the
+// applied versions in the schema_migrations table.
+type Migrator struct {
+	DB *sql.DB
+	// LockTimeout is how long to wait for the migrations of another
+	// controller. It is rounded up to whole seconds and defaults to 60
+	// seconds.
+	LockTimeout time.Duration
+}
+
+// Version returns the schema version of the database. A database created from
+// the unversioned schema.sql that predates migrations is recorded as
+// version 1.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "error getting connection")
+	}
+	defer conn.Close()
+
+	if err = initVersionTable(ctx, conn); err != nil {
+		return 0, err
+	}
+
+	return version(ctx, conn)
+}
+
+// Up migrates the database to the latest version. It fails with
+// ErrSchemaTooNew if the database is already at a newer version.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.MigrateTo(ctx, LatestVersion())
+}
+
+// MigrateTo migrates the database up or down to the target version. Version 0
+// is an empty database.
+func (m *Migrator) MigrateTo(ctx context.Context, target int) error { //nolint:gocyclo
+	if target < 0 || target > LatestVersion() {
+		return errors.Errorf("unknown schema version %d, latest is %d", target, LatestVersion())
+	}
+
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error getting connection")
+	}
+	defer conn.Close()
+
+	// Serialize migrations between controllers starting at the same time
+	timeout := m.LockTimeout
+	if timeout <= 0 {
+		timeout = defaultLockTimeout
+	}
+	var locked sql.NullInt64
+	if err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)",
+		migrationsLock, int((timeout+time.Second-1)/time.Second)).Scan(&locked); err != nil {
+		return errors.Wrap(err, "error acquiring migrations lock")
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return errors.New("timed out acquiring migrations lock")
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationsLock); err != nil {
+			log.Errf("Error releasing migrations lock: %v", err)
+		}
+	}()
+
+	if err = initVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	current, err := version(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if current > LatestVersion() {
+		return errors.Wrapf(ErrSchemaTooNew, "database is at version %d, latest is %d",
+			current, LatestVersion())
+	}
+
+	// An interrupted migration must be resumed in the same direction
+	p, err := readProgress(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if p != nil {
+		switch {
+		case !p.down && p.version == current+1 && target >= p.version:
+		case p.down && p.version == current && target < p.version:
+		case p.down:
+			return errors.Errorf("reverting migration %d was interrupted, migrate to version %d to resume",
+				p.version, p.version-1)
+		default:
+			return errors.Errorf("applying migration %d was interrupted, migrate to version %d to resume",
+				p.version, p.version)
+		}
+	}
+
+	// Apply up migrations in ascending order
+	for _, mig := range migrations {
+		if mig.Version <= current || mig.Version > target {
+			continue
+		}
+		log.Infof("Applying schema migration %d: %s", mig.Version, mig.Description)
+		if err = apply(ctx, conn, mig, false, p); err != nil {
+			return errors.Wrapf(err, "error applying migration %d", mig.Version)
+		}
+	}
+
+	// Apply down migrations in descending order
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if mig.Version > current || mig.Version <= target {
+			continue
+		}
+		log.Infof("Reverting schema migration %d: %s", mig.Version, mig.Description)
+		if err = apply(ctx, conn, mig, true, p); err != nil {
+			return errors.Wrapf(err, "error reverting migration %d", mig.Version)
+		}
+	}
+
+	return nil
+}
+
+// progress is the number of statements of an interrupted migration that were
+// executed.
+type progress struct {
+	version int
+	down    bool
+	steps   int
+}
+
+func readProgress(ctx context.Context, conn *sql.Conn) (*progress, error) {
+	var p progress
+	err := conn.QueryRowContext(ctx,
+		"SELECT version, down, steps FROM schema_migration_steps ORDER BY version LIMIT 1").Scan(
+		&p.version, &p.down, &p.steps)
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "error reading migration progress")
+	}
+
+	return &p, nil
+}
+
+// apply executes the up or down statements of a migration, skipping those
+// already executed by an interrupted run, and records the migration once all
+// statements are executed.
+func apply(ctx context.Context, conn *sql.Conn, mig Migration, down bool, p *progress) error {
+	stmts := mig.Up
+	if down {
+		stmts = mig.Down
+	}
+
+	start := 0
+	if p != nil && p.version == mig.Version && p.down == down {
+		start = p.steps
+		log.Infof("Resuming schema migration %d after statement %d", mig.Version, start)
+	}
+
+	if !down && mig.Check != nil {
+		if err := mig.Check(ctx, conn); err != nil {
+			return err
+		}
+	}
+
+	for i := start; i < len(stmts); i++ {
+		if _, err := conn.ExecContext(ctx, stmts[i]); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO schema_migration_steps (version, down, steps) VALUES (?, ?, ?)
+             ON DUPLICATE KEY UPDATE down = VALUES(down), steps = VALUES(steps)`,
+			mig.Version, down, i+1); err != nil {
+			return errors.Wrap(err, "error recording migration progress")
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error beginning transaction")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Errf("Error rolling back transaction: %v", err)
+		}
+	}()
+
+	record := "INSERT INTO schema_migrations (version) VALUES (?)"
+	if down {
+		record = "DELETE FROM schema_migrations WHERE version = ?"
+	}
+	if _, err = tx.ExecContext(ctx, record, mig.Version); err != nil {
+		return errors.Wrap(err, "error recording migration")
+	}
+	if _, err = tx.ExecContext(ctx,
+		"DELETE FROM schema_migration_steps WHERE version = ?", mig.Version); err != nil {
+		return errors.Wrap(err, "error recording migration progress")
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "error committing transaction")
+	}
+
+	return nil
+}
+
+// initVersionTable creates the schema_migrations and schema_migration_steps
+// tables if they do not exist. If the entity tables already exist without
+// them, the database was created from the unversioned schema.sql and is
+// recorded as version 1.
+func initVersionTable(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migration_steps (
+    version INT NOT NULL PRIMARY KEY,
+    down BOOL NOT NULL,
+    steps INT NOT NULL
+)`); err != nil {
+		return errors.Wrap(err, "error creating schema_migration_steps table")
+	}
+
+	exists, err := tableExists(ctx, conn, "schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+
+	if _, err = conn.ExecContext(ctx, `CREATE TABLE schema_migrations (
+    version INT NOT NULL PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`); err != nil {
+		return errors.Wrap(err, "error creating schema_migrations table")
+	}
+
+	legacy, err := tableExists(ctx, conn, "nodes")
+	if err != nil || !legacy {
+		return err
+	}
+
+	log.Info("Recording existing unversioned schema as version 1")
+	if _, err = conn.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (1)"); err != nil {
+		return errors.Wrap(err, "error recording schema version")
+	}
+
+	return nil
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, name string) (bool, error) {
+	var n int
+	if err := conn.QueryRowContext(ctx,
+		`SELECT COUNT(*)
+         FROM information_schema.tables
+         WHERE table_schema = DATABASE() AND table_name = ?`,
+		name).Scan(&n); err != nil {
+		return false, errors.Wrap(err, "error querying information_schema")
+	}
+
+	return n > 0, nil
+}
+
+func version(ctx context.Context, conn *sql.Conn) (int, error) {
+	var v sql.NullInt64
+	if err := conn.QueryRowContext(ctx,
+		"SELECT MAX(version) FROM schema_migrations").Scan(&v); err != nil {
+		return 0, errors.Wrap(err, "error reading schema version")
+	}
+
+	return int(v.Int64), nil
+}
@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		ctx context.Context
+		db  *sql.DB
+		m   *Migrator
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		db = createTestDatabase()
+		m = &Migrator{DB: db, LockTimeout: time.Second}
+	})
+
+	AfterEach(func() {
+		dropTestDatabase(db)
+	})
+
+	It("Should report an empty database as version 0", func() {
+		Expect(m.Version(ctx)).To(Equal(0))
+	})
+
+	It("Should migrate up to the latest version", func() {
+		Expect(m.Up(ctx)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(LatestVersion()))
+		Expect(tableNames(db)).To(ContainElement("nodes"))
+		Expect(tableNames(db)).To(ContainElement("interface_profiles"))
+
+		By("Migrating up again")
+		Expect(m.Up(ctx)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(LatestVersion()))
+	})
+
+	It("Should migrate down and up to a version", func() {
+		Expect(m.Up(ctx)).To(Succeed())
+
+		By("Migrating down to version 12")
+		Expect(m.MigrateTo(ctx, 12)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(12))
+		Expect(tableNames(db)).ToNot(ContainElement("interface_profiles"))
+		Expect(tableNames(db)).To(ContainElement("node_groups"))
+
+		By("Migrating down to version 0")
+		Expect(m.MigrateTo(ctx, 0)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(0))
+		Expect(tableNames(db)).ToNot(ContainElement("nodes"))
+
+		By("Migrating up to version 12")
+		Expect(m.MigrateTo(ctx, 12)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(12))
+		Expect(tableNames(db)).To(ContainElement("node_groups"))
+		Expect(tableNames(db)).ToNot(ContainElement("interface_profiles"))
+	})
+
+	It("Should widen the serial of the nodes", func() {
+		// serialLength returns the length of the serial column of the nodes
+		serialLength := func() (length int) {
+			Expect(db.QueryRow(
+				`SELECT CHARACTER_MAXIMUM_LENGTH FROM information_schema.columns
+				 WHERE table_schema = DATABASE() AND table_name = 'nodes' AND column_name = 'serial'`,
+			).Scan(&length)).To(Succeed())
+			return length
+		}
+
+		Expect(m.Up(ctx)).To(Succeed())
+		Expect(serialLength()).To(Equal(64))
+
+		By("Migrating down to version 13")
+		Expect(m.MigrateTo(ctx, 13)).To(Succeed())
+		Expect(serialLength()).To(Equal(36))
+	})
+
+	It("Should require unique serials of the nodes", func() {
+		Expect(m.MigrateTo(ctx, 14)).To(Succeed())
+
+		// insertNode inserts a node with the serial
+		insertNode := func(id, serial string) error {
+			_, err := db.Exec("INSERT INTO nodes (entity) VALUES (?)",
+				fmt.Sprintf(`{"id": %q, "name": "n", "location": "l", "serial": %q}`, id, serial))
+			return err
+		}
+		Expect(insertNode("node-1", "s1")).To(Succeed())
+		Expect(insertNode("node-2", "s1")).To(Succeed())
+		Expect(insertNode("node-3", "s2")).To(Succeed())
+
+		By("Failing while nodes share a serial")
+		Expect(m.Up(ctx)).To(MatchError(
+			"error applying migration 15: nodes share the serials s1 (nodes node-1, node-2): " +
+				"delete the duplicate nodes and migrate again"))
+		Expect(m.Version(ctx)).To(Equal(14))
+
+		By("Migrating once the duplicate is deleted")
+		_, err := db.Exec("DELETE FROM nodes WHERE id = 'node-2'")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Up(ctx)).To(Succeed())
+		Expect(insertNode("node-4", "s1")).To(MatchError(MatchRegexp("Duplicate entry 's1'")))
+
+		By("Migrating down to version 14")
+		Expect(m.MigrateTo(ctx, 14)).To(Succeed())
+		Expect(insertNode("node-4", "s1")).To(Succeed())
+	})
+
+	It("Should return an error for an unknown version", func() {
+		Expect(m.MigrateTo(ctx, LatestVersion()+1)).To(MatchError(
+			MatchRegexp("unknown schema version")))
+		Expect(m.MigrateTo(ctx, -1)).To(MatchError(
+			MatchRegexp("unknown schema version")))
+	})
+
+	It("Should return ErrSchemaTooNew for a newer database", func() {
+		Expect(m.Up(ctx)).To(Succeed())
+		_, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", LatestVersion()+1)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = m.Up(ctx)
+		Expect(errors.Cause(err)).To(Equal(ErrSchemaTooNew))
+		Expect(m.Version(ctx)).To(Equal(LatestVersion() + 1))
+	})
+
+	It("Should record an unversioned schema as version 1", func() {
+		for _, stmt := range migrations[0].Up {
+			_, err := db.Exec(stmt)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(m.Version(ctx)).To(Equal(1))
+
+		By("Migrating up from version 1")
+		Expect(m.Up(ctx)).To(Succeed())
+		Expect(m.Version(ctx)).To(Equal(LatestVersion()))
+	})
+
+	It("Should wait for the migrations lock", func() {
+		conn, err := db.Conn(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.Close()
+
+		var locked int
+		Expect(conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", migrationsLock).Scan(&locked)).To(Succeed())
+		Expect(locked).To(Equal(1))
+
+		Expect(m.Up(ctx)).To(MatchError("timed out acquiring migrations lock"))
+		Expect(m.Version(ctx)).To(Equal(0))
+
+		By("Releasing the lock")
+		_, err = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(?)", migrationsLock)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(m.Up(ctx)).To(Succeed())
+	})
+
+	Describe("Interrupted migrations", func() {
+		var released []Migration
+
+		BeforeEach(func() {
+			released = migrations
+			migrations = []Migration{
+				{
+					Version: 1,
+					Up: []string{
+						"CREATE TABLE t1 (id INT)",
+						"CREATE TABLE t2 (id INT)",
+						"CREATE TABLE t3 (id MISSING)",
+					},
+					Down: []string{
+						"DROP TABLE t3",
+						"DROP TABLE t2",
+						"DROP TABLE t1",
+					},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			migrations = released
+		})
+
+		It("Should resume after the executed statements", func() {
+			Expect(m.Up(ctx)).To(MatchError(MatchRegexp("error applying migration 1")))
+			Expect(m.Version(ctx)).To(Equal(0))
+			Expect(tableNames(db)).To(ContainElement("t2"))
+
+			By("Reverting the interrupted migration")
+			Expect(m.MigrateTo(ctx, 0)).To(MatchError(
+				"applying migration 1 was interrupted, migrate to version 1 to resume"))
+
+			By("Resuming the migration")
+			migrations[0].Up[2] = "CREATE TABLE t3 (id INT)"
+			Expect(m.Up(ctx)).To(Succeed())
+			Expect(m.Version(ctx)).To(Equal(1))
+			Expect(tableNames(db)).To(ContainElement("t3"))
+
+			By("Resuming an interrupted down migration")
+			migrations[0].Down[1] = "DROP TABLE missing"
+			Expect(m.MigrateTo(ctx, 0)).To(MatchError(MatchRegexp("error reverting migration 1")))
+			Expect(m.Version(ctx)).To(Equal(1))
+			Expect(m.Up(ctx)).To(MatchError(
+				"reverting migration 1 was interrupted, migrate to version 0 to resume"))
+			migrations[0].Down[1] = "DROP TABLE t2"
+			Expect(m.MigrateTo(ctx, 0)).To(Succeed())
+			Expect(m.Version(ctx)).To(Equal(0))
+			Expect(tableNames(db)).To(ConsistOf("schema_migrations", "schema_migration_steps"))
+		})
+	})
+})
@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// migrations are the numbered schema migrations in version order. Released
+// migrations must never be edited: add a new migration instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial schema",
+		Up: []string{
+			// -------------
+			// Entity tables
+			// -------------
+
+			`CREATE TABLE nodes (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    serial VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.serial') STORED,
+    entity JSON
+)`,
+
+			// the grpc target for a node may or may not exist yet, so we
+			// specify ON DELETE CASCADE to handle deletion without requiring
+			// extra logic in the code
+			`CREATE TABLE node_grpc_targets (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    grpc_target VARCHAR(47) GENERATED ALWAYS AS (entity->>'$.grpc_target') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    UNIQUE KEY (node_id),
+    UNIQUE KEY (grpc_target)
+)`,
+
+			`CREATE TABLE apps (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    type VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.type') STORED,
+    entity JSON
+)`,
+
+			`CREATE TABLE traffic_policies (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    entity JSON
+)`,
+
+			`CREATE TABLE dns_configs (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    entity JSON
+)`,
+
+			`CREATE TABLE credentials (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    entity JSON
+)`,
+
+			// -------------------
+			// Primary join tables
+			// -------------------
+
+			// dns_configs x apps
+			`CREATE TABLE dns_configs_app_aliases (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    dns_config_id  VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.dns_config_id') STORED,
+    app_id  VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.app_id') STORED,
+    entity JSON,
+    FOREIGN KEY (dns_config_id) REFERENCES dns_configs(id),
+    FOREIGN KEY (app_id) REFERENCES apps(id),
+    UNIQUE KEY (dns_config_id, app_id)
+)`,
+
+			// nodes x apps
+			`CREATE TABLE nodes_apps (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    app_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.app_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id),
+    FOREIGN KEY (app_id) REFERENCES apps(id),
+    UNIQUE KEY (node_id, app_id)
+)`,
+
+			// nodes x dns_configs
+			`CREATE TABLE nodes_dns_configs (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED UNIQUE KEY,
+    dns_config_id VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.dns_config_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id),
+    FOREIGN KEY (dns_config_id) REFERENCES dns_configs(id)
+)`,
+
+			// nodes (network_interfaces) x traffic_policies
+			`CREATE TABLE nodes_network_interfaces_traffic_policies (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    network_interface_id VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.network_interface_id') STORED,
+    traffic_policy_id VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.traffic_policy_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id),
+    FOREIGN KEY (traffic_policy_id) REFERENCES traffic_policies(id),
+    UNIQUE KEY (node_id, network_interface_id)
+)`,
+
+			// ---------------------
+			// Secondary join tables
+			// ---------------------
+
+			// nodes_apps x traffic_policies
+			`CREATE TABLE nodes_apps_traffic_policies (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    nodes_apps_id VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.nodes_apps_id') STORED,
+    traffic_policy_id VARCHAR(36) GENERATED ALWAYS AS
+        (entity->>'$.traffic_policy_id') STORED,
+    entity JSON,
+    FOREIGN KEY (nodes_apps_id) REFERENCES nodes_apps(id),
+    FOREIGN KEY (traffic_policy_id) REFERENCES traffic_policies(id),
+    UNIQUE KEY (nodes_apps_id, traffic_policy_id)
+)`,
+		},
+		Down: []string{
+			"DROP TABLE nodes_apps_traffic_policies",
+			"DROP TABLE nodes_network_interfaces_traffic_policies",
+			"DROP TABLE nodes_dns_configs",
+			"DROP TABLE nodes_apps",
+			"DROP TABLE dns_configs_app_aliases",
+			"DROP TABLE credentials",
+			"DROP TABLE dns_configs",
+			"DROP TABLE traffic_policies",
+			"DROP TABLE apps",
+			"DROP TABLE node_grpc_targets",
+			"DROP TABLE nodes",
+		},
+	},
+	{
+		Version:     2,
+		Description: "users and API tokens",
+		Up: []string{
+			`CREATE TABLE users (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    username VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.username') STORED UNIQUE KEY,
+    entity JSON
+)`,
+
+			// revoking a token deletes it and deleting a user revokes its
+			// tokens
+			`CREATE TABLE api_tokens (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    user_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.user_id') STORED,
+    hash VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.hash') STORED UNIQUE KEY,
+    entity JSON,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+)`,
+		},
+		Down: []string{
+			"DROP TABLE api_tokens",
+			"DROP TABLE users",
+		},
+	},
+	{
+		Version:     3,
+		Description: "revoked tokens",
+		Up: []string{
+			// revoked tokens outlive their user until they expire
+			`CREATE TABLE revoked_tokens (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE revoked_tokens",
+		},
+	},
+	{
+		Version:     4,
+		Description: "audit events",
+		Up: []string{
+			// audit events are append-only
+			`CREATE TABLE audit_events (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE audit_events",
+		},
+	},
+	{
+		Version:     5,
+		Description: "revoked certificates",
+		Up: []string{
+			// revoked certificates outlive their node until they expire
+			`CREATE TABLE revoked_certificates (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    serial_number VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.serial_number') STORED UNIQUE KEY,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE revoked_certificates",
+		},
+	},
+	{
+		Version:     6,
+		Description: "credentials history",
+		Up: []string{
+			// credentials were stored by node ID before
+			`UPDATE credentials SET entity = JSON_SET(entity, '$.node_id', id, '$.superseded', false)
+    WHERE entity->>'$.node_id' IS NULL`,
+			`ALTER TABLE credentials
+    ADD COLUMN node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    ADD INDEX credentials_node_id (node_id)`,
+		},
+		Down: []string{
+			"DELETE FROM credentials WHERE entity->>'$.superseded' = 'true'",
+			`UPDATE credentials SET entity = JSON_REMOVE(JSON_SET(entity, '$.id', node_id), '$.node_id', '$.superseded')`,
+			"ALTER TABLE credentials DROP INDEX credentials_node_id, DROP COLUMN node_id",
+		},
+	},
+	{
+		Version:     7,
+		Description: "pending nodes",
+		Up: []string{
+			// nodes that requested enrollment without being approved
+			`CREATE TABLE pending_nodes (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    serial VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.serial') STORED UNIQUE KEY,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE pending_nodes",
+		},
+	},
+	{
+		Version:     8,
+		Description: "node health",
+		Up: []string{
+			// the health and status events of a node are deleted with it
+			`CREATE TABLE node_health (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    UNIQUE KEY (node_id)
+)`,
+			`CREATE TABLE node_status_events (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+)`,
+		},
+		Down: []string{
+			"DROP TABLE node_status_events",
+			"DROP TABLE node_health",
+		},
+	},
+	{
+		Version:     9,
+		Description: "node drift",
+		Up: []string{
+			// the drift report of a node is deleted with it
+			`CREATE TABLE node_drift (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE,
+    UNIQUE KEY (node_id)
+)`,
+		},
+		Down: []string{
+			"DROP TABLE node_drift",
+		},
+	},
+	{
+		Version:     10,
+		Description: "operations",
+		Up: []string{
+			// the operations on a node are deleted with it
+			`CREATE TABLE operations (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    node_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.node_id') STORED,
+    app_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.app_id') STORED,
+    status VARCHAR(16) GENERATED ALWAYS AS (entity->>'$.status') STORED,
+    entity JSON,
+    FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+)`,
+		},
+		Down: []string{
+			"DROP TABLE operations",
+		},
+	},
+	{
+		Version:     11,
+		Description: "rollouts",
+		Up: []string{
+			`CREATE TABLE rollouts (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    app_id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.app_id') STORED,
+    status VARCHAR(16) GENERATED ALWAYS AS (entity->>'$.status') STORED,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE rollouts",
+		},
+	},
+	{
+		Version:     12,
+		Description: "node groups",
+		Up: []string{
+			`CREATE TABLE node_groups (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    name VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.name') STORED,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE node_groups",
+		},
+	},
+	{
+		Version:     13,
+		Description: "interface profiles",
+		Up: []string{
+			`CREATE TABLE interface_profiles (
+    id VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.id') STORED UNIQUE KEY,
+    name VARCHAR(255) GENERATED ALWAYS AS (entity->>'$.name') STORED,
+    entity JSON
+)`,
+		},
+		Down: []string{
+			"DROP TABLE interface_profiles",
+		},
+	},
+	{
+		Version:     14,
+		Description: "node serials of SHA-256 hashes",
+		Up: []string{
+			"ALTER TABLE nodes MODIFY serial VARCHAR(64) GENERATED ALWAYS AS (entity->>'$.serial') STORED",
+		},
+		// Reverting fails while a node has a serial longer than 36 characters
+		Down: []string{
+			"ALTER TABLE nodes MODIFY serial VARCHAR(36) GENERATED ALWAYS AS (entity->>'$.serial') STORED",
+		},
+	},
+	{
+		Version:     15,
+		Description: "unique node serials",
+		Check:       checkUniqueNodeSerials,
+		Up: []string{
+			"ALTER TABLE nodes ADD UNIQUE KEY serial (serial)",
+		},
+		Down: []string{
+			"ALTER TABLE nodes DROP KEY serial",
+		},
+	},
+}
+
+// checkUniqueNodeSerials fails if nodes share a serial. The duplicates are not
+// deleted automatically since the configuration of every node is kept with it.
+func checkUniqueNodeSerials(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx,
+		`SELECT serial, GROUP_CONCAT(id ORDER BY id SEPARATOR ', ')
+         FROM nodes
+         GROUP BY serial
+         HAVING COUNT(*) > 1
+         ORDER BY serial`)
+	if err != nil {
+		return errors.Wrap(err, "error querying duplicate node serials")
+	}
+	defer rows.Close()
+
+	var dups []string
+	for rows.Next() {
+		var serial, ids string
+		if err = rows.Scan(&serial, &ids); err != nil {
+			return errors.Wrap(err, "error scanning duplicate node serials")
+		}
+		dups = append(dups, serial+" (nodes "+ids+")")
+	}
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, "error querying duplicate node serials")
+	}
+
+	if len(dups) > 0 {
+		return errors.Errorf("nodes share the serials %s: delete the duplicate nodes and migrate again",
+			strings.Join(dups, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/joho/godotenv"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// testDatabase is the scratch database created for each test in the MySQL
+// instance used by the controller tests.
+const testDatabase = "controller_ce_mysql_test"
+
+var serverDSN string
+
+var _ = BeforeSuite(func() {
+	Expect(godotenv.Load("../.env")).To(Succeed())
+	serverDSN = fmt.Sprintf("root:%s@tcp(:8083)/", os.Getenv("MYSQL_ROOT_PASSWORD"))
+})
+
+func TestMySQL(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MySQL Persistence Suite")
+}
+
+// createTestDatabase recreates the empty scratch database and returns a
+// handle on it.
+func createTestDatabase() *sql.DB {
+	server, err := sql.Open("mysql", serverDSN)
+	Expect(err).ToNot(HaveOccurred())
+	defer server.Close()
+
+	_, err = server.Exec("DROP DATABASE IF EXISTS " + testDatabase)
+	Expect(err).ToNot(HaveOccurred())
+	_, err = server.Exec("CREATE DATABASE " + testDatabase)
+	Expect(err).ToNot(HaveOccurred())
+
+	db, err := sql.Open("mysql", serverDSN+testDatabase)
+	Expect(err).ToNot(HaveOccurred())
+
+	return db
+}
+
+// dropTestDatabase closes the handle on the scratch database and drops it.
+func dropTestDatabase(db *sql.DB) {
+	Expect(db.Close()).To(Succeed())
+
+	server, err := sql.Open("mysql", serverDSN)
+	Expect(err).ToNot(HaveOccurred())
+	defer server.Close()
+
+	_, err = server.Exec("DROP DATABASE IF EXISTS " + testDatabase)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+// tableNames returns the names of the tables of the scratch database.
+func tableNames(db *sql.DB) []string {
+	rows, err := db.Query(
+		`SELECT table_name
+         FROM information_schema.tables
+         WHERE table_schema = DATABASE()`)
+	Expect(err).ToNot(HaveOccurred())
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		Expect(rows.Scan(&name)).To(Succeed())
+		names = append(names, name)
+	}
+	Expect(rows.Err()).ToNot(HaveOccurred())
+
+	return names
+}
@@ -31,12 +31,26 @@ func (s *PersistenceService) Create(
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
 
+	return insert(ctx, s.DB, e)
+}
+
+// execer executes statements on the DB or in a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insert inserts the record of a resource.
+func insert(ctx context.Context, db execer, e cce.Persistable) error {
+	if v, ok := e.(cce.Versioned); ok && v.GetResourceVersion() == 0 {
+		v.SetResourceVersion(1)
+	}
+
 	bytes, err := json.Marshal(e)
 	if err != nil {
 		return errors.Wrap(err, "error marshaling")
 	}
 
-	_, err = s.DB.ExecContext(
+	_, err = db.ExecContext(
 		ctx,
 		// gosec: Table name is not based on user input
 		fmt.Sprintf( //nolint:gosec
@@ -118,7 +132,7 @@ func (s *PersistenceService) Filter(
 		if !allowed {
 			return nil, errors.Errorf("disallowed filter field %q", f.Field)
 		}
-		fields = append(fields, fmt.Sprintf("%s = ?", f.Field))
+		fields = append(fields, fmt.Sprintf("%s = ?", column(f.Field)))
 		params = append(params, f.Value)
 	}
 	if len(params) > 0 {
@@ -143,6 +157,119 @@ func (s *PersistenceService) Filter(
 	return
 }
 
+// FilterPage retrieves a sorted page of a collection of resources of the given
+// type using a set of filters. The returned token requests the next page and
+// is empty on the last page.
+func (s *PersistenceService) FilterPage(
+	ctx context.Context,
+	zv cce.Filterable,
+	q cce.Query,
+) (es []cce.Persistable, next string, err error) {
+	// Create a timeout context for a DB operation
+	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
+	defer cancel()
+
+	// gosec: Only whitelisted fields are allowed to be injected into the SQL
+	// query
+	if err = q.Validate(zv); err != nil {
+		return nil, "", errors.Wrap(cce.ErrInvalidQuery, err.Error())
+	}
+	cursor, _ := q.Cursor()
+
+	var (
+		where  []string
+		params []interface{}
+	)
+	for _, f := range q.Filters {
+		where = append(where, fmt.Sprintf("%s = ?", column(f.Field)))
+		params = append(params, f.Value)
+	}
+	for _, r := range q.Selector {
+		// The label keys of a parsed selector cannot contain quotes
+		path := fmt.Sprintf(`$.labels."%s"`, r.Key)
+		switch r.Operator {
+		case cce.SelectorEquals:
+			where = append(where, "JSON_UNQUOTE(JSON_EXTRACT(entity, ?)) = ?")
+			params = append(params, path, r.Value)
+		case cce.SelectorNotEquals:
+			where = append(where, "(JSON_EXTRACT(entity, ?) IS NULL OR JSON_UNQUOTE(JSON_EXTRACT(entity, ?)) != ?)")
+			params = append(params, path, path, r.Value)
+		case cce.SelectorExists:
+			where = append(where, "JSON_CONTAINS_PATH(entity, 'one', ?)")
+			params = append(params, path)
+		case cce.SelectorDoesNotExist:
+			where = append(where, "NOT JSON_CONTAINS_PATH(entity, 'one', ?)")
+			params = append(params, path)
+		}
+	}
+
+	field, desc := q.SortField()
+	sortCol := "id"
+	if field != "id" {
+		// Absent fields sort as empty strings, like in the page cursor
+		sortCol = fmt.Sprintf("COALESCE(%s, '')", column(field))
+	}
+	order, cmp := "ASC", ">"
+	if desc {
+		order, cmp = "DESC", "<"
+	}
+	if cursor != nil {
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", sortCol, cmp))
+		params = append(params, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	// gosec: Table name is not based on user input
+	query := fmt.Sprintf("SELECT entity, %s FROM %s", sortCol, zv.GetTableName()) //nolint:gosec
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ") //nolint:gosec
+	}
+	query += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", sortCol, order)
+	if q.Limit > 0 {
+		// Fetch one more entity to know whether there is a next page
+		query += fmt.Sprintf(" LIMIT %d", q.Limit+1)
+	}
+
+	rows, err := s.DB.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "error running query")
+	}
+	defer rows.Close()
+
+	var last string
+	for rows.Next() {
+		if q.Limit > 0 && len(es) == q.Limit {
+			next = (&cce.PageCursor{Sort: q.Sort, Value: last, ID: es[len(es)-1].GetID()}).Token()
+			break
+		}
+
+		var (
+			bytes []byte
+			value string
+		)
+		if err = rows.Scan(&bytes, &value); err != nil {
+			return nil, "", errors.Wrap(err, "error scanning row")
+		}
+		e, err := unmarshal(bytes, zv)
+		if err != nil {
+			return nil, "", err
+		}
+
+		es = append(es, e)
+		last = value
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", errors.Wrap(err, "error reading rows")
+	}
+
+	return es, next, nil
+}
+
+// column returns the SQL expression of a filter field. It is the expression of
+// the generated columns so that their indexes are used where they exist.
+func column(field string) string {
+	return fmt.Sprintf("entity->>'$.%s'", field)
+}
+
 // ReadAll retrieves all resources of the given type.
 func (s *PersistenceService) ReadAll(
 	ctx context.Context,
@@ -182,6 +309,10 @@ func (s *PersistenceService) scan(
 		return nil, errors.Wrap(err, "error scanning row")
 	}
 
+	return unmarshal(bytes, zv)
+}
+
+func unmarshal(bytes []byte, zv cce.Persistable) (cce.Persistable, error) {
 	e := reflect.New(reflect.ValueOf(zv).Elem().Type()).Interface().(cce.Persistable)
 	if err := json.Unmarshal(bytes, e); err != nil {
 		return nil, errors.Wrap(err, "error unmarshaling")
@@ -190,39 +321,125 @@ func (s *PersistenceService) scan(
 	return e, nil
 }
 
-// BulkUpdate updates multiple resources.
+// BulkUpdate updates multiple resources. All updates are applied in a single
+// transaction. The resource version of Versioned resources is checked and
+// incremented.
 func (s *PersistenceService) BulkUpdate(
 	ctx context.Context,
 	es []cce.Persistable,
+) error {
+	return s.BulkWrite(ctx, nil, es)
+}
+
+// BulkWrite creates and updates multiple resources in a single transaction,
+// the creates first. Resources are created and updated as by Create and
+// BulkUpdate.
+func (s *PersistenceService) BulkWrite(
+	ctx context.Context,
+	creates []cce.Persistable,
+	updates []cce.Persistable,
 ) error {
 	// Create a timeout context for a DB operation
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
 
-	for _, e := range es {
-		bytes, err := json.Marshal(e)
-		if err != nil {
-			return errors.Wrap(err, "error marshaling")
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "error beginning transaction")
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Errf("Error rolling back transaction: %v", err)
 		}
+	}()
+
+	for _, e := range creates {
+		if err = insert(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+	for _, e := range updates {
+		if err = update(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return errors.Wrap(err, "error committing transaction")
+	}
 
-		_, err = s.DB.ExecContext(
-			ctx,
-			// gosec: Table name is not based on user input
-			fmt.Sprintf( //nolint:gosec
-				`UPDATE %s
-                 SET entity = ?
-                 WHERE id = JSON_EXTRACT(?, "$.id")`,
-				e.GetTableName()),
-			bytes, bytes)
+	return nil
+}
+
+// update updates the record of a resource in the transaction.
+func update(ctx context.Context, tx *sql.Tx, e cce.Persistable) error {
+	if v, ok := e.(cce.Versioned); ok {
+		found, err := checkVersion(ctx, tx, v)
 		if err != nil {
-			return errors.Wrap(err, "error updating record")
+			return err
+		}
+		if !found {
+			// nothing to update
+			return nil
 		}
 	}
 
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling")
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf( //nolint:gosec
+			`UPDATE %s
+             SET entity = ?
+             WHERE id = JSON_EXTRACT(?, "$.id")`,
+			e.GetTableName()),
+		bytes, bytes)
+	if err != nil {
+		return errors.Wrap(err, "error updating record")
+	}
+
 	return nil
 }
 
-// Delete deletes a resource of the given type.
+// checkVersion locks the row of a Versioned resource and verifies that its
+// resource version, if set, matches the persisted one. On success the resource
+// version is set to the next version. It returns false if the row does not
+// exist.
+func checkVersion(ctx context.Context, tx *sql.Tx, v cce.Versioned) (bool, error) {
+	var current uint64
+	err := tx.QueryRowContext(
+		ctx,
+		// gosec: Table name is not based on user input
+		fmt.Sprintf( //nolint:gosec
+			`SELECT COALESCE(CAST(entity->>'$.resource_version' AS UNSIGNED), 0)
+             FROM %s
+             WHERE id = ?
+             FOR UPDATE`, v.GetTableName()),
+		v.GetID()).Scan(&current)
+	switch {
+	case err == sql.ErrNoRows:
+		return false, nil
+	case err != nil:
+		return false, errors.Wrap(err, "error reading resource version")
+	}
+
+	if v.GetResourceVersion() != 0 && v.GetResourceVersion() != current {
+		return false, errors.Wrapf(cce.ErrVersionConflict,
+			"%s %s is at version %d, not %d",
+			v.GetTableName(), v.GetID(), current, v.GetResourceVersion())
+	}
+	v.SetResourceVersion(current + 1)
+
+	return true, nil
+}
+
+// Delete deletes a resource of the given type. If zv is Versioned with a
+// non-zero resource version, the resource is only deleted if the version
+// matches.
 func (s *PersistenceService) Delete(
 	ctx context.Context,
 	id string,
@@ -232,14 +449,25 @@ func (s *PersistenceService) Delete(
 	ctx, cancel := context.WithTimeout(ctx, cce.MaxDBRequestTime)
 	defer cancel()
 
+	var (
+		where  = "id = ?"
+		params = []interface{}{id}
+		rv     uint64
+	)
+	if v, ok := zv.(cce.Versioned); ok && v.GetResourceVersion() != 0 {
+		rv = v.GetResourceVersion()
+		where += " AND COALESCE(CAST(entity->>'$.resource_version' AS UNSIGNED), 0) = ?"
+		params = append(params, rv)
+	}
+
 	result, err := s.DB.ExecContext(
 		ctx,
 		// gosec: Table name is not based on user input
 		fmt.Sprintf( //nolint:gosec
 			`DELETE
              FROM %s
-             WHERE id = ?`, zv.GetTableName()),
-		id)
+             WHERE %s`, zv.GetTableName(), where),
+		params...)
 	if err != nil {
 		return false, errors.Wrap(err, "error deleting record")
 	}
@@ -250,7 +478,20 @@ func (s *PersistenceService) Delete(
 	}
 
 	if rows != 1 {
-		return false, nil
+		if rv == 0 {
+			return false, nil
+		}
+		// Distinguish a missing resource from a stale version
+		e, err := s.Read(ctx, id, zv)
+		if err != nil {
+			return false, err
+		}
+		if e == nil {
+			return false, nil
+		}
+		return false, errors.Wrapf(cce.ErrVersionConflict,
+			"%s %s is at version %d, not %d",
+			zv.GetTableName(), id, e.(cce.Versioned).GetResourceVersion(), rv)
 	}
 
 	return true, nil
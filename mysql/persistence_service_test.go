@@ -0,0 +1,246 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/uuid"
+	"github.com/pkg/errors"
+)
+
+var _ = Describe("PersistenceService", func() {
+	var (
+		ctx  = context.Background()
+		db   *sql.DB
+		ps   *PersistenceService
+		node *cce.Node
+	)
+
+	BeforeEach(func() {
+		By("Migrating a scratch database to the latest version")
+		db = createTestDatabase()
+		Expect((&Migrator{DB: db}).Up(ctx)).To(Succeed())
+		ps = &PersistenceService{DB: db}
+
+		By("Creating a node")
+		node = &cce.Node{
+			ID:       uuid.New(),
+			Name:     "test-node",
+			Location: "test-location",
+			Serial:   "test-serial",
+		}
+		Expect(ps.Create(ctx, node)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		dropTestDatabase(db)
+	})
+
+	Describe("Filter", func() {
+		It("Should return entities matching all filters", func() {
+			es, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{
+				{Field: "serial", Value: "test-serial"},
+				{Field: "location", Value: "test-location"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+
+			es, err = ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "serial", Value: "other"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(BeEmpty())
+		})
+
+		It("Should filter on a SHA-256 node serial", func() {
+			serial := cce.NodeSerial([]byte("test-public-key"))
+			Expect(len(serial)).To(BeNumerically(">", 36))
+			n := &cce.Node{ID: uuid.New(), Name: "sha-256", Location: "l", Serial: serial}
+			Expect(ps.Create(ctx, n)).To(Succeed())
+
+			es, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "serial", Value: serial}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{n}))
+		})
+
+		It("Should filter on fields without a generated column", func() {
+			es, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "name", Value: "test-node"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+		})
+
+		It("Should reject fields that are not filterable", func() {
+			_, err := ps.Filter(ctx, &cce.Node{}, []cce.Filter{{Field: "id", Value: node.ID}})
+			Expect(err).To(MatchError(`disallowed filter field "id"`))
+		})
+	})
+
+	Describe("FilterPage", func() {
+		var nodes []cce.Persistable
+
+		BeforeEach(func() {
+			nodes = []cce.Persistable{node}
+			for _, name := range []string{"c", "a", "b"} {
+				n := &cce.Node{ID: uuid.New(), Name: name, Location: "l", Serial: "s-" + name}
+				Expect(ps.Create(ctx, n)).To(Succeed())
+				nodes = append(nodes, n)
+			}
+		})
+
+		It("Should sort and page through the entities", func() {
+			q := cce.Query{
+				Filters: []cce.Filter{{Field: "location", Value: "l"}},
+				Sort:    "-name",
+				Limit:   2,
+			}
+			es, next, err := ps.FilterPage(ctx, &cce.Node{}, q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[1], nodes[3]}))
+			Expect(next).ToNot(BeEmpty())
+
+			q.PageToken = next
+			es, next, err = ps.FilterPage(ctx, &cce.Node{}, q)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[2]}))
+			Expect(next).To(BeEmpty())
+		})
+
+		It("Should page through entities with equal values by ID", func() {
+			q := cce.Query{Sort: "location", Limit: 1}
+			var ids []string
+			for {
+				es, next, err := ps.FilterPage(ctx, &cce.Node{}, q)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(es).To(HaveLen(1))
+				ids = append(ids, es[0].GetID())
+				if next == "" {
+					break
+				}
+				q.PageToken = next
+			}
+
+			Expect(ids).To(HaveLen(4))
+			Expect(sort.StringsAreSorted(ids[:3])).To(BeTrue())
+			Expect(ids[3]).To(Equal(node.ID))
+		})
+
+		It("Should select the entities by their labels", func() {
+			nodes[1].(*cce.Node).Labels = map[string]string{"region": "eu", "tier": "far-edge"}
+			nodes[2].(*cce.Node).Labels = map[string]string{"region": "eu"}
+			Expect(ps.BulkUpdate(ctx, nodes[1:3])).To(Succeed())
+
+			sel, err := cce.ParseSelector("region=eu,tier!=far-edge")
+			Expect(err).ToNot(HaveOccurred())
+			es, _, err := ps.FilterPage(ctx, &cce.Node{}, cce.Query{Selector: sel})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{nodes[2]}))
+		})
+
+		It("Should reject invalid queries", func() {
+			_, _, err := ps.FilterPage(ctx, &cce.Node{}, cce.Query{Sort: "resource_version"})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrInvalidQuery))
+
+			_, _, err = ps.FilterPage(ctx, &cce.Node{}, cce.Query{Sort: "name", PageToken: "malformed"})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrInvalidQuery))
+		})
+	})
+
+	Describe("BulkUpdate", func() {
+		It("Should increment the resource version", func() {
+			Expect(node.ResourceVersion).To(Equal(uint64(1)))
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+			Expect(node.ResourceVersion).To(Equal(uint64(2)))
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).ResourceVersion).To(Equal(uint64(2)))
+		})
+
+		It("Should reject a stale resource version", func() {
+			stale := *node
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+
+			stale.Name = "stale"
+			err := ps.BulkUpdate(ctx, []cce.Persistable{&stale})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).Name).To(Equal("test-node"))
+		})
+
+		It("Should update any resource version without one", func() {
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+
+			node.Name = "updated"
+			node.ResourceVersion = 0
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{node})).To(Succeed())
+			Expect(node.ResourceVersion).To(Equal(uint64(3)))
+
+			e, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.(*cce.Node).Name).To(Equal("updated"))
+		})
+
+		It("Should skip an unknown entity", func() {
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{
+				&cce.Node{ID: uuid.New(), Name: "n2", ResourceVersion: 1},
+			})).To(Succeed())
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+		})
+	})
+
+	Describe("BulkWrite", func() {
+		It("Should create and update the entities", func() {
+			created := &cce.Node{ID: uuid.New(), Name: "n2", Location: "l", Serial: "s2"}
+			node.Name = "updated"
+			Expect(ps.BulkWrite(ctx, []cce.Persistable{created}, []cce.Persistable{node})).To(Succeed())
+			Expect(created.ResourceVersion).To(Equal(uint64(1)))
+			Expect(node.ResourceVersion).To(Equal(uint64(2)))
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node, created}))
+		})
+
+		It("Should write nothing if a write fails", func() {
+			created := &cce.Node{ID: uuid.New(), Name: "n2", Location: "l", Serial: "s2"}
+			duplicate := &cce.Node{ID: node.ID, Name: "n3", Location: "l", Serial: "s3"}
+			updated := *node
+			updated.Name = "updated"
+			Expect(ps.BulkWrite(
+				ctx,
+				[]cce.Persistable{created, duplicate},
+				[]cce.Persistable{&updated})).ToNot(Succeed())
+
+			es, err := ps.ReadAll(ctx, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(es).To(Equal([]cce.Persistable{node}))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("Should return false for an unknown ID", func() {
+			ok, err := ps.Delete(ctx, uuid.New(), &cce.Node{ResourceVersion: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+
+		It("Should reject a stale resource version", func() {
+			_, err := ps.Delete(ctx, node.ID, &cce.Node{ResourceVersion: 2})
+			Expect(errors.Cause(err)).To(Equal(cce.ErrVersionConflict))
+
+			ok, err := ps.Delete(ctx, node.ID, &cce.Node{ResourceVersion: 1})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
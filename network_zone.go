@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxZoneIDLength is the maximum length of the ID of a network zone.
+const MaxZoneIDLength = 63
+
+var zoneIDRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+
+// NetworkZone is a network zone of a node. The network interfaces of the node
+// refer to the zones they are in by ID.
+type NetworkZone struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// Validate validates the model.
+func (z *NetworkZone) Validate() error {
+	if z.ID == "" {
+		return errors.New("id cannot be empty")
+	}
+	if len(z.ID) > MaxZoneIDLength || !zoneIDRegexp.MatchString(z.ID) {
+		return fmt.Errorf("id %q is invalid", z.ID)
+	}
+
+	return nil
+}
+
+func (z *NetworkZone) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+NetworkZone[
+    ID: %s
+    Description: %s
+]`),
+		z.ID,
+		z.Description)
+}
+
+// CheckInterfaceZones checks that the zones of the network interfaces are
+// among the zones.
+func CheckInterfaceZones(ifaces []*NetworkInterface, zones []*NetworkZone) error {
+	ids := make(map[string]bool, len(zones))
+	for _, z := range zones {
+		ids[z.ID] = true
+	}
+
+	for _, ni := range ifaces {
+		for _, id := range ni.Zones {
+			if !ids[id] {
+				return fmt.Errorf("zone %s of network interface %s not found", id, ni.ID)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: NetworkZone", func() {
+	var zone *cce.NetworkZone
+
+	BeforeEach(func() {
+		zone = &cce.NetworkZone{
+			ID:          "far-edge",
+			Description: "far edge zone",
+		}
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(zone.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is empty", func() {
+			zone.ID = ""
+			Expect(zone.Validate()).To(MatchError("id cannot be empty"))
+		})
+
+		It("Should return an error if ID is invalid", func() {
+			zone.ID = "far edge"
+			Expect(zone.Validate()).To(MatchError(`id "far edge" is invalid`))
+		})
+
+		It("Should return an error if ID is too long", func() {
+			zone.ID = strings.Repeat("a", cce.MaxZoneIDLength+1)
+			Expect(zone.Validate()).To(MatchError(`id "` + zone.ID + `" is invalid`))
+		})
+	})
+
+	Describe("CheckInterfaceZones", func() {
+		ifaces := []*cce.NetworkInterface{
+			{ID: "if0", Zones: []string{"far-edge"}},
+			{ID: "if1"},
+		}
+
+		It("Should accept the zones of the node", func() {
+			Expect(cce.CheckInterfaceZones(ifaces, []*cce.NetworkZone{zone})).To(Succeed())
+		})
+
+		It("Should return an error if a zone is not found", func() {
+			Expect(cce.CheckInterfaceZones(ifaces, nil)).To(MatchError(
+				"zone far-edge of network interface if0 not found"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(zone.String()).To(Equal(strings.TrimSpace(`
+NetworkZone[
+    ID: far-edge
+    Description: far edge zone
+]`,
+			)))
+		})
+	})
+})
@@ -17,6 +17,10 @@ type Node struct {
 	Name     string `json:"name"`
 	Location string `json:"location"`
 	Serial   string `json:"serial"`
+	// Labels are the key/value labels selected by label selectors.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
 }
 
 // NodeReq is a Node request.
@@ -67,11 +71,26 @@ func (n *Node) SetID(id string) {
 	n.ID = id
 }
 
+// GetResourceVersion gets the resource version.
+func (n *Node) GetResourceVersion() uint64 {
+	return n.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (n *Node) SetResourceVersion(v uint64) {
+	n.ResourceVersion = v
+}
+
 // GetNodeID gets the node ID.
 func (n *Node) GetNodeID() string {
 	return n.ID
 }
 
+// GetLabels gets the labels.
+func (n *Node) GetLabels() map[string]string {
+	return n.Labels
+}
+
 // Validate validates the model.
 func (n *Node) Validate() error {
 	if !uuid.IsValid(n.ID) {
@@ -87,13 +106,15 @@ func (n *Node) Validate() error {
 		return errors.New("serial cannot be empty")
 	}
 
-	return nil
+	return ValidateLabels(n.Labels)
 }
 
 // FilterFields returns the filterable fields for this model.
 func (*Node) FilterFields() []string {
 	return []string{
 		"serial",
+		"name",
+		"location",
 	}
 }
 
@@ -104,11 +125,13 @@ Node[
     Name: %s
     Location: %s
     Serial: %s
+    Labels: %v
 ]`),
 		n.ID,
 		n.Name,
 		n.Location,
-		n.Serial)
+		n.Serial,
+		n.Labels)
 }
 
 // Validate validates the request model.
@@ -16,6 +16,8 @@ type NodeApp struct {
 	ID     string `json:"id"`
 	NodeID string `json:"node_id"`
 	AppID  string `json:"app_id"`
+	// Revision is the revision of the app deployed to the node.
+	Revision int `json:"revision,omitempty"`
 }
 
 // NodeAppReq is a NodeApp request.
@@ -102,8 +104,10 @@ NodeApp[
     ID: %s
     NodeID: %s
     AppID: %s
+    Revision: %d
 ]`),
 		n_a.ID,
 		n_a.NodeID,
-		n_a.AppID)
+		n_a.AppID,
+		n_a.Revision)
 }
@@ -88,6 +88,7 @@ NodeApp[
     ID: 7a41f67a-086a-4ec2-a980-5db97d9c9f4e
     NodeID: 48606c73-3905-47e0-864f-14bc7466f5bb
     AppID: efcece3c-6b58-4993-8d45-bde6239d4baa
+    Revision: 0
 ]`,
 			)))
 		})
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Kinds of configuration that drift on a node.
+const (
+	NodeDriftApp             = "app"
+	NodeDriftDNSConfig       = "dns_config"
+	NodeDriftAppPolicy       = "app_policy"
+	NodeDriftInterfacePolicy = "interface_policy"
+)
+
+// NodeDrift is the result of the last reconciliation of a node: the
+// configuration of the node that differed from the persisted one.
+type NodeDrift struct {
+	ID           string          `json:"id"`
+	NodeID       string          `json:"node_id"`
+	ReconciledAt time.Time       `json:"reconciled_at"`
+	Items        []NodeDriftItem `json:"items"`
+	// Error is set if the node could not be reconciled.
+	Error string `json:"error,omitempty"`
+}
+
+// NodeDriftItem is a configuration that differed on a node.
+type NodeDriftItem struct {
+	Kind string `json:"kind"`
+	// ID is the ID of the app, DNS configuration or interface.
+	ID     string `json:"id"`
+	Detail string `json:"detail"`
+	// Repaired is set if the persisted configuration was re-applied.
+	Repaired bool `json:"repaired"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*NodeDrift) GetTableName() string {
+	return "node_drift"
+}
+
+// GetID gets the ID.
+func (d *NodeDrift) GetID() string {
+	return d.ID
+}
+
+// SetID sets the ID.
+func (d *NodeDrift) SetID(id string) {
+	d.ID = id
+}
+
+// GetNodeID gets the node ID.
+func (d *NodeDrift) GetNodeID() string {
+	return d.NodeID
+}
+
+// Validate validates the model.
+func (d *NodeDrift) Validate() error {
+	if !uuid.IsValid(d.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(d.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	if d.ReconciledAt.IsZero() {
+		return errors.New("reconciled_at cannot be empty")
+	}
+	for i, item := range d.Items {
+		switch item.Kind {
+		case NodeDriftApp, NodeDriftDNSConfig, NodeDriftAppPolicy, NodeDriftInterfacePolicy:
+		default:
+			return fmt.Errorf("items[%d].kind must be one of [%s, %s, %s, %s]", i,
+				NodeDriftApp, NodeDriftDNSConfig, NodeDriftAppPolicy, NodeDriftInterfacePolicy)
+		}
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*NodeDrift) FilterFields() []string {
+	return []string{
+		"node_id",
+	}
+}
+
+func (d *NodeDrift) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+NodeDrift[
+    ID: %s
+    NodeID: %s
+    ReconciledAt: %s
+    Items: %+v
+    Error: %s
+]`),
+		d.ID,
+		d.NodeID,
+		d.ReconciledAt,
+		d.Items,
+		d.Error)
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: NodeDrift", func() {
+	var drift *cce.NodeDrift
+
+	BeforeEach(func() {
+		drift = &cce.NodeDrift{
+			ID:           "6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e",
+			NodeID:       "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+			ReconciledAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			Items: []cce.NodeDriftItem{
+				{
+					Kind:     cce.NodeDriftApp,
+					ID:       "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c",
+					Detail:   "not deployed",
+					Repaired: true,
+				},
+			},
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "node_drift"`, func() {
+			Expect(drift.GetTableName()).To(Equal("node_drift"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(drift.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			drift.ID = "123"
+			Expect(drift.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			drift.NodeID = "123"
+			Expect(drift.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error if ReconciledAt is empty", func() {
+			drift.ReconciledAt = time.Time{}
+			Expect(drift.Validate()).To(MatchError("reconciled_at cannot be empty"))
+		})
+
+		It("Should return an error if an item kind is invalid", func() {
+			drift.Items[0].Kind = "zone"
+			Expect(drift.Validate()).To(MatchError(
+				"items[0].kind must be one of [app, dns_config, app_policy, interface_policy]"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(drift.FilterFields()).To(Equal([]string{
+				"node_id",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(drift.String()).To(Equal(strings.TrimSpace(`
+NodeDrift[
+    ID: 6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e
+    NodeID: 9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e
+    ReconciledAt: 2019-12-01 00:00:00 +0000 UTC
+    Items: [{Kind:app ID:3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c Detail:not deployed Repaired:true}]
+    Error: 
+]`,
+			)))
+		})
+	})
+})
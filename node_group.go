@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// NodeGroup is a group of the nodes whose labels match a selector. The apps,
+// DNS configuration and app traffic policies of the group are assigned to
+// every node of the group, including the nodes that later join it.
+type NodeGroup struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Selector is the label selector of the nodes of the group, such as
+	// "region=eu,tier=far-edge".
+	Selector string `json:"selector"`
+	// Apps are the IDs of the apps deployed to the nodes of the group.
+	Apps []string `json:"apps"`
+	// DNSConfigID is the DNS configuration of the nodes of the group that do
+	// not have one.
+	DNSConfigID     string                   `json:"dns_config_id,omitempty"`
+	TrafficPolicies []NodeGroupTrafficPolicy `json:"traffic_policies"`
+}
+
+// NodeGroupTrafficPolicy is the traffic policy of an app of a node group.
+type NodeGroupTrafficPolicy struct {
+	AppID           string `json:"app_id"`
+	TrafficPolicyID string `json:"traffic_policy_id"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*NodeGroup) GetTableName() string {
+	return "node_groups"
+}
+
+// GetID gets the ID.
+func (g *NodeGroup) GetID() string {
+	return g.ID
+}
+
+// SetID sets the ID.
+func (g *NodeGroup) SetID(id string) {
+	g.ID = id
+}
+
+// Validate validates the model.
+func (g *NodeGroup) Validate() error { // nolint: gocyclo
+	if !uuid.IsValid(g.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if strings.TrimSpace(g.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	sel, err := ParseSelector(g.Selector)
+	if err != nil {
+		return err
+	}
+	if sel.Empty() {
+		return errEmptySelector
+	}
+	apps := make(map[string]bool, len(g.Apps))
+	for i, id := range g.Apps {
+		if !uuid.IsValid(id) {
+			return fmt.Errorf("apps[%d] not a valid uuid", i)
+		}
+		if apps[id] {
+			return fmt.Errorf("apps[%d] %s is duplicated", i, id)
+		}
+		apps[id] = true
+	}
+	if g.DNSConfigID != "" && !uuid.IsValid(g.DNSConfigID) {
+		return errors.New("dns_config_id not a valid uuid")
+	}
+	policies := make(map[string]bool, len(g.TrafficPolicies))
+	for i, p := range g.TrafficPolicies {
+		if !apps[p.AppID] {
+			return fmt.Errorf("traffic_policies[%d].app_id not an app of the group", i)
+		}
+		if policies[p.AppID] {
+			return fmt.Errorf("traffic_policies[%d].app_id %s is duplicated", i, p.AppID)
+		}
+		policies[p.AppID] = true
+		if !uuid.IsValid(p.TrafficPolicyID) {
+			return fmt.Errorf("traffic_policies[%d].traffic_policy_id not a valid uuid", i)
+		}
+	}
+
+	return nil
+}
+
+// Matches returns whether the node is in the group. A group with an invalid
+// selector matches no node.
+func (g *NodeGroup) Matches(n *Node) bool {
+	sel, err := ParseSelector(g.Selector)
+	if err != nil || sel.Empty() {
+		return false
+	}
+
+	return sel.Matches(n.Labels)
+}
+
+// TrafficPolicyID returns the ID of the traffic policy of the app of the
+// group, or an empty string if the app has none.
+func (g *NodeGroup) TrafficPolicyID(appID string) string {
+	for _, p := range g.TrafficPolicies {
+		if p.AppID == appID {
+			return p.TrafficPolicyID
+		}
+	}
+
+	return ""
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*NodeGroup) FilterFields() []string {
+	return []string{
+		"name",
+	}
+}
+
+func (g *NodeGroup) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+NodeGroup[
+    ID: %s
+    Name: %s
+    Selector: %s
+    Apps: %v
+    DNSConfigID: %s
+    TrafficPolicies: %+v
+]`),
+		g.ID,
+		g.Name,
+		g.Selector,
+		g.Apps,
+		g.DNSConfigID,
+		g.TrafficPolicies)
+}
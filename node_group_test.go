@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: NodeGroup", func() {
+	var group *cce.NodeGroup
+
+	BeforeEach(func() {
+		group = &cce.NodeGroup{
+			ID:          "2c4e6a8b-1d3f-4a5c-9e7b-0f2d4b6a8c1e",
+			Name:        "eu-far-edge",
+			Selector:    "region=eu,tier=far-edge",
+			Apps:        []string{"3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c"},
+			DNSConfigID: "7c4d9e2b-6a1f-4b8c-8d3e-2f5a7b9c1d0e",
+			TrafficPolicies: []cce.NodeGroupTrafficPolicy{
+				{
+					AppID:           "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c",
+					TrafficPolicyID: "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+				},
+			},
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "node_groups"`, func() {
+			Expect(group.GetTableName()).To(Equal("node_groups"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(group.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			group.ID = "123"
+			Expect(group.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if Name is empty", func() {
+			group.Name = " "
+			Expect(group.Validate()).To(MatchError("name cannot be empty"))
+		})
+
+		It("Should return an error if Selector is empty", func() {
+			group.Selector = ""
+			Expect(group.Validate()).To(MatchError("selector cannot be empty"))
+		})
+
+		It("Should return an error if Selector is invalid", func() {
+			group.Selector = "region=e u"
+			Expect(group.Validate()).To(MatchError(`selector "region=e u": label region value "e u" is invalid`))
+		})
+
+		It("Should return an error if an app ID is not a UUID", func() {
+			group.Apps[0] = "123"
+			Expect(group.Validate()).To(MatchError("apps[0] not a valid uuid"))
+		})
+
+		It("Should return an error if an app is duplicated", func() {
+			group.Apps = append(group.Apps, group.Apps[0])
+			Expect(group.Validate()).To(MatchError(
+				"apps[1] 3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c is duplicated"))
+		})
+
+		It("Should return an error if DNSConfigID is not a UUID", func() {
+			group.DNSConfigID = "123"
+			Expect(group.Validate()).To(MatchError("dns_config_id not a valid uuid"))
+		})
+
+		It("Should return an error if a traffic policy app is not an app of the group", func() {
+			group.Apps = nil
+			Expect(group.Validate()).To(MatchError("traffic_policies[0].app_id not an app of the group"))
+		})
+
+		It("Should return an error if a traffic policy ID is not a UUID", func() {
+			group.TrafficPolicies[0].TrafficPolicyID = "123"
+			Expect(group.Validate()).To(MatchError("traffic_policies[0].traffic_policy_id not a valid uuid"))
+		})
+	})
+
+	Describe("Matches", func() {
+		It("Should match the nodes with matching labels", func() {
+			Expect(group.Matches(&cce.Node{Labels: map[string]string{
+				"region": "eu",
+				"tier":   "far-edge",
+				"gpu":    "",
+			}})).To(BeTrue())
+			Expect(group.Matches(&cce.Node{Labels: map[string]string{"region": "eu"}})).To(BeFalse())
+			Expect(group.Matches(&cce.Node{})).To(BeFalse())
+		})
+	})
+
+	Describe("TrafficPolicyID", func() {
+		It("Should return the traffic policy of the app", func() {
+			Expect(group.TrafficPolicyID("3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c")).To(
+				Equal("9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e"))
+			Expect(group.TrafficPolicyID("7c4d9e2b-6a1f-4b8c-8d3e-2f5a7b9c1d0e")).To(BeEmpty())
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(group.FilterFields()).To(Equal([]string{
+				"name",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(group.String()).To(Equal(strings.TrimSpace(`
+NodeGroup[
+    ID: 2c4e6a8b-1d3f-4a5c-9e7b-0f2d4b6a8c1e
+    Name: eu-far-edge
+    Selector: region=eu,tier=far-edge
+    Apps: [3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c]
+    DNSConfigID: 7c4d9e2b-6a1f-4b8c-8d3e-2f5a7b9c1d0e
+    TrafficPolicies: [{AppID:3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c TrafficPolicyID:9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e}]
+]`,
+			)))
+		})
+	})
+})
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Connectivity statuses of a node.
+const (
+	// NodeStatusOnline is the status of a node reachable on all of its ports.
+	NodeStatusOnline = "online"
+	// NodeStatusDegraded is the status of a node reachable on some of its
+	// ports, or only with a high latency.
+	NodeStatusDegraded = "degraded"
+	// NodeStatusOffline is the status of a node that is not reachable.
+	NodeStatusOffline = "offline"
+)
+
+// NodeHealth is the connectivity status of an enrolled node, as last probed
+// by the controller.
+type NodeHealth struct {
+	ID     string `json:"id"`
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	// LastSeen is when the node was last reachable, or zero if it never was.
+	LastSeen time.Time `json:"last_seen"`
+	// Latency is how long it took to connect to the node when it was last
+	// reachable.
+	Latency   time.Duration `json:"latency"`
+	CheckedAt time.Time     `json:"checked_at"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*NodeHealth) GetTableName() string {
+	return "node_health"
+}
+
+// GetID gets the ID.
+func (h *NodeHealth) GetID() string {
+	return h.ID
+}
+
+// SetID sets the ID.
+func (h *NodeHealth) SetID(id string) {
+	h.ID = id
+}
+
+// GetNodeID gets the node ID.
+func (h *NodeHealth) GetNodeID() string {
+	return h.NodeID
+}
+
+// Validate validates the model.
+func (h *NodeHealth) Validate() error {
+	if !uuid.IsValid(h.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(h.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	switch h.Status {
+	case NodeStatusOnline, NodeStatusDegraded, NodeStatusOffline:
+	default:
+		return fmt.Errorf("status must be one of [%s, %s, %s]",
+			NodeStatusOnline, NodeStatusDegraded, NodeStatusOffline)
+	}
+	if h.CheckedAt.IsZero() {
+		return errors.New("checked_at cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*NodeHealth) FilterFields() []string {
+	return []string{
+		"node_id",
+		"status",
+	}
+}
+
+func (h *NodeHealth) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+NodeHealth[
+    ID: %s
+    NodeID: %s
+    Status: %s
+    LastSeen: %s
+    Latency: %s
+    CheckedAt: %s
+]`),
+		h.ID,
+		h.NodeID,
+		h.Status,
+		h.LastSeen,
+		h.Latency,
+		h.CheckedAt)
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: NodeHealth", func() {
+	var health *cce.NodeHealth
+
+	BeforeEach(func() {
+		health = &cce.NodeHealth{
+			ID:        "6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e",
+			NodeID:    "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+			Status:    cce.NodeStatusOnline,
+			LastSeen:  time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			Latency:   25 * time.Millisecond,
+			CheckedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "node_health"`, func() {
+			Expect(health.GetTableName()).To(Equal("node_health"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(health.Validate()).To(Succeed())
+		})
+
+		It("Should not return an error if the node was never seen", func() {
+			health.Status = cce.NodeStatusOffline
+			health.LastSeen = time.Time{}
+			Expect(health.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			health.ID = "123"
+			Expect(health.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			health.NodeID = "123"
+			Expect(health.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error if Status is invalid", func() {
+			health.Status = "unknown"
+			Expect(health.Validate()).To(MatchError("status must be one of [online, degraded, offline]"))
+		})
+
+		It("Should return an error if CheckedAt is empty", func() {
+			health.CheckedAt = time.Time{}
+			Expect(health.Validate()).To(MatchError("checked_at cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(health.FilterFields()).To(Equal([]string{
+				"node_id",
+				"status",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(health.String()).To(Equal(strings.TrimSpace(`
+NodeHealth[
+    ID: 6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e
+    NodeID: 9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e
+    Status: online
+    LastSeen: 2019-12-01 00:00:00 +0000 UTC
+    Latency: 25ms
+    CheckedAt: 2019-12-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
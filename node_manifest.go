@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Media types of node manifests.
+const (
+	NodeManifestJSON = "application/json"
+	NodeManifestCSV  = "text/csv"
+)
+
+// nodeManifestColumns are the columns of a CSV node manifest.
+var nodeManifestColumns = []string{"name", "location", "serial"}
+
+// ParseNodeManifest parses a manifest of nodes to import of the media type.
+// A JSON manifest is an array of objects with the name, location and serial of
+// each node. A CSV manifest has a header row naming the name, location and
+// serial columns in any order, followed by a row per node. The nodes have no
+// ID and are not validated.
+func ParseNodeManifest(r io.Reader, mediaType string) ([]*Node, error) {
+	switch mediaType {
+	case NodeManifestJSON:
+		var nodes []*Node
+		if err := json.NewDecoder(r).Decode(&nodes); err != nil {
+			return nil, fmt.Errorf("invalid JSON manifest: %v", err)
+		}
+		for i, n := range nodes {
+			if n == nil {
+				return nil, fmt.Errorf("nodes[%d] cannot be null", i)
+			}
+		}
+		return nodes, nil
+	case NodeManifestCSV:
+		return parseCSVNodeManifest(r)
+	default:
+		return nil, fmt.Errorf("unsupported manifest type %q", mediaType)
+	}
+}
+
+func parseCSVNodeManifest(r io.Reader) ([]*Node, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV manifest: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV manifest has no header row")
+	}
+
+	// Map the columns named by the header row
+	index := make(map[string]int)
+	for i, name := range records[0] {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range nodeManifestColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("CSV manifest has no %s column", name)
+		}
+	}
+
+	nodes := []*Node{}
+	for _, record := range records[1:] {
+		nodes = append(nodes, &Node{
+			Name:     strings.TrimSpace(record[index["name"]]),
+			Location: strings.TrimSpace(record[index["location"]]),
+			Serial:   strings.TrimSpace(record[index["serial"]]),
+		})
+	}
+
+	return nodes, nil
+}
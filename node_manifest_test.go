@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("ParseNodeManifest", func() {
+	expected := []*cce.Node{
+		{Name: "node-1", Location: "Site A", Serial: "ysRMvw8aMviv9qPcXIlAsA"},
+		{Name: "node-2", Location: "Site B", Serial: "cJl_0X_uNWvMGiYNS4_PSA"},
+	}
+
+	It("Should parse a JSON manifest", func() {
+		nodes, err := cce.ParseNodeManifest(strings.NewReader(`[
+			{"name": "node-1", "location": "Site A", "serial": "ysRMvw8aMviv9qPcXIlAsA"},
+			{"name": "node-2", "location": "Site B", "serial": "cJl_0X_uNWvMGiYNS4_PSA"}
+		]`), cce.NodeManifestJSON)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodes).To(Equal(expected))
+	})
+
+	It("Should parse a CSV manifest with columns in any order", func() {
+		nodes, err := cce.ParseNodeManifest(strings.NewReader(strings.TrimSpace(`
+serial,name,location
+ysRMvw8aMviv9qPcXIlAsA,node-1,Site A
+cJl_0X_uNWvMGiYNS4_PSA, node-2 ,Site B`)), cce.NodeManifestCSV)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodes).To(Equal(expected))
+	})
+
+	It("Should return an error if a CSV column is missing", func() {
+		_, err := cce.ParseNodeManifest(strings.NewReader("name,serial\nnode-1,ysRMvw8aMviv9qPcXIlAsA"),
+			cce.NodeManifestCSV)
+		Expect(err).To(MatchError("CSV manifest has no location column"))
+	})
+
+	It("Should return an error if the CSV rows have different lengths", func() {
+		_, err := cce.ParseNodeManifest(strings.NewReader("name,location,serial\nnode-1,Site A"),
+			cce.NodeManifestCSV)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should return an error if the JSON manifest is not an array", func() {
+		_, err := cce.ParseNodeManifest(strings.NewReader(`{"name": "node-1"}`), cce.NodeManifestJSON)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should return an error for an unsupported media type", func() {
+		_, err := cce.ParseNodeManifest(strings.NewReader(""), "text/plain")
+		Expect(err).To(MatchError(`unsupported manifest type "text/plain"`))
+	})
+})
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"context"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NodeSerial returns the serial identifying the node with the DER-encoded
+// public key: the base64-encoded (w/o padding) SHA-256 hash of the key.
+func NodeSerial(publicKeyDER []byte) string {
+	hash := sha256.Sum256(publicKeyDER)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// LegacyNodeSerial returns the serial nodes were identified by before
+// NodeSerial: the base64-encoded (w/o padding) MD5 hash of the key. It is only
+// used to match nodes approved before the transition.
+func LegacyNodeSerial(publicKeyDER []byte) string {
+	// gosec: not used for new serials
+	hash := md5.Sum(publicKeyDER) //nolint:gosec
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// MigrateNodeSerials rewrites the legacy serial of every enrolled node to the
+// serial of the public key of its current certificate and returns the number
+// of nodes rewritten. The serial of a node that is not enrolled cannot be
+// derived, as its public key is unknown, and is rewritten when it enrolls.
+func MigrateNodeSerials(ctx context.Context, ps PersistenceService) (int, error) {
+	nodes, err := ps.ReadAll(ctx, &Node{})
+	if err != nil {
+		return 0, err
+	}
+
+	var migrated []Persistable
+	for _, e := range nodes {
+		node := e.(*Node)
+		creds, err := CurrentCredentials(ctx, ps, node.ID)
+		if err != nil {
+			return 0, err
+		}
+		if creds == nil {
+			continue
+		}
+		cert, err := creds.ParseCertificate()
+		if err != nil {
+			return 0, err
+		}
+
+		// Only rewrite the serial the node was actually approved by
+		if node.Serial == LegacyNodeSerial(cert.RawSubjectPublicKeyInfo) {
+			node.Serial = NodeSerial(cert.RawSubjectPublicKeyInfo)
+			migrated = append(migrated, node)
+		}
+	}
+	if len(migrated) == 0 {
+		return 0, nil
+	}
+
+	if err = ps.BulkUpdate(ctx, migrated); err != nil {
+		return 0, err
+	}
+
+	return len(migrated), nil
+}
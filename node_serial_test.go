@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	"github.com/open-ness/edgecontroller/uuid"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Node serials", func() {
+	Describe("NodeSerial", func() {
+		It("Should return the base64-encoded SHA-256 hash of the key", func() {
+			Expect(cce.NodeSerial([]byte("public key"))).To(
+				Equal("9WmobTwsjX3aJrXb6iC9XBnus138Y_23JLrE8hwieFA"))
+		})
+	})
+
+	Describe("LegacyNodeSerial", func() {
+		It("Should return the base64-encoded MD5 hash of the key", func() {
+			Expect(cce.LegacyNodeSerial([]byte("public key"))).To(
+				Equal("0dj2ay6LLwv9w3m2iTEbeA"))
+		})
+	})
+
+	Describe("MigrateNodeSerials", func() {
+		var (
+			ctx    = context.Background()
+			tmpDir string
+			db     *bbolt.DB
+			ps     *bolt.PersistenceService
+		)
+
+		BeforeEach(func() {
+			var err error
+
+			By("Opening a db in a temp directory")
+			tmpDir, err = ioutil.TempDir("", "cce_test")
+			Expect(err).ToNot(HaveOccurred())
+			db, err = bbolt.Open(filepath.Join(tmpDir, "controller_ce.db"), 0600, nil)
+			Expect(err).ToNot(HaveOccurred())
+			ps = &bolt.PersistenceService{DB: db}
+		})
+
+		AfterEach(func() {
+			Expect(db.Close()).To(Succeed())
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		})
+
+		// enroll creates a node approved by the serial function and its
+		// credentials.
+		enroll := func(serial func([]byte) string) *cce.Node {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			node := &cce.Node{
+				ID:       uuid.New(),
+				Name:     "test-node",
+				Location: "test-location",
+			}
+			der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: node.ID},
+				NotAfter:     time.Now().Add(time.Hour),
+			}, &x509.Certificate{}, key.Public(), key)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := x509.ParseCertificate(der)
+			Expect(err).ToNot(HaveOccurred())
+
+			node.Serial = serial(cert.RawSubjectPublicKeyInfo)
+			Expect(ps.Create(ctx, node)).To(Succeed())
+			Expect(ps.Create(ctx, &cce.Credentials{
+				ID:          uuid.New(),
+				NodeID:      node.ID,
+				Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			})).To(Succeed())
+
+			return node
+		}
+
+		serialOf := func(node *cce.Node) string {
+			persisted, err := ps.Read(ctx, node.ID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			return persisted.(*cce.Node).Serial
+		}
+
+		It("Should rewrite the legacy serials of enrolled nodes", func() {
+			legacy := enroll(cce.LegacyNodeSerial)
+			current := enroll(cce.NodeSerial)
+
+			By("Pre-approving a node that is not enrolled")
+			pending := &cce.Node{
+				ID:       uuid.New(),
+				Name:     "test-node",
+				Location: "test-location",
+				Serial:   "0dj2ay6LLwv9w3m2iTEbeA",
+			}
+			Expect(ps.Create(ctx, pending)).To(Succeed())
+
+			n, err := cce.MigrateNodeSerials(ctx, ps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(1))
+
+			Expect(serialOf(legacy)).ToNot(Equal(legacy.Serial))
+			Expect(serialOf(legacy)).To(HaveLen(len(current.Serial)))
+			Expect(serialOf(current)).To(Equal(current.Serial))
+			Expect(serialOf(pending)).To(Equal(pending.Serial))
+
+			By("Migrating again")
+			n, err = cce.MigrateNodeSerials(ctx, ps)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+		})
+	})
+})
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// NodeStatusEvent records a change of the connectivity status of a node.
+type NodeStatusEvent struct {
+	ID     string `json:"id"`
+	NodeID string `json:"node_id"`
+	// From is the status before the change, or empty when the node was probed
+	// for the first time.
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*NodeStatusEvent) GetTableName() string {
+	return "node_status_events"
+}
+
+// GetID gets the ID.
+func (e *NodeStatusEvent) GetID() string {
+	return e.ID
+}
+
+// SetID sets the ID.
+func (e *NodeStatusEvent) SetID(id string) {
+	e.ID = id
+}
+
+// GetNodeID gets the node ID.
+func (e *NodeStatusEvent) GetNodeID() string {
+	return e.NodeID
+}
+
+// Validate validates the model.
+func (e *NodeStatusEvent) Validate() error {
+	if !uuid.IsValid(e.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(e.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	if e.To == "" {
+		return errors.New("to cannot be empty")
+	}
+	if e.Timestamp.IsZero() {
+		return errors.New("timestamp cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*NodeStatusEvent) FilterFields() []string {
+	return []string{
+		"node_id",
+		"to",
+	}
+}
+
+func (e *NodeStatusEvent) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+NodeStatusEvent[
+    ID: %s
+    NodeID: %s
+    From: %s
+    To: %s
+    Timestamp: %s
+]`),
+		e.ID,
+		e.NodeID,
+		e.From,
+		e.To,
+		e.Timestamp)
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: NodeStatusEvent", func() {
+	var event *cce.NodeStatusEvent
+
+	BeforeEach(func() {
+		event = &cce.NodeStatusEvent{
+			ID:        "6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e",
+			NodeID:    "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+			From:      cce.NodeStatusOnline,
+			To:        cce.NodeStatusOffline,
+			Timestamp: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "node_status_events"`, func() {
+			Expect(event.GetTableName()).To(Equal("node_status_events"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(event.Validate()).To(Succeed())
+		})
+
+		It("Should not return an error if From is empty", func() {
+			event.From = ""
+			Expect(event.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			event.ID = "123"
+			Expect(event.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			event.NodeID = "123"
+			Expect(event.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error if To is empty", func() {
+			event.To = ""
+			Expect(event.Validate()).To(MatchError("to cannot be empty"))
+		})
+
+		It("Should return an error if Timestamp is empty", func() {
+			event.Timestamp = time.Time{}
+			Expect(event.Validate()).To(MatchError("timestamp cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(event.FilterFields()).To(Equal([]string{
+				"node_id",
+				"to",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(event.String()).To(Equal(strings.TrimSpace(`
+NodeStatusEvent[
+    ID: 6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e
+    NodeID: 9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e
+    From: online
+    To: offline
+    Timestamp: 2019-12-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
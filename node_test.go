@@ -22,6 +22,7 @@ var _ = Describe("Entities: Node", func() {
 			Name:     "test-node",
 			Location: "test-location",
 			Serial:   "test-serial",
+			Labels:   map[string]string{"region": "eu"},
 		}
 	})
 
@@ -61,6 +62,11 @@ var _ = Describe("Entities: Node", func() {
 			Expect(node.Validate()).To(MatchError("id not a valid uuid"))
 		})
 
+		It("Should return an error if a label is invalid", func() {
+			node.Labels["tier"] = "far edge"
+			Expect(node.Validate()).To(MatchError(`label tier value "far edge" is invalid`))
+		})
+
 		It("Should return an error if Name is empty", func() {
 			node.Name = ""
 			Expect(node.Validate()).To(MatchError("name cannot be empty"))
@@ -81,6 +87,8 @@ var _ = Describe("Entities: Node", func() {
 		It("Should return the filterable fields", func() {
 			Expect(node.FilterFields()).To(Equal([]string{
 				"serial",
+				"name",
+				"location",
 			}))
 		})
 	})
@@ -93,6 +101,7 @@ Node[
     Name: test-node
     Location: test-location
     Serial: test-serial
+    Labels: map[region:eu]
 ]`,
 			)))
 		})
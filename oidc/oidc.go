@@ -0,0 +1,325 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// DefaultGroupsClaim is the ID token claim listing the groups of the user if
+// no claim is set.
+const DefaultGroupsClaim = "groups"
+
+// clockSkew is the leeway when validating the time claims of an ID token.
+const clockSkew = time.Minute
+
+// Provider is an external OpenID Connect identity provider that users log in
+// with using the authorization code flow. Its endpoints are discovered from the
+// issuer on first use.
+type Provider struct {
+	// Issuer is the issuer URL of the identity provider.
+	Issuer string
+	// ClientID and ClientSecret are the credentials of the controller
+	// registered with the identity provider.
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL of the controller registered with the
+	// identity provider.
+	RedirectURL string
+	// Scopes are requested in addition to the openid scope.
+	Scopes []string
+	// GroupsClaim is the ID token claim listing the groups of the user. If
+	// empty DefaultGroupsClaim is used.
+	GroupsClaim string
+	// GroupRoles maps identity provider groups to the controller roles granted
+	// to their members.
+	GroupRoles map[string][]string
+	// Client is the HTTP client for requests to the identity provider. If nil
+	// http.DefaultClient is used.
+	Client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     jose.JSONWebKeySet
+}
+
+// metadata is the subset of the OpenID Provider Metadata used by the
+// controller.
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Identity is a user authenticated by the identity provider.
+type Identity struct {
+	// Subject is the unique ID of the user at the identity provider.
+	Subject string
+	// Username is the preferred username of the user, or its email or
+	// subject if the identity provider does not provide one.
+	Username string
+	Groups   []string
+	// Roles are the controller roles mapped from the groups.
+	Roles []string
+}
+
+// idTokenClaims are the claims of an ID token used by the controller.
+type idTokenClaims struct {
+	jwt.Claims
+	Nonce             string `json:"nonce"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+}
+
+// AuthCodeURL returns the URL of the identity provider to redirect the user to
+// for logging in. The state and nonce must be random and unique per login.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	cfg, err := p.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return cfg.AuthCodeURL(state, oauth2.SetAuthURLParam("nonce", nonce)), nil
+}
+
+// Exchange exchanges the authorization code the identity provider redirected
+// the user back with for an ID token, verifies it was issued for this login
+// and returns the identity of the user.
+func (p *Provider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	cfg, err := p.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := cfg.Exchange(context.WithValue(ctx, oauth2.HTTPClient, p.client()), code)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to exchange authorization code")
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verify(ctx, rawIDToken, nonce)
+}
+
+// Roles returns the controller roles granted to the groups, sorted and
+// without duplicates.
+func (p *Provider) Roles(groups []string) []string {
+	set := make(map[string]bool)
+	for _, group := range groups {
+		for _, role := range p.GroupRoles[group] {
+			set[role] = true
+		}
+	}
+
+	roles := []string{}
+	for role := range set {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+
+	return roles
+}
+
+func (p *Provider) verify(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	idToken, err := jwt.ParseSigned(rawIDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse ID token")
+	}
+	if len(idToken.Headers) != 1 {
+		return nil, errors.New("ID token must have exactly one signature")
+	}
+
+	key, err := p.verificationKey(ctx, idToken.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		claims idTokenClaims
+		extra  map[string]interface{}
+	)
+	if err = idToken.Claims(key, &claims, &extra); err != nil {
+		return nil, errors.Wrap(err, "unable to verify ID token")
+	}
+
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   md.Issuer,
+		Audience: jwt.Audience{p.ClientID},
+		Time:     time.Now(),
+	}, clockSkew); err != nil {
+		return nil, errors.Wrap(err, "invalid ID token")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match the login")
+	}
+
+	groups, err := p.groups(extra)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject:  claims.Subject,
+		Username: claims.PreferredUsername,
+		Groups:   groups,
+		Roles:    p.Roles(groups),
+	}
+	if identity.Username == "" {
+		identity.Username = claims.Email
+	}
+	if identity.Username == "" {
+		identity.Username = claims.Subject
+	}
+
+	return identity, nil
+}
+
+// groups returns the groups listed by the groups claim, which may be absent.
+func (p *Provider) groups(claims map[string]interface{}) ([]string, error) {
+	name := p.GroupsClaim
+	if name == "" {
+		name = DefaultGroupsClaim
+	}
+
+	raw, ok := claims[name]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, errors.Errorf("ID token claim %q is not a list", name)
+	}
+
+	var groups []string
+	for _, v := range list {
+		group, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("ID token claim %q is not a list of strings", name)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+// verificationKey returns the key with the key ID from the key set of the
+// identity provider. The key set is fetched again if the key is unknown, as
+// the identity provider may have rotated its keys.
+func (p *Provider) verificationKey(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	keys := p.keys.Key(kid)
+	p.mu.Unlock()
+
+	if len(keys) == 0 {
+		md, err := p.discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var set jose.JSONWebKeySet
+		if err = p.getJSON(ctx, md.JWKSURI, &set); err != nil {
+			return nil, errors.Wrap(err, "unable to fetch identity provider keys")
+		}
+
+		p.mu.Lock()
+		p.keys = set
+		p.mu.Unlock()
+
+		keys = set.Key(kid)
+	}
+	if len(keys) == 0 {
+		return nil, errors.Errorf("unknown ID token signing key %q", kid)
+	}
+
+	return keys[0].Key, nil
+}
+
+// discover fetches the provider metadata from the issuer once.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var md metadata
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &md); err != nil {
+		return nil, errors.Wrap(err, "unable to discover identity provider")
+	}
+	if md.Issuer != p.Issuer {
+		return nil, errors.Errorf("identity provider issuer %q does not match %q", md.Issuer, p.Issuer)
+	}
+	p.metadata = &md
+
+	return p.metadata, nil
+}
+
+func (p *Provider) oauth2Config(ctx context.Context) (*oauth2.Config, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Scopes:       append([]string{"openid"}, p.Scopes...),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  md.AuthorizationEndpoint,
+			TokenURL: md.TokenEndpoint,
+		},
+	}, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client().Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *Provider) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+
+	return http.DefaultClient
+}
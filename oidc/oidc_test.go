@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package oidc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/open-ness/edgecontroller/oidc"
+)
+
+// mockProvider is a local OpenID Connect identity provider that issues an ID
+// token with the configured claims for any authorization code.
+type mockProvider struct {
+	*httptest.Server
+	key    jose.JSONWebKey
+	claims map[string]interface{}
+}
+
+func newMockProvider() *mockProvider {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	m := &mockProvider{key: jose.JSONWebKey{Key: priv, KeyID: "test-key", Algorithm: "ES256", Use: "sig"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		m.writeJSON(w, map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		m.writeJSON(w, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{m.key.Public()}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "test-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			m.writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: m.key},
+			new(jose.SignerOptions).WithType("JWT"))
+		Expect(err).ToNot(HaveOccurred())
+		idToken, err := jwt.Signed(signer).Claims(m.claims).CompactSerialize()
+		Expect(err).ToNot(HaveOccurred())
+
+		m.writeJSON(w, map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idToken,
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+
+	m.claims = map[string]interface{}{
+		"iss":                m.URL,
+		"sub":                "test-subject",
+		"aud":                "test-client",
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              "test-nonce",
+		"preferred_username": "test-user",
+		"groups":             []string{"ops", "everyone"},
+	}
+
+	return m
+}
+
+func (m *mockProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	Expect(json.NewEncoder(w).Encode(v)).To(Succeed())
+}
+
+var _ = Describe("Provider", func() {
+	var (
+		mock *mockProvider
+		idp  *oidc.Provider
+	)
+
+	BeforeEach(func() {
+		mock = newMockProvider()
+		idp = &oidc.Provider{
+			Issuer:       mock.URL,
+			ClientID:     "test-client",
+			ClientSecret: "test-secret",
+			RedirectURL:  "https://controller.example.com/auth/oidc/callback",
+			GroupRoles: map[string][]string{
+				"ops":      {"operator"},
+				"everyone": {"viewer"},
+				"admins":   {"admin"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		mock.Close()
+	})
+
+	Describe("AuthCodeURL", func() {
+		It("Should redirect to the authorization endpoint", func() {
+			authURL, err := idp.AuthCodeURL(context.Background(), "test-state", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+
+			u, err := url.Parse(authURL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(u.Path).To(Equal("/authorize"))
+			Expect(u.Query().Get("client_id")).To(Equal("test-client"))
+			Expect(u.Query().Get("redirect_uri")).To(Equal(idp.RedirectURL))
+			Expect(u.Query().Get("response_type")).To(Equal("code"))
+			Expect(u.Query().Get("scope")).To(Equal("openid"))
+			Expect(u.Query().Get("state")).To(Equal("test-state"))
+			Expect(u.Query().Get("nonce")).To(Equal("test-nonce"))
+		})
+
+		It("Should reject an identity provider with a different issuer", func() {
+			idp.Issuer = mock.URL + "/other"
+			_, err := idp.AuthCodeURL(context.Background(), "test-state", "test-nonce")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Exchange", func() {
+		It("Should return the identity with roles mapped from groups", func() {
+			identity, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity).To(Equal(&oidc.Identity{
+				Subject:  "test-subject",
+				Username: "test-user",
+				Groups:   []string{"ops", "everyone"},
+				Roles:    []string{"operator", "viewer"},
+			}))
+		})
+
+		It("Should read the groups from the configured claim", func() {
+			idp.GroupsClaim = "roles"
+			mock.claims["roles"] = []string{"admins"}
+			identity, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity.Roles).To(Equal([]string{"admin"}))
+		})
+
+		It("Should fall back to the subject as username", func() {
+			delete(mock.claims, "preferred_username")
+			identity, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity.Username).To(Equal("test-subject"))
+		})
+
+		It("Should reject an invalid authorization code", func() {
+			_, err := idp.Exchange(context.Background(), "other-code", "test-nonce")
+			Expect(err).To(MatchError(ContainSubstring("unable to exchange authorization code")))
+		})
+
+		It("Should reject an ID token for another login", func() {
+			_, err := idp.Exchange(context.Background(), "test-code", "other-nonce")
+			Expect(err).To(MatchError("ID token nonce does not match the login"))
+		})
+
+		It("Should reject an ID token for another client", func() {
+			mock.claims["aud"] = "other-client"
+			_, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid ID token")))
+		})
+
+		It("Should reject an expired ID token", func() {
+			mock.claims["exp"] = time.Now().Add(-time.Hour).Unix()
+			_, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).To(MatchError(ContainSubstring("invalid ID token")))
+		})
+
+		It("Should fetch the keys again after the identity provider rotated them", func() {
+			_, err := idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+
+			priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			mock.key = jose.JSONWebKey{Key: priv, KeyID: "rotated-key", Algorithm: "ES256", Use: "sig"}
+
+			_, err = idp.Exchange(context.Background(), "test-code", "test-nonce")
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
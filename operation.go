@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Kinds of operations.
+const (
+	// OperationDeployApp deploys an app to a node.
+	OperationDeployApp = "deploy_app"
+	// OperationUpdateApp runs a lifecycle command on an app of a node.
+	OperationUpdateApp = "update_app"
+	// OperationUpgradeApp redeploys the current revision of an app to a node.
+	OperationUpgradeApp = "upgrade_app"
+)
+
+// Statuses of an operation.
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+	OperationCanceled  = "canceled"
+)
+
+// Operation is a long-running operation on a node, run in the background
+// after the request that started it was answered.
+type Operation struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	NodeID string `json:"node_id"`
+	AppID  string `json:"app_id"`
+	// Cmd is the lifecycle command of an update_app operation.
+	Cmd    string `json:"cmd,omitempty"`
+	Status string `json:"status"`
+	// Progress describes the step the operation is at.
+	Progress string `json:"progress,omitempty"`
+	// Result is the path of the resource the operation created or updated,
+	// set once the operation succeeded.
+	Result    string    `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*Operation) GetTableName() string {
+	return "operations"
+}
+
+// GetID gets the ID.
+func (o *Operation) GetID() string {
+	return o.ID
+}
+
+// SetID sets the ID.
+func (o *Operation) SetID(id string) {
+	o.ID = id
+}
+
+// GetNodeID gets the node ID.
+func (o *Operation) GetNodeID() string {
+	return o.NodeID
+}
+
+// Done returns whether the operation ended.
+func (o *Operation) Done() bool {
+	switch o.Status {
+	case OperationSucceeded, OperationFailed, OperationCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate validates the model.
+func (o *Operation) Validate() error {
+	if !uuid.IsValid(o.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(o.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	if !uuid.IsValid(o.AppID) {
+		return errors.New("app_id not a valid uuid")
+	}
+	switch o.Kind {
+	case OperationDeployApp, OperationUpgradeApp:
+	case OperationUpdateApp:
+		switch o.Cmd {
+		case "start", "stop", "restart":
+		case "":
+			return errors.New("cmd missing")
+		default:
+			return fmt.Errorf(`cmd "%s" is invalid`, o.Cmd)
+		}
+	default:
+		return fmt.Errorf("kind must be one of [%s, %s, %s]",
+			OperationDeployApp, OperationUpdateApp, OperationUpgradeApp)
+	}
+	switch o.Status {
+	case OperationPending, OperationRunning, OperationSucceeded, OperationFailed, OperationCanceled:
+	default:
+		return fmt.Errorf("status must be one of [%s, %s, %s, %s, %s]",
+			OperationPending, OperationRunning, OperationSucceeded, OperationFailed, OperationCanceled)
+	}
+	if o.CreatedAt.IsZero() {
+		return errors.New("created_at cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*Operation) FilterFields() []string {
+	return []string{
+		"node_id",
+		"app_id",
+		"status",
+	}
+}
+
+func (o *Operation) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+Operation[
+    ID: %s
+    Kind: %s
+    NodeID: %s
+    AppID: %s
+    Cmd: %s
+    Status: %s
+    Progress: %s
+    Result: %s
+    Error: %s
+    CreatedAt: %s
+    UpdatedAt: %s
+]`),
+		o.ID,
+		o.Kind,
+		o.NodeID,
+		o.AppID,
+		o.Cmd,
+		o.Status,
+		o.Progress,
+		o.Result,
+		o.Error,
+		o.CreatedAt,
+		o.UpdatedAt)
+}
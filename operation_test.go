@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: Operation", func() {
+	var op *cce.Operation
+
+	BeforeEach(func() {
+		op = &cce.Operation{
+			ID:        "2c6b8e4a-7d1f-4a3b-9c5e-0f8d2a4b6c1e",
+			Kind:      cce.OperationUpdateApp,
+			NodeID:    "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+			AppID:     "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c",
+			Cmd:       "start",
+			Status:    cce.OperationRunning,
+			Progress:  "starting app",
+			CreatedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2019, 12, 1, 0, 1, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "operations"`, func() {
+			Expect(op.GetTableName()).To(Equal("operations"))
+		})
+	})
+
+	Describe("Done", func() {
+		It("Should return whether the operation ended", func() {
+			Expect(op.Done()).To(BeFalse())
+			op.Status = cce.OperationPending
+			Expect(op.Done()).To(BeFalse())
+			op.Status = cce.OperationSucceeded
+			Expect(op.Done()).To(BeTrue())
+			op.Status = cce.OperationFailed
+			Expect(op.Done()).To(BeTrue())
+			op.Status = cce.OperationCanceled
+			Expect(op.Done()).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(op.Validate()).To(Succeed())
+		})
+
+		It("Should not require a cmd to deploy an app", func() {
+			op.Kind = cce.OperationDeployApp
+			op.Cmd = ""
+			Expect(op.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			op.ID = "123"
+			Expect(op.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			op.NodeID = "123"
+			Expect(op.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error if AppID is not a UUID", func() {
+			op.AppID = "123"
+			Expect(op.Validate()).To(MatchError("app_id not a valid uuid"))
+		})
+
+		It("Should return an error if Kind is invalid", func() {
+			op.Kind = "delete_app"
+			Expect(op.Validate()).To(MatchError("kind must be one of [deploy_app, update_app, upgrade_app]"))
+		})
+
+		It("Should return an error if Cmd is missing", func() {
+			op.Cmd = ""
+			Expect(op.Validate()).To(MatchError("cmd missing"))
+		})
+
+		It("Should return an error if Cmd is invalid", func() {
+			op.Cmd = "pause"
+			Expect(op.Validate()).To(MatchError(`cmd "pause" is invalid`))
+		})
+
+		It("Should return an error if Status is invalid", func() {
+			op.Status = "done"
+			Expect(op.Validate()).To(MatchError(
+				"status must be one of [pending, running, succeeded, failed, canceled]"))
+		})
+
+		It("Should return an error if CreatedAt is empty", func() {
+			op.CreatedAt = time.Time{}
+			Expect(op.Validate()).To(MatchError("created_at cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(op.FilterFields()).To(Equal([]string{
+				"node_id",
+				"app_id",
+				"status",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(op.String()).To(Equal(strings.TrimSpace(`
+Operation[
+    ID: 2c6b8e4a-7d1f-4a3b-9c5e-0f8d2a4b6c1e
+    Kind: update_app
+    NodeID: 9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e
+    AppID: 3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c
+    Cmd: start
+    Status: running
+    Progress: starting app
+    Result: 
+    Error: 
+    CreatedAt: 2019-12-01 00:00:00 +0000 UTC
+    UpdatedAt: 2019-12-01 00:01:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
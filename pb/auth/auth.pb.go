@@ -170,39 +170,39 @@ func init() {
 func init() { proto.RegisterFile("auth.proto", fileDescriptor_8bbd6f3875b0e874) }
 
 var fileDescriptor_8bbd6f3875b0e874 = []byte{
-	// 497 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x92, 0x41, 0x6f, 0xd3, 0x30,
-	0x14, 0xc7, 0x95, 0x96, 0x6d, 0x9d, 0x0b, 0xd3, 0x64, 0x09, 0x56, 0xa2, 0x1e, 0xac, 0xc0, 0x61,
-	0x2a, 0x34, 0x6e, 0xcb, 0x4e, 0xe5, 0x42, 0x56, 0xf5, 0x50, 0x34, 0xa1, 0xaa, 0x15, 0x17, 0x2e,
-	0x93, 0xeb, 0xbc, 0x25, 0x86, 0xd4, 0x36, 0xb6, 0xc3, 0x04, 0x07, 0x0e, 0x7c, 0x83, 0x8d, 0x0f,
-	0xc1, 0x07, 0xe2, 0xcc, 0x8d, 0x03, 0x17, 0xbe, 0x03, 0x72, 0x08, 0xa2, 0x30, 0x6d, 0xa7, 0x38,
-	0xef, 0xf7, 0xcb, 0x7b, 0x2f, 0x7f, 0x19, 0x21, 0x56, 0xba, 0x3c, 0xd6, 0x46, 0x39, 0x85, 0xef,
-	0x28, 0x0d, 0x52, 0x82, 0xb5, 0xb1, 0x2f, 0x86, 0xdd, 0x4c, 0xa9, 0xac, 0x00, 0xca, 0xb4, 0xa0,
-	0x4c, 0x4a, 0xe5, 0x98, 0x13, 0x4a, 0xda, 0xdf, 0x72, 0xf8, 0xb8, 0x7a, 0xf0, 0x7e, 0x06, 0xb2,
-	0x6f, 0xcf, 0x59, 0x96, 0x81, 0xa1, 0x4a, 0x57, 0xc6, 0x55, 0x3b, 0xea, 0xa2, 0xd6, 0x2c, 0x05,
-	0xe9, 0x84, 0x7b, 0x8f, 0xf7, 0x51, 0x93, 0x5b, 0xd3, 0x09, 0x48, 0x70, 0xb8, 0xbb, 0xf0, 0xc7,
-	0xc8, 0xa2, 0xf6, 0xc4, 0x40, 0xc5, 0x59, 0x61, 0xf1, 0x1e, 0x6a, 0x88, 0xb4, 0xe6, 0x0d, 0x91,
-	0x62, 0x82, 0xda, 0x1c, 0x8c, 0x13, 0x67, 0x82, 0x33, 0x07, 0x9d, 0x46, 0x05, 0x36, 0x4b, 0xf8,
-	0x3e, 0x6a, 0x71, 0x76, 0xca, 0x73, 0x26, 0x64, 0xa7, 0x49, 0x9a, 0x87, 0xbb, 0x8b, 0x1d, 0xce,
-	0x26, 0xfe, 0x15, 0x1f, 0xa0, 0x1d, 0xce, 0x4e, 0xb5, 0x52, 0x45, 0xe7, 0x56, 0x45, 0xb6, 0x39,
-	0x9b, 0x2b, 0x55, 0x8c, 0x7e, 0x06, 0xa8, 0x9d, 0x94, 0x2e, 0x5f, 0x82, 0x79, 0x27, 0x38, 0xe0,
-	0x6f, 0x01, 0xc2, 0x0b, 0x78, 0x5b, 0x82, 0x75, 0x9b, 0xcb, 0x1c, 0xc4, 0xff, 0xa4, 0x12, 0xff,
-	0xf9, 0x8d, 0x30, 0xfc, 0x0f, 0x6c, 0x7c, 0x14, 0x5d, 0x04, 0x97, 0xc9, 0xc7, 0x30, 0xaa, 0xdb,
-	0x11, 0xcf, 0x3d, 0xe2, 0x55, 0x26, 0x84, 0xff, 0x35, 0x9f, 0x3f, 0x42, 0xcd, 0xd1, 0x60, 0x88,
-	0x1f, 0xa2, 0x28, 0xb9, 0x56, 0xf2, 0x67, 0xe6, 0x20, 0xf5, 0xf2, 0xd1, 0xe0, 0xc8, 0xcb, 0x75,
-	0x67, 0x48, 0x89, 0xa8, 0xf7, 0x21, 0x52, 0x39, 0xf2, 0x46, 0xaa, 0x73, 0x49, 0xcf, 0x54, 0x29,
-	0xd3, 0x4f, 0x5f, 0xbf, 0x7f, 0x6e, 0xa0, 0x68, 0x8b, 0xfa, 0xe1, 0xe3, 0xa0, 0x77, 0x7c, 0xd1,
-	0xb8, 0x4c, 0x7e, 0x04, 0xf8, 0x4b, 0x80, 0x5a, 0x7e, 0x14, 0x49, 0xe6, 0xb3, 0xe8, 0x18, 0xa1,
-	0xe5, 0x9a, 0x19, 0x47, 0xa6, 0x69, 0x06, 0xb8, 0x9b, 0x09, 0x97, 0x97, 0xab, 0x98, 0xab, 0x35,
-	0xb5, 0xbe, 0x0c, 0x69, 0x06, 0x6b, 0xe0, 0x55, 0x8b, 0xf0, 0x9e, 0x2d, 0xb5, 0x56, 0xc6, 0x3d,
-	0xab, 0x50, 0xdf, 0x33, 0x6f, 0xf6, 0xe6, 0x08, 0x27, 0x9a, 0xf1, 0x1c, 0xc8, 0x28, 0x1e, 0x90,
-	0x13, 0xc1, 0x41, 0x5a, 0xc0, 0xe3, 0xdc, 0x39, 0x6d, 0xc7, 0x94, 0x5e, 0xd7, 0xd3, 0xf2, 0x1c,
-	0xd6, 0x8c, 0xae, 0x0a, 0xb5, 0xa2, 0x6b, 0x66, 0x1d, 0x18, 0x7a, 0x32, 0x9b, 0x4c, 0x5f, 0x2c,
-	0xa7, 0xa3, 0xad, 0x61, 0x3c, 0x88, 0x07, 0xbd, 0x20, 0x18, 0xed, 0x33, 0xad, 0x8b, 0x3a, 0x11,
-	0xfa, 0xda, 0x2a, 0x39, 0xbe, 0x52, 0x59, 0xdc, 0xf5, 0xa1, 0x0c, 0xf1, 0x1e, 0xba, 0xfd, 0x52,
-	0xfa, 0x45, 0x95, 0x11, 0x1f, 0x20, 0x7d, 0xf5, 0xe0, 0xe6, 0xc1, 0x4f, 0xbd, 0xba, 0xda, 0xae,
-	0x6e, 0xe7, 0x93, 0x5f, 0x01, 0x00, 0x00, 0xff, 0xff, 0xb0, 0x0c, 0x90, 0xc7, 0x06, 0x03, 0x00,
-	0x00,
+	// 507 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x92, 0x41, 0x6f, 0xd3, 0x3c,
+	0x18, 0xc7, 0x95, 0xf4, 0xdd, 0xd6, 0xb9, 0x2f, 0x53, 0x65, 0x09, 0x56, 0xa2, 0x1e, 0xac, 0xc0,
+	0x61, 0x2a, 0x34, 0x6e, 0xcb, 0x4e, 0xe5, 0x42, 0x56, 0x55, 0xa8, 0x68, 0x42, 0x55, 0x2b, 0x2e,
+	0x5c, 0x26, 0xd7, 0x79, 0x96, 0x18, 0x52, 0xdb, 0xc4, 0x0e, 0x15, 0x1c, 0x38, 0xf0, 0x0d, 0x36,
+	0x3e, 0x04, 0xdf, 0x80, 0x2f, 0xc2, 0x99, 0x1b, 0x07, 0x3e, 0x06, 0x72, 0x08, 0xa2, 0x30, 0x8d,
+	0x0b, 0xa7, 0x38, 0xcf, 0xef, 0x97, 0xc7, 0xff, 0xe7, 0x51, 0x10, 0x62, 0xa5, 0xcd, 0x22, 0x5d,
+	0x28, 0xab, 0xf0, 0x0d, 0xa5, 0x41, 0x4a, 0x30, 0x26, 0x72, 0xc5, 0xa0, 0x9b, 0x2a, 0x95, 0xe6,
+	0x40, 0x99, 0x16, 0x94, 0x49, 0xa9, 0x2c, 0xb3, 0x42, 0x49, 0xf3, 0x43, 0x0e, 0xee, 0x57, 0x0f,
+	0xde, 0x4f, 0x41, 0xf6, 0xcd, 0x86, 0xa5, 0x29, 0x14, 0x54, 0xe9, 0xca, 0xb8, 0x6a, 0x87, 0x5d,
+	0xd4, 0x9c, 0x25, 0x20, 0xad, 0xb0, 0x6f, 0x70, 0x1b, 0x35, 0xb8, 0x29, 0x3a, 0x1e, 0xf1, 0x8e,
+	0xf6, 0x17, 0xee, 0x18, 0x1a, 0xd4, 0x9a, 0x14, 0x50, 0x71, 0x96, 0x1b, 0x7c, 0x80, 0x7c, 0x91,
+	0xd4, 0xdc, 0x17, 0x09, 0x26, 0xa8, 0xc5, 0xa1, 0xb0, 0xe2, 0x5c, 0x70, 0x66, 0xa1, 0xe3, 0x57,
+	0x60, 0xbb, 0x84, 0x6f, 0xa3, 0x26, 0x67, 0x67, 0x3c, 0x63, 0x42, 0x76, 0x1a, 0xa4, 0x71, 0xb4,
+	0xbf, 0xd8, 0xe3, 0x6c, 0xe2, 0x5e, 0xf1, 0x21, 0xda, 0xe3, 0xec, 0x4c, 0x2b, 0x95, 0x77, 0xfe,
+	0xab, 0xc8, 0x2e, 0x67, 0x73, 0xa5, 0xf2, 0xd1, 0x27, 0x1f, 0xb5, 0xe2, 0xd2, 0x66, 0x4b, 0x28,
+	0x5e, 0x0b, 0x0e, 0xf8, 0x8b, 0x87, 0xf0, 0x02, 0x5e, 0x95, 0x60, 0xec, 0x76, 0x98, 0xc3, 0xe8,
+	0xb7, 0xad, 0x44, 0x3f, 0xc7, 0x08, 0x82, 0x3f, 0xc0, 0xd6, 0x47, 0xe1, 0x85, 0x77, 0x19, 0xbf,
+	0x0b, 0xc2, 0xba, 0x1d, 0x71, 0xdc, 0x21, 0x5e, 0xed, 0x84, 0xf0, 0x5f, 0xe6, 0x93, 0x7b, 0xa8,
+	0x31, 0x1a, 0x0c, 0xf1, 0x5d, 0x14, 0xc6, 0xd7, 0x4a, 0xee, 0xcc, 0x2c, 0x24, 0x4e, 0x3e, 0x1e,
+	0x1c, 0x3b, 0xb9, 0xee, 0x0c, 0x09, 0x11, 0x75, 0x1e, 0x22, 0x95, 0x25, 0x2f, 0xa5, 0xda, 0x48,
+	0x7a, 0xae, 0x4a, 0x99, 0xbc, 0xff, 0xfc, 0xf5, 0x83, 0x8f, 0xc6, 0x5e, 0x2f, 0xdc, 0xa1, 0xee,
+	0x7e, 0xfc, 0x18, 0xb5, 0x17, 0x20, 0x61, 0xf3, 0xaf, 0xc3, 0x9d, 0x5c, 0xf8, 0x97, 0xf1, 0x37,
+	0x0f, 0x7f, 0xf4, 0x50, 0xd3, 0x65, 0x26, 0xf1, 0x7c, 0x16, 0x9e, 0x20, 0xb4, 0x5c, 0xb3, 0xc2,
+	0x92, 0x69, 0x92, 0x02, 0xee, 0xa6, 0xc2, 0x66, 0xe5, 0x2a, 0xe2, 0x6a, 0x4d, 0x8d, 0x2b, 0x43,
+	0x92, 0xc2, 0x1a, 0x78, 0x15, 0x24, 0xb8, 0x65, 0x4a, 0xad, 0x55, 0x61, 0x1f, 0x55, 0xa8, 0xef,
+	0x98, 0x33, 0x7b, 0x73, 0x84, 0x63, 0xcd, 0x78, 0x06, 0x64, 0x14, 0x0d, 0xc8, 0xa9, 0xe0, 0x20,
+	0x0d, 0xe0, 0x71, 0x66, 0xad, 0x36, 0x63, 0x4a, 0xaf, 0xeb, 0x69, 0x78, 0x06, 0x6b, 0x46, 0x57,
+	0xb9, 0x5a, 0xd1, 0x35, 0x33, 0x16, 0x0a, 0x7a, 0x3a, 0x9b, 0x4c, 0x9f, 0x2e, 0xa7, 0xa3, 0x9d,
+	0x61, 0x34, 0x88, 0x06, 0x3d, 0xcf, 0x1b, 0xb5, 0x99, 0xd6, 0x79, 0xbd, 0x5a, 0xfa, 0xc2, 0x28,
+	0x39, 0xbe, 0x52, 0x59, 0xdc, 0x74, 0xdb, 0x1d, 0xe2, 0x03, 0xf4, 0xff, 0x33, 0xe9, 0x82, 0xaa,
+	0x42, 0xbc, 0x85, 0xe4, 0xf9, 0x9d, 0xbf, 0x5f, 0xfc, 0xd0, 0xa9, 0xab, 0xdd, 0xea, 0x37, 0x7f,
+	0xf0, 0x7d, 0x00, 0x91, 0xb9, 0xdb, 0x82, 0x4f, 0x03, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -218,6 +218,7 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type AuthServiceClient interface {
 	RequestCredentials(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Credentials, error)
+	RenewCredentials(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Credentials, error)
 }
 
 type authServiceClient struct {
@@ -237,9 +238,19 @@ func (c *authServiceClient) RequestCredentials(ctx context.Context, in *Identity
 	return out, nil
 }
 
+func (c *authServiceClient) RenewCredentials(ctx context.Context, in *Identity, opts ...grpc.CallOption) (*Credentials, error) {
+	out := new(Credentials)
+	err := c.cc.Invoke(ctx, "/openness.auth.AuthService/RenewCredentials", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServiceServer is the server API for AuthService service.
 type AuthServiceServer interface {
 	RequestCredentials(context.Context, *Identity) (*Credentials, error)
+	RenewCredentials(context.Context, *Identity) (*Credentials, error)
 }
 
 func RegisterAuthServiceServer(s *grpc.Server, srv AuthServiceServer) {
@@ -264,6 +275,24 @@ func _AuthService_RequestCredentials_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_RenewCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Identity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).RenewCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/openness.auth.AuthService/RenewCredentials",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).RenewCredentials(ctx, req.(*Identity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _AuthService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "openness.auth.AuthService",
 	HandlerType: (*AuthServiceServer)(nil),
@@ -272,6 +301,10 @@ var _AuthService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RequestCredentials",
 			Handler:    _AuthService_RequestCredentials_Handler,
 		},
+		{
+			MethodName: "RenewCredentials",
+			Handler:    _AuthService_RenewCredentials_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "auth.proto",
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// PendingNode is a node that requested credentials before it was added to the
+// controller. It is kept until an operator approves it as a node or rejects
+// it.
+type PendingNode struct {
+	ID     string `json:"id"`
+	Serial string `json:"serial"`
+	// Address is the IP address the node requested credentials from.
+	Address     string    `json:"address"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*PendingNode) GetTableName() string {
+	return "pending_nodes"
+}
+
+// GetID gets the ID.
+func (n *PendingNode) GetID() string {
+	return n.ID
+}
+
+// SetID sets the ID.
+func (n *PendingNode) SetID(id string) {
+	n.ID = id
+}
+
+// Validate validates the model.
+func (n *PendingNode) Validate() error {
+	if !uuid.IsValid(n.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if n.Serial == "" {
+		return errors.New("serial cannot be empty")
+	}
+	if n.RequestedAt.IsZero() {
+		return errors.New("requested_at cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*PendingNode) FilterFields() []string {
+	return []string{
+		"serial",
+		"address",
+	}
+}
+
+func (n *PendingNode) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+PendingNode[
+    ID: %s
+    Serial: %s
+    Address: %s
+    RequestedAt: %s
+]`),
+		n.ID,
+		n.Serial,
+		n.Address,
+		n.RequestedAt)
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: PendingNode", func() {
+	var node *cce.PendingNode
+
+	BeforeEach(func() {
+		node = &cce.PendingNode{
+			ID:          "6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e",
+			Serial:      "ysRMvw8aMviv9qPcXIlAsA",
+			Address:     "192.0.2.10",
+			RequestedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "pending_nodes"`, func() {
+			Expect(node.GetTableName()).To(Equal("pending_nodes"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(node.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			node.ID = "123"
+			Expect(node.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if Serial is empty", func() {
+			node.Serial = ""
+			Expect(node.Validate()).To(MatchError("serial cannot be empty"))
+		})
+
+		It("Should return an error if RequestedAt is empty", func() {
+			node.RequestedAt = time.Time{}
+			Expect(node.Validate()).To(MatchError("requested_at cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(node.FilterFields()).To(Equal([]string{
+				"serial",
+				"address",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(node.String()).To(Equal(strings.TrimSpace(`
+PendingNode[
+    ID: 6a1e3b9c-2d4f-4c8a-9e7b-5f0d1c3a2b4e
+    Serial: ysRMvw8aMviv9qPcXIlAsA
+    Address: 192.0.2.10
+    RequestedAt: 2019-12-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
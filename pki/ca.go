@@ -12,7 +12,6 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"math/big"
-	rdm "math/rand"
 	"os"
 	"path/filepath"
 	"time"
@@ -99,30 +98,60 @@ func (ca *RootCA) CAChain() ([]*x509.Certificate, error) {
 	return []*x509.Certificate{ca.Cert}, nil
 }
 
-// SignCSR signs a ASN.1 DER encoded certificate signing request.
+// SignCSR signs a ASN.1 DER encoded certificate signing request. The
+// certificate is valid until the NotAfter of the template, if set, or until the
+// CA expires, whichever is earlier.
 func (ca *RootCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certificate, error) {
+	return signCSR(ca.Cert, ca.Key, der, template)
+}
+
+// NewTLSClientCert creates a new TLS client certificate with a given SNI.
+func (ca *RootCA) NewTLSClientCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
+	return newTLSCert(ca.Cert, ca.Key, key, sni, x509.ExtKeyUsageClientAuth)
+}
+
+// NewTLSServerCert creates a new TLS server certificate with a given SNI.
+func (ca *RootCA) NewTLSServerCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
+	return newTLSCert(ca.Cert, ca.Key, key, sni, x509.ExtKeyUsageServerAuth)
+}
+
+// signCSR signs a certificate signing request with the key of the issuing CA.
+func signCSR(
+	issuer *x509.Certificate,
+	issuerKey crypto.PrivateKey,
+	der []byte,
+	template *x509.Certificate,
+) (*x509.Certificate, error) {
 	csr, err := x509.ParseCertificateRequest(der)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to parse CSR")
 	}
 
 	// Pick random serial number
-	source := rdm.NewSource(time.Now().UnixNano())
-	serial := big.NewInt(int64(rdm.New(source).Uint64()))
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	// Valid until CA expires unless a shorter lifetime is requested
+	notAfter := issuer.NotAfter
+	if !template.NotAfter.IsZero() && template.NotAfter.Before(notAfter) {
+		notAfter = template.NotAfter
+	}
 
 	// Sign certificate request
 	tmpl := &x509.Certificate{
 		SerialNumber: serial,
 		Subject:      template.Subject,
 		NotBefore:    time.Now(),
-		NotAfter:     ca.Cert.NotAfter, // Valid until CA expires
+		NotAfter:     notAfter,
 	}
 	certDER, err := x509.CreateCertificate(
 		rand.Reader,
 		tmpl,
-		ca.Cert,
+		issuer,
 		csr.PublicKey,
-		ca.Key,
+		issuerKey,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to sign certificate")
@@ -131,17 +160,11 @@ func (ca *RootCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certifi
 	return x509.ParseCertificate(certDER)
 }
 
-// NewTLSClientCert creates a new TLS client certificate with a given SNI.
-func (ca *RootCA) NewTLSClientCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
-	return ca.newTLSCert(key, sni, x509.ExtKeyUsageClientAuth)
-}
-
-// NewTLSServerCert creates a new TLS server certificate with a given SNI.
-func (ca *RootCA) NewTLSServerCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
-	return ca.newTLSCert(key, sni, x509.ExtKeyUsageServerAuth)
-}
-
-func (ca *RootCA) newTLSCert(
+// newTLSCert creates a TLS certificate for the key signed with the key of the
+// issuing CA.
+func newTLSCert(
+	issuer *x509.Certificate,
+	issuerKey crypto.PrivateKey,
 	key crypto.PrivateKey,
 	sni string,
 	extKeyUsage ...x509.ExtKeyUsage,
@@ -152,8 +175,10 @@ func (ca *RootCA) newTLSCert(
 	}
 
 	// Pick random serial number
-	source := rdm.NewSource(time.Now().UnixNano())
-	serial := big.NewInt(int64(rdm.New(source).Uint64()))
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
 
 	// Generate certificate
 	template := &x509.Certificate{
@@ -162,14 +187,14 @@ func (ca *RootCA) newTLSCert(
 		KeyUsage:     x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  extKeyUsage,
 		NotBefore:    time.Now(),
-		NotAfter:     ca.Cert.NotAfter, // Valid until CA expires
+		NotAfter:     issuer.NotAfter, // Valid until CA expires
 	}
 	certDER, err := x509.CreateCertificate(
 		rand.Reader,
 		template,
-		ca.Cert,
+		issuer,
 		pkey.Public(),
-		ca.Key,
+		issuerKey,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to sign certificate")
@@ -184,7 +209,6 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 		err      error
 		k        crypto.Signer
 		ok       bool
-		source   rdm.Source
 		serial   *big.Int
 		template *x509.Certificate
 		der      []byte
@@ -194,9 +218,9 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 		return nil, errors.Wrap(err, "unable to parse key")
 	}
 
-	source = rdm.NewSource(time.Now().UnixNano())
-
-	serial = big.NewInt(int64(rdm.New(source).Uint64()))
+	if serial, err = newSerialNumber(); err != nil {
+		return nil, err
+	}
 
 	template = &x509.Certificate{
 		SerialNumber: serial,
@@ -219,3 +243,14 @@ func generateRootCA(key crypto.PrivateKey) (*x509.Certificate, error) {
 
 	return x509.ParseCertificate(der)
 }
+
+// newSerialNumber picks a random positive 128-bit certificate serial number,
+// so that serial numbers are unique for revocation.
+func newSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate serial number")
+	}
+
+	return serial.Add(serial, big.NewInt(1)), nil
+}
@@ -62,3 +62,33 @@ func LoadCertificate(path string) (*x509.Certificate, error) {
 
 	return x509.ParseCertificate(block.Bytes)
 }
+
+// LoadCertificates loads a PEM-encoded certificate chain from disk.
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	bytes, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read certificate file")
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, bytes = pem.Decode(bytes); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("unable to decode certificate")
+	}
+
+	return certs, nil
+}
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// IntermediateCA is an issuing CA whose certificate was signed by a root CA
+// that is kept offline. Only the crypto.Signer interface of its key is used,
+// so that the key can be held by an external signer such as a PKCS #11 token.
+type IntermediateCA struct {
+	// Cert is the certificate of the issuing CA.
+	Cert *x509.Certificate
+	// Chain is the chain of CA certificates that issued Cert, ending with the
+	// self-signed root CA.
+	Chain []*x509.Certificate
+	// Signer signs with the key of the issuing CA.
+	Signer crypto.Signer
+}
+
+// NewIntermediateCA creates an IntermediateCA from the certificate of the
+// issuing CA, the chain of CA certificates that issued it and the signer of
+// its key. The certificate must be a CA certificate for the key of the signer
+// that chains up to the self-signed root CA.
+func NewIntermediateCA(
+	cert *x509.Certificate,
+	chain []*x509.Certificate,
+	signer crypto.Signer,
+) (*IntermediateCA, error) {
+	if !cert.IsCA || cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return nil, errors.New("certificate is not a CA certificate")
+	}
+
+	pubDER, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal signer public key")
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, pubDER) {
+		return nil, errors.New("certificate was not issued for the signer key")
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("certificate chain cannot be empty")
+	}
+	root := chain[len(chain)-1]
+	if err = root.CheckSignatureFrom(root); err != nil {
+		return nil, errors.Wrap(err, "certificate chain does not end with a self-signed root CA")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[:len(chain)-1] {
+		intermediates.AddCert(c)
+	}
+	if _, err = cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return nil, errors.Wrap(err, "unable to verify certificate chain")
+	}
+
+	return &IntermediateCA{
+		Cert:   cert,
+		Chain:  chain,
+		Signer: signer,
+	}, nil
+}
+
+// LoadIntermediateCA loads an IntermediateCA from the certificates directory.
+// The cert.pem file holds the certificate of the issuing CA followed by the
+// chain of CA certificates that issued it, ending with the root CA. If signer
+// is nil, the key of the issuing CA is loaded from the key.pem file.
+func LoadIntermediateCA(certsDir string, signer crypto.Signer) (*IntermediateCA, error) {
+	certs, err := LoadCertificates(filepath.Join(certsDir, "cert.pem"))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load CA certificates")
+	}
+	if len(certs) < 2 {
+		return nil, errors.New("CA certificates must include the chain to the root CA")
+	}
+
+	if signer == nil {
+		if signer, err = LoadSigner(filepath.Join(certsDir, "key.pem")); err != nil {
+			return nil, errors.Wrap(err, "unable to load CA key")
+		}
+	}
+
+	return NewIntermediateCA(certs[0], certs[1:], signer)
+}
+
+// CAChain returns the issuing CA certificate followed by its chain, ending
+// with the root CA certificate.
+func (ca *IntermediateCA) CAChain() ([]*x509.Certificate, error) {
+	return append([]*x509.Certificate{ca.Cert}, ca.Chain...), nil
+}
+
+// SignCSR signs a ASN.1 DER encoded certificate signing request. The
+// certificate is valid until the NotAfter of the template, if set, or until the
+// CA expires, whichever is earlier.
+func (ca *IntermediateCA) SignCSR(der []byte, template *x509.Certificate) (*x509.Certificate, error) {
+	return signCSR(ca.Cert, ca.Signer, der, template)
+}
+
+// NewTLSClientCert creates a new TLS client certificate with a given SNI.
+func (ca *IntermediateCA) NewTLSClientCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
+	return newTLSCert(ca.Cert, ca.Signer, key, sni, x509.ExtKeyUsageClientAuth)
+}
+
+// NewTLSServerCert creates a new TLS server certificate with a given SNI.
+func (ca *IntermediateCA) NewTLSServerCert(key crypto.PrivateKey, sni string) (*x509.Certificate, error) {
+	return newTLSCert(ca.Cert, ca.Signer, key, sni, x509.ExtKeyUsageServerAuth)
+}
+
+// CreateCRL creates a DER-encoded certificate revocation list of the revoked
+// certificates signed by the CA, valid until nextUpdate.
+func (ca *IntermediateCA) CreateCRL(
+	revoked []pkix.RevokedCertificate,
+	nextUpdate time.Time,
+) ([]byte, error) {
+	return createCRL(ca.Cert, ca.Signer, revoked, nextUpdate)
+}
@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+// externalSigner stands in for a key held by an HSM, exposing only the
+// crypto.Signer interface and counting the signatures it makes.
+type externalSigner struct {
+	key   crypto.Signer
+	signs int
+}
+
+func (s *externalSigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *externalSigner) Sign(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	s.signs++
+	return s.key.Sign(r, digest, opts)
+}
+
+// newTestCA creates a CA certificate for the key signed by the parent, or a
+// self-signed root CA if parent is nil.
+func newTestCA(
+	key crypto.Signer,
+	parent *x509.Certificate,
+	parentKey crypto.Signer,
+) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+
+	return cert
+}
+
+var _ = Describe("IntermediateCA", func() {
+	var (
+		rootKey, intKey *ecdsa.PrivateKey
+		rootCert        *x509.Certificate
+		intCert         *x509.Certificate
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		By("Creating an offline root CA")
+		rootKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		rootCert = newTestCA(rootKey, nil, nil)
+
+		By("Creating an intermediate CA signed by the root CA")
+		intKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		intCert = newTestCA(intKey, rootCert, rootKey)
+	})
+
+	Describe("NewIntermediateCA", func() {
+		It("Should return the full chain ending with the root CA", func() {
+			ca, err := pki.NewIntermediateCA(intCert, []*x509.Certificate{rootCert}, intKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			chain, err := ca.CAChain()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(chain).To(Equal([]*x509.Certificate{intCert, rootCert}))
+		})
+
+		It("Should return an error if the signer key does not match", func() {
+			otherKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = pki.NewIntermediateCA(intCert, []*x509.Certificate{rootCert}, otherKey)
+			Expect(err).To(MatchError("certificate was not issued for the signer key"))
+		})
+
+		It("Should return an error if the chain does not end with a root CA", func() {
+			_, err := pki.NewIntermediateCA(intCert, []*x509.Certificate{intCert}, intKey)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should return an error if the chain does not verify", func() {
+			otherKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			otherRoot := newTestCA(otherKey, nil, nil)
+
+			_, err = pki.NewIntermediateCA(intCert, []*x509.Certificate{otherRoot}, intKey)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("LoadIntermediateCA", func() {
+		var tmpDir string
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "intermediate_test")
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Storing the intermediate CA followed by its chain")
+			Expect(pki.StoreCertificate(filepath.Join(tmpDir, "cert.pem"), intCert, rootCert)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tmpDir)
+		})
+
+		It("Should load the CA key from disk", func() {
+			Expect(pki.StoreKey(intKey, filepath.Join(tmpDir, "key.pem"))).To(Succeed())
+
+			ca, err := pki.LoadIntermediateCA(tmpDir, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ca.Cert).To(Equal(intCert))
+			Expect(ca.Chain).To(Equal([]*x509.Certificate{rootCert}))
+		})
+
+		It("Should use a key loaded from another file", func() {
+			keyFile := filepath.Join(tmpDir, "external-key.pem")
+			Expect(pki.StoreKey(intKey, keyFile)).To(Succeed())
+			signer, err := pki.LoadSigner(keyFile)
+			Expect(err).ToNot(HaveOccurred())
+
+			ca, err := pki.LoadIntermediateCA(tmpDir, signer)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ca.Signer).To(Equal(intKey))
+			_, err = os.Stat(filepath.Join(tmpDir, "key.pem"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("Should use the external signer", func() {
+			ca, err := pki.LoadIntermediateCA(tmpDir, &externalSigner{key: intKey})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ca.Signer).To(BeAssignableToTypeOf(&externalSigner{}))
+		})
+
+		It("Should return an error without the chain", func() {
+			Expect(pki.StoreCertificate(filepath.Join(tmpDir, "cert.pem"), intCert)).To(Succeed())
+
+			_, err := pki.LoadIntermediateCA(tmpDir, intKey)
+			Expect(err).To(MatchError("CA certificates must include the chain to the root CA"))
+		})
+	})
+
+	Describe("SignCSR", func() {
+		It("Should sign with the external signer a certificate verified by the root CA", func() {
+			signer := &externalSigner{key: intKey}
+			ca, err := pki.NewIntermediateCA(intCert, []*x509.Certificate{rootCert}, signer)
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Signing a certificate signing request")
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := ca.SignCSR(csrDER, &x509.Certificate{
+				Subject: pkix.Name{CommonName: "node"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer.signs).To(Equal(1))
+
+			By("Verifying the certificate with the CA chain")
+			roots := x509.NewCertPool()
+			roots.AddCert(rootCert)
+			intermediates := x509.NewCertPool()
+			intermediates.AddCert(intCert)
+			_, err = cert.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.NotAfter).To(Equal(intCert.NotAfter))
+		})
+	})
+
+	Describe("NewTLSServerCert", func() {
+		It("Should create a server certificate verified by the root CA", func() {
+			ca, err := pki.NewIntermediateCA(intCert, []*x509.Certificate{rootCert}, intKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := ca.NewTLSServerCert(key, "controller.openness")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.CheckSignatureFrom(intCert)).To(Succeed())
+		})
+	})
+
+	Describe("CreateCRL", func() {
+		It("Should create a CRL signed by the intermediate CA", func() {
+			ca, err := pki.NewIntermediateCA(intCert, []*x509.Certificate{rootCert}, intKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			der, err := ca.CreateCRL([]pkix.RevokedCertificate{{
+				SerialNumber:   big.NewInt(42),
+				RevocationTime: time.Now(),
+			}}, time.Now().Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+
+			crl, err := x509.ParseCRL(der)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(intCert.CheckCRLSignature(crl)).To(Succeed())
+		})
+	})
+})
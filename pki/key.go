@@ -66,3 +66,18 @@ func LoadKey(path string) (crypto.PrivateKey, error) {
 
 	return x509.ParsePKCS8PrivateKey(block.Bytes)
 }
+
+// LoadSigner loads a private key from disk at path that can sign.
+func LoadSigner(path string) (crypto.Signer, error) {
+	key, err := LoadKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.Errorf("invalid key type: %T", key)
+	}
+
+	return signer, nil
+}
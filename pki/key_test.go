@@ -96,4 +96,19 @@ var _ = Describe("Key Persistence", func() {
 			Expect(storedKey).To(Equal(key))
 		})
 	})
+
+	Describe("LoadSigner", func() {
+		It("Should load a signing key from disk", func() {
+			Expect(pki.StoreKey(key, keyFile)).To(Succeed())
+
+			signer, err := pki.LoadSigner(keyFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(signer).To(Equal(key))
+		})
+
+		It("Should return an error if the key file is missing", func() {
+			_, err := pki.LoadSigner(filepath.Join(tmpDir, "missing.pem"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RevocationList is the set of serial numbers of revoked certificates that
+// are rejected when verifying a peer certificate. The zero value is an empty
+// list ready to use.
+type RevocationList struct {
+	mu      sync.RWMutex
+	serials map[string]bool
+}
+
+// Revoke adds serial numbers to the list.
+func (l *RevocationList) Revoke(serials ...*big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.serials == nil {
+		l.serials = make(map[string]bool)
+	}
+	for _, serial := range serials {
+		l.serials[serial.Text(16)] = true
+	}
+}
+
+// IsRevoked returns whether the serial number is in the list. A nil list
+// contains no serial numbers.
+func (l *RevocationList) IsRevoked(serial *big.Int) bool {
+	if l == nil {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.serials[serial.Text(16)]
+}
+
+// VerifyPeerCertificate rejects revoked peer certificates. It is meant to be
+// set as the tls.Config callback of the same name on a config that verifies
+// peer certificates.
+func (l *RevocationList) VerifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		if len(chain) > 0 && l.IsRevoked(chain[0].SerialNumber) {
+			return errors.Errorf("certificate %x has been revoked", chain[0].SerialNumber)
+		}
+	}
+
+	return nil
+}
+
+// CreateCRL creates a DER-encoded certificate revocation list of the revoked
+// certificates signed by the CA, valid until nextUpdate.
+func (ca *RootCA) CreateCRL(
+	revoked []pkix.RevokedCertificate,
+	nextUpdate time.Time,
+) ([]byte, error) {
+	return createCRL(ca.Cert, ca.Key, revoked, nextUpdate)
+}
+
+// createCRL creates a certificate revocation list signed with the key of the
+// issuing CA.
+func createCRL(
+	issuer *x509.Certificate,
+	issuerKey crypto.PrivateKey,
+	revoked []pkix.RevokedCertificate,
+	nextUpdate time.Time,
+) ([]byte, error) {
+	crl, err := issuer.CreateCRL(rand.Reader, issuerKey, revoked, time.Now(), nextUpdate)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create CRL")
+	}
+
+	return crl, nil
+}
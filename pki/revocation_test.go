@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+var _ = Describe("Revocation", func() {
+	Describe("RevocationList", func() {
+		var list *pki.RevocationList
+
+		BeforeEach(func() {
+			list = &pki.RevocationList{}
+		})
+
+		It("Should report revoked serial numbers", func() {
+			Expect(list.IsRevoked(big.NewInt(1))).To(BeFalse())
+			list.Revoke(big.NewInt(1), big.NewInt(2))
+			Expect(list.IsRevoked(big.NewInt(1))).To(BeTrue())
+			Expect(list.IsRevoked(big.NewInt(2))).To(BeTrue())
+			Expect(list.IsRevoked(big.NewInt(3))).To(BeFalse())
+		})
+
+		It("Should reject revoked peer certificates", func() {
+			leaf := &x509.Certificate{SerialNumber: big.NewInt(42)}
+			chains := [][]*x509.Certificate{{leaf}}
+			Expect(list.VerifyPeerCertificate(nil, chains)).To(Succeed())
+
+			list.Revoke(big.NewInt(42))
+			Expect(list.VerifyPeerCertificate(nil, chains)).To(
+				MatchError("certificate 2a has been revoked"))
+		})
+
+		It("Should accept connections without peer certificates", func() {
+			list.Revoke(big.NewInt(42))
+			Expect(list.VerifyPeerCertificate(nil, nil)).To(Succeed())
+		})
+	})
+
+	Describe("CreateCRL", func() {
+		var (
+			tmpDir string
+			ca     *pki.RootCA
+		)
+
+		BeforeEach(func() {
+			var err error
+			tmpDir, err = ioutil.TempDir("", "revocation_test")
+			Expect(err).ToNot(HaveOccurred())
+			ca, err = pki.InitRootCA(tmpDir)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tmpDir)).To(Succeed())
+		})
+
+		It("Should list the revoked certificates signed by the CA", func() {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+			cert, err := ca.NewTLSClientCert(key, "test-node")
+			Expect(err).ToNot(HaveOccurred())
+
+			der, err := ca.CreateCRL([]pkix.RevokedCertificate{{
+				SerialNumber:   cert.SerialNumber,
+				RevocationTime: time.Now(),
+			}}, time.Now().Add(time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+
+			crl, err := x509.ParseCRL(der)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ca.Cert.CheckCRLSignature(crl)).To(Succeed())
+			Expect(crl.TBSCertList.RevokedCertificates).To(HaveLen(1))
+			Expect(crl.TBSCertList.RevokedCertificates[0].SerialNumber).To(Equal(cert.SerialNumber))
+		})
+	})
+})
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki
+
+import (
+	"crypto"
+	"regexp"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SignerOpener opens the signer of a key URI, such as a key held by a PKCS #11
+// token.
+type SignerOpener func(uri string) (crypto.Signer, error)
+
+var (
+	signerOpenersMu sync.RWMutex
+	signerOpeners   = make(map[string]SignerOpener)
+
+	// keyURIScheme matches the scheme of a key URI, like "pkcs11" in the RFC
+	// 7512 URI "pkcs11:token=ca;object=intermediate". Single letter schemes
+	// are left to Windows drive letters.
+	keyURIScheme = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]+):`)
+)
+
+// RegisterSigner registers the opener of the key URIs of the scheme, so that
+// OpenSigner opens them. A program embedding the controller registers e.g. a
+// "pkcs11" opener backed by a PKCS #11 library to sign with a key in an HSM.
+func RegisterSigner(scheme string, open SignerOpener) {
+	signerOpenersMu.Lock()
+	defer signerOpenersMu.Unlock()
+
+	signerOpeners[scheme] = open
+}
+
+// OpenSigner opens the signer of a key, either a key URI of a registered
+// scheme or the path of a PKCS #8 PEM-encoded key file.
+func OpenSigner(key string) (crypto.Signer, error) {
+	m := keyURIScheme.FindStringSubmatch(key)
+	if m == nil {
+		return LoadSigner(key)
+	}
+
+	signerOpenersMu.RLock()
+	open, ok := signerOpeners[m[1]]
+	signerOpenersMu.RUnlock()
+	if !ok {
+		return nil, errors.Errorf("no signer registered for key URI scheme %q", m[1])
+	}
+
+	signer, err := open(key)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open signer %q", key)
+	}
+
+	return signer, nil
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package pki_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/open-ness/edgecontroller/pki"
+)
+
+// softHSM stands in for a PKCS #11 token holding keys by label, opened by
+// URIs like "softhsm:token=ca;object=<label>".
+type softHSM struct {
+	keys map[string]*externalSigner
+}
+
+func (h *softHSM) open(uri string) (crypto.Signer, error) {
+	for _, attr := range strings.Split(strings.TrimPrefix(uri, "softhsm:"), ";") {
+		if strings.HasPrefix(attr, "object=") {
+			if key, ok := h.keys[strings.TrimPrefix(attr, "object=")]; ok {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, errors.New("object not found")
+}
+
+var _ = Describe("OpenSigner", func() {
+	var (
+		tmpDir string
+		key    *ecdsa.PrivateKey
+		hsm    *softHSM
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "signer_test")
+		Expect(err).ToNot(HaveOccurred())
+
+		key, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("Registering a stand-in for a PKCS #11 token")
+		hsm = &softHSM{keys: map[string]*externalSigner{"intermediate": {key: key}}}
+		pki.RegisterSigner("softhsm", hsm.open)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("Should load a key file by path", func() {
+		keyFile := filepath.Join(tmpDir, "key.pem")
+		Expect(pki.StoreKey(key, keyFile)).To(Succeed())
+
+		signer, err := pki.OpenSigner(keyFile)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer).To(Equal(key))
+	})
+
+	It("Should sign with the key of the registered signer", func() {
+		signer, err := pki.OpenSigner("softhsm:token=ca;object=intermediate")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signer).To(BeIdenticalTo(hsm.keys["intermediate"]))
+
+		By("Signing a certificate with an intermediate CA using the signer")
+		rootKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		rootCert := newTestCA(rootKey, nil, nil)
+		intCert := newTestCA(key, rootCert, rootKey)
+		Expect(pki.StoreCertificate(filepath.Join(tmpDir, "cert.pem"), intCert, rootCert)).To(Succeed())
+		ca, err := pki.LoadIntermediateCA(tmpDir, signer)
+		Expect(err).ToNot(HaveOccurred())
+
+		nodeKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, nodeKey)
+		Expect(err).ToNot(HaveOccurred())
+		cert, err := ca.SignCSR(csrDER, &x509.Certificate{Subject: pkix.Name{CommonName: "node"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hsm.keys["intermediate"].signs).To(Equal(1))
+		Expect(cert.CheckSignatureFrom(intCert)).To(Succeed())
+	})
+
+	It("Should return an error if the registered signer fails", func() {
+		_, err := pki.OpenSigner("softhsm:token=ca;object=missing")
+		Expect(err).To(MatchError(`unable to open signer "softhsm:token=ca;object=missing": object not found`))
+	})
+
+	It("Should return an error for a scheme without a registered signer", func() {
+		_, err := pki.OpenSigner("pkcs11:token=ca;object=intermediate")
+		Expect(err).To(MatchError(`no signer registered for key URI scheme "pkcs11"`))
+	})
+})
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidQuery is returned by PersistenceService.FilterPage for a query on
+// fields that are not filterable or with a malformed page token.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Query selects a sorted page of a filtered collection in
+// PersistenceService.FilterPage.
+type Query struct {
+	// Filters are the filters every entity must match.
+	Filters []Filter
+	// Selector selects the entities by their labels. Only Labeled entities
+	// can be selected.
+	Selector Selector
+	// Sort is the filter field or "id" the entities are sorted by, prefixed
+	// with "-" for descending order. Entities with equal values are sorted by
+	// ID. An empty Sort sorts by ID. Persisted values are compared as
+	// strings, so only string fields can be sorted by.
+	Sort string
+	// Limit is the maximum number of entities in the page. Zero means no
+	// limit.
+	Limit int
+	// PageToken is the token returned with the previous page, or empty for the
+	// first page.
+	PageToken string
+}
+
+// SortField returns the field the query sorts by and whether the order is
+// descending.
+func (q Query) SortField() (field string, desc bool) {
+	if q.Sort == "" {
+		return "id", false
+	}
+	if strings.HasPrefix(q.Sort, "-") {
+		return q.Sort[1:], true
+	}
+
+	return q.Sort, false
+}
+
+// Cursor returns the cursor decoded from the page token, or nil for the first
+// page.
+func (q Query) Cursor() (*PageCursor, error) {
+	if q.PageToken == "" {
+		return nil, nil
+	}
+
+	bytes, err := base64.RawURLEncoding.DecodeString(q.PageToken)
+	if err != nil {
+		return nil, errors.New("malformed page token")
+	}
+	c := &PageCursor{}
+	if err = json.Unmarshal(bytes, c); err != nil {
+		return nil, errors.New("malformed page token")
+	}
+	if c.Sort != q.Sort {
+		return nil, errors.New("page token was issued for a different sort")
+	}
+
+	return c, nil
+}
+
+// Validate validates the query for a Filterable.
+func (q Query) Validate(zv Filterable) error {
+	if q.Limit < 0 {
+		return errors.New("limit cannot be negative")
+	}
+	for _, f := range q.Filters {
+		if !IsFilterField(zv, f.Field) {
+			return fmt.Errorf("disallowed filter field %q", f.Field)
+		}
+	}
+	if _, ok := zv.(Labeled); !ok && !q.Selector.Empty() {
+		return fmt.Errorf("%s cannot be selected by labels", zv.GetTableName())
+	}
+	if field, _ := q.SortField(); field != "id" {
+		if !IsFilterField(zv, field) {
+			return fmt.Errorf("disallowed sort field %q", field)
+		}
+		if !isStringField(reflect.TypeOf(zv), field) {
+			return fmt.Errorf("sort field %q is not a string", field)
+		}
+	}
+	if _, err := q.Cursor(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PageCursor is the position of the last entity of a page in a sorted
+// collection.
+type PageCursor struct {
+	Sort  string `json:"s,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Token encodes the cursor as an opaque page token.
+func (c *PageCursor) Token() string {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		// Marshaling a struct of strings cannot fail
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// IsFilterField returns whether the field is one of the filter fields of a
+// Filterable.
+func IsFilterField(zv Filterable, field string) bool {
+	for _, ff := range zv.FilterFields() {
+		if ff == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStringField returns whether the field of the JSON representation of a
+// struct type is a string.
+func isStringField(t reflect.Type, field string) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.Anonymous && name == "" {
+			if isStringField(f.Type, field) {
+				return true
+			}
+			continue
+		}
+		if name == field {
+			return f.Type.Kind() == reflect.String
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Query", func() {
+	Describe("SortField", func() {
+		It("Should sort by ID by default", func() {
+			field, desc := cce.Query{}.SortField()
+			Expect(field).To(Equal("id"))
+			Expect(desc).To(BeFalse())
+		})
+
+		It("Should sort in descending order with a - prefix", func() {
+			field, desc := cce.Query{Sort: "-name"}.SortField()
+			Expect(field).To(Equal("name"))
+			Expect(desc).To(BeTrue())
+		})
+	})
+
+	Describe("Cursor", func() {
+		It("Should return nil for the first page", func() {
+			Expect(cce.Query{}.Cursor()).To(BeNil())
+		})
+
+		It("Should decode the page token", func() {
+			c := &cce.PageCursor{Sort: "name", Value: "test-node", ID: "123"}
+			Expect(cce.Query{Sort: "name", PageToken: c.Token()}.Cursor()).To(Equal(c))
+		})
+
+		It("Should reject a token issued for a different sort", func() {
+			c := &cce.PageCursor{Sort: "name", Value: "test-node", ID: "123"}
+			_, err := cce.Query{Sort: "-name", PageToken: c.Token()}.Cursor()
+			Expect(err).To(MatchError("page token was issued for a different sort"))
+		})
+
+		It("Should reject a malformed token", func() {
+			_, err := cce.Query{PageToken: "!"}.Cursor()
+			Expect(err).To(MatchError("malformed page token"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should accept filter and sort fields", func() {
+			Expect(cce.Query{
+				Filters: []cce.Filter{{Field: "serial", Value: "test-serial"}},
+				Sort:    "-location",
+				Limit:   10,
+			}.Validate(&cce.Node{})).To(Succeed())
+		})
+
+		It("Should reject a negative limit", func() {
+			Expect(cce.Query{Limit: -1}.Validate(&cce.Node{})).To(
+				MatchError("limit cannot be negative"))
+		})
+
+		It("Should reject fields that are not filterable", func() {
+			Expect(cce.Query{
+				Filters: []cce.Filter{{Field: "id", Value: "123"}},
+			}.Validate(&cce.Node{})).To(MatchError(`disallowed filter field "id"`))
+			Expect(cce.Query{Sort: "cores"}.Validate(&cce.App{})).To(
+				MatchError(`disallowed sort field "cores"`))
+		})
+
+		It("Should reject sort fields that are not strings", func() {
+			Expect(cce.Query{
+				Filters: []cce.Filter{{Field: "count", Value: "2"}},
+				Sort:    "name",
+			}.Validate(&counter{})).To(Succeed())
+			Expect(cce.Query{Sort: "-count"}.Validate(&counter{})).To(
+				MatchError(`sort field "count" is not a string`))
+		})
+
+		It("Should reject selectors of entities without labels", func() {
+			sel := cce.Selector{{Key: "region", Operator: cce.SelectorExists}}
+			Expect(cce.Query{Selector: sel}.Validate(&cce.Node{})).To(Succeed())
+			Expect(cce.Query{Selector: sel}.Validate(&cce.App{})).To(
+				MatchError("apps cannot be selected by labels"))
+		})
+	})
+})
+
+// counter is a Filterable with a filter field that is not a string.
+type counter struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (*counter) GetTableName() string {
+	return "counters"
+}
+
+func (c *counter) GetID() string {
+	return c.ID
+}
+
+func (c *counter) SetID(id string) {
+	c.ID = id
+}
+
+func (*counter) FilterFields() []string {
+	return []string{"name", "count"}
+}
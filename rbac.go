@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+// Roles that can be granted to a user.
+const (
+	// RoleAdmin can do everything, including managing users and API tokens.
+	RoleAdmin = "admin"
+	// RoleOperator can view and modify nodes, apps, policies and DNS.
+	RoleOperator = "operator"
+	// RoleViewer can only view nodes, apps, policies and DNS.
+	RoleViewer = "viewer"
+)
+
+// Permission is a permission required to call an API endpoint.
+type Permission int
+
+const (
+	// PermissionRead allows viewing resources.
+	PermissionRead Permission = iota
+	// PermissionWrite allows creating, modifying and deleting resources.
+	PermissionWrite
+	// PermissionAdmin allows managing users and API tokens.
+	PermissionAdmin
+)
+
+// rolePermissions are the permissions granted by each role.
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:    {PermissionRead, PermissionWrite, PermissionAdmin},
+	RoleOperator: {PermissionRead, PermissionWrite},
+	RoleViewer:   {PermissionRead},
+}
+
+// IsValidRole returns whether the role exists.
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission returns whether any of the roles grants the permission.
+func HasPermission(roles []string, p Permission) bool {
+	for _, role := range roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == p {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionRead:
+		return "read"
+	case PermissionWrite:
+		return "write"
+	case PermissionAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,738 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+// Package reconcile compares the persisted configuration of the enrolled
+// nodes with the configuration the nodes run, re-applies what drifted and
+// records a drift report per node.
+package reconcile
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "reconcile")
+
+// Defaults of the reconciler settings.
+const (
+	DefaultInterval = 10 * time.Minute
+	DefaultWorkers  = 16
+
+	defaultELAPort = "42101"
+	defaultEVAPort = "42102"
+)
+
+// ConnectFunc connects to the port of the node.
+type ConnectFunc func(ctx context.Context, nodeID, port string) (*node.ClientConn, error)
+
+// Reconciler periodically reconciles every enrolled node. An app that is not
+// deployed on the node is redeployed, unless the apps are orchestrated by
+// Kubernetes, in which case it is only reported. The DNS configuration and the
+// app and interface traffic policies of the node are re-applied, since the
+// node does not report them, and those that cannot be re-applied are
+// reported. The apps, DNS configuration and app traffic policies of the node
+// groups the node is in are first assigned to the node if it lacks them. The
+// result is stored as the NodeDrift of the node.
+type Reconciler struct {
+	Controller *cce.Controller
+	// Interval is the period between two reconciliations of the nodes.
+	Interval time.Duration
+	// Workers is the maximum number of nodes reconciled at once. If zero
+	// DefaultWorkers is used.
+	Workers int
+	// Connect connects to a node. If nil the node is dialed over gRPC with
+	// the controller's edge node credentials.
+	Connect ConnectFunc
+
+	semOnce sync.Once
+	// sem limits the nodes reconciled at once by ReconcileAll and Start
+	sem chan struct{}
+
+	mu sync.Mutex
+	// started are the IDs of the nodes started that wait for a worker
+	started map[string]bool
+}
+
+// Run reconciles the nodes every interval until the context is done.
+func (r *Reconciler) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.ReconcileAll(ctx); err != nil {
+			log.Errf("Error reconciling nodes: %v", err)
+		}
+	}
+}
+
+// ReconcileAll reconciles every enrolled node once, at most Workers nodes at
+// a time.
+func (r *Reconciler) ReconcileAll(ctx context.Context) error {
+	targets, err := r.Controller.PersistenceService.ReadAll(ctx, &cce.NodeGRPCTarget{})
+	if err != nil {
+		return errors.Wrap(err, "error reading node addresses")
+	}
+
+	sem := r.semaphore()
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(nodeID string) {
+			defer func() { <-sem; wg.Done() }()
+			if _, err := r.Reconcile(ctx, nodeID); err != nil {
+				log.Errf("Error reconciling node %s: %v", nodeID, err)
+			}
+		}(t.(*cce.NodeGRPCTarget).NodeID)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// Start reconciles the nodes in the background, each within
+// cce.MaxOperationTime, sharing the Workers of ReconcileAll. A node that is
+// already waiting for a worker is not started again.
+func (r *Reconciler) Start(ctx context.Context, nodeIDs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started == nil {
+		r.started = make(map[string]bool)
+	}
+
+	sem := r.semaphore()
+	for _, id := range nodeIDs {
+		if r.started[id] {
+			continue
+		}
+		r.started[id] = true
+
+		go func(nodeID string) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			// The node can be started again once a worker took it
+			r.mu.Lock()
+			delete(r.started, nodeID)
+			r.mu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, cce.MaxOperationTime)
+			defer cancel()
+			if _, err := r.Reconcile(ctx, nodeID); err != nil {
+				log.Errf("Error reconciling node %s: %v", nodeID, err)
+			}
+		}(id)
+	}
+}
+
+// semaphore returns the semaphore limiting the nodes reconciled at once to
+// Workers.
+func (r *Reconciler) semaphore() chan struct{} {
+	r.semOnce.Do(func() {
+		workers := r.Workers
+		if workers <= 0 {
+			workers = DefaultWorkers
+		}
+		r.sem = make(chan struct{}, workers)
+	})
+
+	return r.sem
+}
+
+// Reconcile reconciles the node and stores its drift report. A failure to
+// reach the node is recorded in the report; the returned error is only set if
+// the report could not be stored.
+func (r *Reconciler) Reconcile(ctx context.Context, nodeID string) (*cce.NodeDrift, error) {
+	drift, stored, err := r.drift(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	drift.ReconciledAt = time.Now().UTC()
+	drift.Items = []cce.NodeDriftItem{}
+	drift.Error = ""
+
+	if err = r.reconcileGroups(ctx, drift); err == nil {
+		err = r.reconcileApps(ctx, drift)
+	}
+	if err == nil {
+		err = r.reconcileELA(ctx, drift)
+	}
+	if err != nil {
+		log.Noticef("Could not reconcile node %s: %v", nodeID, err)
+		drift.Error = err.Error()
+	}
+	for _, item := range drift.Items {
+		log.Infof("Node %s drifted: %s %s: %s (repaired: %t)",
+			nodeID, item.Kind, item.ID, item.Detail, item.Repaired)
+	}
+
+	if stored {
+		err = r.Controller.PersistenceService.BulkUpdate(ctx, []cce.Persistable{drift})
+	} else {
+		err = r.Controller.PersistenceService.Create(ctx, drift)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error storing node drift")
+	}
+
+	return drift, nil
+}
+
+// drift returns the stored drift report of the node, or a new report if the
+// node was never reconciled.
+func (r *Reconciler) drift(ctx context.Context, nodeID string) (drift *cce.NodeDrift, stored bool, err error) {
+	persisted, err := r.Controller.PersistenceService.Filter(
+		ctx,
+		&cce.NodeDrift{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, false, errors.Wrap(err, "error reading node drift")
+	}
+	if len(persisted) > 0 {
+		return persisted[0].(*cce.NodeDrift), true, nil
+	}
+
+	return &cce.NodeDrift{ID: uuid.New(), NodeID: nodeID}, false, nil
+}
+
+// reconcileGroups assigns the configuration of the node groups matching the
+// labels of the node to the node. The apps are only assigned to the node if
+// they are deployed by the controller; otherwise they are reported. The DNS
+// configuration of a group is only assigned to a node without one, and the
+// traffic policy of an app only to an app without one.
+func (r *Reconciler) reconcileGroups(ctx context.Context, drift *cce.NodeDrift) error { // nolint: gocyclo
+	ps := r.Controller.PersistenceService
+
+	n, err := ps.Read(ctx, drift.NodeID, &cce.Node{})
+	if err != nil {
+		return errors.Wrap(err, "error reading node")
+	}
+	if n == nil {
+		return nil
+	}
+	groups, err := ps.ReadAll(ctx, &cce.NodeGroup{})
+	if err != nil {
+		return errors.Wrap(err, "error reading node groups")
+	}
+
+	for _, e := range groups {
+		group := e.(*cce.NodeGroup)
+		if !group.Matches(n.(*cce.Node)) {
+			continue
+		}
+
+		for _, appID := range group.Apps {
+			items, err := r.assignApp(ctx, drift.NodeID, group, appID)
+			if err != nil {
+				return err
+			}
+			drift.Items = append(drift.Items, items...)
+		}
+
+		if group.DNSConfigID == "" {
+			continue
+		}
+		nodeDNSConfigs, err := ps.Filter(
+			ctx,
+			&cce.NodeDNSConfig{},
+			[]cce.Filter{{Field: "node_id", Value: drift.NodeID}})
+		if err != nil {
+			return errors.Wrap(err, "error reading node DNS configs")
+		}
+		if len(nodeDNSConfigs) > 0 {
+			continue
+		}
+		if err = ps.Create(ctx, &cce.NodeDNSConfig{
+			ID:          uuid.New(),
+			NodeID:      drift.NodeID,
+			DNSConfigID: group.DNSConfigID,
+		}); err != nil {
+			return errors.Wrap(err, "error storing node DNS config")
+		}
+		drift.Items = append(drift.Items, cce.NodeDriftItem{
+			Kind:     cce.NodeDriftDNSConfig,
+			ID:       group.DNSConfigID,
+			Detail:   "DNS config of node group " + group.Name + " assigned",
+			Repaired: true,
+		})
+	}
+
+	return nil
+}
+
+// assignApp assigns the app of the group and its traffic policy to the node
+// and returns the drift items to report. If the controller enforces the
+// analysis of the traffic policies, a policy with findings is reported
+// instead of being assigned.
+func (r *Reconciler) assignApp( // nolint: gocyclo
+	ctx context.Context,
+	nodeID string,
+	group *cce.NodeGroup,
+	appID string,
+) ([]cce.NodeDriftItem, error) {
+	ps := r.Controller.PersistenceService
+
+	nodeApps, err := ps.Filter(
+		ctx,
+		&cce.NodeApp{},
+		[]cce.Filter{
+			{Field: "node_id", Value: nodeID},
+			{Field: "app_id", Value: appID},
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node apps")
+	}
+
+	var item *cce.NodeDriftItem
+	if len(nodeApps) == 0 {
+		item = &cce.NodeDriftItem{
+			Kind:   cce.NodeDriftApp,
+			ID:     appID,
+			Detail: "app of node group " + group.Name + " is not assigned",
+		}
+		if r.Controller.OrchestrationMode != cce.OrchestrationModeNative {
+			return []cce.NodeDriftItem{*item}, nil
+		}
+
+		app, err := ps.Read(ctx, appID, &cce.App{})
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading app")
+		}
+		if app == nil {
+			item.Detail += ": app not found"
+			return []cce.NodeDriftItem{*item}, nil
+		}
+		nodeApp := &cce.NodeApp{
+			ID:       uuid.New(),
+			NodeID:   nodeID,
+			AppID:    appID,
+			Revision: app.(*cce.App).Revision,
+		}
+		if err = ps.Create(ctx, nodeApp); err != nil {
+			return nil, errors.Wrap(err, "error storing node app")
+		}
+		item.Detail = "app of node group " + group.Name + " assigned"
+		item.Repaired = true
+		nodeApps = append(nodeApps, nodeApp)
+	}
+
+	var items []cce.NodeDriftItem
+	if item != nil {
+		items = append(items, *item)
+	}
+
+	policyID := group.TrafficPolicyID(appID)
+	if policyID == "" {
+		return items, nil
+	}
+	nodeAppPolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node app traffic policies")
+	}
+	if len(nodeAppPolicies) > 0 {
+		return items, nil
+	}
+
+	findings, err := r.analyzePolicy(ctx, nodeID, appID, policyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(findings) > 0 {
+		return append(items, cce.NodeDriftItem{
+			Kind: cce.NodeDriftAppPolicy,
+			ID:   appID,
+			Detail: "traffic policy of node group " + group.Name + " failed analysis: " +
+				strings.Join(findings, "; "),
+		}), nil
+	}
+
+	if err = ps.Create(ctx, &cce.NodeAppTrafficPolicy{
+		ID:              uuid.New(),
+		NodeAppID:       nodeApps[0].GetID(),
+		TrafficPolicyID: policyID,
+	}); err != nil {
+		return nil, errors.Wrap(err, "error storing node app traffic policy")
+	}
+	if item == nil {
+		items = append(items, cce.NodeDriftItem{
+			Kind:     cce.NodeDriftAppPolicy,
+			ID:       appID,
+			Detail:   "traffic policy of node group " + group.Name + " assigned",
+			Repaired: true,
+		})
+	}
+
+	return items, nil
+}
+
+// analyzePolicy returns the messages of the findings of the analysis of the
+// traffic policy about to be attached to the app of the node against the
+// other policies of the node, if the controller enforces the analysis.
+func (r *Reconciler) analyzePolicy(ctx context.Context, nodeID, appID, policyID string) ([]string, error) {
+	if !r.Controller.EnforcePolicyAnalysis {
+		return nil, nil
+	}
+	ps := r.Controller.PersistenceService
+
+	policy, err := ps.Read(ctx, policyID, &cce.TrafficPolicy{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading traffic policy")
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	others, err := r.otherPolicies(ctx, nodeID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := cce.AnalyzeTrafficPolicy(policy.(*cce.TrafficPolicy), others)
+	msgs := make([]string, 0, len(findings))
+	for _, f := range findings {
+		msgs = append(msgs, f.Message)
+	}
+
+	return msgs, nil
+}
+
+// otherPolicies returns the traffic policies of the interfaces of the node
+// and of its apps other than appID.
+func (r *Reconciler) otherPolicies(ctx context.Context, nodeID, appID string) ([]*cce.TrafficPolicy, error) {
+	ps := r.Controller.PersistenceService
+
+	nodeIfacePolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node interface traffic policies")
+	}
+	nodeAppPolicies, err := r.nodeAppPolicies(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, e := range nodeIfacePolicies {
+		ids[e.(*cce.NodeInterfaceTrafficPolicy).TrafficPolicyID] = true
+	}
+	for id, policyID := range nodeAppPolicies {
+		if id != appID {
+			ids[policyID] = true
+		}
+	}
+
+	var policies []*cce.TrafficPolicy
+	for id := range ids {
+		policy, err := ps.Read(ctx, id, &cce.TrafficPolicy{})
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading traffic policy")
+		}
+		if policy != nil {
+			policies = append(policies, policy.(*cce.TrafficPolicy))
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ID < policies[j].ID })
+
+	return policies, nil
+}
+
+// reconcileApps redeploys the apps of the node that are not deployed.
+func (r *Reconciler) reconcileApps(ctx context.Context, drift *cce.NodeDrift) error {
+	ps := r.Controller.PersistenceService
+
+	nodeApps, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{{Field: "node_id", Value: drift.NodeID}})
+	if err != nil {
+		return errors.Wrap(err, "error reading node apps")
+	}
+	if len(nodeApps) == 0 {
+		return nil
+	}
+
+	_, evaPort := r.ports()
+	nodeCC, err := r.connect(ctx, drift.NodeID, evaPort)
+	if err != nil {
+		return errors.Wrap(err, "error connecting to node")
+	}
+	defer nodeCC.Disconnect()
+
+	for _, e := range nodeApps {
+		appID := e.(*cce.NodeApp).AppID
+		s, err := nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+		switch {
+		case err == nil:
+			if s == cce.Error {
+				drift.Items = append(drift.Items, cce.NodeDriftItem{
+					Kind:   cce.NodeDriftApp,
+					ID:     appID,
+					Detail: "app is in error",
+				})
+			}
+			continue
+		case status.Code(errors.Cause(err)) != codes.NotFound:
+			return errors.Wrapf(err, "error reading status of app %s", appID)
+		}
+
+		item := cce.NodeDriftItem{Kind: cce.NodeDriftApp, ID: appID, Detail: "app is not deployed"}
+		if r.Controller.OrchestrationMode == cce.OrchestrationModeNative {
+			if err = r.redeploy(ctx, nodeCC, appID); err != nil {
+				item.Detail += ": " + err.Error()
+			} else {
+				item.Repaired = true
+			}
+		}
+		drift.Items = append(drift.Items, item)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) redeploy(ctx context.Context, nodeCC *node.ClientConn, appID string) error {
+	app, err := r.Controller.PersistenceService.Read(ctx, appID, &cce.App{})
+	if err != nil {
+		return errors.Wrap(err, "error reading app")
+	}
+	if app == nil {
+		return errors.New("app not found")
+	}
+
+	return nodeCC.AppDeploySvcCli.Deploy(ctx, app.(*cce.App))
+}
+
+// reconcileELA re-applies the DNS configuration and the traffic policies of
+// the node.
+func (r *Reconciler) reconcileELA(ctx context.Context, drift *cce.NodeDrift) error {
+	ps := r.Controller.PersistenceService
+
+	nodeDNSConfigs, err := ps.Filter(ctx, &cce.NodeDNSConfig{}, []cce.Filter{{Field: "node_id", Value: drift.NodeID}})
+	if err != nil {
+		return errors.Wrap(err, "error reading node DNS configs")
+	}
+	nodeIfacePolicies, err := ps.Filter(
+		ctx,
+		&cce.NodeInterfaceTrafficPolicy{},
+		[]cce.Filter{{Field: "node_id", Value: drift.NodeID}})
+	if err != nil {
+		return errors.Wrap(err, "error reading node interface traffic policies")
+	}
+	nodeAppPolicies, err := r.nodeAppPolicies(ctx, drift.NodeID)
+	if err != nil {
+		return err
+	}
+	if len(nodeDNSConfigs)+len(nodeIfacePolicies)+len(nodeAppPolicies) == 0 {
+		return nil
+	}
+
+	elaPort, _ := r.ports()
+	nodeCC, err := r.connect(ctx, drift.NodeID, elaPort)
+	if err != nil {
+		return errors.Wrap(err, "error connecting to node")
+	}
+	defer nodeCC.Disconnect()
+
+	for _, e := range nodeDNSConfigs {
+		dnsConfigID := e.(*cce.NodeDNSConfig).DNSConfigID
+		if err = r.applyDNSConfig(ctx, nodeCC, dnsConfigID); err != nil {
+			drift.Items = append(drift.Items, cce.NodeDriftItem{
+				Kind:   cce.NodeDriftDNSConfig,
+				ID:     dnsConfigID,
+				Detail: err.Error(),
+			})
+		}
+	}
+
+	for appID, policyID := range nodeAppPolicies {
+		if err = r.applyPolicy(ctx, policyID, func(policy *cce.TrafficPolicy) error {
+			return nodeCC.AppPolicySvcCli.Set(ctx, appID, policy)
+		}); err != nil {
+			drift.Items = append(drift.Items, cce.NodeDriftItem{
+				Kind:   cce.NodeDriftAppPolicy,
+				ID:     appID,
+				Detail: err.Error(),
+			})
+		}
+	}
+
+	for _, e := range nodeIfacePolicies {
+		nitp := e.(*cce.NodeInterfaceTrafficPolicy)
+		if err = r.applyPolicy(ctx, nitp.TrafficPolicyID, func(policy *cce.TrafficPolicy) error {
+			return nodeCC.IfacePolicySvcCli.Set(ctx, nitp.NetworkInterfaceID, policy)
+		}); err != nil {
+			drift.Items = append(drift.Items, cce.NodeDriftItem{
+				Kind:   cce.NodeDriftInterfacePolicy,
+				ID:     nitp.NetworkInterfaceID,
+				Detail: err.Error(),
+			})
+		}
+	}
+
+	return nil
+}
+
+// nodeAppPolicies returns the IDs of the traffic policies of the apps of the
+// node, keyed by app ID.
+func (r *Reconciler) nodeAppPolicies(ctx context.Context, nodeID string) (map[string]string, error) {
+	ps := r.Controller.PersistenceService
+
+	nodeApps, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node apps")
+	}
+
+	policies := map[string]string{}
+	for _, e := range nodeApps {
+		nodeAppPolicies, err := ps.Filter(
+			ctx,
+			&cce.NodeAppTrafficPolicy{},
+			[]cce.Filter{{Field: "nodes_apps_id", Value: e.GetID()}})
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading node app traffic policies")
+		}
+		for _, p := range nodeAppPolicies {
+			policies[e.(*cce.NodeApp).AppID] = p.(*cce.NodeAppTrafficPolicy).TrafficPolicyID
+		}
+	}
+
+	return policies, nil
+}
+
+// applyDNSConfig sets the records and forwarders of the DNS configuration on
+// the node.
+func (r *Reconciler) applyDNSConfig(ctx context.Context, nodeCC *node.ClientConn, dnsConfigID string) error {
+	ps := r.Controller.PersistenceService
+
+	e, err := ps.Read(ctx, dnsConfigID, &cce.DNSConfig{})
+	if err != nil {
+		return errors.Wrap(err, "error reading DNS config")
+	}
+	if e == nil {
+		return errors.New("DNS config not found")
+	}
+	dnsConfig := e.(*cce.DNSConfig)
+
+	aliases, err := ps.Filter(
+		ctx,
+		&cce.DNSConfigAppAlias{},
+		[]cce.Filter{{Field: "dns_config_id", Value: dnsConfigID}})
+	if err != nil {
+		return errors.Wrap(err, "error reading DNS config app aliases")
+	}
+
+	for _, a := range aliases {
+		alias := a.(*cce.DNSConfigAppAlias)
+		if err = nodeCC.DNSSvcCli.SetA(ctx, &cce.DNSARecord{
+			Name:        alias.AppID,
+			Description: alias.Description,
+			IPs:         []string{alias.AppID},
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, aRecord := range dnsConfig.ARecords {
+		if err = nodeCC.DNSSvcCli.SetA(ctx, aRecord); err != nil {
+			return err
+		}
+	}
+
+	if len(dnsConfig.Forwarders) != 0 {
+		return nodeCC.DNSSvcCli.SetForwarders(ctx, dnsConfig.Forwarders)
+	}
+
+	return nil
+}
+
+// applyPolicy reads the traffic policy and sets it with the set function.
+func (r *Reconciler) applyPolicy(
+	ctx context.Context,
+	policyID string,
+	set func(*cce.TrafficPolicy) error,
+) error {
+	policy, err := r.Controller.PersistenceService.Read(ctx, policyID, &cce.TrafficPolicy{})
+	if err != nil {
+		return errors.Wrap(err, "error reading traffic policy")
+	}
+	if policy == nil {
+		return errors.New("traffic policy not found")
+	}
+
+	return set(policy.(*cce.TrafficPolicy))
+}
+
+func (r *Reconciler) connect(ctx context.Context, nodeID, port string) (*node.ClientConn, error) {
+	if r.Connect != nil {
+		return r.Connect(ctx, nodeID, port)
+	}
+
+	targets, err := r.Controller.PersistenceService.Filter(
+		ctx,
+		&cce.NodeGRPCTarget{},
+		[]cce.Filter{{Field: "node_id", Value: nodeID}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node address")
+	}
+	if len(targets) != 1 {
+		return nil, errors.Errorf("node has %d addresses", len(targets))
+	}
+
+	conf := r.Controller.EdgeNodeCreds
+	if conf != nil {
+		conf = conf.Clone()
+		conf.ServerName = nodeID
+	}
+
+	nodeCC := &node.ClientConn{
+		Addr: targets[0].(*cce.NodeGRPCTarget).GRPCTarget,
+		Port: port,
+		TLS:  conf,
+	}
+	if err = nodeCC.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	return nodeCC, nil
+}
+
+func (r *Reconciler) ports() (ela, eva string) {
+	ela, eva = r.Controller.ELAPort, r.Controller.EVAPort
+	if ela == "" {
+		ela = defaultELAPort
+	}
+	if eva == "" {
+		eva = defaultEVAPort
+	}
+
+	return ela, eva
+}
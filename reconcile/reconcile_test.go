@@ -0,0 +1,532 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package reconcile_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	gclients "github.com/open-ness/edgecontroller/grpc/clients"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	cmock "github.com/open-ness/edgecontroller/mock/controller/grpc"
+	gmock "github.com/open-ness/edgecontroller/mock/node/grpc"
+	evapb "github.com/open-ness/edgecontroller/pb/eva"
+	"github.com/open-ness/edgecontroller/reconcile"
+	"github.com/open-ness/edgecontroller/uuid"
+	bbolt "go.etcd.io/bbolt"
+)
+
+var _ = Describe("Reconciler", func() {
+	var (
+		ctx        = context.Background()
+		tmpDir     string
+		db         *bbolt.DB
+		ps         *bolt.PersistenceService
+		mockNode   *gmock.MockNode
+		ctrl       *cce.Controller
+		reconciler *reconcile.Reconciler
+		nodeID     string
+		app        *cce.App
+		nodeApp    *cce.NodeApp
+		policy     *cce.TrafficPolicy
+
+		// connectErr is returned by the fake connect if set
+		connectErr error
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		By("Opening a db in a temp directory")
+		tmpDir, err = ioutil.TempDir("", "reconcile_test")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bbolt.Open(filepath.Join(tmpDir, "controller_ce.db"), 0600, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		By("Creating an enrolled node with an app, a DNS config and policies")
+		nodeID = uuid.New()
+		Expect(ps.Create(ctx, &cce.Node{
+			ID:       nodeID,
+			Name:     "test-node",
+			Location: "test-location",
+			Serial:   "test-serial",
+		})).To(Succeed())
+		Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+			ID:         uuid.New(),
+			NodeID:     nodeID,
+			GRPCTarget: "127.0.0.1",
+		})).To(Succeed())
+
+		app = &cce.App{
+			ID:          uuid.New(),
+			Type:        "container",
+			Name:        "test-app",
+			Version:     "latest",
+			Vendor:      "test-vendor",
+			Description: "test app",
+			Cores:       1,
+			Memory:      1024,
+			Source:      "http://www.test.com/test.tar.gz",
+		}
+		Expect(ps.Create(ctx, app)).To(Succeed())
+		nodeApp = &cce.NodeApp{ID: uuid.New(), NodeID: nodeID, AppID: app.ID}
+		Expect(ps.Create(ctx, nodeApp)).To(Succeed())
+
+		policy = &cce.TrafficPolicy{ID: uuid.New(), Name: "test-policy"}
+		Expect(ps.Create(ctx, policy)).To(Succeed())
+		Expect(ps.Create(ctx, &cce.NodeAppTrafficPolicy{
+			ID:              uuid.New(),
+			NodeAppID:       nodeApp.ID,
+			TrafficPolicyID: policy.ID,
+		})).To(Succeed())
+		Expect(ps.Create(ctx, &cce.NodeInterfaceTrafficPolicy{
+			ID:                 uuid.New(),
+			NodeID:             nodeID,
+			NetworkInterfaceID: "if0",
+			TrafficPolicyID:    policy.ID,
+		})).To(Succeed())
+
+		dnsConfig := &cce.DNSConfig{
+			ID:   uuid.New(),
+			Name: "test-dns",
+			ARecords: []*cce.DNSARecord{
+				{Name: "test.example.com", IPs: []string{"10.0.0.1"}},
+			},
+		}
+		Expect(ps.Create(ctx, dnsConfig)).To(Succeed())
+		Expect(ps.Create(ctx, &cce.NodeDNSConfig{
+			ID:          uuid.New(),
+			NodeID:      nodeID,
+			DNSConfigID: dnsConfig.ID,
+		})).To(Succeed())
+
+		mockNode = gmock.NewMockNode()
+		connectErr = nil
+		ctrl = &cce.Controller{PersistenceService: ps}
+		reconciler = &reconcile.Reconciler{
+			Controller: ctrl,
+			Connect: func(ctx context.Context, id, port string) (*node.ClientConn, error) {
+				Expect(id).To(Equal(nodeID))
+				if connectErr != nil {
+					return nil, connectErr
+				}
+				return &node.ClientConn{
+					AppDeploySvcCli: &gclients.ApplicationDeploymentServiceClient{
+						PBCli: &cmock.MockPBApplicationDeploymentServiceClient{MockNode: mockNode}},
+					AppLifeSvcCli: &gclients.ApplicationLifecycleServiceClient{
+						PBCli: &cmock.MockPBApplicationLifecycleServiceClient{MockNode: mockNode}},
+					AppPolicySvcCli: &gclients.ApplicationPolicyServiceClient{
+						PBCli: &cmock.MockPBApplicationPolicyServiceClient{MockNode: mockNode}},
+					IfacePolicySvcCli: &gclients.InterfacePolicyServiceClient{
+						PBCli: &cmock.MockPBInterfacePolicyServiceClient{MockNode: mockNode}},
+					DNSSvcCli: &gclients.DNSServiceClient{
+						PBCli: &cmock.MockPBDNSServiceClient{MockNode: mockNode}},
+				}, nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	storedDrift := func() []cce.Persistable {
+		drift, err := ps.Filter(ctx, &cce.NodeDrift{}, []cce.Filter{{Field: "node_id", Value: nodeID}})
+		Expect(err).ToNot(HaveOccurred())
+		return drift
+	}
+
+	Describe("Reconcile", func() {
+		It("Should redeploy an app that is not deployed", func() {
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Error).To(BeEmpty())
+			Expect(drift.Items).To(Equal([]cce.NodeDriftItem{
+				{
+					Kind:     cce.NodeDriftApp,
+					ID:       app.ID,
+					Detail:   "app is not deployed",
+					Repaired: true,
+				},
+			}))
+
+			By("Checking the app is deployed")
+			_, err = mockNode.AppLifeSvc.GetStatus(ctx, &evapb.ApplicationID{Id: app.ID})
+			Expect(err).ToNot(HaveOccurred())
+
+			By("Checking the drift is stored")
+			stored := storedDrift()
+			Expect(stored).To(HaveLen(1))
+			Expect(stored[0].GetID()).To(Equal(drift.ID))
+			Expect(stored[0].(*cce.NodeDrift).Items).To(Equal(drift.Items))
+		})
+
+		It("Should not report a node without drift", func() {
+			first, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Error).To(BeEmpty())
+			Expect(drift.Items).To(BeEmpty())
+
+			By("Checking the drift report is replaced")
+			stored := storedDrift()
+			Expect(stored).To(HaveLen(1))
+			Expect(stored[0].GetID()).To(Equal(first.ID))
+			Expect(stored[0].(*cce.NodeDrift).Items).To(BeEmpty())
+		})
+
+		It("Should only report an app that is not deployed by Kubernetes", func() {
+			ctrl.OrchestrationMode = cce.OrchestrationModeKubernetes
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Items).To(ContainElement(cce.NodeDriftItem{
+				Kind:   cce.NodeDriftApp,
+				ID:     app.ID,
+				Detail: "app is not deployed",
+			}))
+
+			_, err = mockNode.AppLifeSvc.GetStatus(ctx, &evapb.ApplicationID{Id: app.ID})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("Should assign the apps of the node groups the node is in", func() {
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{&cce.Node{
+				ID:       nodeID,
+				Name:     "test-node",
+				Location: "test-location",
+				Serial:   "test-serial",
+				Labels:   map[string]string{"region": "eu"},
+			}})).To(Succeed())
+			groupApp := &cce.App{
+				ID:          uuid.New(),
+				Type:        "container",
+				Name:        "group-app",
+				Version:     "latest",
+				Vendor:      "test-vendor",
+				Description: "group app",
+				Cores:       1,
+				Memory:      1024,
+				Source:      "http://www.test.com/group.tar.gz",
+			}
+			Expect(ps.Create(ctx, groupApp)).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeGroup{
+				ID:       uuid.New(),
+				Name:     "eu",
+				Selector: "region=eu",
+				Apps:     []string{groupApp.ID},
+				TrafficPolicies: []cce.NodeGroupTrafficPolicy{
+					{AppID: groupApp.ID, TrafficPolicyID: policy.ID},
+				},
+			})).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeGroup{
+				ID:       uuid.New(),
+				Name:     "us",
+				Selector: "region=us",
+				Apps:     []string{uuid.New()},
+			})).To(Succeed())
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Error).To(BeEmpty())
+			Expect(drift.Items).To(ContainElement(cce.NodeDriftItem{
+				Kind:     cce.NodeDriftApp,
+				ID:       groupApp.ID,
+				Detail:   "app of node group eu assigned",
+				Repaired: true,
+			}))
+
+			By("Checking the app is deployed with its traffic policy")
+			_, err = mockNode.AppLifeSvc.GetStatus(ctx, &evapb.ApplicationID{Id: groupApp.ID})
+			Expect(err).ToNot(HaveOccurred())
+			nodeApps, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{
+				{Field: "node_id", Value: nodeID},
+				{Field: "app_id", Value: groupApp.ID},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeApps).To(HaveLen(1))
+			nodeAppPolicies, err := ps.Filter(ctx, &cce.NodeAppTrafficPolicy{},
+				[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeAppPolicies).To(HaveLen(1))
+			Expect(nodeAppPolicies[0].(*cce.NodeAppTrafficPolicy).TrafficPolicyID).To(Equal(policy.ID))
+
+			By("Checking the app is not assigned again")
+			drift, err = reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Items).To(BeEmpty())
+		})
+
+		It("Should not assign a traffic policy of a node group that fails the analysis", func() {
+			ctrl.EnforcePolicyAnalysis = true
+			ipRule := func(action string) *cce.TrafficRule {
+				return &cce.TrafficRule{
+					Priority: 1,
+					Destination: &cce.TrafficSelector{
+						IP: &cce.IPFilter{Address: "10.0.0.0", Mask: 24, Protocol: "tcp"},
+					},
+					Target: &cce.TrafficTarget{Action: action},
+				}
+			}
+
+			By("Attaching a policy accepting the traffic to the interface of the node")
+			policy.Rules = []*cce.TrafficRule{ipRule("accept")}
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{policy})).To(Succeed())
+
+			By("Creating a node group whose app policy drops the traffic")
+			conflicting := &cce.TrafficPolicy{
+				ID:    uuid.New(),
+				Name:  "conflicting-policy",
+				Rules: []*cce.TrafficRule{ipRule("drop")},
+			}
+			Expect(ps.Create(ctx, conflicting)).To(Succeed())
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{&cce.Node{
+				ID:       nodeID,
+				Name:     "test-node",
+				Location: "test-location",
+				Serial:   "test-serial",
+				Labels:   map[string]string{"region": "eu"},
+			}})).To(Succeed())
+			groupApp := &cce.App{
+				ID:          uuid.New(),
+				Type:        "container",
+				Name:        "group-app",
+				Version:     "latest",
+				Vendor:      "test-vendor",
+				Description: "group app",
+				Cores:       1,
+				Memory:      1024,
+				Source:      "http://www.test.com/group.tar.gz",
+			}
+			Expect(ps.Create(ctx, groupApp)).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeGroup{
+				ID:       uuid.New(),
+				Name:     "eu",
+				Selector: "region=eu",
+				Apps:     []string{groupApp.ID},
+				TrafficPolicies: []cce.NodeGroupTrafficPolicy{
+					{AppID: groupApp.ID, TrafficPolicyID: conflicting.ID},
+				},
+			})).To(Succeed())
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Items).To(ContainElement(cce.NodeDriftItem{
+				Kind:     cce.NodeDriftApp,
+				ID:       groupApp.ID,
+				Detail:   "app of node group eu assigned",
+				Repaired: true,
+			}))
+
+			By("Checking the policy is reported and not assigned")
+			var reported *cce.NodeDriftItem
+			for i, item := range drift.Items {
+				if item.Kind == cce.NodeDriftAppPolicy && item.ID == groupApp.ID {
+					reported = &drift.Items[i]
+				}
+			}
+			Expect(reported).ToNot(BeNil())
+			Expect(reported.Detail).To(HavePrefix("traffic policy of node group eu failed analysis: "))
+			Expect(reported.Repaired).To(BeFalse())
+
+			nodeApps, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{
+				{Field: "node_id", Value: nodeID},
+				{Field: "app_id", Value: groupApp.ID},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeApps).To(HaveLen(1))
+			nodeAppPolicies, err := ps.Filter(ctx, &cce.NodeAppTrafficPolicy{},
+				[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApps[0].GetID()}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(nodeAppPolicies).To(BeEmpty())
+		})
+
+		It("Should report a policy that cannot be re-applied", func() {
+			Expect(ps.Create(ctx, &cce.NodeInterfaceTrafficPolicy{
+				ID:                 uuid.New(),
+				NodeID:             nodeID,
+				NetworkInterfaceID: "if9",
+				TrafficPolicyID:    policy.ID,
+			})).To(Succeed())
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Items).To(HaveLen(2))
+			Expect(drift.Items[1].Kind).To(Equal(cce.NodeDriftInterfacePolicy))
+			Expect(drift.Items[1].ID).To(Equal("if9"))
+			Expect(drift.Items[1].Detail).To(ContainSubstring("Network Interface if9 not found"))
+			Expect(drift.Items[1].Repaired).To(BeFalse())
+		})
+
+		It("Should record a node that cannot be reached", func() {
+			connectErr = errors.New("connection refused")
+
+			drift, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Error).To(Equal("error connecting to node: connection refused"))
+
+			stored := storedDrift()
+			Expect(stored).To(HaveLen(1))
+			Expect(stored[0].(*cce.NodeDrift).Error).To(Equal(drift.Error))
+		})
+	})
+
+	Describe("ReconcileAll", func() {
+		It("Should reconcile every enrolled node", func() {
+			Expect(reconciler.ReconcileAll(ctx)).To(Succeed())
+			Expect(storedDrift()).To(HaveLen(1))
+		})
+
+		It("Should reconcile at most Workers nodes at once", func() {
+			for i := 0; i < 8; i++ {
+				n := &cce.Node{
+					ID:       uuid.New(),
+					Name:     fmt.Sprintf("test-node-%d", i),
+					Location: "test-location",
+					Serial:   fmt.Sprintf("test-serial-%d", i),
+				}
+				Expect(ps.Create(ctx, n)).To(Succeed())
+				Expect(ps.Create(ctx, &cce.NodeGRPCTarget{
+					ID:         uuid.New(),
+					NodeID:     n.ID,
+					GRPCTarget: fmt.Sprintf("127.0.0.%d", i+2),
+				})).To(Succeed())
+				Expect(ps.Create(ctx, &cce.NodeApp{ID: uuid.New(), NodeID: n.ID, AppID: app.ID})).To(Succeed())
+			}
+
+			var (
+				mu               sync.Mutex
+				active, maxSeen  int
+				connectedNodeIDs = map[string]bool{}
+			)
+			reconciler.Workers = 3
+			reconciler.Connect = func(ctx context.Context, id, port string) (*node.ClientConn, error) {
+				mu.Lock()
+				active++
+				if active > maxSeen {
+					maxSeen = active
+				}
+				connectedNodeIDs[id] = true
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return nil, errors.New("node unreachable")
+			}
+
+			Expect(reconciler.ReconcileAll(ctx)).To(Succeed())
+			Expect(connectedNodeIDs).To(HaveLen(9))
+			Expect(maxSeen).To(BeNumerically("<=", 3))
+		})
+
+		It("Should reconcile the started nodes with at most Workers nodes at once", func() {
+			var nodeIDs []string
+			for i := 0; i < 8; i++ {
+				n := &cce.Node{
+					ID:       uuid.New(),
+					Name:     fmt.Sprintf("test-node-%d", i),
+					Location: "test-location",
+					Serial:   fmt.Sprintf("test-serial-%d", i),
+				}
+				Expect(ps.Create(ctx, n)).To(Succeed())
+				Expect(ps.Create(ctx, &cce.NodeApp{ID: uuid.New(), NodeID: n.ID, AppID: app.ID})).To(Succeed())
+				nodeIDs = append(nodeIDs, n.ID)
+			}
+
+			var (
+				mu              sync.Mutex
+				active, maxSeen int
+				connects        = map[string]int{}
+			)
+			reconciler.Workers = 3
+			reconciler.Connect = func(ctx context.Context, id, port string) (*node.ClientConn, error) {
+				mu.Lock()
+				active++
+				if active > maxSeen {
+					maxSeen = active
+				}
+				connects[id]++
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return nil, errors.New("node unreachable")
+			}
+
+			By("Starting every node twice while the workers are busy")
+			reconciler.Start(ctx, nodeIDs...)
+			reconciler.Start(ctx, nodeIDs[3:]...)
+
+			Eventually(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(connects)
+			}).Should(Equal(8))
+			Eventually(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return active
+			}).Should(BeZero())
+
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(maxSeen).To(BeNumerically("<=", 3))
+			for _, id := range nodeIDs[3:] {
+				Expect(connects[id]).To(Equal(1))
+			}
+		})
+	})
+
+	Describe("Deleting a node", func() {
+		It("Should delete its drift report", func() {
+			_, err := reconciler.Reconcile(ctx, nodeID)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, e := range []cce.Filterable{
+				&cce.NodeInterfaceTrafficPolicy{},
+				&cce.NodeDNSConfig{},
+				&cce.NodeGRPCTarget{},
+			} {
+				persisted, err := ps.Filter(ctx, e, []cce.Filter{{Field: "node_id", Value: nodeID}})
+				Expect(err).ToNot(HaveOccurred())
+				for _, p := range persisted {
+					_, err = ps.Delete(ctx, p.GetID(), e)
+					Expect(err).ToNot(HaveOccurred())
+				}
+			}
+			nodeAppPolicies, err := ps.Filter(ctx, &cce.NodeAppTrafficPolicy{},
+				[]cce.Filter{{Field: "nodes_apps_id", Value: nodeApp.ID}})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ps.Delete(ctx, nodeAppPolicies[0].GetID(), &cce.NodeAppTrafficPolicy{})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = ps.Delete(ctx, nodeApp.ID, &cce.NodeApp{})
+			Expect(err).ToNot(HaveOccurred())
+
+			ok, err := ps.Delete(ctx, nodeID, &cce.Node{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(storedDrift()).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// RevokedCertificate is a node certificate that was revoked before it expired.
+// Revoked certificates are listed in the certificate revocation list of the
+// controller and rejected when a node connects.
+type RevokedCertificate struct {
+	ID     string `json:"id"`
+	NodeID string `json:"node_id"`
+	// SerialNumber is the hex-encoded serial number of the certificate.
+	SerialNumber string    `json:"serial_number"`
+	RevokedAt    time.Time `json:"revoked_at"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*RevokedCertificate) GetTableName() string {
+	return "revoked_certificates"
+}
+
+// GetID gets the ID.
+func (c *RevokedCertificate) GetID() string {
+	return c.ID
+}
+
+// SetID sets the ID.
+func (c *RevokedCertificate) SetID(id string) {
+	c.ID = id
+}
+
+// Serial returns the serial number of the certificate.
+func (c *RevokedCertificate) Serial() (*big.Int, bool) {
+	return new(big.Int).SetString(c.SerialNumber, 16)
+}
+
+// Validate validates the model.
+func (c *RevokedCertificate) Validate() error {
+	if !uuid.IsValid(c.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(c.NodeID) {
+		return errors.New("node_id not a valid uuid")
+	}
+	if _, ok := c.Serial(); !ok {
+		return errors.New("serial_number not a hex-encoded number")
+	}
+	if c.RevokedAt.IsZero() {
+		return errors.New("revoked_at cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*RevokedCertificate) FilterFields() []string {
+	return []string{
+		"node_id",
+		"serial_number",
+	}
+}
+
+func (c *RevokedCertificate) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+RevokedCertificate[
+    ID: %s
+    NodeID: %s
+    SerialNumber: %s
+    RevokedAt: %s
+]`),
+		c.ID,
+		c.NodeID,
+		c.SerialNumber,
+		c.RevokedAt)
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: RevokedCertificate", func() {
+	var cert *cce.RevokedCertificate
+
+	BeforeEach(func() {
+		cert = &cce.RevokedCertificate{
+			ID:           "3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b",
+			NodeID:       "9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d",
+			SerialNumber: "1a2b3c",
+			RevokedAt:    time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "revoked_certificates"`, func() {
+			Expect(cert.GetTableName()).To(Equal("revoked_certificates"))
+		})
+	})
+
+	Describe("Serial", func() {
+		It("Should decode the serial number", func() {
+			serial, ok := cert.Serial()
+			Expect(ok).To(BeTrue())
+			Expect(serial).To(Equal(big.NewInt(0x1a2b3c)))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(cert.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			cert.ID = "123"
+			Expect(cert.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if NodeID is not a UUID", func() {
+			cert.NodeID = "123"
+			Expect(cert.Validate()).To(MatchError("node_id not a valid uuid"))
+		})
+
+		It("Should return an error if SerialNumber is not hex-encoded", func() {
+			cert.SerialNumber = "xyz"
+			Expect(cert.Validate()).To(MatchError("serial_number not a hex-encoded number"))
+		})
+
+		It("Should return an error if RevokedAt is empty", func() {
+			cert.RevokedAt = time.Time{}
+			Expect(cert.Validate()).To(MatchError("revoked_at cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(cert.FilterFields()).To(Equal([]string{
+				"node_id",
+				"serial_number",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(cert.String()).To(Equal(strings.TrimSpace(`
+RevokedCertificate[
+    ID: 3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b
+    NodeID: 9d7a3c8e-5f1b-4e2a-8c6d-7b4e1f0a2c3d
+    SerialNumber: 1a2b3c
+    RevokedAt: 2019-12-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
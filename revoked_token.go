@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// RevokedToken is a JSON Web Token that was revoked before it expired, e.g. by
+// logging out. Its ID is the jti claim of the token. It can be deleted once
+// the token has expired.
+type RevokedToken struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*RevokedToken) GetTableName() string {
+	return "revoked_tokens"
+}
+
+// GetID gets the ID.
+func (t *RevokedToken) GetID() string {
+	return t.ID
+}
+
+// SetID sets the ID.
+func (t *RevokedToken) SetID(id string) {
+	t.ID = id
+}
+
+// Expired returns whether the token has expired.
+func (t *RevokedToken) Expired() bool {
+	return !time.Now().Before(t.ExpiresAt)
+}
+
+// Validate validates the model.
+func (t *RevokedToken) Validate() error {
+	if !uuid.IsValid(t.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(t.UserID) {
+		return errors.New("user_id not a valid uuid")
+	}
+	if t.ExpiresAt.IsZero() {
+		return errors.New("expires_at cannot be empty")
+	}
+
+	return nil
+}
+
+func (t *RevokedToken) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+RevokedToken[
+    ID: %s
+    UserID: %s
+    ExpiresAt: %s
+]`),
+		t.ID,
+		t.UserID,
+		t.ExpiresAt)
+}
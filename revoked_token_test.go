@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: RevokedToken", func() {
+	var token *cce.RevokedToken
+
+	BeforeEach(func() {
+		token = &cce.RevokedToken{
+			ID:        "3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b",
+			UserID:    "b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54",
+			ExpiresAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "revoked_tokens"`, func() {
+			Expect(token.GetTableName()).To(Equal("revoked_tokens"))
+		})
+	})
+
+	Describe("Expired", func() {
+		It("Should return whether the token has expired", func() {
+			Expect(token.Expired()).To(BeTrue())
+			token.ExpiresAt = time.Now().Add(time.Hour)
+			Expect(token.Expired()).To(BeFalse())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(token.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			token.ID = "123"
+			Expect(token.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if UserID is not a UUID", func() {
+			token.UserID = "123"
+			Expect(token.Validate()).To(MatchError("user_id not a valid uuid"))
+		})
+
+		It("Should return an error if ExpiresAt is empty", func() {
+			token.ExpiresAt = time.Time{}
+			Expect(token.Validate()).To(MatchError("expires_at cannot be empty"))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(token.String()).To(Equal(strings.TrimSpace(`
+RevokedToken[
+    ID: 3d2b5a7e-6c1f-4f0e-8a4b-2f5e9c7d1a3b
+    UserID: b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54
+    ExpiresAt: 2019-12-01 00:00:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+// Statuses of a rollout.
+const (
+	RolloutPending     = "pending"
+	RolloutRunning     = "running"
+	RolloutSucceeded   = "succeeded"
+	RolloutRollingBack = "rolling_back"
+	RolloutRolledBack  = "rolled_back"
+	// RolloutFailed is the status of a rollout that could not be rolled back.
+	RolloutFailed = "failed"
+)
+
+// Statuses of a node of a rollout.
+const (
+	RolloutNodePending    = "pending"
+	RolloutNodeUpgrading  = "upgrading"
+	RolloutNodeUpgraded   = "upgraded"
+	RolloutNodeFailed     = "failed"
+	RolloutNodeRolledBack = "rolled_back"
+)
+
+// Rollout deploys an app to a set of nodes in waves, replacing the previous
+// app on each node. The rollout halts and is rolled back to the previous app
+// once more than MaxFailures nodes failed to run the app.
+type Rollout struct {
+	ID    string `json:"id"`
+	AppID string `json:"app_id"`
+	// PreviousAppID is the app replaced by the rollout, if any.
+	PreviousAppID string `json:"previous_app_id,omitempty"`
+	// WaveSize is the number of nodes upgraded by each wave.
+	WaveSize int `json:"wave_size"`
+	// MaxUnavailable is the number of nodes of a wave upgraded at the same
+	// time.
+	MaxUnavailable int `json:"max_unavailable"`
+	// MaxFailures is the number of nodes that may fail before the rollout
+	// halts.
+	MaxFailures int           `json:"max_failures"`
+	Status      string        `json:"status"`
+	Nodes       []RolloutNode `json:"nodes"`
+	// Wave is the current wave, from 1.
+	Wave      int       `json:"wave"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RolloutNode is the progress of a rollout on a node.
+type RolloutNode struct {
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	// Deployed is set if the app was deployed to the node by the rollout.
+	Deployed bool `json:"deployed,omitempty"`
+	// Replaced is set if the previous app was undeployed from the node by the
+	// rollout.
+	Replaced bool   `json:"replaced,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*Rollout) GetTableName() string {
+	return "rollouts"
+}
+
+// GetID gets the ID.
+func (r *Rollout) GetID() string {
+	return r.ID
+}
+
+// SetID sets the ID.
+func (r *Rollout) SetID(id string) {
+	r.ID = id
+}
+
+// Done returns whether the rollout ended.
+func (r *Rollout) Done() bool {
+	switch r.Status {
+	case RolloutSucceeded, RolloutRolledBack, RolloutFailed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate validates the model.
+func (r *Rollout) Validate() error { // nolint: gocyclo
+	if !uuid.IsValid(r.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if !uuid.IsValid(r.AppID) {
+		return errors.New("app_id not a valid uuid")
+	}
+	if r.PreviousAppID != "" {
+		if !uuid.IsValid(r.PreviousAppID) {
+			return errors.New("previous_app_id not a valid uuid")
+		}
+		if r.PreviousAppID == r.AppID {
+			return errors.New("previous_app_id cannot be app_id")
+		}
+	}
+	if r.WaveSize < 1 {
+		return errors.New("wave_size must be at least 1")
+	}
+	if r.MaxUnavailable < 1 {
+		return errors.New("max_unavailable must be at least 1")
+	}
+	if r.MaxFailures < 0 {
+		return errors.New("max_failures cannot be negative")
+	}
+	switch r.Status {
+	case RolloutPending, RolloutRunning, RolloutSucceeded, RolloutRollingBack, RolloutRolledBack, RolloutFailed:
+	default:
+		return fmt.Errorf("status must be one of [%s, %s, %s, %s, %s, %s]",
+			RolloutPending, RolloutRunning, RolloutSucceeded, RolloutRollingBack, RolloutRolledBack, RolloutFailed)
+	}
+	if len(r.Nodes) == 0 {
+		return errors.New("nodes cannot be empty")
+	}
+	seen := make(map[string]bool, len(r.Nodes))
+	for i, n := range r.Nodes {
+		if !uuid.IsValid(n.NodeID) {
+			return fmt.Errorf("nodes[%d].node_id not a valid uuid", i)
+		}
+		if seen[n.NodeID] {
+			return fmt.Errorf("nodes[%d].node_id %s is duplicated", i, n.NodeID)
+		}
+		seen[n.NodeID] = true
+		switch n.Status {
+		case RolloutNodePending, RolloutNodeUpgrading, RolloutNodeUpgraded, RolloutNodeFailed, RolloutNodeRolledBack:
+		default:
+			return fmt.Errorf("nodes[%d].status must be one of [%s, %s, %s, %s, %s]", i,
+				RolloutNodePending, RolloutNodeUpgrading, RolloutNodeUpgraded, RolloutNodeFailed,
+				RolloutNodeRolledBack)
+		}
+	}
+	if r.CreatedAt.IsZero() {
+		return errors.New("created_at cannot be empty")
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*Rollout) FilterFields() []string {
+	return []string{
+		"app_id",
+		"status",
+	}
+}
+
+func (r *Rollout) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+Rollout[
+    ID: %s
+    AppID: %s
+    PreviousAppID: %s
+    WaveSize: %d
+    MaxUnavailable: %d
+    MaxFailures: %d
+    Status: %s
+    Nodes: %+v
+    Wave: %d
+    Error: %s
+    CreatedAt: %s
+    UpdatedAt: %s
+]`),
+		r.ID,
+		r.AppID,
+		r.PreviousAppID,
+		r.WaveSize,
+		r.MaxUnavailable,
+		r.MaxFailures,
+		r.Status,
+		r.Nodes,
+		r.Wave,
+		r.Error,
+		r.CreatedAt,
+		r.UpdatedAt)
+}
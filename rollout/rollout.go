@@ -0,0 +1,604 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+// Package rollout deploys an app to sets of nodes in waves and rolls the
+// nodes back to the previous app when too many of them fail.
+package rollout
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	logger "github.com/open-ness/common/log"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	"github.com/open-ness/edgecontroller/uuid"
+)
+
+var log = logger.DefaultLogger.WithField("pkg", "rollout")
+
+// Defaults of the runner settings.
+const (
+	DefaultReadyTimeout = 10 * time.Minute
+	DefaultPollInterval = 5 * time.Second
+
+	defaultEVAPort = "42102"
+)
+
+// ConnectFunc connects to the port of the node.
+type ConnectFunc func(ctx context.Context, nodeID, port string) (*node.ClientConn, error)
+
+// Runner runs the rollouts in the background.
+//
+// On each node the app is deployed and started and its LifecycleStatus is
+// polled until the app is running, then the previous app is undeployed and its
+// traffic policies are attached to the app. A rollback restores and starts the
+// previous app the same way.
+type Runner struct {
+	Controller *cce.Controller
+	// Connect connects to a node.
+	Connect ConnectFunc
+	// ReadyTimeout is how long the app may take to be deployed, and then to
+	// start, on a node.
+	ReadyTimeout time.Duration
+	// PollInterval is the period between two checks of the status of the app
+	// on a node.
+	PollInterval time.Duration
+
+	mu sync.Mutex
+	// ctx is the context the rollouts run in
+	ctx context.Context
+	// running are the IDs of the running rollouts
+	running map[string]bool
+}
+
+// Resume runs the rollouts in the context from now on and restarts the
+// rollouts that did not end before the controller last shut down.
+func (r *Runner) Resume(ctx context.Context) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+
+	for _, s := range []string{cce.RolloutPending, cce.RolloutRunning, cce.RolloutRollingBack} {
+		persisted, err := r.Controller.PersistenceService.Filter(
+			ctx,
+			&cce.Rollout{},
+			[]cce.Filter{{Field: "status", Value: s}})
+		if err != nil {
+			log.Errf("Error reading %s rollouts: %v", s, err)
+			continue
+		}
+		for _, e := range persisted {
+			log.Infof("Resuming rollout %s", e.GetID())
+			r.Start(e.(*cce.Rollout))
+		}
+	}
+}
+
+// Start runs a copy of the rollout in the background, unless it is already
+// running.
+func (r *Runner) Start(rollout *cce.Rollout) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running == nil {
+		r.running = make(map[string]bool)
+	}
+	if r.running[rollout.ID] {
+		return
+	}
+	r.running[rollout.ID] = true
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ro := *rollout
+	ro.Nodes = append([]cce.RolloutNode(nil), rollout.Nodes...)
+	go func() {
+		if err := r.Run(ctx, &ro); err != nil {
+			log.Errf("Error running rollout %s: %v", ro.ID, err)
+		}
+
+		r.mu.Lock()
+		delete(r.running, ro.ID)
+		r.mu.Unlock()
+	}()
+}
+
+// Run runs the rollout until it ends, storing its progress. If the context is
+// done the rollout is interrupted and can be run again.
+func (r *Runner) Run(ctx context.Context, ro *cce.Rollout) error {
+	run := &run{Runner: r, ro: ro}
+
+	if ro.Status == cce.RolloutPending {
+		ro.Status = cce.RolloutRunning
+		run.store()
+	}
+	if ro.Status == cce.RolloutRunning {
+		if err := run.upgrade(ctx); err != nil {
+			return err
+		}
+	}
+	if ro.Status == cce.RolloutRollingBack {
+		if err := run.rollback(ctx); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Rollout %s %s", ro.ID, ro.Status)
+	return nil
+}
+
+// run is a rollout being run. The rollout is locked since its nodes are
+// upgraded concurrently.
+type run struct {
+	*Runner
+
+	mu sync.Mutex
+	ro *cce.Rollout
+}
+
+// upgrade upgrades the nodes wave by wave. It halts once more than
+// MaxFailures nodes failed and marks the rollout for rollback.
+func (run *run) upgrade(ctx context.Context) error {
+	ro := run.ro
+	if ro.Wave < 1 {
+		ro.Wave = 1
+	}
+
+	for ; (ro.Wave-1)*ro.WaveSize < len(ro.Nodes); ro.Wave++ {
+		run.store()
+
+		start := (ro.Wave - 1) * ro.WaveSize
+		end := start + ro.WaveSize
+		if end > len(ro.Nodes) {
+			end = len(ro.Nodes)
+		}
+
+		sem := make(chan struct{}, ro.MaxUnavailable)
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			sem <- struct{}{}
+			if run.halted() {
+				<-sem
+				break
+			}
+
+			run.mu.Lock()
+			n := &ro.Nodes[i]
+			pending := n.Status == cce.RolloutNodePending || n.Status == cce.RolloutNodeUpgrading
+			if pending {
+				n.Status = cce.RolloutNodeUpgrading
+			}
+			run.mu.Unlock()
+			if !pending {
+				<-sem
+				continue
+			}
+			run.store()
+
+			wg.Add(1)
+			go func(i int) {
+				defer func() { <-sem; wg.Done() }()
+				run.upgradeNode(ctx, i)
+			}(i)
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "interrupted")
+		}
+		if run.halted() {
+			log.Noticef("Rollout %s halted at wave %d", ro.ID, ro.Wave)
+			ro.Status = cce.RolloutRollingBack
+			ro.Error = "too many nodes failed"
+			run.store()
+			return nil
+		}
+	}
+
+	ro.Wave--
+	ro.Status = cce.RolloutSucceeded
+	run.store()
+	return nil
+}
+
+// halted returns whether more than MaxFailures nodes failed.
+func (run *run) halted() bool {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	failures := 0
+	for _, n := range run.ro.Nodes {
+		if n.Status == cce.RolloutNodeFailed {
+			failures++
+		}
+	}
+
+	return failures > run.ro.MaxFailures
+}
+
+// upgradeNode deploys the app to the node and replaces the previous app.
+func (run *run) upgradeNode(ctx context.Context, i int) {
+	run.mu.Lock()
+	n := run.ro.Nodes[i]
+	run.mu.Unlock()
+
+	err := run.deploy(ctx, &n)
+	if err == nil {
+		err = run.replace(ctx, &n)
+	}
+	if ctx.Err() != nil {
+		// Interrupted: the node is upgraded again when the rollout resumes
+		return
+	}
+
+	if err != nil {
+		log.Noticef("Rollout %s failed on node %s: %v", run.ro.ID, n.NodeID, err)
+		n.Status = cce.RolloutNodeFailed
+		n.Error = err.Error()
+	} else {
+		n.Status = cce.RolloutNodeUpgraded
+	}
+
+	run.mu.Lock()
+	run.ro.Nodes[i] = n
+	run.mu.Unlock()
+	run.store()
+}
+
+// deploy deploys the app to the node, unless it is already, and waits for it
+// to be running.
+func (run *run) deploy(ctx context.Context, n *cce.RolloutNode) error {
+	nodeApp, err := run.nodeApp(ctx, n.NodeID, run.ro.AppID)
+	if err != nil {
+		return err
+	}
+	if nodeApp != nil {
+		return nil
+	}
+
+	nodeCC, err := run.connect(ctx, n.NodeID)
+	if err != nil {
+		return err
+	}
+	defer nodeCC.Disconnect()
+
+	app, err := run.deployApp(ctx, nodeCC, run.ro.AppID)
+	if err != nil {
+		return err
+	}
+	n.Deployed = true
+
+	return run.ps().Create(ctx, &cce.NodeApp{
+		ID:       uuid.New(),
+		NodeID:   n.NodeID,
+		AppID:    run.ro.AppID,
+		Revision: app.Revision,
+	})
+}
+
+// replace undeploys the previous app from the node and attaches its traffic
+// policies to the app.
+func (run *run) replace(ctx context.Context, n *cce.RolloutNode) error {
+	if run.ro.PreviousAppID == "" {
+		return nil
+	}
+	previous, err := run.nodeApp(ctx, n.NodeID, run.ro.PreviousAppID)
+	if err != nil || previous == nil {
+		return err
+	}
+	nodeApp, err := run.nodeApp(ctx, n.NodeID, run.ro.AppID)
+	if err != nil {
+		return err
+	}
+
+	nodeCC, err := run.connect(ctx, n.NodeID)
+	if err != nil {
+		return err
+	}
+	defer nodeCC.Disconnect()
+
+	// The app may have been on the node, stopped, before the rollout
+	if err = run.startApp(ctx, nodeCC, run.ro.AppID); err != nil {
+		return err
+	}
+	if err = undeploy(ctx, nodeCC, previous.AppID); err != nil {
+		return err
+	}
+	n.Replaced = true
+
+	return run.movePolicies(ctx, previous, nodeApp)
+}
+
+// rollback undeploys the app from the nodes it was deployed to and deploys
+// the previous app to the nodes it was undeployed from.
+func (run *run) rollback(ctx context.Context) error {
+	ro := run.ro
+	failed := false
+
+	for i := range ro.Nodes {
+		n := &ro.Nodes[i]
+		if n.Status == cce.RolloutNodePending || n.Status == cce.RolloutNodeRolledBack {
+			continue
+		}
+
+		err := run.rollbackNode(ctx, n)
+		if ctx.Err() != nil {
+			return errors.Wrap(ctx.Err(), "interrupted")
+		}
+		if err != nil {
+			log.Errf("Error rolling back rollout %s on node %s: %v", ro.ID, n.NodeID, err)
+			n.Error = "rollback: " + err.Error()
+			failed = true
+		} else {
+			n.Status = cce.RolloutNodeRolledBack
+		}
+		run.store()
+	}
+
+	if failed {
+		ro.Status = cce.RolloutFailed
+	} else {
+		ro.Status = cce.RolloutRolledBack
+	}
+	run.store()
+	return nil
+}
+
+func (run *run) rollbackNode(ctx context.Context, n *cce.RolloutNode) error {
+	if !n.Deployed && !n.Replaced {
+		return nil
+	}
+
+	nodeCC, err := run.connect(ctx, n.NodeID)
+	if err != nil {
+		return err
+	}
+	defer nodeCC.Disconnect()
+
+	nodeApp, err := run.nodeApp(ctx, n.NodeID, run.ro.AppID)
+	if err != nil {
+		return err
+	}
+
+	if n.Replaced {
+		app, err := run.deployApp(ctx, nodeCC, run.ro.PreviousAppID)
+		if err != nil {
+			return err
+		}
+		previous, err := run.nodeApp(ctx, n.NodeID, run.ro.PreviousAppID)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			previous = &cce.NodeApp{
+				ID:       uuid.New(),
+				NodeID:   n.NodeID,
+				AppID:    run.ro.PreviousAppID,
+				Revision: app.Revision,
+			}
+			if err = run.ps().Create(ctx, previous); err != nil {
+				return err
+			}
+		}
+		n.Replaced = false
+
+		// The policies stay with the app if it was deployed before the rollout
+		if n.Deployed && nodeApp != nil {
+			if err = run.movePolicies(ctx, nodeApp, previous); err != nil {
+				return err
+			}
+			nodeApp = nil
+		}
+	}
+
+	if n.Deployed {
+		if err = undeploy(ctx, nodeCC, run.ro.AppID); err != nil {
+			return err
+		}
+		if nodeApp != nil {
+			if _, err = run.ps().Delete(ctx, nodeApp.ID, &cce.NodeApp{}); err != nil {
+				return err
+			}
+		}
+		n.Deployed = false
+	}
+
+	return nil
+}
+
+// deployApp deploys the app to the node, unless the node already has it, and
+// starts it. The deployed app is returned.
+func (run *run) deployApp(ctx context.Context, nodeCC *node.ClientConn, appID string) (*cce.App, error) {
+	app, err := run.ps().Read(ctx, appID, &cce.App{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading app")
+	}
+	if app == nil {
+		return nil, errors.Errorf("app %s not found", appID)
+	}
+
+	_, err = nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+	switch {
+	case status.Code(errors.Cause(err)) == codes.NotFound:
+		if err = nodeCC.AppDeploySvcCli.Deploy(ctx, app.(*cce.App)); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	return app.(*cce.App), run.startApp(ctx, nodeCC, appID)
+}
+
+// startApp waits for the app to be deployed on the node, starts it unless it
+// is already starting or running and waits for it to be running.
+func (run *run) startApp(ctx context.Context, nodeCC *node.ClientConn, appID string) error {
+	s, err := run.waitStatus(ctx, nodeCC, appID, cce.Unknown, cce.Deploying, cce.Stopping)
+	if err != nil {
+		return err
+	}
+	if s == cce.Deployed || s == cce.Stopped {
+		if err = nodeCC.AppLifeSvcCli.Start(ctx, appID); err != nil {
+			return err
+		}
+	}
+
+	s, err = run.waitStatus(ctx, nodeCC, appID, cce.Deployed, cce.Stopped, cce.Starting)
+	if err != nil {
+		return err
+	}
+	if s != cce.Running {
+		return errors.Errorf("app %s is %s instead of running", appID, s)
+	}
+	return nil
+}
+
+// waitStatus polls the status of the app on the node while it is one of the
+// pending statuses and returns the first other status.
+func (run *run) waitStatus(
+	ctx context.Context,
+	nodeCC *node.ClientConn,
+	appID string,
+	pending ...cce.LifecycleStatus,
+) (cce.LifecycleStatus, error) {
+	timeout := run.ReadyTimeout
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	interval := run.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		s, err := nodeCC.AppLifeSvcCli.GetStatus(ctx, appID)
+		if err != nil {
+			return cce.Unknown, err
+		}
+		if s == cce.Error {
+			return s, errors.Errorf("app %s is in error", appID)
+		}
+		if !isPending(s, pending) {
+			return s, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return s, errors.Errorf("app %s still %s after %s", appID, s, timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// isPending returns whether the status is one of the pending statuses.
+func isPending(s cce.LifecycleStatus, pending []cce.LifecycleStatus) bool {
+	for _, p := range pending {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// undeploy undeploys the app from the node, unless the node does not have it.
+func undeploy(ctx context.Context, nodeCC *node.ClientConn, appID string) error {
+	err := nodeCC.AppDeploySvcCli.Undeploy(ctx, appID)
+	if status.Code(errors.Cause(err)) == codes.NotFound {
+		return nil
+	}
+
+	return err
+}
+
+// movePolicies attaches the traffic policies of a node app to another one.
+// The policies are applied to the node by the reconciler.
+func (run *run) movePolicies(ctx context.Context, from, to *cce.NodeApp) error {
+	ps := run.ps()
+
+	policies, err := ps.Filter(
+		ctx,
+		&cce.NodeAppTrafficPolicy{},
+		[]cce.Filter{{Field: "nodes_apps_id", Value: from.ID}})
+	if err != nil {
+		return err
+	}
+	for _, p := range policies {
+		if _, err = ps.Delete(ctx, p.GetID(), &cce.NodeAppTrafficPolicy{}); err != nil {
+			return err
+		}
+		if to == nil {
+			continue
+		}
+		if err = ps.Create(ctx, &cce.NodeAppTrafficPolicy{
+			ID:              uuid.New(),
+			NodeAppID:       to.ID,
+			TrafficPolicyID: p.(*cce.NodeAppTrafficPolicy).TrafficPolicyID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err = ps.Delete(ctx, from.ID, &cce.NodeApp{})
+	return err
+}
+
+// nodeApp returns the node app of the app on the node, or nil if the app is
+// not deployed to the node.
+func (run *run) nodeApp(ctx context.Context, nodeID, appID string) (*cce.NodeApp, error) {
+	nodeApps, err := run.ps().Filter(
+		ctx,
+		&cce.NodeApp{},
+		[]cce.Filter{
+			{Field: "node_id", Value: nodeID},
+			{Field: "app_id", Value: appID},
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading node apps")
+	}
+	if len(nodeApps) == 0 {
+		return nil, nil
+	}
+
+	return nodeApps[0].(*cce.NodeApp), nil
+}
+
+func (run *run) connect(ctx context.Context, nodeID string) (*node.ClientConn, error) {
+	port := run.Controller.EVAPort
+	if port == "" {
+		port = defaultEVAPort
+	}
+
+	nodeCC, err := run.Connect(ctx, nodeID, port)
+	if err != nil {
+		return nil, errors.Wrap(err, "error connecting to node")
+	}
+
+	return nodeCC, nil
+}
+
+// store stores the rollout. It is stored even if the context of the run is
+// done so that its progress is recorded.
+func (run *run) store() {
+	ctx, cancel := context.WithTimeout(context.Background(), cce.MaxDBRequestTime)
+	defer cancel()
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.ro.UpdatedAt = time.Now().UTC()
+	if err := run.ps().BulkUpdate(ctx, []cce.Persistable{run.ro}); err != nil {
+		log.Errf("Error storing rollout %s: %v", run.ro.ID, err)
+	}
+}
+
+func (r *Runner) ps() cce.PersistenceService {
+	return r.Controller.PersistenceService
+}
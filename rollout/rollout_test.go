@@ -0,0 +1,322 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package rollout_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+	"github.com/open-ness/edgecontroller/bolt"
+	gclients "github.com/open-ness/edgecontroller/grpc/clients"
+	"github.com/open-ness/edgecontroller/grpc/node"
+	cmock "github.com/open-ness/edgecontroller/mock/controller/grpc"
+	gmock "github.com/open-ness/edgecontroller/mock/node/grpc"
+	evapb "github.com/open-ness/edgecontroller/pb/eva"
+	"github.com/open-ness/edgecontroller/rollout"
+	"github.com/open-ness/edgecontroller/uuid"
+	bbolt "go.etcd.io/bbolt"
+	"google.golang.org/grpc"
+)
+
+// failingDeployClient fails to deploy apps.
+type failingDeployClient struct {
+	cmock.MockPBApplicationDeploymentServiceClient
+}
+
+func (c *failingDeployClient) DeployContainer(
+	ctx context.Context,
+	in *evapb.Application,
+	opts ...grpc.CallOption,
+) (*empty.Empty, error) {
+	return nil, errors.New("no space left on device")
+}
+
+var _ = Describe("Rollout", func() {
+	var (
+		ctx      = context.Background()
+		tmpDir   string
+		db       *bbolt.DB
+		ps       *bolt.PersistenceService
+		runner   *rollout.Runner
+		nodeIDs  []string
+		nodes    map[string]*gmock.MockNode
+		failing  map[string]bool
+		app      *cce.App
+		previous *cce.App
+		policy   *cce.TrafficPolicy
+	)
+
+	newApp := func(version string) *cce.App {
+		app := &cce.App{
+			ID:          uuid.New(),
+			Type:        "container",
+			Name:        "test-app",
+			Version:     version,
+			Vendor:      "test-vendor",
+			Description: "test app",
+			Cores:       1,
+			Memory:      1024,
+			Source:      "http://www.test.com/test.tar.gz",
+		}
+		Expect(ps.Create(ctx, app)).To(Succeed())
+		return app
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		By("Opening a db in a temp directory")
+		tmpDir, err = ioutil.TempDir("", "rollout_test")
+		Expect(err).ToNot(HaveOccurred())
+		db, err = bbolt.Open(filepath.Join(tmpDir, "controller_ce.db"), 0600, nil)
+		Expect(err).ToNot(HaveOccurred())
+		ps = &bolt.PersistenceService{DB: db}
+
+		previous = newApp("1.0")
+		app = newApp("2.0")
+		policy = &cce.TrafficPolicy{ID: uuid.New(), Name: "test-policy"}
+		Expect(ps.Create(ctx, policy)).To(Succeed())
+
+		By("Creating nodes running the previous app with a policy")
+		nodeIDs = nil
+		nodes = make(map[string]*gmock.MockNode)
+		failing = make(map[string]bool)
+		for i := 0; i < 3; i++ {
+			id := uuid.New()
+			Expect(ps.Create(ctx, &cce.Node{
+				ID:       id,
+				Name:     "test-node",
+				Location: "test-location",
+				Serial:   uuid.New(),
+			})).To(Succeed())
+			nodeIDs = append(nodeIDs, id)
+
+			nodes[id] = gmock.NewMockNode()
+			_, err = nodes[id].AppDeploySvc.DeployContainer(ctx, &evapb.Application{Id: previous.ID})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = nodes[id].AppLifeSvc.Start(ctx, &evapb.LifecycleCommand{Id: previous.ID})
+			Expect(err).ToNot(HaveOccurred())
+
+			nodeApp := &cce.NodeApp{ID: uuid.New(), NodeID: id, AppID: previous.ID}
+			Expect(ps.Create(ctx, nodeApp)).To(Succeed())
+			Expect(ps.Create(ctx, &cce.NodeAppTrafficPolicy{
+				ID:              uuid.New(),
+				NodeAppID:       nodeApp.ID,
+				TrafficPolicyID: policy.ID,
+			})).To(Succeed())
+		}
+
+		runner = &rollout.Runner{
+			Controller:   &cce.Controller{PersistenceService: ps},
+			PollInterval: time.Millisecond,
+			ReadyTimeout: time.Second,
+			Connect: func(ctx context.Context, id, port string) (*node.ClientConn, error) {
+				mockNode, ok := nodes[id]
+				Expect(ok).To(BeTrue())
+
+				var deployCli evapb.ApplicationDeploymentServiceClient = &cmock.MockPBApplicationDeploymentServiceClient{
+					MockNode: mockNode}
+				if failing[id] {
+					deployCli = &failingDeployClient{
+						cmock.MockPBApplicationDeploymentServiceClient{MockNode: mockNode}}
+				}
+				return &node.ClientConn{
+					AppDeploySvcCli: &gclients.ApplicationDeploymentServiceClient{PBCli: deployCli},
+					AppLifeSvcCli: &gclients.ApplicationLifecycleServiceClient{
+						PBCli: &cmock.MockPBApplicationLifecycleServiceClient{MockNode: mockNode}},
+				}, nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(db.Close()).To(Succeed())
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	newRollout := func(waveSize, maxFailures int) *cce.Rollout {
+		now := time.Now().UTC()
+		ro := &cce.Rollout{
+			ID:             uuid.New(),
+			AppID:          app.ID,
+			PreviousAppID:  previous.ID,
+			WaveSize:       waveSize,
+			MaxUnavailable: 1,
+			MaxFailures:    maxFailures,
+			Status:         cce.RolloutPending,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		for _, id := range nodeIDs {
+			ro.Nodes = append(ro.Nodes, cce.RolloutNode{NodeID: id, Status: cce.RolloutNodePending})
+		}
+		Expect(ro.Validate()).To(Succeed())
+		Expect(ps.Create(ctx, ro)).To(Succeed())
+		return ro
+	}
+
+	// deployed returns the apps deployed to the node.
+	deployed := func(nodeID string) []string {
+		var ids []string
+		for _, id := range []string{previous.ID, app.ID} {
+			if _, err := nodes[nodeID].AppLifeSvc.GetStatus(ctx, &evapb.ApplicationID{Id: id}); err == nil {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	// lifecycle returns the status of the app on the node.
+	lifecycle := func(nodeID, appID string) evapb.LifecycleStatus_Status {
+		s, err := nodes[nodeID].AppLifeSvc.GetStatus(ctx, &evapb.ApplicationID{Id: appID})
+		Expect(err).ToNot(HaveOccurred())
+		return s.Status
+	}
+
+	// policies returns the apps of the node with the policy.
+	policies := func(nodeID string) []string {
+		nodeApps, err := ps.Filter(ctx, &cce.NodeApp{}, []cce.Filter{{Field: "node_id", Value: nodeID}})
+		Expect(err).ToNot(HaveOccurred())
+
+		var ids []string
+		for _, na := range nodeApps {
+			joins, err := ps.Filter(
+				ctx,
+				&cce.NodeAppTrafficPolicy{},
+				[]cce.Filter{{Field: "nodes_apps_id", Value: na.GetID()}})
+			Expect(err).ToNot(HaveOccurred())
+			if len(joins) == 1 {
+				ids = append(ids, na.(*cce.NodeApp).AppID)
+			}
+		}
+		return ids
+	}
+
+	stored := func(id string) *cce.Rollout {
+		ro, err := ps.Read(ctx, id, &cce.Rollout{})
+		Expect(err).ToNot(HaveOccurred())
+		return ro.(*cce.Rollout)
+	}
+
+	Describe("Run", func() {
+		It("Should replace the previous app on every node", func() {
+			ro := newRollout(2, 0)
+			Expect(runner.Run(ctx, ro)).To(Succeed())
+
+			ro = stored(ro.ID)
+			Expect(ro.Status).To(Equal(cce.RolloutSucceeded))
+			Expect(ro.Wave).To(Equal(2))
+			for _, n := range ro.Nodes {
+				Expect(n.Status).To(Equal(cce.RolloutNodeUpgraded))
+				Expect(n.Deployed).To(BeTrue())
+				Expect(n.Replaced).To(BeTrue())
+
+				By("Checking the app replaced the previous app")
+				Expect(deployed(n.NodeID)).To(Equal([]string{app.ID}))
+				Expect(lifecycle(n.NodeID, app.ID)).To(Equal(evapb.LifecycleStatus_RUNNING))
+				Expect(policies(n.NodeID)).To(Equal([]string{app.ID}))
+			}
+		})
+
+		It("Should start an app that was on the node but stopped", func() {
+			_, err := nodes[nodeIDs[0]].AppDeploySvc.DeployContainer(ctx, &evapb.Application{Id: app.ID})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ps.Create(ctx, &cce.NodeApp{ID: uuid.New(), NodeID: nodeIDs[0], AppID: app.ID})).To(Succeed())
+
+			ro := newRollout(3, 0)
+			Expect(runner.Run(ctx, ro)).To(Succeed())
+
+			ro = stored(ro.ID)
+			Expect(ro.Status).To(Equal(cce.RolloutSucceeded))
+			Expect(deployed(nodeIDs[0])).To(Equal([]string{app.ID}))
+			Expect(lifecycle(nodeIDs[0], app.ID)).To(Equal(evapb.LifecycleStatus_RUNNING))
+		})
+
+		It("Should tolerate up to MaxFailures failed nodes", func() {
+			failing[nodeIDs[1]] = true
+
+			ro := newRollout(1, 1)
+			Expect(runner.Run(ctx, ro)).To(Succeed())
+
+			ro = stored(ro.ID)
+			Expect(ro.Status).To(Equal(cce.RolloutSucceeded))
+			Expect(ro.Nodes[0].Status).To(Equal(cce.RolloutNodeUpgraded))
+			Expect(ro.Nodes[1].Status).To(Equal(cce.RolloutNodeFailed))
+			Expect(ro.Nodes[1].Error).To(ContainSubstring("no space left on device"))
+			Expect(ro.Nodes[2].Status).To(Equal(cce.RolloutNodeUpgraded))
+
+			By("Checking the failed node still runs the previous app")
+			Expect(deployed(nodeIDs[1])).To(Equal([]string{previous.ID}))
+			Expect(lifecycle(nodeIDs[1], previous.ID)).To(Equal(evapb.LifecycleStatus_RUNNING))
+			Expect(policies(nodeIDs[1])).To(Equal([]string{previous.ID}))
+		})
+
+		It("Should roll back once more than MaxFailures nodes failed", func() {
+			failing[nodeIDs[1]] = true
+
+			ro := newRollout(1, 0)
+			Expect(runner.Run(ctx, ro)).To(Succeed())
+
+			ro = stored(ro.ID)
+			Expect(ro.Status).To(Equal(cce.RolloutRolledBack))
+			Expect(ro.Wave).To(Equal(2))
+			Expect(ro.Error).To(Equal("too many nodes failed"))
+			Expect(ro.Nodes[0].Status).To(Equal(cce.RolloutNodeRolledBack))
+			Expect(ro.Nodes[1].Status).To(Equal(cce.RolloutNodeRolledBack))
+			Expect(ro.Nodes[2].Status).To(Equal(cce.RolloutNodePending))
+
+			By("Checking every node runs the previous app")
+			for _, id := range nodeIDs {
+				Expect(deployed(id)).To(Equal([]string{previous.ID}))
+				Expect(lifecycle(id, previous.ID)).To(Equal(evapb.LifecycleStatus_RUNNING))
+				Expect(policies(id)).To(Equal([]string{previous.ID}))
+			}
+		})
+
+		It("Should fail if the app is not deployed in time", func() {
+			runner.ReadyTimeout = 10 * time.Millisecond
+			ro := newRollout(3, 0)
+			ro.AppID = uuid.New()
+			Expect(runner.Run(ctx, ro)).To(Succeed())
+
+			ro = stored(ro.ID)
+			Expect(ro.Status).To(Equal(cce.RolloutRolledBack))
+			Expect(ro.Nodes[0].Status).To(Equal(cce.RolloutNodeRolledBack))
+			Expect(ro.Nodes[0].Error).To(ContainSubstring("not found"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("Should run the rollout in the background", func() {
+			ro := newRollout(3, 0)
+			runner.Start(ro)
+
+			Eventually(func() string {
+				return stored(ro.ID).Status
+			}).Should(Equal(cce.RolloutSucceeded))
+		})
+	})
+
+	Describe("Resume", func() {
+		It("Should restart the rollouts that did not end", func() {
+			ro := newRollout(3, 0)
+			ro.Status = cce.RolloutRunning
+			Expect(ps.BulkUpdate(ctx, []cce.Persistable{ro})).To(Succeed())
+
+			runner.Resume(ctx)
+
+			Eventually(func() string {
+				return stored(ro.ID).Status
+			}).Should(Equal(cce.RolloutSucceeded))
+		})
+	})
+})
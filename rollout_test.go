@@ -0,0 +1,167 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: Rollout", func() {
+	var rollout *cce.Rollout
+
+	BeforeEach(func() {
+		rollout = &cce.Rollout{
+			ID:             "5b2e7c1a-8d4f-4e6b-a3c9-1f0e2d4c6b8a",
+			AppID:          "3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c",
+			PreviousAppID:  "7c4d9e2b-6a1f-4b8c-8d3e-2f5a7b9c1d0e",
+			WaveSize:       10,
+			MaxUnavailable: 2,
+			MaxFailures:    1,
+			Status:         cce.RolloutRunning,
+			Nodes: []cce.RolloutNode{
+				{
+					NodeID: "9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e",
+					Status: cce.RolloutNodeUpgraded,
+				},
+			},
+			Wave:      1,
+			CreatedAt: time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2019, 12, 1, 0, 1, 0, 0, time.UTC),
+		}
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "rollouts"`, func() {
+			Expect(rollout.GetTableName()).To(Equal("rollouts"))
+		})
+	})
+
+	Describe("Done", func() {
+		It("Should return whether the rollout ended", func() {
+			Expect(rollout.Done()).To(BeFalse())
+			rollout.Status = cce.RolloutRollingBack
+			Expect(rollout.Done()).To(BeFalse())
+			rollout.Status = cce.RolloutSucceeded
+			Expect(rollout.Done()).To(BeTrue())
+			rollout.Status = cce.RolloutRolledBack
+			Expect(rollout.Done()).To(BeTrue())
+			rollout.Status = cce.RolloutFailed
+			Expect(rollout.Done()).To(BeTrue())
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(rollout.Validate()).To(Succeed())
+		})
+
+		It("Should not require a previous app", func() {
+			rollout.PreviousAppID = ""
+			Expect(rollout.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			rollout.ID = "123"
+			Expect(rollout.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if AppID is not a UUID", func() {
+			rollout.AppID = "123"
+			Expect(rollout.Validate()).To(MatchError("app_id not a valid uuid"))
+		})
+
+		It("Should return an error if PreviousAppID is not a UUID", func() {
+			rollout.PreviousAppID = "123"
+			Expect(rollout.Validate()).To(MatchError("previous_app_id not a valid uuid"))
+		})
+
+		It("Should return an error if PreviousAppID is AppID", func() {
+			rollout.PreviousAppID = rollout.AppID
+			Expect(rollout.Validate()).To(MatchError("previous_app_id cannot be app_id"))
+		})
+
+		It("Should return an error if WaveSize is less than 1", func() {
+			rollout.WaveSize = 0
+			Expect(rollout.Validate()).To(MatchError("wave_size must be at least 1"))
+		})
+
+		It("Should return an error if MaxUnavailable is less than 1", func() {
+			rollout.MaxUnavailable = 0
+			Expect(rollout.Validate()).To(MatchError("max_unavailable must be at least 1"))
+		})
+
+		It("Should return an error if MaxFailures is negative", func() {
+			rollout.MaxFailures = -1
+			Expect(rollout.Validate()).To(MatchError("max_failures cannot be negative"))
+		})
+
+		It("Should return an error if Status is invalid", func() {
+			rollout.Status = "done"
+			Expect(rollout.Validate()).To(MatchError(
+				"status must be one of [pending, running, succeeded, rolling_back, rolled_back, failed]"))
+		})
+
+		It("Should return an error if Nodes is empty", func() {
+			rollout.Nodes = nil
+			Expect(rollout.Validate()).To(MatchError("nodes cannot be empty"))
+		})
+
+		It("Should return an error if a node ID is not a UUID", func() {
+			rollout.Nodes[0].NodeID = "123"
+			Expect(rollout.Validate()).To(MatchError("nodes[0].node_id not a valid uuid"))
+		})
+
+		It("Should return an error if a node is duplicated", func() {
+			rollout.Nodes = append(rollout.Nodes, rollout.Nodes[0])
+			Expect(rollout.Validate()).To(MatchError(
+				"nodes[1].node_id 9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e is duplicated"))
+		})
+
+		It("Should return an error if a node status is invalid", func() {
+			rollout.Nodes[0].Status = "done"
+			Expect(rollout.Validate()).To(MatchError(
+				"nodes[0].status must be one of [pending, upgrading, upgraded, failed, rolled_back]"))
+		})
+
+		It("Should return an error if CreatedAt is empty", func() {
+			rollout.CreatedAt = time.Time{}
+			Expect(rollout.Validate()).To(MatchError("created_at cannot be empty"))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(rollout.FilterFields()).To(Equal([]string{
+				"app_id",
+				"status",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(rollout.String()).To(Equal(strings.TrimSpace(`
+Rollout[
+    ID: 5b2e7c1a-8d4f-4e6b-a3c9-1f0e2d4c6b8a
+    AppID: 3e6b2a5c-1f4d-4e8a-9b7c-0d2e4f6a8b1c
+    PreviousAppID: 7c4d9e2b-6a1f-4b8c-8d3e-2f5a7b9c1d0e
+    WaveSize: 10
+    MaxUnavailable: 2
+    MaxFailures: 1
+    Status: running
+    Nodes: [{NodeID:9d740e8a-5ad5-4c1b-8f4e-7c2a2b3c4d5e Status:upgraded Deployed:false Replaced:false Error:}]
+    Wave: 1
+    Error: 
+    CreatedAt: 2019-12-01 00:00:00 +0000 UTC
+    UpdatedAt: 2019-12-01 00:01:00 +0000 UTC
+]`,
+			)))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Operators of a label selector requirement.
+const (
+	// SelectorEquals requires the label to have the value.
+	SelectorEquals = "="
+	// SelectorNotEquals requires the label to be absent or have another
+	// value.
+	SelectorNotEquals = "!="
+	// SelectorExists requires the label to be present.
+	SelectorExists = "exists"
+	// SelectorDoesNotExist requires the label to be absent.
+	SelectorDoesNotExist = "!"
+)
+
+// MaxLabelLength is the maximum length of a label key or value.
+const MaxLabelLength = 63
+
+var labelKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_./-]*[A-Za-z0-9])?$`)
+var labelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?)?$`)
+
+// Labeled is an entity with labels that label selectors select.
+type Labeled interface {
+	GetLabels() map[string]string
+}
+
+// ValidateLabels validates the keys and values of labels.
+func ValidateLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := validateLabelKey(k); err != nil {
+			return err
+		}
+		if err := validateLabelValue(k, labels[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateLabelKey(k string) error {
+	if len(k) > MaxLabelLength || !labelKeyRegexp.MatchString(k) {
+		return fmt.Errorf("label key %q is invalid", k)
+	}
+
+	return nil
+}
+
+func validateLabelValue(k, v string) error {
+	if len(v) > MaxLabelLength || !labelValueRegexp.MatchString(v) {
+		return fmt.Errorf("label %s value %q is invalid", k, v)
+	}
+
+	return nil
+}
+
+// Selector selects the labeled entities that match all its requirements.
+type Selector []Requirement
+
+// Requirement is a requirement on a label of a selector.
+type Requirement struct {
+	Key      string
+	Operator string
+	// Value is the value of the label for the SelectorEquals and
+	// SelectorNotEquals operators.
+	Value string
+}
+
+// ParseSelector parses a comma-separated list of requirements such as
+// "region=eu,tier!=cloud,gpu,!maintenance".
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	if strings.TrimSpace(s) == "" {
+		return sel, nil
+	}
+
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+
+		var r Requirement
+		switch {
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = Requirement{Key: parts[0], Operator: SelectorNotEquals, Value: parts[1]}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			r = Requirement{Key: parts[0], Operator: SelectorEquals, Value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			r = Requirement{Key: term[1:], Operator: SelectorDoesNotExist}
+		default:
+			r = Requirement{Key: term, Operator: SelectorExists}
+		}
+		r.Key = strings.TrimSpace(r.Key)
+		r.Value = strings.TrimSpace(r.Value)
+
+		if err := validateLabelKey(r.Key); err != nil {
+			return nil, fmt.Errorf("selector %q: %v", s, err)
+		}
+		if err := validateLabelValue(r.Key, r.Value); err != nil {
+			return nil, fmt.Errorf("selector %q: %v", s, err)
+		}
+		sel = append(sel, r)
+	}
+
+	return sel, nil
+}
+
+// Matches returns whether the labels match every requirement of the selector.
+// An empty selector matches any labels.
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		v, ok := labels[r.Key]
+		switch r.Operator {
+		case SelectorEquals:
+			if !ok || v != r.Value {
+				return false
+			}
+		case SelectorNotEquals:
+			if ok && v == r.Value {
+				return false
+			}
+		case SelectorExists:
+			if !ok {
+				return false
+			}
+		case SelectorDoesNotExist:
+			if ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Empty returns whether the selector has no requirements.
+func (sel Selector) Empty() bool {
+	return len(sel) == 0
+}
+
+func (sel Selector) String() string {
+	terms := make([]string, 0, len(sel))
+	for _, r := range sel {
+		switch r.Operator {
+		case SelectorExists:
+			terms = append(terms, r.Key)
+		case SelectorDoesNotExist:
+			terms = append(terms, "!"+r.Key)
+		default:
+			terms = append(terms, r.Key+r.Operator+r.Value)
+		}
+	}
+
+	return strings.Join(terms, ",")
+}
+
+// errEmptySelector is returned when a selector has no requirements.
+var errEmptySelector = errors.New("selector cannot be empty")
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Selector", func() {
+	Describe("ParseSelector", func() {
+		It("Should parse every operator", func() {
+			sel, err := cce.ParseSelector("region=eu, tier!=cloud,gpu,!maintenance,zone==a")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sel).To(Equal(cce.Selector{
+				{Key: "region", Operator: cce.SelectorEquals, Value: "eu"},
+				{Key: "tier", Operator: cce.SelectorNotEquals, Value: "cloud"},
+				{Key: "gpu", Operator: cce.SelectorExists},
+				{Key: "maintenance", Operator: cce.SelectorDoesNotExist},
+				{Key: "zone", Operator: cce.SelectorEquals, Value: "a"},
+			}))
+			Expect(sel.String()).To(Equal("region=eu,tier!=cloud,gpu,!maintenance,zone=a"))
+		})
+
+		It("Should parse an empty selector", func() {
+			sel, err := cce.ParseSelector(" ")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sel.Empty()).To(BeTrue())
+		})
+
+		It("Should return an error if a key is invalid", func() {
+			_, err := cce.ParseSelector("region=eu,")
+			Expect(err).To(MatchError(`selector "region=eu,": label key "" is invalid`))
+		})
+
+		It("Should return an error if a value is invalid", func() {
+			_, err := cce.ParseSelector("region=e u")
+			Expect(err).To(MatchError(`selector "region=e u": label region value "e u" is invalid`))
+		})
+	})
+
+	Describe("Matches", func() {
+		labels := map[string]string{"region": "eu", "tier": "far-edge"}
+
+		DescribeTable("Should match the labels",
+			func(s string, matches bool) {
+				sel, err := cce.ParseSelector(s)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sel.Matches(labels)).To(Equal(matches))
+			},
+			Entry("empty selector", "", true),
+			Entry("equal value", "region=eu,tier=far-edge", true),
+			Entry("other value", "region=us", false),
+			Entry("not equal value", "region!=us", true),
+			Entry("not equal to the value", "region!=eu", false),
+			Entry("not equal to an absent label", "gpu!=yes", true),
+			Entry("existing label", "tier", true),
+			Entry("absent label", "gpu", false),
+			Entry("not existing label", "!gpu", true),
+			Entry("not existing present label", "!tier", false),
+		)
+	})
+
+	Describe("ValidateLabels", func() {
+		It("Should accept valid labels", func() {
+			Expect(cce.ValidateLabels(map[string]string{
+				"region":                  "eu",
+				"example.com/tier":        "far-edge",
+				"maintenance":             "",
+				"openness.org/node_group": "a.b-c_d",
+			})).To(Succeed())
+		})
+
+		It("Should return an error if a key is invalid", func() {
+			Expect(cce.ValidateLabels(map[string]string{"-region": "eu"})).To(
+				MatchError(`label key "-region" is invalid`))
+		})
+
+		It("Should return an error if a value is too long", func() {
+			long := "a"
+			for len(long) <= cce.MaxLabelLength {
+				long += "a"
+			}
+			Expect(cce.ValidateLabels(map[string]string{"region": long})).To(
+				MatchError(`label region value "` + long + `" is invalid`))
+		})
+	})
+})
@@ -25,9 +25,27 @@ type AppDetail struct {
 	Ports       []cce.PortProto  `json:"ports"`
 	Source      string           `json:"source"`
 	EPAFeatures []cce.EPAFeature `json:"epafeatures,omitempty"`
+	// Revision is set by the controller and ignored in requests.
+	Revision int `json:"revision,omitempty"`
+}
+
+// AppNode is the revision of the app deployed to a node.
+type AppNode struct {
+	NodeID   string `json:"node_id"`
+	Revision int    `json:"revision"`
+	// UpToDate is whether the node runs the current revision of the app.
+	UpToDate bool `json:"up_to_date"`
+	// Upgrade is the last upgrade of the app on the node, if any.
+	Upgrade *OperationDetail `json:"upgrade,omitempty"`
+}
+
+// AppNodeList is a list representation of the nodes running an app.
+type AppNodeList struct {
+	Nodes []AppNode `json:"nodes"`
 }
 
 // AppList is a list representation of apps.
 type AppList struct {
 	Apps []AppSummary `json:"apps"`
+	Next string       `json:"next,omitempty"`
 }
@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import (
+	cce "github.com/open-ness/edgecontroller"
+)
+
+// AuditEventList is a list representation of audit events.
+type AuditEventList struct {
+	Events []*cce.AuditEvent `json:"events"`
+	Next   string            `json:"next,omitempty"`
+}
@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// CredentialsSummary is a summary representation of the credentials issued to
+// a node.
+type CredentialsSummary struct {
+	ID           string    `json:"id"`
+	SerialNumber string    `json:"serial_number"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	Superseded   bool      `json:"superseded"`
+	Revoked      bool      `json:"revoked"`
+}
+
+// CredentialsList is a list representation of the credentials issued to a
+// node, most recently issued first.
+type CredentialsList struct {
+	Credentials []CredentialsSummary `json:"credentials"`
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import cce "github.com/open-ness/edgecontroller"
+
+// InterfaceProfileSummary is a summary representation of the interface
+// profile.
+type InterfaceProfileSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// InterfaceProfileDetail is a detailed representation of the interface
+// profile.
+type InterfaceProfileDetail struct {
+	InterfaceProfileSummary
+	Match    cce.InterfaceMatch    `json:"match"`
+	Set      cce.InterfaceSettings `json:"set"`
+	NodeIDs  []string              `json:"node_ids,omitempty"`
+	Selector string                `json:"selector,omitempty"`
+}
+
+// InterfaceProfileList is a list representation of interface profiles.
+type InterfaceProfileList struct {
+	InterfaceProfiles []InterfaceProfileSummary `json:"interface_profiles"`
+	Next              string                    `json:"next,omitempty"`
+}
+
+// InterfaceProfileApplyReq is a request to apply an interface profile to the
+// nodes it is attached to. With DryRun the changes are only reported.
+type InterfaceProfileApplyReq struct {
+	DryRun bool `json:"dry_run"`
+}
+
+// InterfaceProfileApplyResult is the result of applying an interface profile.
+type InterfaceProfileApplyResult struct {
+	DryRun bool                         `json:"dry_run"`
+	Nodes  []InterfaceProfileNodeResult `json:"nodes"`
+}
+
+// InterfaceProfileNodeResult is the result of applying an interface profile
+// to a node: the changes made, or that would be made, to its interfaces.
+type InterfaceProfileNodeResult struct {
+	NodeID  string                `json:"node_id"`
+	Changes []cce.InterfaceChange `json:"changes"`
+	Error   string                `json:"error,omitempty"`
+}
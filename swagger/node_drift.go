@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// NodeDrift is a representation of the configuration of a node that differed
+// from the persisted one when the node was last reconciled.
+type NodeDrift struct {
+	// ReconciledAt is omitted if the node was never reconciled.
+	ReconciledAt *time.Time      `json:"reconciled_at,omitempty"`
+	Items        []NodeDriftItem `json:"items"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// NodeDriftItem is a representation of a configuration that differed on a
+// node.
+type NodeDriftItem struct {
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+// NodeGroupSummary is a summary representation of the node group.
+type NodeGroupSummary struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Selector is the label selector of the nodes of the group.
+	Selector string `json:"selector"`
+}
+
+// NodeGroupDetail is a detailed representation of the node group.
+type NodeGroupDetail struct {
+	NodeGroupSummary
+	Apps            []string                 `json:"apps"`
+	DNSConfigID     string                   `json:"dns_config_id,omitempty"`
+	TrafficPolicies []NodeGroupTrafficPolicy `json:"traffic_policies"`
+}
+
+// NodeGroupTrafficPolicy is the traffic policy of an app of the node group.
+type NodeGroupTrafficPolicy struct {
+	AppID           string `json:"app_id"`
+	TrafficPolicyID string `json:"traffic_policy_id"`
+}
+
+// NodeGroupList is a list representation of node groups.
+type NodeGroupList struct {
+	NodeGroups []NodeGroupSummary `json:"node_groups"`
+	Next       string             `json:"next,omitempty"`
+}
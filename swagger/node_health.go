@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// NodeHealth is a representation of the connectivity status of a node.
+type NodeHealth struct {
+	Status string `json:"status"`
+	// LastSeen is omitted if the node was never reachable.
+	LastSeen  *time.Time `json:"last_seen,omitempty"`
+	LatencyMS float64    `json:"latency_ms"`
+	CheckedAt time.Time  `json:"checked_at"`
+}
+
+// NodeStatusEventSummary is a summary representation of a change of the
+// connectivity status of a node.
+type NodeStatusEventSummary struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NodeStatusEventList is a list representation of the changes of the
+// connectivity status of a node, most recent first.
+type NodeStatusEventList struct {
+	Events []NodeStatusEventSummary `json:"events"`
+}
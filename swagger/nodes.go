@@ -9,6 +9,10 @@ type NodeSummary struct {
 	Name     string `json:"name"`
 	Location string `json:"location"`
 	Serial   string `json:"serial"`
+	// Labels are the key/value labels selected by label selectors.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Health is omitted until the node is probed.
+	Health *NodeHealth `json:"health,omitempty"`
 }
 
 // NodeDetail is a detailed representation of the node.
@@ -19,4 +23,5 @@ type NodeDetail struct {
 // NodeList is a list representation of nodes.
 type NodeList struct {
 	Nodes []NodeSummary `json:"nodes"`
+	Next  string        `json:"next,omitempty"`
 }
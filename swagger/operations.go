@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// OperationDetail is a detailed representation of a long-running operation on
+// a node.
+type OperationDetail struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	NodeID  string `json:"node_id"`
+	AppID   string `json:"app_id"`
+	Command string `json:"command,omitempty"`
+	Status  string `json:"status"`
+	// Progress describes the step a running operation is at.
+	Progress string `json:"progress,omitempty"`
+	// Result is the path of the resource a succeeded operation created or
+	// updated.
+	Result    string    `json:"result,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// PendingNodeSummary is a summary representation of a node pending approval.
+type PendingNodeSummary struct {
+	ID          string    `json:"id"`
+	Serial      string    `json:"serial"`
+	Address     string    `json:"address"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// PendingNodeList is a list representation of nodes pending approval.
+type PendingNodeList struct {
+	PendingNodes []PendingNodeSummary `json:"pending_nodes"`
+	Next         string               `json:"next,omitempty"`
+}
+
+// PendingNodeApproval approves a node pending approval with the name and
+// location of the node to create.
+type PendingNodeApproval struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
@@ -29,4 +29,46 @@ type PolicyKubeOVNDetail struct {
 // PolicyList is a list representation of traffic policies.
 type PolicyList struct {
 	Policies []PolicySummary `json:"policies"`
+	Next     string          `json:"next,omitempty"`
+}
+
+// PolicyAnalyzeReq is a request to analyze a traffic policy. The rules, if
+// any, are analyzed instead of the rules of the policy.
+type PolicyAnalyzeReq struct {
+	NodeID string             `json:"node_id,omitempty"`
+	Rules  []*cce.TrafficRule `json:"traffic_rules,omitempty"`
+}
+
+// PolicyAnalysis is the analysis of a traffic policy.
+type PolicyAnalysis struct {
+	PolicyID string              `json:"policy_id"`
+	NodeID   string              `json:"node_id,omitempty"`
+	Findings []cce.PolicyFinding `json:"findings"`
+}
+
+// PolicySimulationReq is a request to evaluate a packet against the traffic
+// policies of a node. With an interface_id or app_id only the policy of the
+// interface or app is evaluated.
+type PolicySimulationReq struct {
+	Packet      cce.Packet `json:"packet"`
+	InterfaceID string     `json:"interface_id,omitempty"`
+	AppID       string     `json:"app_id,omitempty"`
+}
+
+// PolicySimulation is the evaluation of a packet against the traffic policies
+// of a node.
+type PolicySimulation struct {
+	NodeID  string                   `json:"node_id"`
+	Results []PolicySimulationResult `json:"results"`
+}
+
+// PolicySimulationResult is the evaluation of a packet against the traffic
+// policy of an interface or app. The rule is only set if a rule matched.
+type PolicySimulationResult struct {
+	InterfaceID string           `json:"interface_id,omitempty"`
+	AppID       string           `json:"app_id,omitempty"`
+	PolicyID    string           `json:"policy_id"`
+	Matched     bool             `json:"matched"`
+	Rule        *int             `json:"rule,omitempty"`
+	TrafficRule *cce.TrafficRule `json:"traffic_rule,omitempty"`
 }
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// RolloutReq is a request to roll out an app to a set of nodes. The nodes are
+// the nodes listed by ID, or else the nodes matching the label selector, or
+// else the nodes at the location.
+type RolloutReq struct {
+	AppID          string   `json:"app_id"`
+	PreviousAppID  string   `json:"previous_app_id,omitempty"`
+	NodeIDs        []string `json:"node_ids,omitempty"`
+	Selector       string   `json:"selector,omitempty"`
+	Location       string   `json:"location,omitempty"`
+	WaveSize       int      `json:"wave_size"`
+	MaxUnavailable int      `json:"max_unavailable"`
+	MaxFailures    int      `json:"max_failures"`
+}
+
+// RolloutSummary is a summary representation of the rollout.
+type RolloutSummary struct {
+	ID            string    `json:"id"`
+	AppID         string    `json:"app_id"`
+	PreviousAppID string    `json:"previous_app_id,omitempty"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RolloutDetail is a detailed representation of the rollout.
+type RolloutDetail struct {
+	RolloutSummary
+	WaveSize       int `json:"wave_size"`
+	MaxUnavailable int `json:"max_unavailable"`
+	MaxFailures    int `json:"max_failures"`
+	// Wave is the wave being upgraded, or the last one once the rollout
+	// ended.
+	Wave  int           `json:"wave"`
+	Error string        `json:"error,omitempty"`
+	Nodes []RolloutNode `json:"nodes"`
+}
+
+// RolloutNode is the progress of the rollout on a node.
+type RolloutNode struct {
+	NodeID string `json:"node_id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RolloutList is a list representation of rollouts.
+type RolloutList struct {
+	Rollouts []RolloutSummary `json:"rollouts"`
+	Next     string           `json:"next,omitempty"`
+}
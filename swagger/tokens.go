@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+import "time"
+
+// TokenSummary is a summary representation of the API token.
+type TokenSummary struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenDetail is a detailed representation of the API token. The token secret
+// is only returned when the token is created.
+type TokenDetail struct {
+	TokenSummary
+	Token string `json:"token,omitempty"`
+}
+
+// TokenList is a list representation of API tokens.
+type TokenList struct {
+	Tokens []TokenSummary `json:"tokens"`
+	Next   string         `json:"next,omitempty"`
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+// UserSummary is a summary representation of the user.
+type UserSummary struct {
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+}
+
+// UserDetail is a detailed representation of the user. The password is only
+// accepted in requests and never returned.
+type UserDetail struct {
+	UserSummary
+	Password string `json:"password,omitempty"`
+}
+
+// UserList is a list representation of users.
+type UserList struct {
+	Users []UserSummary `json:"users"`
+	Next  string        `json:"next,omitempty"`
+}
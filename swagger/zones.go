@@ -0,0 +1,20 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package swagger
+
+// ZoneSummary is a summary representation of the network zone.
+type ZoneSummary struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+}
+
+// ZoneDetail is a detailed representation of the network zone.
+type ZoneDetail struct {
+	ZoneSummary
+}
+
+// ZoneList is a list representation of network zones.
+type ZoneList struct {
+	Zones []ZoneSummary `json:"zones"`
+}
@@ -18,6 +18,8 @@ type TrafficPolicy struct {
 	ID    string         `json:"id"`
 	Name  string         `json:"name"`
 	Rules []*TrafficRule `json:"traffic_rules"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
 }
 
 // GetTableName returns the name of the persistence table.
@@ -35,6 +37,16 @@ func (tp *TrafficPolicy) SetID(id string) {
 	tp.ID = id
 }
 
+// GetResourceVersion gets the resource version.
+func (tp *TrafficPolicy) GetResourceVersion() uint64 {
+	return tp.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (tp *TrafficPolicy) SetResourceVersion(v uint64) {
+	tp.ResourceVersion = v
+}
+
 // Validate validates the model.
 func (tp *TrafficPolicy) Validate() error {
 	if !uuid.IsValid(tp.ID) {
@@ -57,7 +69,9 @@ func (tp *TrafficPolicy) Validate() error {
 
 // FilterFields returns the filterable fields for this model.
 func (*TrafficPolicy) FilterFields() []string {
-	return []string{}
+	return []string{
+		"name",
+	}
 }
 
 func (tp *TrafficPolicy) String() string {
@@ -0,0 +1,363 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Kinds of the findings of a traffic policy analysis.
+const (
+	// PolicyFindingDuplicatePriority is reported for a rule with the same
+	// priority as another rule of the policy. The order of the two rules is
+	// undefined.
+	PolicyFindingDuplicatePriority = "duplicate_priority"
+	// PolicyFindingShadowed is reported for a rule whose traffic is all
+	// matched by a rule evaluated before it with a different target.
+	PolicyFindingShadowed = "shadowed"
+	// PolicyFindingUnreachable is reported for a rule that matches no
+	// traffic, or whose traffic is all matched by a rule evaluated before it
+	// with the same target.
+	PolicyFindingUnreachable = "unreachable"
+	// PolicyFindingConflict is reported for a rule matching traffic that a
+	// rule of another policy on the same node matches with a different
+	// target.
+	PolicyFindingConflict = "conflict"
+)
+
+// PolicyFinding is a problem found by the analysis of a traffic policy. Rules
+// are referred to by their index in the rules of their policy.
+type PolicyFinding struct {
+	Kind string `json:"kind"`
+	Rule int    `json:"rule"`
+	// OtherPolicyID is the ID of the other policy of a conflict.
+	OtherPolicyID string `json:"other_policy_id,omitempty"`
+	// OtherRule is the rule the rule is compared with, if any.
+	OtherRule *int   `json:"other_rule,omitempty"`
+	Message   string `json:"message"`
+}
+
+// AnalyzeTrafficPolicy analyzes the rules of a valid traffic policy and
+// reports the rules that are shadowed, unreachable or share a priority. The
+// rules are also compared with the rules of the other policies applied on the
+// same node, nodePolicies, and the overlapping rules with different targets
+// are reported as conflicts. A policy in nodePolicies with the ID of the
+// analyzed policy is skipped.
+//
+// Rules are evaluated in ascending order of priority. A rule is only reported
+// as shadowed or unreachable if a single rule before it matches all its
+// traffic.
+func AnalyzeTrafficPolicy(tp *TrafficPolicy, nodePolicies []*TrafficPolicy) []PolicyFinding {
+	findings := []PolicyFinding{}
+
+	order := tp.ruleOrder()
+	for n, i := range order {
+		rule := tp.Rules[i]
+
+		if n > 0 && tp.Rules[order[n-1]].Priority == rule.Priority {
+			findings = append(findings, ruleFinding(PolicyFindingDuplicatePriority, i, order[n-1],
+				fmt.Sprintf("rules[%d] has the same priority %d as rules[%d]", i, rule.Priority, order[n-1])))
+		}
+
+		if !rule.matchesAny() {
+			findings = append(findings, PolicyFinding{
+				Kind:    PolicyFindingUnreachable,
+				Rule:    i,
+				Message: fmt.Sprintf("rules[%d] matches no traffic", i),
+			})
+			continue
+		}
+
+		for _, j := range order[:n] {
+			before := tp.Rules[j]
+			if before.Priority == rule.Priority || !before.covers(rule) {
+				continue
+			}
+			if before.Target.equals(rule.Target) {
+				findings = append(findings, ruleFinding(PolicyFindingUnreachable, i, j,
+					fmt.Sprintf("rules[%d] is unreachable: its traffic is matched by rules[%d]", i, j)))
+			} else {
+				findings = append(findings, ruleFinding(PolicyFindingShadowed, i, j,
+					fmt.Sprintf("rules[%d] is shadowed by rules[%d] with action %s", i, j, before.Target.Action)))
+			}
+			break
+		}
+	}
+
+	for _, other := range nodePolicies {
+		if other.ID == tp.ID {
+			continue
+		}
+		for i, rule := range tp.Rules {
+			for j, otherRule := range other.Rules {
+				if !rule.overlaps(otherRule) || rule.Target.equals(otherRule.Target) {
+					continue
+				}
+				f := ruleFinding(PolicyFindingConflict, i, j, fmt.Sprintf(
+					"rules[%d] matches traffic of rules[%d] of traffic policy %s with action %s instead of %s",
+					i, j, other.ID, rule.Target.Action, otherRule.Target.Action))
+				f.OtherPolicyID = other.ID
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	return findings
+}
+
+// ruleOrder returns the indexes of the rules in the order they are evaluated,
+// keeping the order of the policy for the rules with the same priority.
+func (tp *TrafficPolicy) ruleOrder() []int {
+	order := make([]int, len(tp.Rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return tp.Rules[order[i]].Priority < tp.Rules[order[j]].Priority
+	})
+
+	return order
+}
+
+func ruleFinding(kind string, rule, other int, msg string) PolicyFinding {
+	return PolicyFinding{Kind: kind, Rule: rule, OtherRule: &other, Message: msg}
+}
+
+// matchesAny returns whether some traffic can match the rule.
+func (tr *TrafficRule) matchesAny() bool {
+	return tr.Source.matchesAny() && tr.Destination.matchesAny()
+}
+
+// covers returns whether all the traffic matching other matches the rule.
+func (tr *TrafficRule) covers(other *TrafficRule) bool {
+	return tr.Source.covers(other.Source) && tr.Destination.covers(other.Destination)
+}
+
+// overlaps returns whether some traffic matches both rules.
+func (tr *TrafficRule) overlaps(other *TrafficRule) bool {
+	return tr.matchesAny() && other.matchesAny() &&
+		tr.Source.overlaps(other.Source) && tr.Destination.overlaps(other.Destination)
+}
+
+// A nil selector matches any traffic.
+
+func (ts *TrafficSelector) matchesAny() bool {
+	return ts == nil || ts.IP.matchesAny()
+}
+
+func (ts *TrafficSelector) covers(other *TrafficSelector) bool {
+	if ts == nil {
+		return true
+	}
+	if other == nil {
+		other = &TrafficSelector{}
+	}
+
+	return ts.MACs.covers(other.MACs) && ts.IP.covers(other.IP) && ts.GTP.covers(other.GTP)
+}
+
+func (ts *TrafficSelector) overlaps(other *TrafficSelector) bool {
+	if ts == nil || other == nil {
+		return true
+	}
+
+	return ts.MACs.overlaps(other.MACs) && ts.IP.overlaps(other.IP) && ts.GTP.overlaps(other.GTP)
+}
+
+// A nil filter or a filter without MAC addresses matches any MAC address.
+
+func (f *MACFilter) covers(other *MACFilter) bool {
+	if f == nil || len(f.MACAddresses) == 0 {
+		return true
+	}
+	if other == nil || len(other.MACAddresses) == 0 {
+		return false
+	}
+
+	return isSubset(macSet(other.MACAddresses), macSet(f.MACAddresses))
+}
+
+func (f *MACFilter) overlaps(other *MACFilter) bool {
+	if f == nil || len(f.MACAddresses) == 0 || other == nil || len(other.MACAddresses) == 0 {
+		return true
+	}
+
+	return intersects(macSet(f.MACAddresses), macSet(other.MACAddresses))
+}
+
+// A nil filter matches any IP traffic. An end port of 0 matches any port.
+
+func (f *IPFilter) matchesAny() bool {
+	// ICMP has no ports
+	return f == nil || f.Protocol != "icmp" || f.EndPort == 0
+}
+
+func (f *IPFilter) covers(other *IPFilter) bool {
+	if f == nil {
+		return true
+	}
+	if other == nil {
+		return false
+	}
+	if f.Protocol != "all" && f.Protocol != other.Protocol {
+		return false
+	}
+	begin, end := f.ports()
+	otherBegin, otherEnd := other.ports()
+
+	return begin <= otherBegin && otherEnd <= end &&
+		subnetCovers(subnet(f.Address, f.Mask), subnet(other.Address, other.Mask))
+}
+
+func (f *IPFilter) overlaps(other *IPFilter) bool {
+	if f == nil || other == nil {
+		return true
+	}
+	if f.Protocol != "all" && other.Protocol != "all" && f.Protocol != other.Protocol {
+		return false
+	}
+	begin, end := f.ports()
+	otherBegin, otherEnd := other.ports()
+
+	return begin <= otherEnd && otherBegin <= end &&
+		subnetsOverlap(subnet(f.Address, f.Mask), subnet(other.Address, other.Mask))
+}
+
+// ports returns the range of ports matched by the filter.
+func (f *IPFilter) ports() (begin, end int) {
+	if f.EndPort == 0 {
+		return 0, 65535
+	}
+
+	return f.BeginPort, f.EndPort
+}
+
+// A nil filter matches any GTP traffic. A filter without IMSIs matches any
+// IMSI.
+
+func (f *GTPFilter) covers(other *GTPFilter) bool {
+	if f == nil {
+		return true
+	}
+	if other == nil {
+		return false
+	}
+	if len(f.IMSIs) != 0 && (len(other.IMSIs) == 0 || !isSubset(stringSet(other.IMSIs), stringSet(f.IMSIs))) {
+		return false
+	}
+
+	return subnetCovers(subnet(f.Address, f.Mask), subnet(other.Address, other.Mask))
+}
+
+func (f *GTPFilter) overlaps(other *GTPFilter) bool {
+	if f == nil || other == nil {
+		return true
+	}
+	if len(f.IMSIs) != 0 && len(other.IMSIs) != 0 && !intersects(stringSet(f.IMSIs), stringSet(other.IMSIs)) {
+		return false
+	}
+
+	return subnetsOverlap(subnet(f.Address, f.Mask), subnet(other.Address, other.Mask))
+}
+
+// equals returns whether the targets handle the traffic the same way.
+func (tt *TrafficTarget) equals(other *TrafficTarget) bool {
+	if tt.Action != other.Action {
+		return false
+	}
+
+	switch {
+	case (tt.MAC == nil) != (other.MAC == nil):
+		return false
+	case tt.MAC != nil && normalizeMAC(tt.MAC.MACAddress) != normalizeMAC(other.MAC.MACAddress):
+		return false
+	case (tt.IP == nil) != (other.IP == nil):
+		return false
+	case tt.IP != nil && (!net.ParseIP(tt.IP.Address).Equal(net.ParseIP(other.IP.Address)) ||
+		tt.IP.Port != other.IP.Port):
+		return false
+	}
+
+	return true
+}
+
+// subnet returns the subnet of the address with the mask, which is capped to
+// the length of the address.
+func subnet(address string, mask int) *net.IPNet {
+	ip := net.ParseIP(address)
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	if mask > bits {
+		mask = bits
+	}
+	m := net.CIDRMask(mask, bits)
+
+	return &net.IPNet{IP: ip.Mask(m), Mask: m}
+}
+
+// subnetCovers returns whether the subnet a contains the subnet b.
+func subnetCovers(a, b *net.IPNet) bool {
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+
+	return aBits == bBits && aOnes <= bOnes && a.Contains(b.IP)
+}
+
+func subnetsOverlap(a, b *net.IPNet) bool {
+	return subnetCovers(a, b) || subnetCovers(b, a)
+}
+
+// normalizeMAC returns the MAC address in its canonical form so that
+// addresses written differently compare equal.
+func normalizeMAC(mac string) string {
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return strings.ToLower(mac)
+	}
+
+	return hw.String()
+}
+
+func macSet(macs []string) map[string]bool {
+	set := make(map[string]bool, len(macs))
+	for _, mac := range macs {
+		set[normalizeMAC(mac)] = true
+	}
+
+	return set
+}
+
+func stringSet(ss []string) map[string]bool {
+	set := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		set[s] = true
+	}
+
+	return set
+}
+
+func isSubset(a, b map[string]bool) bool {
+	for k := range a {
+		if !b[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func intersects(a, b map[string]bool) bool {
+	for k := range a {
+		if b[k] {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("AnalyzeTrafficPolicy", func() {
+	// ipRule returns a rule matching the destination subnet and ports.
+	ipRule := func(priority int, address string, mask, beginPort, endPort int, action string) *cce.TrafficRule {
+		return &cce.TrafficRule{
+			Priority: priority,
+			Destination: &cce.TrafficSelector{
+				IP: &cce.IPFilter{
+					Address:   address,
+					Mask:      mask,
+					BeginPort: beginPort,
+					EndPort:   endPort,
+					Protocol:  "tcp",
+				},
+			},
+			Target: &cce.TrafficTarget{Action: action},
+		}
+	}
+
+	policy := func(id string, rules ...*cce.TrafficRule) *cce.TrafficPolicy {
+		return &cce.TrafficPolicy{ID: id, Name: "policy", Rules: rules}
+	}
+
+	kinds := func(findings []cce.PolicyFinding) []string {
+		ks := []string{}
+		for _, f := range findings {
+			ks = append(ks, f.Kind)
+		}
+		return ks
+	}
+
+	It("Should report nothing for independent rules", func() {
+		tp := policy("p1",
+			ipRule(1, "10.0.0.0", 24, 80, 80, "accept"),
+			ipRule(2, "10.0.1.0", 24, 0, 0, "drop"))
+		Expect(cce.AnalyzeTrafficPolicy(tp, nil)).To(BeEmpty())
+	})
+
+	It("Should report a duplicate priority", func() {
+		tp := policy("p1",
+			ipRule(1, "10.0.0.0", 24, 80, 80, "accept"),
+			ipRule(1, "10.0.1.0", 24, 80, 80, "drop"))
+		findings := cce.AnalyzeTrafficPolicy(tp, nil)
+		Expect(kinds(findings)).To(Equal([]string{cce.PolicyFindingDuplicatePriority}))
+		Expect(findings[0].Rule).To(Equal(1))
+		Expect(*findings[0].OtherRule).To(Equal(0))
+		Expect(findings[0].Message).To(Equal("rules[1] has the same priority 1 as rules[0]"))
+	})
+
+	It("Should report a rule shadowed by a rule with a higher priority", func() {
+		tp := policy("p1",
+			ipRule(2, "10.0.0.0", 24, 80, 80, "accept"),
+			ipRule(1, "10.0.0.0", 16, 0, 0, "drop"))
+		findings := cce.AnalyzeTrafficPolicy(tp, nil)
+		Expect(kinds(findings)).To(Equal([]string{cce.PolicyFindingShadowed}))
+		Expect(findings[0].Rule).To(Equal(0))
+		Expect(*findings[0].OtherRule).To(Equal(1))
+		Expect(findings[0].Message).To(Equal("rules[0] is shadowed by rules[1] with action drop"))
+	})
+
+	It("Should not report a rule partially matched by a rule before it", func() {
+		tp := policy("p1",
+			ipRule(1, "10.0.0.0", 24, 80, 90, "drop"),
+			ipRule(2, "10.0.0.0", 16, 80, 80, "accept"))
+		Expect(cce.AnalyzeTrafficPolicy(tp, nil)).To(BeEmpty())
+	})
+
+	It("Should report a rule redundant with a rule before it as unreachable", func() {
+		tp := policy("p1",
+			ipRule(1, "10.0.0.0", 16, 0, 0, "drop"),
+			ipRule(2, "10.0.3.7", 32, 443, 443, "drop"))
+		findings := cce.AnalyzeTrafficPolicy(tp, nil)
+		Expect(kinds(findings)).To(Equal([]string{cce.PolicyFindingUnreachable}))
+		Expect(findings[0].Rule).To(Equal(1))
+	})
+
+	It("Should report a rule matching no traffic as unreachable", func() {
+		rule := ipRule(1, "10.0.0.0", 16, 80, 80, "drop")
+		rule.Destination.IP.Protocol = "icmp"
+		findings := cce.AnalyzeTrafficPolicy(policy("p1", rule), nil)
+		Expect(findings).To(Equal([]cce.PolicyFinding{{
+			Kind:    cce.PolicyFindingUnreachable,
+			Rule:    0,
+			Message: "rules[0] matches no traffic",
+		}}))
+	})
+
+	It("Should compare the MAC addresses in their canonical form", func() {
+		macRule := func(priority int, action string, macs ...string) *cce.TrafficRule {
+			return &cce.TrafficRule{
+				Priority: priority,
+				Source:   &cce.TrafficSelector{MACs: &cce.MACFilter{MACAddresses: macs}},
+				Target:   &cce.TrafficTarget{Action: action},
+			}
+		}
+		tp := policy("p1",
+			macRule(1, "drop", "F0-59-8E-7B-36-8A", "23-20-8E-15-89-D1"),
+			macRule(2, "accept", "f0:59:8e:7b:36:8a"))
+		Expect(kinds(cce.AnalyzeTrafficPolicy(tp, nil))).To(Equal([]string{cce.PolicyFindingShadowed}))
+	})
+
+	It("Should report conflicts with the other policies of the node", func() {
+		tp := policy("p1", ipRule(1, "10.0.0.0", 24, 80, 80, "accept"))
+		nodePolicies := []*cce.TrafficPolicy{
+			tp,
+			policy("p2",
+				ipRule(1, "10.0.0.0", 16, 0, 0, "drop"),
+				ipRule(2, "10.0.0.0", 16, 0, 0, "accept"),
+				ipRule(3, "10.1.0.0", 16, 0, 0, "drop")),
+		}
+		findings := cce.AnalyzeTrafficPolicy(tp, nodePolicies)
+		Expect(kinds(findings)).To(Equal([]string{cce.PolicyFindingConflict}))
+		Expect(findings[0].OtherPolicyID).To(Equal("p2"))
+		Expect(*findings[0].OtherRule).To(Equal(0))
+		Expect(findings[0].Message).To(Equal(
+			"rules[0] matches traffic of rules[0] of traffic policy p2 with action accept instead of drop"))
+	})
+})
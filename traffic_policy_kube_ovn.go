@@ -22,6 +22,8 @@ type TrafficPolicyKubeOVN struct {
 	Name    string         `json:"name"`
 	Ingress []*IngressRule `json:"ingress_rules"`
 	Egress  []*EgressRule  `json:"egress_rules"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
 }
 
 // GetTableName returns the name of the persistence table.
@@ -39,6 +41,16 @@ func (tp *TrafficPolicyKubeOVN) SetID(id string) {
 	tp.ID = id
 }
 
+// GetResourceVersion gets the resource version.
+func (tp *TrafficPolicyKubeOVN) GetResourceVersion() uint64 {
+	return tp.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (tp *TrafficPolicyKubeOVN) SetResourceVersion(v uint64) {
+	tp.ResourceVersion = v
+}
+
 // Validate validates the model.
 func (tp *TrafficPolicyKubeOVN) Validate() error {
 	if !uuid.IsValid(tp.ID) {
@@ -69,7 +81,9 @@ func (tp *TrafficPolicyKubeOVN) Validate() error {
 
 // FilterFields returns the filterable fields for this model.
 func (*TrafficPolicyKubeOVN) FilterFields() []string {
-	return []string{}
+	return []string{
+		"name",
+	}
 }
 
 func (tp *TrafficPolicyKubeOVN) String() string {
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Packet is a synthetic packet evaluated against traffic policies to find the
+// rule handling it.
+type Packet struct {
+	Source      PacketEndpoint `json:"source"`
+	Destination PacketEndpoint `json:"destination"`
+	// Protocol is one of tcp, udp, icmp or sctp.
+	Protocol string `json:"protocol"`
+	// IMSI is the IMSI of the subscriber of GTP traffic.
+	IMSI string `json:"imsi,omitempty"`
+}
+
+// PacketEndpoint is the source or destination of a packet. The fields that
+// are not set only match filters matching any value.
+type PacketEndpoint struct {
+	MAC  string `json:"mac,omitempty"`
+	IP   string `json:"ip,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// GTPAddress is the address of the GTP tunnel endpoint.
+	GTPAddress string `json:"gtp_address,omitempty"`
+}
+
+// Validate validates the model.
+func (p *Packet) Validate() error {
+	if err := p.Source.Validate(); err != nil {
+		return fmt.Errorf("source.%s", err.Error())
+	}
+	if err := p.Destination.Validate(); err != nil {
+		return fmt.Errorf("destination.%s", err.Error())
+	}
+	switch p.Protocol {
+	case "tcp", "udp", "icmp", "sctp":
+	default:
+		return errors.New("protocol must be one of [tcp, udp, icmp, sctp]")
+	}
+	if p.IMSI != "" {
+		if _, err := strconv.ParseInt(p.IMSI, 10, 64); err != nil || (len(p.IMSI) != 14 && len(p.IMSI) != 15) {
+			return errors.New("imsi must be 14 or 15 digits")
+		}
+	}
+
+	return nil
+}
+
+// Validate validates the model.
+func (e *PacketEndpoint) Validate() error {
+	if e.MAC != "" {
+		if _, err := net.ParseMAC(e.MAC); err != nil {
+			return fmt.Errorf("mac could not be parsed (%s)", err.Error())
+		}
+	}
+	if e.IP != "" && net.ParseIP(e.IP) == nil {
+		return errors.New("ip could not be parsed")
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		return errors.New("port must be in [0..65535]")
+	}
+	if e.GTPAddress != "" && net.ParseIP(e.GTPAddress) == nil {
+		return errors.New("gtp_address could not be parsed")
+	}
+
+	return nil
+}
+
+// Match returns the rule of the policy matching the packet that is evaluated
+// first, and its index in the rules. If no rule matches nil and -1 are
+// returned. Rules are evaluated in ascending order of priority.
+func (tp *TrafficPolicy) Match(p *Packet) (*TrafficRule, int) {
+	for _, i := range tp.ruleOrder() {
+		if tp.Rules[i].matches(p) {
+			return tp.Rules[i], i
+		}
+	}
+
+	return nil, -1
+}
+
+func (tr *TrafficRule) matches(p *Packet) bool {
+	return tr.Source.matches(p, &p.Source) && tr.Destination.matches(p, &p.Destination)
+}
+
+func (ts *TrafficSelector) matches(p *Packet, e *PacketEndpoint) bool {
+	if ts == nil {
+		return true
+	}
+
+	return ts.MACs.matches(e) && ts.IP.matches(p, e) && ts.GTP.matches(p, e)
+}
+
+func (f *MACFilter) matches(e *PacketEndpoint) bool {
+	if f == nil || len(f.MACAddresses) == 0 {
+		return true
+	}
+
+	return e.MAC != "" && macSet(f.MACAddresses)[normalizeMAC(e.MAC)]
+}
+
+func (f *IPFilter) matches(p *Packet, e *PacketEndpoint) bool {
+	if f == nil {
+		return true
+	}
+	if f.Protocol != "all" && f.Protocol != p.Protocol {
+		return false
+	}
+	if f.EndPort != 0 && (p.Protocol == "icmp" || e.Port < f.BeginPort || e.Port > f.EndPort) {
+		return false
+	}
+
+	return subnetContains(subnet(f.Address, f.Mask), e.IP)
+}
+
+func (f *GTPFilter) matches(p *Packet, e *PacketEndpoint) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IMSIs) != 0 && !stringSet(f.IMSIs)[p.IMSI] {
+		return false
+	}
+
+	return subnetContains(subnet(f.Address, f.Mask), e.GTPAddress)
+}
+
+// subnetContains returns whether the address is in the subnet.
+func subnetContains(n *net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	return len(ip) == len(n.IP) && n.Contains(ip)
+}
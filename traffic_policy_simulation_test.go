@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Traffic policy simulation", func() {
+	var (
+		tp  *cce.TrafficPolicy
+		pkt *cce.Packet
+	)
+
+	BeforeEach(func() {
+		tp = &cce.TrafficPolicy{
+			ID:   "9d740cee-035f-4076-847c-d1c80cdf19db",
+			Name: "policy-1",
+			Rules: []*cce.TrafficRule{
+				{
+					Description: "accept-subscribers",
+					Priority:    2,
+					Source: &cce.TrafficSelector{
+						GTP: &cce.GTPFilter{
+							Address: "10.6.0.0",
+							Mask:    16,
+							IMSIs:   []string{"310150123456789"},
+						},
+					},
+					Destination: &cce.TrafficSelector{
+						IP: &cce.IPFilter{
+							Address:   "64.1.0.0",
+							Mask:      16,
+							BeginPort: 1000,
+							EndPort:   1012,
+							Protocol:  "tcp",
+						},
+					},
+					Target: &cce.TrafficTarget{
+						Action: "accept",
+						MAC:    &cce.MACModifier{MACAddress: "C7-5A-E7-98-1B-A3"},
+						IP:     &cce.IPModifier{Address: "123.2.3.4", Port: 1600},
+					},
+				},
+				{
+					Description: "drop-blocked-device",
+					Priority:    1,
+					Source: &cce.TrafficSelector{
+						MACs: &cce.MACFilter{MACAddresses: []string{"F0-59-8E-7B-36-8A"}},
+					},
+					Target: &cce.TrafficTarget{Action: "drop"},
+				},
+			},
+		}
+
+		pkt = &cce.Packet{
+			Source: cce.PacketEndpoint{
+				MAC:        "23:20:8e:15:89:d1",
+				IP:         "10.0.0.7",
+				Port:       40000,
+				GTPAddress: "10.6.7.2",
+			},
+			Destination: cce.PacketEndpoint{
+				IP:   "64.1.1.1",
+				Port: 1001,
+			},
+			Protocol: "tcp",
+			IMSI:     "310150123456789",
+		}
+	})
+
+	Describe("Validate", func() {
+		It("Should accept a valid packet", func() {
+			Expect(pkt.Validate()).To(Succeed())
+		})
+
+		It("Should return an error for an invalid protocol", func() {
+			pkt.Protocol = "all"
+			Expect(pkt.Validate()).To(MatchError("protocol must be one of [tcp, udp, icmp, sctp]"))
+		})
+
+		It("Should return an error for an invalid address", func() {
+			pkt.Destination.IP = "64.1.1"
+			Expect(pkt.Validate()).To(MatchError("destination.ip could not be parsed"))
+		})
+
+		It("Should return an error for an invalid IMSI", func() {
+			pkt.IMSI = "3101501234"
+			Expect(pkt.Validate()).To(MatchError("imsi must be 14 or 15 digits"))
+		})
+	})
+
+	Describe("Match", func() {
+		It("Should match the rule with the highest priority", func() {
+			pkt.Source.MAC = "f0:59:8e:7b:36:8a"
+			rule, i := tp.Match(pkt)
+			Expect(i).To(Equal(1))
+			Expect(rule.Target.Action).To(Equal("drop"))
+		})
+
+		DescribeTable("Should evaluate the filters of the rules",
+			func(modify func(*cce.Packet), matched int) {
+				modify(pkt)
+				_, i := tp.Match(pkt)
+				Expect(i).To(Equal(matched))
+			},
+			Entry("matching packet", func(*cce.Packet) {}, 0),
+			Entry("other port", func(p *cce.Packet) { p.Destination.Port = 1013 }, -1),
+			Entry("other protocol", func(p *cce.Packet) { p.Protocol = "udp" }, -1),
+			Entry("other subnet", func(p *cce.Packet) { p.Destination.IP = "64.2.1.1" }, -1),
+			Entry("other IMSI", func(p *cce.Packet) { p.IMSI = "310150123456790" }, -1),
+			Entry("no GTP tunnel", func(p *cce.Packet) { p.Source.GTPAddress = "" }, -1),
+			Entry("IPv4-mapped destination", func(p *cce.Packet) { p.Destination.IP = "::ffff:4001:101" }, 0),
+		)
+	})
+})
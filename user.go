@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/open-ness/edgecontroller/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is a user of the controller API.
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the password. It is empty for users
+	// that log in with an external identity provider.
+	PasswordHash string   `json:"password_hash"`
+	Roles        []string `json:"roles"`
+	// ExternalID identifies a user that logs in with an external identity
+	// provider as the issuer URL and subject separated by a space.
+	ExternalID string `json:"external_id,omitempty"`
+
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
+}
+
+// GetTableName returns the name of the persistence table.
+func (*User) GetTableName() string {
+	return "users"
+}
+
+// GetID gets the ID.
+func (u *User) GetID() string {
+	return u.ID
+}
+
+// SetID sets the ID.
+func (u *User) SetID(id string) {
+	u.ID = id
+}
+
+// GetResourceVersion gets the resource version.
+func (u *User) GetResourceVersion() uint64 {
+	return u.ResourceVersion
+}
+
+// SetResourceVersion sets the resource version.
+func (u *User) SetResourceVersion(v uint64) {
+	u.ResourceVersion = v
+}
+
+// SetPassword sets the password hash from a plaintext password.
+func (u *User) SetPassword(password string) error {
+	if password == "" {
+		return errors.New("password cannot be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+
+	return nil
+}
+
+// CheckPassword returns whether the plaintext password matches the password
+// hash.
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
+
+// Validate validates the model.
+func (u *User) Validate() error {
+	if !uuid.IsValid(u.ID) {
+		return errors.New("id not a valid uuid")
+	}
+	if u.Username == "" {
+		return errors.New("username cannot be empty")
+	}
+	if len(u.Username) > 255 {
+		return errors.New("username cannot be longer than 255 characters")
+	}
+	if u.PasswordHash == "" && u.ExternalID == "" {
+		return errors.New("password_hash cannot be empty")
+	}
+	if len(u.Roles) == 0 {
+		return errors.New("roles cannot be empty")
+	}
+	for i, role := range u.Roles {
+		if !IsValidRole(role) {
+			return fmt.Errorf("roles[%d] %q is not a valid role", i, role)
+		}
+	}
+
+	return nil
+}
+
+// FilterFields returns the filterable fields for this model.
+func (*User) FilterFields() []string {
+	return []string{
+		"username",
+		"external_id",
+	}
+}
+
+func (u *User) String() string {
+	return fmt.Sprintf(strings.TrimSpace(`
+User[
+    ID: %s
+    Username: %s
+    Roles: %v
+]`),
+		u.ID,
+		u.Username,
+		u.Roles)
+}
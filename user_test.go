@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright (c) 2019 Intel Corporation
+
+package cce_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	cce "github.com/open-ness/edgecontroller"
+)
+
+var _ = Describe("Entities: User", func() {
+	var (
+		user *cce.User
+	)
+
+	BeforeEach(func() {
+		user = &cce.User{
+			ID:       "b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54",
+			Username: "test-user",
+			Roles:    []string{cce.RoleViewer},
+		}
+		Expect(user.SetPassword("test-password")).To(Succeed())
+	})
+
+	Describe("GetTableName", func() {
+		It(`Should return "users"`, func() {
+			Expect(user.GetTableName()).To(Equal("users"))
+		})
+	})
+
+	Describe("GetID", func() {
+		It("Should return the ID", func() {
+			Expect(user.GetID()).To(Equal(
+				"b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54"))
+		})
+	})
+
+	Describe("SetID", func() {
+		It("Should set and return the updated ID", func() {
+			By("Setting the ID")
+			user.SetID("456")
+
+			By("Getting the updated ID")
+			Expect(user.ID).To(Equal("456"))
+		})
+	})
+
+	Describe("SetPassword and CheckPassword", func() {
+		It("Should not store the plaintext password", func() {
+			Expect(user.PasswordHash).ToNot(ContainSubstring("test-password"))
+		})
+
+		It("Should match the password", func() {
+			Expect(user.CheckPassword("test-password")).To(BeTrue())
+			Expect(user.CheckPassword("wrong-password")).To(BeFalse())
+		})
+
+		It("Should reject an empty password", func() {
+			Expect(user.SetPassword("")).To(MatchError("password cannot be empty"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("Should not return an error", func() {
+			Expect(user.Validate()).To(Succeed())
+		})
+
+		It("Should return an error if ID is not a UUID", func() {
+			user.ID = "123"
+			Expect(user.Validate()).To(MatchError("id not a valid uuid"))
+		})
+
+		It("Should return an error if Username is empty", func() {
+			user.Username = ""
+			Expect(user.Validate()).To(MatchError("username cannot be empty"))
+		})
+
+		It("Should return an error if PasswordHash is empty", func() {
+			user.PasswordHash = ""
+			Expect(user.Validate()).To(MatchError("password_hash cannot be empty"))
+		})
+
+		It("Should not require a PasswordHash for external users", func() {
+			user.PasswordHash = ""
+			user.ExternalID = "https://idp.example.com test-subject"
+			Expect(user.Validate()).To(Succeed())
+			Expect(user.CheckPassword("")).To(BeFalse())
+		})
+
+		It("Should return an error if Roles is empty", func() {
+			user.Roles = nil
+			Expect(user.Validate()).To(MatchError("roles cannot be empty"))
+		})
+
+		It("Should return an error if a role is not valid", func() {
+			user.Roles = []string{cce.RoleViewer, "root"}
+			Expect(user.Validate()).To(MatchError(`roles[1] "root" is not a valid role`))
+		})
+	})
+
+	Describe("FilterFields", func() {
+		It("Should return the filterable fields", func() {
+			Expect(user.FilterFields()).To(Equal([]string{
+				"username",
+				"external_id",
+			}))
+		})
+	})
+
+	Describe("String", func() {
+		It("Should return the string value", func() {
+			Expect(user.String()).To(Equal(strings.TrimSpace(`
+User[
+    ID: b9d7ba70-0d4e-4e4a-9b8c-36c9d3ab6a54
+    Username: test-user
+    Roles: [viewer]
+]`,
+			)))
+		})
+	})
+})
+
+var _ = Describe("HasPermission", func() {
+	It("Should only let viewers read", func() {
+		roles := []string{cce.RoleViewer}
+		Expect(cce.HasPermission(roles, cce.PermissionRead)).To(BeTrue())
+		Expect(cce.HasPermission(roles, cce.PermissionWrite)).To(BeFalse())
+		Expect(cce.HasPermission(roles, cce.PermissionAdmin)).To(BeFalse())
+	})
+
+	It("Should let operators read and write", func() {
+		roles := []string{cce.RoleOperator}
+		Expect(cce.HasPermission(roles, cce.PermissionRead)).To(BeTrue())
+		Expect(cce.HasPermission(roles, cce.PermissionWrite)).To(BeTrue())
+		Expect(cce.HasPermission(roles, cce.PermissionAdmin)).To(BeFalse())
+	})
+
+	It("Should let admins do everything", func() {
+		roles := []string{cce.RoleAdmin}
+		Expect(cce.HasPermission(roles, cce.PermissionAdmin)).To(BeTrue())
+	})
+
+	It("Should grant nothing to unknown roles", func() {
+		Expect(cce.HasPermission([]string{"root"}, cce.PermissionRead)).To(BeFalse())
+	})
+})